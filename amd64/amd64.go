@@ -0,0 +1,668 @@
+package amd64
+
+import (
+	"compiler/ast"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/*
+x86-64 assembly (AT&T syntax, GNU as) of a checked program, for Linux and the System V calling convention.
+Build it with the C library, which does the output, and libm for the extern math functions:
+
+	gcc program.s -o program -lm
+
+Types: int and bool are 32 bit in %eax, string is a pointer to a NUL terminated string in %rax,
+double is in %xmm0. Expressions leave their value there and push intermediate values on the stack.
+Every variable has its own 8 byte slot below %rbp, parameters are copied there at the start of the function.
+Functions are called like C functions: the first 6 int/bool/string arguments in %rdi, %rsi, %rdx, %rcx, %r8, %r9,
+the first 8 doubles in %xmm0 - %xmm7, the others on the stack. Functions of the program get the prefix neon_,
+extern functions are called by their own name, Sqrt, Pow, Floor, ParseInt and ReadLine are included.
+main calls Main with null for the arguments.
+*/
+
+var intRegisters = []string{"%rdi", "%rsi", "%rdx", "%rcx", "%r8", "%r9"}
+
+const floatRegisters = 8
+
+func Generate(program *ast.Program) (string, error) {
+	g := &generator{program: program, functions: make(map[string]*ast.Function), strings: make(map[string]string), doubles: make(map[uint64]string)}
+	for _, function := range program.Functions {
+		g.functions[function.Name] = function
+	}
+	main := g.functions["Main"]
+	if main == nil {
+		main = g.functions["main"]
+	}
+	if main == nil || main.Extern {
+		return "", errors.New("Assembly Error: No Main function found")
+	}
+
+	var text strings.Builder
+	text.WriteString("\t.text\n\t.globl main\n")
+	for _, function := range program.Functions {
+		if function.Extern {
+			if host, ok := hostFunctions[function.Name]; ok {
+				text.WriteString(host)
+			}
+			continue
+		}
+		code, err := g.generateFunction(function)
+		if err != nil {
+			return "", err
+		}
+		text.WriteString(code)
+	}
+	text.WriteString("\nmain:\n\tsubq $8, %rsp\n")
+	if len(main.Params) == 1 {
+		text.WriteString("\txorl %edi, %edi\n")
+	}
+	text.WriteString("\tcall " + symbol(main) + "\n\txorl %eax, %eax\n\taddq $8, %rsp\n\tret\n")
+	text.WriteString(runtime)
+
+	text.WriteString(runtimeData)
+	for _, literal := range g.literals {
+		text.WriteString(g.strings[literal] + ":\n\t.string \"" + escape(literal) + "\"\n")
+	}
+	if len(g.doubleBits) > 0 {
+		text.WriteString("\t.align 8\n")
+	}
+	for _, bits := range g.doubleBits {
+		text.WriteString(g.doubles[bits] + ":\n\t.quad 0x" + strconv.FormatUint(bits, 16) + "\n")
+	}
+	text.WriteString("\t.section .note.GNU-stack,\"\",@progbits\n")
+	return text.String(), nil
+}
+
+type generator struct {
+	program   *ast.Program
+	functions map[string]*ast.Function
+	// Labels of the string and double constants, in the order they were found
+	strings    map[string]string
+	literals   []string
+	doubles    map[uint64]string
+	doubleBits []uint64
+	labels     int
+
+	// Of the function being generated
+	function *ast.Function
+	scopes   []map[string]local
+	slots    int
+	// 8 byte values pushed since the prologue, calls need the stack aligned to 16 bytes
+	depth int
+	code  strings.Builder
+}
+
+type local struct {
+	// Offset from %rbp
+	offset int
+	typ    string
+}
+
+// Unsupported programs panic with a generateError, only possible if they did not pass the checks
+type generateError struct {
+	message string
+}
+
+func (g *generator) generateFunction(function *ast.Function) (code string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			generateErr, ok := r.(generateError)
+			if !ok {
+				panic(r)
+			}
+			err = errors.New("Assembly Error: " + generateErr.message)
+		}
+	}()
+
+	g.function = function
+	g.scopes = []map[string]local{{}}
+	g.slots = 0
+	g.depth = 0
+	g.code.Reset()
+
+	ints, floats, stack := 0, 0, 0
+	for _, param := range function.Params {
+		variable := g.declare(param.Name, param.Type)
+		switch {
+		case param.Type == "double" && floats < floatRegisters:
+			g.emit("movsd %xmm" + strconv.Itoa(floats) + ", " + slot(variable))
+			floats++
+		case param.Type != "double" && ints < len(intRegisters):
+			g.emit("movq " + intRegisters[ints] + ", " + slot(variable))
+			ints++
+		default:
+			// Above the return address and the saved %rbp
+			g.emit("movq " + strconv.Itoa(16+8*stack) + "(%rbp), %rax")
+			g.emit("movq %rax, " + slot(variable))
+			stack++
+		}
+	}
+	g.block(function.Body)
+	if function.ReturnType != "void" {
+		g.emit("leaq " + g.stringLiteral(function.Name) + "(%rip), %rdi")
+		g.emit("call neon_no_return")
+	}
+
+	frame := (8*g.slots + 15) &^ 15
+	header := "\n" + symbol(function) + ":\n\tpushq %rbp\n\tmovq %rsp, %rbp\n"
+	if frame > 0 {
+		header += "\tsubq $" + strconv.Itoa(frame) + ", %rsp\n"
+	}
+	return header + g.code.String() + g.returnLabel() + ":\n\tleave\n\tret\n", nil
+}
+
+func (g *generator) returnLabel() string {
+	return ".Lreturn_" + symbol(g.function)
+}
+
+func (g *generator) block(block *ast.Block) {
+	g.scopes = append(g.scopes, map[string]local{})
+	for _, statement := range block.Statements {
+		g.statement(statement)
+	}
+	g.scopes = g.scopes[:len(g.scopes)-1]
+}
+
+func (g *generator) statement(statement ast.Stmt) {
+	switch s := statement.(type) {
+	case *ast.VarDecl:
+		if s.Value == nil {
+			g.zero(s.Type)
+		} else {
+			g.convert(g.expr(s.Value), s.Type)
+		}
+		g.store(g.declare(s.Name, s.Type))
+	case *ast.Assign:
+		variable := g.lookup(s.Name)
+		g.convert(g.expr(s.Value), variable.typ)
+		g.store(variable)
+	case *ast.CallStmt:
+		g.call(s.Call)
+	case *ast.Return:
+		if s.Value != nil {
+			g.convert(g.expr(s.Value), g.function.ReturnType)
+		}
+		g.emit("jmp " + g.returnLabel())
+	case *ast.If:
+		elseLabel, endLabel := g.label(), g.label()
+		g.condition(s.Cond)
+		g.emit("je " + elseLabel)
+		g.block(s.Then)
+		g.emit("jmp " + endLabel)
+		g.code.WriteString(elseLabel + ":\n")
+		if s.Else != nil {
+			g.block(s.Else)
+		}
+		g.code.WriteString(endLabel + ":\n")
+	case *ast.While:
+		startLabel, endLabel := g.label(), g.label()
+		g.code.WriteString(startLabel + ":\n")
+		g.condition(s.Cond)
+		g.emit("je " + endLabel)
+		g.block(s.Body)
+		g.emit("jmp " + startLabel)
+		g.code.WriteString(endLabel + ":\n")
+	}
+}
+
+// Evaluates the condition and sets the zero flag if it is false
+func (g *generator) condition(cond ast.Expr) {
+	if typ := g.expr(cond); typ != "bool" {
+		panic(generateError{"Condition is not a bool, at line " + strconv.Itoa(cond.Pos().Line)})
+	}
+	g.emit("testl %eax, %eax")
+}
+
+// Emits the expression and returns its type
+func (g *generator) expr(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.IntLit:
+		g.emit("movl $" + strconv.Itoa(int(int32(e.Value))) + ", %eax")
+		return "int"
+	case *ast.DoubleLit:
+		g.emit("movsd " + g.doubleConstant(e.Value) + "(%rip), %xmm0")
+		return "double"
+	case *ast.BoolLit:
+		if e.Value {
+			g.emit("movl $1, %eax")
+		} else {
+			g.emit("xorl %eax, %eax")
+		}
+		return "bool"
+	case *ast.StringLit:
+		g.emit("leaq " + g.stringLiteral(e.Value) + "(%rip), %rax")
+		return "string"
+	case *ast.InterpolatedString:
+		g.emit("leaq " + g.stringLiteral("") + "(%rip), %rax")
+		for _, part := range e.Parts {
+			g.push("string")
+			if part.Expr == nil {
+				g.emit("leaq " + g.stringLiteral(part.Text) + "(%rip), %rax")
+			} else {
+				g.toString(g.expr(part.Expr))
+			}
+			g.emit("movq %rax, %rsi")
+			g.pop("%rdi")
+			g.callRuntime("neon_concat")
+		}
+		return "string"
+	case *ast.Ident:
+		variable := g.lookup(e.Name)
+		switch variable.typ {
+		case "double":
+			g.emit("movsd " + slot(variable) + ", %xmm0")
+		case "int", "bool":
+			g.emit("movl " + slot(variable) + ", %eax")
+		default:
+			g.emit("movq " + slot(variable) + ", %rax")
+		}
+		return variable.typ
+	case *ast.Unary:
+		typ := g.expr(e.Operand)
+		if e.Op == "-" {
+			if typ == "double" {
+				// Flip the sign bit
+				g.emit("movq %xmm0, %rax")
+				g.emit("btcq $63, %rax")
+				g.emit("movq %rax, %xmm0")
+			} else {
+				g.emit("negl %eax")
+			}
+		}
+		return typ
+	case *ast.Binary:
+		return g.binary(e)
+	case *ast.Call:
+		typ := g.call(e)
+		if typ == "void" {
+			panic(generateError{"The void function " + e.FullName() + " has no value, at line " + strconv.Itoa(e.Pos().Line)})
+		}
+		return typ
+	}
+	panic(generateError{"Unknown expression at line " + strconv.Itoa(expr.Pos().Line)})
+}
+
+var intInstructions = map[string]string{"+": "addl %ecx, %eax", "-": "subl %ecx, %eax", "*": "imull %ecx, %eax"}
+
+var doubleInstructions = map[string]string{"+": "addsd", "-": "subsd", "*": "mulsd", "/": "divsd"}
+
+// setcc after cmpl right, left
+var intComparisons = map[string]string{"<": "setl", ">": "setg", "<=": "setle", ">=": "setge", "==": "sete", "!=": "setne"}
+
+func (g *generator) binary(e *ast.Binary) string {
+	left, right := g.typeOf(e.Left), g.typeOf(e.Right)
+	switch {
+	case e.Op == "&&" || e.Op == "||":
+		// Short circuit: the right side is only evaluated if needed
+		shortLabel, endLabel := g.label(), g.label()
+		g.condition(e.Left)
+		if e.Op == "&&" {
+			g.emit("je " + shortLabel)
+		} else {
+			g.emit("jne " + shortLabel)
+		}
+		g.condition(e.Right)
+		g.emit("jmp " + endLabel)
+		g.code.WriteString(shortLabel + ":\n")
+		if e.Op == "&&" {
+			g.emit("xorl %eax, %eax")
+		} else {
+			g.emit("movl $1, %eax")
+		}
+		g.code.WriteString(endLabel + ":\n")
+	case e.Op == "+" && (left == "string" || right == "string"):
+		g.toString(g.expr(e.Left))
+		g.push("string")
+		g.toString(g.expr(e.Right))
+		g.emit("movq %rax, %rsi")
+		g.pop("%rdi")
+		g.callRuntime("neon_concat")
+	case (e.Op == "==" || e.Op == "!=") && left == "string":
+		g.expr(e.Left)
+		g.push("string")
+		g.expr(e.Right)
+		g.emit("movq %rax, %rsi")
+		g.pop("%rdi")
+		g.callRuntime("neon_string_equals")
+		if e.Op == "!=" {
+			g.emit("xorl $1, %eax")
+		}
+	case left == "double" || right == "double":
+		g.convert(g.expr(e.Left), "double")
+		g.push("double")
+		g.convert(g.expr(e.Right), "double")
+		g.emit("movapd %xmm0, %xmm1")
+		g.pop("%xmm0")
+		g.doubleOperation(e.Op)
+	default:
+		g.expr(e.Left)
+		g.push("int")
+		g.expr(e.Right)
+		g.emit("movl %eax, %ecx")
+		g.pop("%rax")
+		g.intOperation(e.Op)
+	}
+	return g.typeOf(e)
+}
+
+// Left in %eax, right in %ecx
+func (g *generator) intOperation(op string) {
+	switch op {
+	case "/", "%":
+		// idivl traps on 0 and on int.MinValue / -1, the interpreter stops with an error on 0 and wraps around on -1
+		divideLabel, minusOneLabel, endLabel := g.label(), g.label(), g.label()
+		g.emit("testl %ecx, %ecx")
+		g.emit("jne " + divideLabel)
+		g.callRuntime("neon_division_by_zero")
+		g.code.WriteString(divideLabel + ":\n")
+		g.emit("cmpl $-1, %ecx")
+		g.emit("je " + minusOneLabel)
+		g.emit("cltd")
+		g.emit("idivl %ecx")
+		if op == "%" {
+			g.emit("movl %edx, %eax")
+		}
+		g.emit("jmp " + endLabel)
+		g.code.WriteString(minusOneLabel + ":\n")
+		if op == "/" {
+			g.emit("negl %eax")
+		} else {
+			g.emit("xorl %eax, %eax")
+		}
+		g.code.WriteString(endLabel + ":\n")
+	case "+", "-", "*":
+		g.emit(intInstructions[op])
+	default:
+		g.emit("cmpl %ecx, %eax")
+		g.emit(intComparisons[op] + " %al")
+		g.emit("movzbl %al, %eax")
+	}
+}
+
+// Left in %xmm0, right in %xmm1. Comparisons with NaN are false, except !=
+func (g *generator) doubleOperation(op string) {
+	switch op {
+	case "+", "-", "*", "/":
+		g.emit(doubleInstructions[op] + " %xmm1, %xmm0")
+	case "%":
+		g.callRuntime("fmod@PLT")
+	case ">", ">=":
+		g.emit("ucomisd %xmm1, %xmm0")
+		g.emit(map[string]string{">": "seta", ">=": "setae"}[op] + " %al")
+		g.emit("movzbl %al, %eax")
+	case "<", "<=":
+		g.emit("ucomisd %xmm0, %xmm1")
+		g.emit(map[string]string{"<": "seta", "<=": "setae"}[op] + " %al")
+		g.emit("movzbl %al, %eax")
+	case "==":
+		g.emit("ucomisd %xmm1, %xmm0")
+		g.emit("sete %al")
+		g.emit("setnp %cl")
+		g.emit("andb %cl, %al")
+		g.emit("movzbl %al, %eax")
+	case "!=":
+		g.emit("ucomisd %xmm1, %xmm0")
+		g.emit("setne %al")
+		g.emit("setp %cl")
+		g.emit("orb %cl, %al")
+		g.emit("movzbl %al, %eax")
+	}
+}
+
+// Returns the return type of the call
+func (g *generator) call(call *ast.Call) string {
+	if function, ok := g.functions[call.Name]; ok && (call.Receiver == "" || call.Receiver == g.program.Class) {
+		g.callFunction(function, call.Args)
+		return function.ReturnType
+	}
+	switch call.FullName() {
+	case "Console.WriteLine", "Console.Write":
+		if len(call.Args) == 0 {
+			g.emit("leaq " + g.stringLiteral("") + "(%rip), %rax")
+		} else {
+			g.toString(g.expr(call.Args[0]))
+		}
+		g.emit("movq %rax, %rdi")
+		if call.Name == "WriteLine" {
+			g.emit("movl $1, %esi")
+		} else {
+			g.emit("xorl %esi, %esi")
+		}
+		g.callRuntime("neon_print")
+		return "void"
+	}
+	panic(generateError{"Function " + call.FullName() + " does not exist, at line " + strconv.Itoa(call.Pos().Line)})
+}
+
+// Pushes all arguments, then moves them into the registers or copies them to the top of the stack
+func (g *generator) callFunction(function *ast.Function, args []ast.Expr) {
+	for i, arg := range args {
+		g.convert(g.expr(arg), function.Params[i].Type)
+		g.push(function.Params[i].Type)
+	}
+	// Offset of the argument from %rsp, after all were pushed
+	offset := func(i int) int {
+		return 8 * (len(args) - 1 - i)
+	}
+	ints, floats := 0, 0
+	stack := []int{}
+	for i, param := range function.Params {
+		switch {
+		case param.Type == "double" && floats < floatRegisters:
+			g.emit("movsd " + strconv.Itoa(offset(i)) + "(%rsp), %xmm" + strconv.Itoa(floats))
+			floats++
+		case param.Type != "double" && ints < len(intRegisters):
+			g.emit("movq " + strconv.Itoa(offset(i)) + "(%rsp), " + intRegisters[ints])
+			ints++
+		default:
+			stack = append(stack, i)
+		}
+	}
+	padding := (g.depth + len(stack)) % 2
+	if padding == 1 {
+		g.emit("subq $8, %rsp")
+	}
+	// The first stack argument has to be on top, so they are pushed from the last one
+	for j := len(stack) - 1; j >= 0; j-- {
+		pushed := len(stack) - 1 - j
+		g.emit("pushq " + strconv.Itoa(offset(stack[j])+8*(padding+pushed)) + "(%rsp)")
+	}
+	if function.Extern {
+		g.emit("call " + function.Name)
+	} else {
+		g.emit("call " + symbol(function))
+	}
+	if size := 8 * (len(args) + len(stack) + padding); size > 0 {
+		g.emit("addq $" + strconv.Itoa(size) + ", %rsp")
+	}
+	g.depth -= len(args)
+}
+
+// Calls a function without arguments on the stack, with the stack aligned
+func (g *generator) callRuntime(name string) {
+	if g.depth%2 == 1 {
+		g.emit("subq $8, %rsp")
+		g.emit("call " + name)
+		g.emit("addq $8, %rsp")
+		return
+	}
+	g.emit("call " + name)
+}
+
+func (g *generator) push(typ string) {
+	if typ == "double" {
+		g.emit("subq $8, %rsp")
+		g.emit("movsd %xmm0, (%rsp)")
+	} else {
+		g.emit("pushq %rax")
+	}
+	g.depth++
+}
+
+func (g *generator) pop(register string) {
+	if strings.HasPrefix(register, "%xmm") {
+		g.emit("movsd (%rsp), " + register)
+		g.emit("addq $8, %rsp")
+	} else {
+		g.emit("popq " + register)
+	}
+	g.depth--
+}
+
+func (g *generator) store(variable local) {
+	if variable.typ == "double" {
+		g.emit("movsd %xmm0, " + slot(variable))
+	} else {
+		g.emit("movq %rax, " + slot(variable))
+	}
+}
+
+// Type of the expression without emitting anything, the same rules as in package types
+func (g *generator) typeOf(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.IntLit:
+		return "int"
+	case *ast.DoubleLit:
+		return "double"
+	case *ast.BoolLit:
+		return "bool"
+	case *ast.StringLit, *ast.InterpolatedString:
+		return "string"
+	case *ast.Ident:
+		return g.lookup(e.Name).typ
+	case *ast.Unary:
+		return g.typeOf(e.Operand)
+	case *ast.Binary:
+		switch e.Op {
+		case "&&", "||", "<", ">", "<=", ">=", "==", "!=":
+			return "bool"
+		}
+		left, right := g.typeOf(e.Left), g.typeOf(e.Right)
+		switch {
+		case e.Op == "+" && (left == "string" || right == "string"):
+			return "string"
+		case left == "double" || right == "double":
+			return "double"
+		}
+		return "int"
+	case *ast.Call:
+		if function, ok := g.functions[e.Name]; ok && (e.Receiver == "" || e.Receiver == g.program.Class) {
+			return function.ReturnType
+		}
+		return "void"
+	}
+	return "void"
+}
+
+func (g *generator) toString(typ string) {
+	switch typ {
+	case "int":
+		g.emit("movl %eax, %edi")
+		g.callRuntime("neon_int_to_string")
+	case "double":
+		g.callRuntime("neon_double_to_string")
+	case "bool":
+		g.emit("movl %eax, %edi")
+		g.callRuntime("neon_bool_to_string")
+	}
+}
+
+// The only implicit conversion: int to double
+func (g *generator) convert(from string, to string) {
+	if from == "int" && to == "double" {
+		g.emit("cvtsi2sdl %eax, %xmm0")
+	}
+}
+
+func (g *generator) zero(typ string) {
+	switch typ {
+	case "double":
+		g.emit("xorpd %xmm0, %xmm0")
+	case "string":
+		g.emit("leaq " + g.stringLiteral("") + "(%rip), %rax")
+	default:
+		g.emit("xorl %eax, %eax")
+	}
+}
+
+func (g *generator) declare(name string, typ string) local {
+	g.slots++
+	variable := local{offset: -8 * g.slots, typ: typ}
+	g.scopes[len(g.scopes)-1][name] = variable
+	return variable
+}
+
+func (g *generator) lookup(name string) local {
+	for i := len(g.scopes) - 1; i >= 0; i-- {
+		if variable, ok := g.scopes[i][name]; ok {
+			return variable
+		}
+	}
+	panic(generateError{"Variable " + name + " is not declared"})
+}
+
+func (g *generator) stringLiteral(text string) string {
+	if label, ok := g.strings[text]; ok {
+		return label
+	}
+	label := ".LS" + strconv.Itoa(len(g.literals))
+	g.strings[text] = label
+	g.literals = append(g.literals, text)
+	return label
+}
+
+// Doubles can not be immediate operands, they are loaded from the data section
+func (g *generator) doubleConstant(value float64) string {
+	bits := math.Float64bits(value)
+	if label, ok := g.doubles[bits]; ok {
+		return label
+	}
+	label := ".LD" + strconv.Itoa(len(g.doubleBits))
+	g.doubles[bits] = label
+	g.doubleBits = append(g.doubleBits, bits)
+	return label
+}
+
+func (g *generator) label() string {
+	g.labels++
+	return ".L" + strconv.Itoa(g.labels)
+}
+
+func (g *generator) emit(instruction string) {
+	g.code.WriteString("\t" + instruction + "\n")
+}
+
+func slot(variable local) string {
+	return strconv.Itoa(variable.offset) + "(%rbp)"
+}
+
+// Symbols can only contain ASCII letters, digits, _ and ., other letters are written as _uXXXX
+func symbol(function *ast.Function) string {
+	var name strings.Builder
+	name.WriteString("neon_")
+	for _, c := range function.Name {
+		if c < 0x80 && (c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			name.WriteRune(c)
+		} else {
+			fmt.Fprintf(&name, "_u%04X", c)
+		}
+	}
+	return name.String()
+}
+
+// Bytes which are not printable ASCII are written as octal escapes
+func escape(text string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(text); i++ {
+		if c := text[i]; c >= 0x20 && c < 0x7f && c != '"' && c != '\\' {
+			escaped.WriteByte(c)
+		} else {
+			fmt.Fprintf(&escaped, "\\%03o", c)
+		}
+	}
+	return escaped.String()
+}
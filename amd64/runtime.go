@@ -0,0 +1,240 @@
+package amd64
+
+// Runtime functions every program contains. Strings are NUL terminated and never freed
+const runtime = `
+# neon_concat(a, b): new string a + b
+neon_concat:
+	pushq %rbp
+	movq %rsp, %rbp
+	subq $32, %rsp
+	movq %rdi, -8(%rbp)
+	movq %rsi, -16(%rbp)
+	call strlen@PLT
+	movq %rax, -24(%rbp)
+	movq -16(%rbp), %rdi
+	call strlen@PLT
+	addq -24(%rbp), %rax
+	leaq 1(%rax), %rdi
+	call malloc@PLT
+	movq %rax, -32(%rbp)
+	movq %rax, %rdi
+	movq -8(%rbp), %rsi
+	call strcpy@PLT
+	movq -32(%rbp), %rdi
+	movq -16(%rbp), %rsi
+	call strcat@PLT
+	movq -32(%rbp), %rax
+	leave
+	ret
+
+# neon_string_equals(a, b): 1 if the strings are equal
+neon_string_equals:
+	subq $8, %rsp
+	call strcmp@PLT
+	testl %eax, %eax
+	sete %al
+	movzbl %al, %eax
+	addq $8, %rsp
+	ret
+
+# neon_int_to_string(i)
+neon_int_to_string:
+	pushq %rbp
+	movq %rsp, %rbp
+	subq $16, %rsp
+	movl %edi, -4(%rbp)
+	movl $16, %edi
+	call malloc@PLT
+	movq %rax, -16(%rbp)
+	movq %rax, %rdi
+	movl $16, %esi
+	leaq .Lint_format(%rip), %rdx
+	movl -4(%rbp), %ecx
+	xorl %eax, %eax
+	call snprintf@PLT
+	movq -16(%rbp), %rax
+	leave
+	ret
+
+# neon_bool_to_string(b): True or False, like C#
+neon_bool_to_string:
+	leaq .Ltrue(%rip), %rax
+	leaq .Lfalse(%rip), %rdx
+	testl %edi, %edi
+	cmove %rdx, %rax
+	ret
+
+# neon_double_to_string(d): the fewest decimals which read back as the same double, without exponent like C#.
+# Very large numbers can show different digits after the 17th
+neon_double_to_string:
+	pushq %rbp
+	movq %rsp, %rbp
+	subq $32, %rsp
+	movsd %xmm0, -8(%rbp)
+	ucomisd %xmm0, %xmm0
+	jp .Lnan
+	movsd .Linfinity(%rip), %xmm1
+	ucomisd %xmm1, %xmm0
+	je .Linf
+	xorpd %xmm2, %xmm2
+	subsd %xmm1, %xmm2
+	ucomisd %xmm2, %xmm0
+	je .Lminus_inf
+	movl $720, %edi
+	call malloc@PLT
+	movq %rax, -16(%rbp)
+	movl $0, -20(%rbp)
+.Lprecision:
+	movq -16(%rbp), %rdi
+	movl $720, %esi
+	leaq .Ldouble_format(%rip), %rdx
+	movl -20(%rbp), %ecx
+	movsd -8(%rbp), %xmm0
+	movl $1, %eax
+	call snprintf@PLT
+	cmpl $340, -20(%rbp)
+	jge .Lformatted
+	movq -16(%rbp), %rdi
+	xorl %esi, %esi
+	call strtod@PLT
+	ucomisd -8(%rbp), %xmm0
+	jp .Lmore
+	je .Lformatted
+.Lmore:
+	incl -20(%rbp)
+	jmp .Lprecision
+.Lformatted:
+	movq -16(%rbp), %rax
+	leave
+	ret
+.Lnan:
+	leaq .Lnan_string(%rip), %rax
+	leave
+	ret
+.Linf:
+	leaq .Linf_string(%rip), %rax
+	leave
+	ret
+.Lminus_inf:
+	leaq .Lminus_inf_string(%rip), %rax
+	leave
+	ret
+
+# neon_print(s, newline)
+neon_print:
+	pushq %rbp
+	movq %rsp, %rbp
+	subq $16, %rsp
+	movl %esi, -4(%rbp)
+	movq %rdi, %rsi
+	leaq .Lstring_format(%rip), %rdi
+	xorl %eax, %eax
+	call printf@PLT
+	cmpl $0, -4(%rbp)
+	je .Lprinted
+	movl $10, %edi
+	call putchar@PLT
+.Lprinted:
+	leave
+	ret
+
+# Like the interpreter: a function which should return a value must not end
+neon_no_return:
+	subq $8, %rsp
+	movq %rdi, %rsi
+	leaq .Lno_return_format(%rip), %rdi
+	xorl %eax, %eax
+	call printf@PLT
+	movl $1, %edi
+	call exit@PLT
+
+# Like the interpreter: stops with an error, after the output printed so far
+neon_division_by_zero:
+	subq $8, %rsp
+	movq stdout@GOTPCREL(%rip), %rdi
+	movq (%rdi), %rdi
+	call fflush@PLT
+	leaq .Ldivision_by_zero(%rip), %rdi
+	xorl %eax, %eax
+	call printf@PLT
+	movl $1, %edi
+	call exit@PLT
+`
+
+const runtimeData = `
+	.section .rodata
+.Lint_format:
+	.string "%d"
+.Ldouble_format:
+	.string "%.*f"
+.Lstring_format:
+	.string "%s"
+.Ltrue:
+	.string "True"
+.Lfalse:
+	.string "False"
+.Lnan_string:
+	.string "NaN"
+.Linf_string:
+	.string "\342\210\236"
+.Lminus_inf_string:
+	.string "-\342\210\236"
+.Lempty:
+	.string ""
+.Lline_end:
+	.string "\r\n"
+.Lno_return_format:
+	.string "[E0401] Runtime Error: Function %s ended without returning a value\n"
+.Ldivision_by_zero:
+	.string "[E0401] Runtime Error: Division by zero\n"
+	.align 8
+.Linfinity:
+	.quad 0x7ff0000000000000
+`
+
+// Host functions bound to extern declarations by default, like interp.HostFunctions
+var hostFunctions = map[string]string{
+	"Sqrt": `
+Sqrt:
+	jmp sqrt@PLT
+`,
+	"Pow": `
+Pow:
+	jmp pow@PLT
+`,
+	"Floor": `
+Floor:
+	jmp floor@PLT
+`,
+	"ParseInt": `
+ParseInt:
+	jmp atoi@PLT
+`,
+	"ReadLine": `
+ReadLine:
+	pushq %rbp
+	movq %rsp, %rbp
+	subq $16, %rsp
+	movq $0, -8(%rbp)
+	movq $0, -16(%rbp)
+	leaq -8(%rbp), %rdi
+	leaq -16(%rbp), %rsi
+	movq stdin@GOTPCREL(%rip), %rdx
+	movq (%rdx), %rdx
+	call getline@PLT
+	testq %rax, %rax
+	jg .Lread
+	leaq .Lempty(%rip), %rax
+	leave
+	ret
+.Lread:
+	movq -8(%rbp), %rdi
+	leaq .Lline_end(%rip), %rsi
+	call strcspn@PLT
+	movq -8(%rbp), %rdi
+	movb $0, (%rdi,%rax)
+	movq %rdi, %rax
+	leave
+	ret
+`,
+}
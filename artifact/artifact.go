@@ -0,0 +1,291 @@
+package artifact
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math"
+	"strconv"
+)
+
+/*
+Binary format for compiled artifacts (parser tables, bytecode), so they can be saved and
+exchanged between tools without building them again:
+
+	"NEON"      magic, 4 bytes
+	0           1 byte, marks the frame with a version. The first frame had the kind here
+	frame       uvarint, version of this frame, 2
+	kind        1 byte, what the payload is
+	version     uvarint, of the payload format of that kind
+	compatible  uvarint, the oldest reader version which can read the payload
+	length      uvarint, of the payload
+	payload
+	CRC-32      (IEEE) of everything before, 4 bytes big endian
+
+The first frame was "NEON" kind version length payload CRC-32, it is still read, compatible is the version then.
+
+The payload is written with an Encoder: ints are varints, strings and lists are prefixed with their length.
+
+Every kind has a Format with the version this build writes. New versions which only append fields keep
+compatible, older readers read the fields they know and skip the rest. Payloads of older versions are
+brought to the current version by the migrations of the Format. Everything else fails with an error which
+says which side is too old.
+*/
+
+type Kind byte
+
+const (
+	ParserTable Kind = 1
+	Bytecode    Kind = 2
+)
+
+var kindNames = map[Kind]string{ParserTable: "parser table", Bytecode: "bytecode"}
+
+func (kind Kind) String() string {
+	if name, ok := kindNames[kind]; ok {
+		return name
+	}
+	return "unknown kind " + strconv.Itoa(int(kind))
+}
+
+const magic = "NEON"
+
+// Version of the frame Seal writes
+const frameVersion = 2
+
+// How the payloads of one kind are written and read across versions
+type Format struct {
+	Kind Kind
+	// Version of the payloads this build writes
+	Version int
+	// Oldest reader version which can read the payloads this build writes.
+	// Raised only if a change is more than fields appended at the end
+	Compatible int
+	// Turns a payload of the version into one of the next version. Needed for every version
+	// from the oldest one which can still be read up to Version
+	Migrations map[int]func(payload []byte) ([]byte, error)
+}
+
+// Frames the payload with the header and the checksum
+func (format Format) Seal(payload []byte) []byte {
+	data := []byte(magic)
+	data = append(data, 0)
+	data = binary.AppendUvarint(data, frameVersion)
+	data = append(data, byte(format.Kind))
+	data = binary.AppendUvarint(data, uint64(format.Version))
+	data = binary.AppendUvarint(data, uint64(format.Compatible))
+	data = binary.AppendUvarint(data, uint64(len(payload)))
+	data = append(data, payload...)
+	return binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(data))
+}
+
+// Checks the header and the checksum and returns a Decoder for the payload in the layout of format.Version:
+// older payloads are migrated, newer compatible ones are read without the fields this build does not know
+func (format Format) Open(data []byte) (*Decoder, error) {
+	header, err := readHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	if header.kind != format.Kind {
+		return nil, errors.New("Artifact Error: Expected a " + format.Kind.String() + ", got a " + header.kind.String())
+	}
+
+	name := format.Kind.String()
+	version := strconv.Itoa(header.version)
+	payload := header.payload
+	switch {
+	case header.version > format.Version && header.compatible > format.Version:
+		return nil, errors.New("Artifact Error: The " + name + " has version " + version + " and needs a reader of version " + strconv.Itoa(header.compatible) +
+			" or newer, this build reads up to version " + strconv.Itoa(format.Version) + ". Update the compiler or build the " + name + " again with this one")
+	case header.version > format.Version:
+		return &Decoder{data: payload, newer: true}, nil
+	}
+	for v := header.version; v < format.Version; v++ {
+		migrate, ok := format.Migrations[v]
+		if !ok {
+			return nil, errors.New("Artifact Error: The " + name + " has version " + version + ", which this build can not read any more (it writes version " +
+				strconv.Itoa(format.Version) + "). Build the " + name + " again")
+		}
+		if payload, err = migrate(payload); err != nil {
+			return nil, errors.New("Artifact Error: Migrating the " + name + " from version " + strconv.Itoa(v) + " failed: " + err.Error())
+		}
+	}
+	return &Decoder{data: payload}, nil
+}
+
+type header struct {
+	kind       Kind
+	version    int
+	compatible int
+	payload    []byte
+}
+
+// Reads both frames
+func readHeader(data []byte) (header, error) {
+	if len(data) < len(magic)+1+4 || string(data[:len(magic)]) != magic {
+		return header{}, errors.New("Artifact Error: Not a compiled artifact")
+	}
+	body, checksum := data[:len(data)-4], binary.BigEndian.Uint32(data[len(data)-4:])
+	if crc32.ChecksumIEEE(body) != checksum {
+		return header{}, errors.New("Artifact Error: Checksum does not match, the file is damaged")
+	}
+
+	decoder := &Decoder{data: body[len(magic):]}
+	result := header{}
+	if decoder.data[0] == 0 {
+		decoder.take(1)
+		if frame := decoder.Uint(); decoder.err == nil && frame != frameVersion {
+			return header{}, errors.New("Artifact Error: Frame version " + strconv.Itoa(frame) + " is not supported, the file was written by a newer compiler. Update the compiler")
+		}
+		result.kind = Kind(decoder.take(1)[0])
+		result.version = decoder.Uint()
+		result.compatible = decoder.Uint()
+	} else {
+		// The first frame, without compatible version
+		result.kind = Kind(decoder.take(1)[0])
+		result.version = decoder.Uint()
+		result.compatible = result.version
+	}
+	length := decoder.Uint()
+	if decoder.err != nil || length != len(decoder.data) {
+		return header{}, errors.New("Artifact Error: Broken header")
+	}
+	result.payload = decoder.data
+	return result, nil
+}
+
+type Encoder struct {
+	data []byte
+}
+
+func (encoder *Encoder) Bytes() []byte {
+	return encoder.data
+}
+
+func (encoder *Encoder) Int(i int) {
+	encoder.data = binary.AppendVarint(encoder.data, int64(i))
+}
+
+func (encoder *Encoder) Uint(i int) {
+	encoder.data = binary.AppendUvarint(encoder.data, uint64(i))
+}
+
+func (encoder *Encoder) Bool(b bool) {
+	if b {
+		encoder.data = append(encoder.data, 1)
+	} else {
+		encoder.data = append(encoder.data, 0)
+	}
+}
+
+func (encoder *Encoder) Float(f float64) {
+	encoder.data = binary.BigEndian.AppendUint64(encoder.data, math.Float64bits(f))
+}
+
+func (encoder *Encoder) String(s string) {
+	encoder.Uint(len(s))
+	encoder.data = append(encoder.data, s...)
+}
+
+func (encoder *Encoder) Strings(list []string) {
+	encoder.Uint(len(list))
+	for _, s := range list {
+		encoder.String(s)
+	}
+}
+
+// Reads what an Encoder wrote. The first error is kept, every read after it returns zero values
+type Decoder struct {
+	data []byte
+	err  error
+	// The payload is from a newer compatible version, the fields at the end are unknown
+	newer bool
+}
+
+func (decoder *Decoder) Err() error {
+	return decoder.err
+}
+
+// Reports an error unless everything was read. Payloads of newer versions may have more fields
+func (decoder *Decoder) Finish() error {
+	if decoder.err == nil && len(decoder.data) > 0 && !decoder.newer {
+		decoder.Fail("Unexpected data at the end")
+	}
+	return decoder.err
+}
+
+// For checks of the caller, e.g. an index out of range
+func (decoder *Decoder) Fail(message string) {
+	if decoder.err == nil {
+		decoder.err = errors.New("Artifact Error: " + message)
+	}
+}
+
+func (decoder *Decoder) Int() int {
+	if decoder.err != nil {
+		return 0
+	}
+	i, n := binary.Varint(decoder.data)
+	if n <= 0 {
+		decoder.Fail("Truncated data")
+		return 0
+	}
+	decoder.data = decoder.data[n:]
+	return int(i)
+}
+
+func (decoder *Decoder) Uint() int {
+	if decoder.err != nil {
+		return 0
+	}
+	i, n := binary.Uvarint(decoder.data)
+	if n <= 0 || i > math.MaxInt32 {
+		decoder.Fail("Truncated data")
+		return 0
+	}
+	decoder.data = decoder.data[n:]
+	return int(i)
+}
+
+// Length of a list. Every element takes at least one byte, so longer lists can not be right
+func (decoder *Decoder) Len() int {
+	length := decoder.Uint()
+	if length > len(decoder.data) {
+		decoder.Fail("Truncated data")
+		return 0
+	}
+	return length
+}
+
+func (decoder *Decoder) Bool() bool {
+	return decoder.take(1)[0] == 1
+}
+
+func (decoder *Decoder) Float() float64 {
+	return math.Float64frombits(binary.BigEndian.Uint64(decoder.take(8)))
+}
+
+func (decoder *Decoder) String() string {
+	return string(decoder.take(decoder.Uint()))
+}
+
+func (decoder *Decoder) Strings() []string {
+	list := make([]string, decoder.Len())
+	for i := range list {
+		list[i] = decoder.String()
+	}
+	return list
+}
+
+// Returns n bytes. After an error zeros, at most 8 so a broken length can not allocate much
+func (decoder *Decoder) take(n int) []byte {
+	if decoder.err == nil && n > len(decoder.data) {
+		decoder.Fail("Truncated data")
+	}
+	if decoder.err != nil {
+		return make([]byte, min(n, 8))
+	}
+	bytes := decoder.data[:n]
+	decoder.data = decoder.data[n:]
+	return bytes
+}
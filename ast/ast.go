@@ -0,0 +1,254 @@
+package ast
+
+/*
+Abstract syntax tree of the C# subset. Built from the parse tree by Build.
+
+Program
+	Function
+		Param
+		Block
+			Statements: VarDecl, Assign, CallStmt, Return, If, While
+				Expressions: IntLit, DoubleLit, BoolLit, StringLit, InterpolatedString, Ident, Unary, Binary, Call
+
+Trees of the tolerant parser (parser.ParseTolerant) can have Error nodes for the regions which could not be parsed.
+They are statements and expressions, the ones between functions are in Program.Errors
+
+The names Assign, Ident and Call use are bound to their declarations (the Decl field) by symtab.Bind, after Build
+they are nil.
+*/
+
+type Node interface {
+	Pos() Position
+	node()
+}
+
+// Nodes which introduce a name: Function, Param, VarDecl
+type Decl interface {
+	Node
+	declNode()
+}
+
+type Expr interface {
+	Node
+	exprNode()
+}
+
+type Stmt interface {
+	Node
+	stmtNode()
+}
+
+// Where the node starts in the source file. Line is 0 for nodes which are not from a file
+type Position struct {
+	Line int
+	// Column in characters, starting at 1. 0 if unknown
+	Col int
+}
+
+func (position Position) Pos() Position {
+	return position
+}
+
+type Program struct {
+	Position
+	Usings    []string
+	Namespace string
+	Class     string
+	Functions []*Function
+	// Regions around the functions which could not be parsed
+	Errors []*Error
+}
+
+type Function struct {
+	Position
+	Name string
+	// void, int, double, bool or string
+	ReturnType string
+	Params     []*Param
+	// External functions are implemented by the host and have no body
+	Extern bool
+	Body   *Block
+}
+
+type Param struct {
+	Position
+	// int, double, bool, string or string[]
+	Type string
+	Name string
+}
+
+type Block struct {
+	Position
+	Statements []Stmt
+}
+
+// Statements
+
+type VarDecl struct {
+	Position
+	Type string
+	Name string
+	// nil for declarations without value
+	Value Expr
+}
+
+type Assign struct {
+	Position
+	Name  string
+	Value Expr
+	// The declaration of the variable, set by symtab.Bind
+	Decl Decl
+}
+
+type CallStmt struct {
+	Position
+	Call *Call
+}
+
+type Return struct {
+	Position
+	// nil for return;
+	Value Expr
+}
+
+type If struct {
+	Position
+	Cond Expr
+	Then *Block
+	// nil without else
+	Else *Block
+}
+
+type While struct {
+	Position
+	Cond Expr
+	Body *Block
+}
+
+// Region which could not be parsed, only in trees of the tolerant parser
+type Error struct {
+	Position
+	// After the last character of the region, the same as Position if the region is empty
+	End Position
+	// The non terminal the region stands for, e.g. EXPRESSION
+	NonTerminal string
+}
+
+// Expressions
+
+type IntLit struct {
+	Position
+	Value int
+}
+
+type DoubleLit struct {
+	Position
+	Value float64
+}
+
+type BoolLit struct {
+	Position
+	Value bool
+}
+
+type StringLit struct {
+	Position
+	Value string
+}
+
+type InterpolatedString struct {
+	Position
+	Parts []InterpolationPart
+}
+
+// Either Text or Expr is set
+type InterpolationPart struct {
+	Text string
+	Expr Expr
+}
+
+type Ident struct {
+	Position
+	Name string
+	// The declaration of the variable or parameter, set by symtab.Bind
+	Decl Decl
+}
+
+type Unary struct {
+	Position
+	// + or -
+	Op      string
+	Operand Expr
+}
+
+type Binary struct {
+	Position
+	// Arithmetic (+ - * / %), comparison (== != < > <= >=) or logical (&& ||)
+	Op    string
+	Left  Expr
+	Right Expr
+}
+
+type Call struct {
+	Position
+	// Class in front of the function (Console in Console.WriteLine), empty for calls inside the class
+	Receiver string
+	Name     string
+	Args     []Expr
+	// The declaration of the function, set by symtab.Bind. nil for built in and library functions
+	Decl Decl
+}
+
+func (*Program) node()  {}
+func (*Function) node() {}
+func (*Param) node()    {}
+func (*Block) node()    {}
+
+func (*VarDecl) node()  {}
+func (*Assign) node()   {}
+func (*CallStmt) node() {}
+func (*Return) node()   {}
+func (*If) node()       {}
+func (*While) node()    {}
+func (*Error) node()    {}
+
+func (*Function) declNode() {}
+func (*Param) declNode()    {}
+func (*VarDecl) declNode()  {}
+
+func (*VarDecl) stmtNode()  {}
+func (*Assign) stmtNode()   {}
+func (*CallStmt) stmtNode() {}
+func (*Return) stmtNode()   {}
+func (*If) stmtNode()       {}
+func (*While) stmtNode()    {}
+func (*Error) stmtNode()    {}
+
+func (*IntLit) node()             {}
+func (*DoubleLit) node()          {}
+func (*BoolLit) node()            {}
+func (*StringLit) node()          {}
+func (*InterpolatedString) node() {}
+func (*Ident) node()              {}
+func (*Unary) node()              {}
+func (*Binary) node()             {}
+func (*Call) node()               {}
+
+func (*IntLit) exprNode()             {}
+func (*DoubleLit) exprNode()          {}
+func (*BoolLit) exprNode()            {}
+func (*StringLit) exprNode()          {}
+func (*InterpolatedString) exprNode() {}
+func (*Ident) exprNode()              {}
+func (*Unary) exprNode()              {}
+func (*Binary) exprNode()             {}
+func (*Call) exprNode()               {}
+func (*Error) exprNode()              {}
+
+// Name of the function, with the receiver if there is one (Console.WriteLine)
+func (call *Call) FullName() string {
+	if call.Receiver == "" {
+		return call.Name
+	}
+	return call.Receiver + "." + call.Name
+}
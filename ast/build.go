@@ -0,0 +1,306 @@
+package ast
+
+import (
+	"compiler/diag"
+	"compiler/parser"
+	"errors"
+)
+
+// Malformed parse trees panic with a buildError, which Build turns into an error
+type buildError struct {
+	message string
+}
+
+// Builds the AST from the parse tree of a whole program (START). ERROR nodes of the tolerant parser become Error nodes
+func Build(tree parser.ParseTree) (program *Program, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			buildErr, ok := r.(buildError)
+			if !ok {
+				panic(r)
+			}
+			program = nil
+			err = errors.New(diag.ASTBroken.Format("AST Error: " + buildErr.message))
+		}
+	}()
+
+	program = new(Program)
+	program.Position = at(tree)
+	if isError(tree) {
+		program.Errors = append(program.Errors, buildErrorNode(tree))
+		return program, nil
+	}
+	expect(tree, "START")
+	buildUsingBlock(tree.Branches[0], program)
+	return program, nil
+}
+
+// Builds the AST of an expression subtree (EXPRESSION, TERM, FACTOR, PRIMARY, ...)
+func BuildExpression(tree parser.ParseTree) (expr Expr, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			buildErr, ok := r.(buildError)
+			if !ok {
+				panic(r)
+			}
+			expr = nil
+			err = errors.New(diag.ASTBroken.Format("AST Error: " + buildErr.message))
+		}
+	}()
+	return buildExpression(tree), nil
+}
+
+func expect(tree parser.ParseTree, name string) {
+	if tree.Leaf.Name != name {
+		panic(buildError{"Expected " + name + " but found " + tree.Leaf.Name})
+	}
+}
+
+// Position of the first token of the tree
+func at(tree parser.ParseTree) Position {
+	return Position{Line: tree.Leaf.Line, Col: tree.Leaf.Col}
+}
+
+func isError(tree parser.ParseTree) bool {
+	return tree.Leaf.Name == parser.ErrorSymbol
+}
+
+func buildErrorNode(tree parser.ParseTree) *Error {
+	region := tree.Leaf.Value.(parser.ErrorRegion)
+	return &Error{Position: at(tree), End: Position{Line: region.EndLine, Col: region.EndCol}, NonTerminal: region.NonTerminal}
+}
+
+func unexpected(tree parser.ParseTree) {
+	panic(buildError{"Unexpected " + tree.Leaf.Name})
+}
+
+func buildUsingBlock(tree parser.ParseTree, program *Program) {
+	if isError(tree) {
+		program.Errors = append(program.Errors, buildErrorNode(tree))
+		return
+	}
+	expect(tree, "USINGBLOCK")
+	// USINGBLOCK -> using name ; USINGBLOCK | NAMESPACE
+	if tree.Branches[0].Leaf.Name == "using" {
+		program.Usings = append(program.Usings, tree.Branches[1].Leaf.Value.(string))
+		buildUsingBlock(tree.Branches[3], program)
+		return
+	}
+	namespace := tree.Branches[0]
+	expect(namespace, "NAMESPACE")
+	// NAMESPACE -> namespace name { CLASS }
+	program.Namespace = namespace.Branches[1].Leaf.Value.(string)
+	class := namespace.Branches[3]
+	if isError(class) {
+		program.Errors = append(program.Errors, buildErrorNode(class))
+		return
+	}
+	expect(class, "CLASS")
+	// CLASS -> class name { FUNCBLOCK
+	program.Class = class.Branches[1].Leaf.Value.(string)
+	funcBlock := class.Branches[3]
+	// FUNCBLOCK -> FUNC FUNCBLOCK | }
+	for funcBlock.Leaf.Name == "FUNCBLOCK" && len(funcBlock.Branches) == 2 {
+		if isError(funcBlock.Branches[0]) {
+			program.Errors = append(program.Errors, buildErrorNode(funcBlock.Branches[0]))
+		} else {
+			program.Functions = append(program.Functions, buildFunction(funcBlock.Branches[0]))
+		}
+		funcBlock = funcBlock.Branches[1]
+	}
+	if isError(funcBlock) {
+		program.Errors = append(program.Errors, buildErrorNode(funcBlock))
+	}
+}
+
+func buildFunction(tree parser.ParseTree) *Function {
+	expect(tree, "FUNC")
+	// FUNC -> static RETURNTYPE name ( INPUTBLOCK { STATEMENTBLOCK
+	// FUNC -> static extern RETURNTYPE name ( INPUTBLOCK ;
+	function := new(Function)
+	function.Position = at(tree)
+	offset := 0
+	if tree.Branches[1].Leaf.Name == "extern" {
+		function.Extern = true
+		offset = 1
+	}
+	returnType := tree.Branches[1+offset]
+	if returnType.Branches[0].Leaf.Name == "void" {
+		function.ReturnType = "void"
+	} else {
+		function.ReturnType = buildType(returnType.Branches[0])
+	}
+	function.Name = tree.Branches[2+offset].Leaf.Value.(string)
+	function.Params = buildParams(tree.Branches[4+offset])
+	if !function.Extern {
+		function.Body = buildBlock(tree.Branches[6])
+	}
+	return function
+}
+
+func buildType(tree parser.ParseTree) string {
+	expect(tree, "TYPE")
+	return tree.Branches[0].Leaf.Name
+}
+
+func buildParams(tree parser.ParseTree) []*Param {
+	expect(tree, "INPUTBLOCK")
+	params := []*Param{}
+	// INPUTBLOCK -> ) | string [ ] name ) | INPUTSTART
+	switch tree.Branches[0].Leaf.Name {
+	case ")":
+		return params
+	case "string":
+		return append(params, &Param{Position: at(tree), Type: "string[]", Name: tree.Branches[3].Leaf.Value.(string)})
+	}
+	// INPUTSTART -> TYPE name INPUTCONTINUED
+	// INPUTCONTINUED -> , TYPE name INPUTCONTINUED | )
+	current := tree.Branches[0]
+	typeIndex := 0
+	for len(current.Branches) > 1 {
+		params = append(params, &Param{Position: at(current.Branches[typeIndex]), Type: buildType(current.Branches[typeIndex]), Name: current.Branches[typeIndex+1].Leaf.Value.(string)})
+		current = current.Branches[typeIndex+2]
+		typeIndex = 1
+	}
+	return params
+}
+
+// STATEMENTBLOCK is right recursive and ends with }
+func buildBlock(tree parser.ParseTree) *Block {
+	block := new(Block)
+	block.Position = at(tree)
+	block.Statements = []Stmt{}
+	for {
+		// The rest of the block could not be parsed
+		if isError(tree) {
+			block.Statements = append(block.Statements, buildErrorNode(tree))
+			return block
+		}
+		expect(tree, "STATEMENTBLOCK")
+		if len(tree.Branches) == 1 {
+			return block
+		}
+		statement := tree.Branches[0]
+		block.Statements = append(block.Statements, buildStatement(statement))
+		tree = tree.Branches[len(tree.Branches)-1]
+	}
+}
+
+func buildStatement(tree parser.ParseTree) Stmt {
+	switch tree.Leaf.Name {
+	case parser.ErrorSymbol:
+		return buildErrorNode(tree)
+	case "FUNCCALL":
+		return &CallStmt{Position: at(tree), Call: buildCall(tree)}
+	case "RETURN":
+		// RETURN -> return EXPRESSION ; | return ;
+		if len(tree.Branches) == 3 {
+			return &Return{Position: at(tree), Value: buildExpression(tree.Branches[1])}
+		}
+		return &Return{Position: at(tree)}
+	case "VARIABLEDECLARATION":
+		// EMPTYVARIABLEDECLARATION -> TYPE name ;
+		// SETVARIABLEDECLARATION -> TYPE name = EXPRESSION ;
+		declaration := tree.Branches[0]
+		varDecl := &VarDecl{Position: at(tree), Type: buildType(declaration.Branches[0]), Name: declaration.Branches[1].Leaf.Value.(string)}
+		if declaration.Leaf.Name == "SETVARIABLEDECLARATION" {
+			varDecl.Value = buildExpression(declaration.Branches[3])
+		}
+		return varDecl
+	case "VARASSIGN":
+		// VARASSIGN -> name = EXPRESSION ;
+		return &Assign{Position: at(tree), Name: tree.Branches[0].Leaf.Value.(string), Value: buildExpression(tree.Branches[2])}
+	case "IF":
+		// IF -> if ( EXPRESSION ) { STATEMENTBLOCK [ELSE]
+		// ELSE -> else { STATEMENTBLOCK
+		ifStatement := &If{Position: at(tree), Cond: buildExpression(tree.Branches[2]), Then: buildBlock(tree.Branches[5])}
+		if len(tree.Branches) == 7 {
+			ifStatement.Else = buildBlock(tree.Branches[6].Branches[2])
+		}
+		return ifStatement
+	case "WHILE":
+		// WHILE -> while ( EXPRESSION ) { STATEMENTBLOCK
+		return &While{Position: at(tree), Cond: buildExpression(tree.Branches[2]), Body: buildBlock(tree.Branches[5])}
+	}
+	unexpected(tree)
+	return nil
+}
+
+func buildCall(tree parser.ParseTree) *Call {
+	expect(tree, "FUNCCALL")
+	// FUNCCALL -> name ( ARGBLOCK | name . name ( ARGBLOCK
+	call := new(Call)
+	call.Position = at(tree)
+	argBlock := tree.Branches[len(tree.Branches)-1]
+	if len(tree.Branches) == 5 {
+		call.Receiver = tree.Branches[0].Leaf.Value.(string)
+		call.Name = tree.Branches[2].Leaf.Value.(string)
+	} else {
+		call.Name = tree.Branches[0].Leaf.Value.(string)
+	}
+
+	// ARGBLOCK -> ) | ARGSSTART
+	// ARGSSTART -> EXPRESSION ARGCONTINUED
+	// ARGCONTINUED -> ) | , EXPRESSION ARGCONTINUED
+	call.Args = []Expr{}
+	if argBlock.Branches[0].Leaf.Name == ")" {
+		return call
+	}
+	args := argBlock.Branches[0]
+	call.Args = append(call.Args, buildExpression(args.Branches[0]))
+	continued := args.Branches[1]
+	for len(continued.Branches) == 3 {
+		call.Args = append(call.Args, buildExpression(continued.Branches[1]))
+		continued = continued.Branches[2]
+	}
+	return call
+}
+
+func buildExpression(tree parser.ParseTree) Expr {
+	switch tree.Leaf.Name {
+	case parser.ErrorSymbol:
+		return buildErrorNode(tree)
+	case "EXPRESSION", "TERM", "FACTOR":
+		// EXPRESSION -> EXPRESSION logicaloperator TERM | TERM, same for TERM and FACTOR
+		if len(tree.Branches) == 1 {
+			return buildExpression(tree.Branches[0])
+		}
+		return &Binary{Position: at(tree), Op: tree.Branches[1].Leaf.Value.(string), Left: buildExpression(tree.Branches[0]), Right: buildExpression(tree.Branches[2])}
+	case "PRIMARY":
+		// PRIMARY -> FUNCCALL | LITERAL | name | unaryoperator PRIMARY | ( EXPRESSION )
+		switch len(tree.Branches) {
+		case 2:
+			return &Unary{Position: at(tree), Op: tree.Branches[0].Leaf.Value.(string), Operand: buildExpression(tree.Branches[1])}
+		case 3:
+			return buildExpression(tree.Branches[1])
+		}
+		return buildExpression(tree.Branches[0])
+	case "FUNCCALL":
+		return buildCall(tree)
+	case "LITERAL", "NUMLITERAL":
+		return buildExpression(tree.Branches[0])
+	case "name":
+		return &Ident{Position: at(tree), Name: tree.Leaf.Value.(string)}
+	case "intliteral":
+		return &IntLit{Position: at(tree), Value: tree.Leaf.Value.(int)}
+	case "doubleliteral":
+		return &DoubleLit{Position: at(tree), Value: tree.Leaf.Value.(float64)}
+	case "boolliteral":
+		return &BoolLit{Position: at(tree), Value: tree.Leaf.Value.(bool)}
+	case "stringliteral":
+		return &StringLit{Position: at(tree), Value: tree.Leaf.Value.(string)}
+	case "interpolatedstring":
+		interpolated := new(InterpolatedString)
+		interpolated.Position = at(tree)
+		for _, segment := range tree.Leaf.Value.([]parser.InterpolationSegment) {
+			if segment.Expression == nil {
+				interpolated.Parts = append(interpolated.Parts, InterpolationPart{Text: segment.Text})
+			} else {
+				interpolated.Parts = append(interpolated.Parts, InterpolationPart{Expr: buildExpression(*segment.Expression)})
+			}
+		}
+		return interpolated
+	}
+	unexpected(tree)
+	return nil
+}
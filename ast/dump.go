@@ -0,0 +1,91 @@
+package ast
+
+import (
+	"strconv"
+	"strings"
+)
+
+// The tree as indented text, one node per line with its position:
+//
+//	Program Test.Program 1:1
+//	  Function int Fib 4:5
+//	    Param int n 4:20
+func Dump(node Node) string {
+	var builder strings.Builder
+	Walk(dumper{builder: &builder}, node)
+	return builder.String()
+}
+
+type dumper struct {
+	builder *strings.Builder
+	depth   int
+}
+
+func (d dumper) Visit(node Node) Visitor {
+	if node == nil {
+		return nil
+	}
+	d.builder.WriteString(strings.Repeat("  ", d.depth) + label(node))
+	if pos := node.Pos(); pos.Line != 0 {
+		d.builder.WriteString(" " + strconv.Itoa(pos.Line) + ":" + strconv.Itoa(pos.Col))
+	}
+	d.builder.WriteString("\n")
+	return dumper{builder: d.builder, depth: d.depth + 1}
+}
+
+func label(node Node) string {
+	switch n := node.(type) {
+	case *Program:
+		return "Program " + n.Namespace + "." + n.Class
+	case *Function:
+		if n.Extern {
+			return "Function extern " + n.ReturnType + " " + n.Name
+		}
+		return "Function " + n.ReturnType + " " + n.Name
+	case *Param:
+		return "Param " + n.Type + " " + n.Name
+	case *Block:
+		return "Block"
+	case *VarDecl:
+		return "VarDecl " + n.Type + " " + n.Name
+	case *Assign:
+		return "Assign " + n.Name
+	case *CallStmt:
+		return "CallStmt"
+	case *Return:
+		return "Return"
+	case *If:
+		return "If"
+	case *While:
+		return "While"
+	case *IntLit:
+		return "IntLit " + strconv.Itoa(n.Value)
+	case *DoubleLit:
+		return "DoubleLit " + strconv.FormatFloat(n.Value, 'g', -1, 64)
+	case *BoolLit:
+		return "BoolLit " + strconv.FormatBool(n.Value)
+	case *StringLit:
+		return "StringLit " + strconv.Quote(n.Value)
+	case *InterpolatedString:
+		text := ""
+		for _, part := range n.Parts {
+			if part.Expr != nil {
+				text += "{}"
+			} else {
+				text += part.Text
+			}
+		}
+		return "InterpolatedString " + strconv.Quote(text)
+	case *Ident:
+		return "Ident " + n.Name
+	case *Unary:
+		return "Unary " + n.Op
+	case *Binary:
+		return "Binary " + n.Op
+	case *Call:
+		return "Call " + n.FullName()
+	case *Error:
+		return "Error " + n.NonTerminal
+	}
+	return "?"
+}
@@ -0,0 +1,99 @@
+package ast
+
+// Visit is called for every node Walk reaches. The returned visitor walks the children of the node, nil skips them.
+// After the children Visit is called with nil on that visitor
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walks the tree depth first in source order, like go/ast.Walk
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		// Functions and errors in source order
+		errors := n.Errors
+		for _, function := range n.Functions {
+			for len(errors) > 0 && before(errors[0].Position, function.Position) {
+				Walk(v, errors[0])
+				errors = errors[1:]
+			}
+			Walk(v, function)
+		}
+		for _, err := range errors {
+			Walk(v, err)
+		}
+	case *Function:
+		for _, param := range n.Params {
+			Walk(v, param)
+		}
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+	case *Block:
+		for _, statement := range n.Statements {
+			Walk(v, statement)
+		}
+	case *VarDecl:
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+	case *Assign:
+		Walk(v, n.Value)
+	case *CallStmt:
+		Walk(v, n.Call)
+	case *Return:
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+	case *If:
+		Walk(v, n.Cond)
+		Walk(v, n.Then)
+		if n.Else != nil {
+			Walk(v, n.Else)
+		}
+	case *While:
+		Walk(v, n.Cond)
+		Walk(v, n.Body)
+	case *InterpolatedString:
+		for _, part := range n.Parts {
+			if part.Expr != nil {
+				Walk(v, part.Expr)
+			}
+		}
+	case *Unary:
+		Walk(v, n.Operand)
+	case *Binary:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+	case *Call:
+		for _, arg := range n.Args {
+			Walk(v, arg)
+		}
+	case *Param, *IntLit, *DoubleLit, *BoolLit, *StringLit, *Ident, *Error:
+		// No children
+	}
+
+	v.Visit(nil)
+}
+
+func before(a Position, b Position) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Col < b.Col
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Calls f for every node, children are skipped if f returns false. f(nil) is called after the children
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
@@ -0,0 +1,79 @@
+package budget
+
+import (
+	"compiler/diag"
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+/*
+Time limits for the phases of the compiler. Every phase runs with its own context, which ends
+when the limit of the phase is reached or the context of the whole compilation ends:
+
+	budgets, err := budget.ParseFlag("parse=2s,check=500ms")
+	ctx, cancel := budgets.Start(ctx, budget.Parsing)
+	defer cancel()
+	tree, ok, err := parser.ParseContext(ctx, path, true, options)
+*/
+
+type Phase string
+
+const (
+	Parsing    Phase = "parse"
+	Checking   Phase = "check"
+	Optimizing Phase = "optimize"
+)
+
+var phases = []Phase{Parsing, Checking, Optimizing}
+
+// Phase -> time limit. Phases without limit only end with the context of the compilation
+type Budget map[Phase]time.Duration
+
+// Parses a list like "parse=2s,check=500ms". The durations use the format of time.ParseDuration
+func ParseFlag(flag string) (Budget, error) {
+	budget := Budget{}
+	if flag == "" {
+		return budget, nil
+	}
+	for _, entry := range strings.Split(flag, ",") {
+		name, value, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, errors.New("Budget " + entry + " has no duration, use phase=duration")
+		}
+		phase := Phase(strings.TrimSpace(name))
+		known := false
+		for _, p := range phases {
+			known = known || p == phase
+		}
+		if !known {
+			return nil, errors.New("Unknown phase " + string(phase) + ", phases are parse, check and optimize")
+		}
+		limit, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || limit <= 0 {
+			return nil, errors.New("Invalid duration " + value + " for phase " + string(phase))
+		}
+		budget[phase] = limit
+	}
+	return budget, nil
+}
+
+// Context for the phase. cancel has to be called when the phase is done
+func (budget Budget) Start(ctx context.Context, phase Phase) (context.Context, context.CancelFunc) {
+	if limit, ok := budget[phase]; ok {
+		return context.WithTimeout(ctx, limit)
+	}
+	return context.WithCancel(ctx)
+}
+
+// Turns the error of a phase context into a diagnostic message
+func (budget Budget) Error(phase Phase, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		if limit, ok := budget[phase]; ok {
+			return errors.New(diag.TimedOut.Format("Timeout: " + string(phase) + " took longer than " + limit.String()))
+		}
+		return errors.New(diag.TimedOut.Format("Timeout: compilation took too long during " + string(phase)))
+	}
+	return errors.New(diag.TimedOut.Format("Compilation was cancelled during " + string(phase)))
+}
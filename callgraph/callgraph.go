@@ -0,0 +1,95 @@
+package callgraph
+
+import "compiler/ast"
+
+/*
+Call graph of a program: which function calls which.
+Only the functions of the program are nodes. Calls of built in functions (Console.WriteLine) are left out.
+Program.Fib() is the same call as Fib().
+*/
+
+type Graph struct {
+	// Functions in the order of the program
+	Functions []*ast.Function
+	class     string
+	byName    map[string]*ast.Function
+	// Function -> functions it calls, in the order of their first call
+	calls map[string][]string
+	// Function -> calls of the functions of the program inside it
+	sites map[string][]*ast.Call
+}
+
+func Build(program *ast.Program) *Graph {
+	graph := new(Graph)
+	graph.Functions = program.Functions
+	graph.class = program.Class
+	graph.byName = make(map[string]*ast.Function)
+	graph.calls = make(map[string][]string)
+	graph.sites = make(map[string][]*ast.Call)
+	for _, function := range program.Functions {
+		graph.byName[function.Name] = function
+	}
+
+	for _, function := range program.Functions {
+		caller := function.Name
+		ast.Inspect(function, func(node ast.Node) bool {
+			call, ok := node.(*ast.Call)
+			if !ok || (call.Receiver != "" && call.Receiver != program.Class) {
+				return true
+			}
+			if _, exists := graph.byName[call.Name]; !exists {
+				return true
+			}
+			if !contains(graph.calls[caller], call.Name) {
+				graph.calls[caller] = append(graph.calls[caller], call.Name)
+			}
+			graph.sites[caller] = append(graph.sites[caller], call)
+			return true
+		})
+	}
+	return graph
+}
+
+func (graph *Graph) Function(name string) *ast.Function {
+	return graph.byName[name]
+}
+
+// Functions called by the function
+func (graph *Graph) Callees(name string) []string {
+	return graph.calls[name]
+}
+
+// Calls of other functions of the program inside the function, in source order
+func (graph *Graph) CallSites(name string) []*ast.Call {
+	return graph.sites[name]
+}
+
+// Functions which can be reached from the roots by calls, including the roots
+func (graph *Graph) Reachable(roots ...string) map[string]bool {
+	reachable := make(map[string]bool)
+	stack := []string{}
+	for _, root := range roots {
+		if graph.byName[root] != nil {
+			stack = append(stack, root)
+		}
+	}
+	for len(stack) > 0 {
+		name := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if reachable[name] {
+			continue
+		}
+		reachable[name] = true
+		stack = append(stack, graph.calls[name]...)
+	}
+	return reachable
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package callgraph
+
+import "compiler/ast"
+
+// Function removed by RemoveDead
+type Removed struct {
+	Function *ast.Function
+	// Number of AST nodes of the function, as measure of the removed code
+	Nodes int
+}
+
+// The program starts in one of these
+var entryPoints = []string{"Main", "main"}
+
+// Removes the functions which can not be reached from Main, including extern functions no one calls.
+// The program is changed in place, the removed functions are returned in program order
+func RemoveDead(program *ast.Program) []Removed {
+	graph := Build(program)
+	removed := []Removed{}
+	hasEntry := false
+	for _, name := range entryPoints {
+		hasEntry = hasEntry || graph.Function(name) != nil
+	}
+	// Without Main everything is unreachable, likely a library. Keep it as it is
+	if !hasEntry {
+		return removed
+	}
+	reachable := graph.Reachable(entryPoints...)
+	kept := []*ast.Function{}
+	for _, function := range program.Functions {
+		if reachable[function.Name] {
+			kept = append(kept, function)
+			continue
+		}
+		removed = append(removed, Removed{Function: function, Nodes: countNodes(function)})
+	}
+	program.Functions = kept
+	return removed
+}
+
+func countNodes(node ast.Node) int {
+	count := 0
+	ast.Inspect(node, func(n ast.Node) bool {
+		if n != nil {
+			count++
+		}
+		return true
+	})
+	return count
+}
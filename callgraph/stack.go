@@ -0,0 +1,308 @@
+package callgraph
+
+import (
+	"compiler/ast"
+	"compiler/diag"
+	"slices"
+	"strconv"
+)
+
+/*
+Stack usage and recursion analysis:
+	Frame of a function: JVM slots for parameters and locals (double takes 2) plus the deepest operand stack of its expressions.
+	Worst case: the most expensive call chain from Main. Every recursive cycle is counted once,
+	with recursion the real stack depth depends on the input.
+	A recursive call should make one argument smaller: n - c with c > 0, or n / c with c > 1,
+	where n is a parameter which is tested in a condition of the function (the base case).
+	Recursive calls without such an argument are reported, the recursion might not end.
+*/
+
+type StackReport struct {
+	// Slots of the frame of every function
+	Frames map[string]int
+	// Worst case slots from Main, with every recursive cycle counted once
+	MaxSlots int
+	// Call chain of the worst case
+	Path []string
+	// Cycles of functions calling each other which can be reached from Main
+	Recursive [][]string
+	// Warnings for recursive calls without a decreasing argument
+	Diagnostics []*diag.Diagnostic
+}
+
+func AnalyzeStack(program *ast.Program) *StackReport {
+	graph := Build(program)
+	report := new(StackReport)
+	report.Frames = make(map[string]int)
+	report.Diagnostics = []*diag.Diagnostic{}
+	for _, function := range program.Functions {
+		report.Frames[function.Name] = frameSize(function, graph)
+	}
+
+	reachable := graph.Reachable(entryPoints...)
+	components := graph.SCCs()
+	componentOf := make(map[string]int)
+	for i, component := range components {
+		for _, name := range component {
+			componentOf[name] = i
+		}
+	}
+
+	// Components come callees first, so the cost of the callees is known already
+	cost := make([]int, len(components))
+	next := make([]int, len(components))
+	for i, component := range components {
+		next[i] = -1
+		best := 0
+		for _, name := range component {
+			cost[i] += report.Frames[name]
+			for _, callee := range graph.Callees(name) {
+				if c := componentOf[callee]; c != i && cost[c] > best {
+					best = cost[c]
+					next[i] = c
+				}
+			}
+		}
+		cost[i] += best
+
+		if graph.isRecursive(component) && reachable[component[0]] {
+			report.Recursive = append(report.Recursive, component)
+			report.Diagnostics = append(report.Diagnostics, checkMeasure(graph, component)...)
+		}
+	}
+
+	for _, entry := range entryPoints {
+		if graph.Function(entry) == nil {
+			continue
+		}
+		for c := componentOf[entry]; c != -1; c = next[c] {
+			report.Path = append(report.Path, components[c]...)
+		}
+		report.MaxSlots = cost[componentOf[entry]]
+		break
+	}
+	return report
+}
+
+// Strongly connected components (Tarjan), callees before callers. Functions of one component call each other
+func (graph *Graph) SCCs() [][]string {
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	stack := []string{}
+	components := [][]string{}
+
+	var connect func(name string)
+	connect = func(name string) {
+		index[name] = len(index)
+		low[name] = index[name]
+		stack = append(stack, name)
+		onStack[name] = true
+		for _, callee := range graph.calls[name] {
+			if _, visited := index[callee]; !visited {
+				connect(callee)
+				low[name] = min(low[name], low[callee])
+			} else if onStack[callee] {
+				low[name] = min(low[name], index[callee])
+			}
+		}
+		if low[name] == index[name] {
+			component := []string{}
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == name {
+					break
+				}
+			}
+			slices.Reverse(component)
+			components = append(components, component)
+		}
+	}
+	for _, function := range graph.Functions {
+		if _, visited := index[function.Name]; !visited {
+			connect(function.Name)
+		}
+	}
+	return components
+}
+
+// A component is recursive if it has more than one function, or its function calls itself
+func (graph *Graph) isRecursive(component []string) bool {
+	return len(component) > 1 || contains(graph.calls[component[0]], component[0])
+}
+
+func checkMeasure(graph *Graph, component []string) []*diag.Diagnostic {
+	diagnostics := []*diag.Diagnostic{}
+	for _, name := range component {
+		function := graph.Function(name)
+		guarded := guardedParams(function)
+		for _, call := range graph.CallSites(name) {
+			if !contains(component, call.Name) {
+				continue
+			}
+			decreasing := false
+			for _, arg := range call.Args {
+				if param := decreasedParam(arg); param != "" && guarded[param] {
+					decreasing = true
+				}
+			}
+			if !decreasing {
+				line := call.Pos().Line
+				diagnostics = append(diagnostics, diag.MakeDiagnostic(diag.RecursionMayNotEnd, "Recursion Warning: The call of "+call.Name+" in "+name+" at line "+strconv.Itoa(line)+" makes no argument smaller, the recursion might not end", line))
+			}
+		}
+	}
+	return diagnostics
+}
+
+// Parameters which are used in the condition of an if or while
+func guardedParams(function *ast.Function) map[string]bool {
+	params := make(map[string]bool)
+	for _, param := range function.Params {
+		params[param.Name] = false
+	}
+	ast.Inspect(function, func(node ast.Node) bool {
+		var cond ast.Expr
+		switch n := node.(type) {
+		case *ast.If:
+			cond = n.Cond
+		case *ast.While:
+			cond = n.Cond
+		default:
+			return true
+		}
+		ast.Inspect(cond, func(node ast.Node) bool {
+			if ident, ok := node.(*ast.Ident); ok {
+				if _, isParam := params[ident.Name]; isParam {
+					params[ident.Name] = true
+				}
+			}
+			return true
+		})
+		return true
+	})
+	return params
+}
+
+// n - c with c > 0 or n / c with c > 1 makes n smaller. Returns n, or "" if the argument is not like that
+func decreasedParam(arg ast.Expr) string {
+	binary, ok := arg.(*ast.Binary)
+	if !ok {
+		return ""
+	}
+	ident, isIdent := binary.Left.(*ast.Ident)
+	literal, isLiteral := binary.Right.(*ast.IntLit)
+	if !isIdent || !isLiteral {
+		return ""
+	}
+	if (binary.Op == "-" && literal.Value > 0) || (binary.Op == "/" && literal.Value > 1) {
+		return ident.Name
+	}
+	return ""
+}
+
+// Slots for parameters and locals, plus the deepest operand stack of the statements
+func frameSize(function *ast.Function, graph *Graph) int {
+	if function.Extern {
+		return 0
+	}
+	types := make(map[string]string)
+	locals := 0
+	for _, param := range function.Params {
+		types[param.Name] = param.Type
+		locals += slots(param.Type)
+	}
+	operands := 0
+	ast.Inspect(function.Body, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.VarDecl:
+			types[n.Name] = n.Type
+			locals += slots(n.Type)
+			if n.Value != nil {
+				operands = max(operands, operandDepth(n.Value, types, graph))
+			}
+			return false
+		case *ast.Assign:
+			operands = max(operands, operandDepth(n.Value, types, graph))
+			return false
+		case *ast.Return:
+			if n.Value != nil {
+				operands = max(operands, operandDepth(n.Value, types, graph))
+			}
+			return false
+		case *ast.CallStmt:
+			operands = max(operands, operandDepth(n.Call, types, graph))
+			return false
+		case *ast.If:
+			operands = max(operands, operandDepth(n.Cond, types, graph))
+		case *ast.While:
+			operands = max(operands, operandDepth(n.Cond, types, graph))
+		}
+		return true
+	})
+	return locals + operands
+}
+
+// Most slots on the operand stack while the expression is evaluated
+func operandDepth(expr ast.Expr, types map[string]string, graph *Graph) int {
+	switch e := expr.(type) {
+	case *ast.Unary:
+		return operandDepth(e.Operand, types, graph)
+	case *ast.Binary:
+		return max(operandDepth(e.Left, types, graph), width(e.Left, types, graph)+operandDepth(e.Right, types, graph))
+	case *ast.Call:
+		depth := 0
+		below := 0
+		for _, arg := range e.Args {
+			depth = max(depth, below+operandDepth(arg, types, graph))
+			below += width(arg, types, graph)
+		}
+		return max(depth, width(e, types, graph))
+	case *ast.InterpolatedString:
+		// The string builder and the part which is added to it
+		depth := 1
+		for _, part := range e.Parts {
+			if part.Expr != nil {
+				depth = max(depth, 1+operandDepth(part.Expr, types, graph))
+			}
+		}
+		return depth
+	}
+	return width(expr, types, graph)
+}
+
+// Slots of the value of the expression
+func width(expr ast.Expr, types map[string]string, graph *Graph) int {
+	switch e := expr.(type) {
+	case *ast.DoubleLit:
+		return 2
+	case *ast.Ident:
+		return slots(types[e.Name])
+	case *ast.Unary:
+		return width(e.Operand, types, graph)
+	case *ast.Binary:
+		switch e.Op {
+		case "+", "-", "*", "/", "%":
+			return max(width(e.Left, types, graph), width(e.Right, types, graph))
+		}
+		return 1
+	case *ast.Call:
+		if function := graph.Function(e.Name); function != nil && (e.Receiver == "" || e.Receiver == graph.class) {
+			if function.ReturnType == "void" {
+				return 0
+			}
+			return slots(function.ReturnType)
+		}
+	}
+	return 1
+}
+
+func slots(typ string) int {
+	if typ == "double" {
+		return 2
+	}
+	return 1
+}
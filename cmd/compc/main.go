@@ -0,0 +1,180 @@
+// Compile driver, which runs the phases of the compiler one after the other and stops after the one given with -emit:
+//
+//	tokens    the tokens of the lexer
+//	parse     the parse tree
+//	ast       the abstract syntax tree
+//	source    the AST printed back as formatted source, -width long lines. Comments are lost
+//	bytecode  the bytecode of the VM, after the name and type checks
+//	wat       WebAssembly text
+//	ir        LLVM IR
+//	asm       x86-64 assembly (the default)
+//
+// -O1 folds constants and removes dead code and unused functions before the code is generated.
+//
+//	go run ./cmd/compc -emit=ir -O1 -o Test.ll testcode/Test.cs
+package main
+
+import (
+	"compiler/amd64"
+	"compiler/ast"
+	"compiler/callgraph"
+	"compiler/diag"
+	"compiler/flow"
+	"compiler/lexer"
+	"compiler/llvm"
+	"compiler/opt"
+	"compiler/parser"
+	"compiler/printer"
+	"compiler/symtab"
+	"compiler/types"
+	"compiler/vm"
+	"compiler/wat"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+var stages = []string{"tokens", "parse", "ast", "source", "bytecode", "wat", "ir", "asm"}
+
+func main() {
+	emit := flag.String("emit", "asm", "Phase to stop after: "+strings.Join(stages, ", "))
+	optimize := flag.Bool("O1", false, "Fold constants, remove dead code and functions which are never called")
+	output := flag.String("o", "", "Output file, standard output by default")
+	normalize := flag.Bool("normalize", false, "NFC-normalize the source before lexing")
+	maxErrors := flag.Int("max-errors", 0, "Stop after this many errors. 0 means no limit")
+	width := flag.Int("width", 100, "Line width of -emit=source")
+	tolerant := flag.Bool("tolerant", false, "Repair syntax errors and go on, with -emit=parse or -emit=ast. The tree has ERROR nodes for the regions which could not be parsed")
+	flag.Parse()
+
+	if flag.NArg() != 1 || !slices.Contains(stages, *emit) {
+		fmt.Fprintln(os.Stderr, "Usage: compc [-emit="+strings.Join(stages, "|")+"] [-O1] [-tolerant] [-o output] program.cs")
+		os.Exit(2)
+	}
+	path := flag.Arg(0)
+	printer := diag.MakePrinter(*maxErrors)
+	options := lexer.Options{Normalize: *normalize, File: path, Output: io.Discard, Printer: printer}
+	// The diagnostics of the lexer and the parser go to standard error, the rest of their output is not needed
+	options.Report = func(diagnostic *diag.Diagnostic) {
+		printer.Print(os.Stderr, diagnostic)
+	}
+
+	result, err := compile(path, *emit, *optimize, *tolerant, *width, options)
+	if err != nil {
+		if err.Error() != "" {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(1)
+	}
+	if *output == "" {
+		fmt.Print(result)
+		return
+	}
+	if err := os.WriteFile(*output, []byte(result), 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// The output of the stage. The error is empty if the diagnostics were printed already
+func compile(path string, emit string, optimize bool, tolerant bool, width int, options lexer.Options) (string, error) {
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	if emit == "tokens" {
+		return dumpTokens(lexer.Tokens(path, options)), nil
+	}
+
+	// The stages after the AST need a whole program
+	tolerant = tolerant && (emit == "parse" || emit == "ast")
+	parse := parser.ParseContext
+	if tolerant {
+		parse = parser.ParseTolerant
+	}
+	tree, ok, err := parse(context.Background(), path, true, options)
+	if err != nil {
+		return "", err
+	}
+	if !ok && !tolerant {
+		return "", errors.New("")
+	}
+	if emit == "parse" {
+		return dumpTree(tree, 0), nil
+	}
+
+	program, err := ast.Build(tree)
+	if err != nil {
+		return "", err
+	}
+	if emit == "ast" {
+		return ast.Dump(program), nil
+	}
+	if emit == "source" {
+		return printer.MakePrinter(width).Print(program), nil
+	}
+
+	diagnostics := symtab.Bind(program)
+	if len(diagnostics) == 0 {
+		_, diagnostics = types.Check(program)
+	}
+	if len(diagnostics) == 0 {
+		diagnostics = flow.Check(program)
+	}
+	for _, diagnostic := range diagnostics {
+		diagnostic.File = path
+		options.Printer.Print(os.Stderr, diagnostic)
+	}
+	if diag.HasErrors(diagnostics) {
+		return "", errors.New("")
+	}
+
+	if optimize {
+		callgraph.RemoveDead(program)
+		pipeline := opt.Pipeline{}.Add(opt.Constants{}).Add(opt.Unreachable{}).Add(opt.UnusedCode{})
+		for _, function := range program.Functions {
+			pipeline.Run(function)
+		}
+	}
+
+	switch emit {
+	case "bytecode":
+		bytecode, err := vm.Compile(program)
+		if err != nil {
+			return "", err
+		}
+		return bytecode.Disassemble() + "\n", nil
+	case "wat":
+		return wat.Generate(program)
+	case "ir":
+		return llvm.Generate(program)
+	}
+	return amd64.Generate(program)
+}
+
+// Indented like ast.Dump, without the colors of parser.RenderTree
+func dumpTree(tree parser.ParseTree, depth int) string {
+	line := strings.Repeat("  ", depth) + tree.Leaf.Name
+	if len(tree.Branches) == 0 {
+		line += fmt.Sprintf(" %v %d:%d", tree.Leaf.Value, tree.Leaf.Line, tree.Leaf.Col)
+	}
+	dump := line + "\n"
+	for _, branch := range tree.Branches {
+		dump += dumpTree(branch, depth+1)
+	}
+	return dump
+}
+
+func dumpTokens(tokens []lexer.Token) string {
+	var builder strings.Builder
+	for _, token := range tokens {
+		if token.Identifier == "LINE" {
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("%d:%d\t%s\t%v\n", token.Line, token.Col, token.Identifier, token.Value))
+	}
+	return builder.String()
+}
@@ -0,0 +1,88 @@
+// Generates a lexer and an LALR(1) parser in Go from one spec file, a yacc grammar with token patterns
+// (see parser/yacc.go and parser/lexgen.go):
+//
+//	%token NUM /[0-9]+/
+//	%skip /[ \t\n]+/
+//	%%
+//	list : list ',' NUM | NUM ;
+//
+// With
+//
+//	//go:generate compilergen calc.cspec
+//
+// it writes lexer.go and parser.go next to the spec, Parse(Lex(input)) parses a string. Install it with go install ./cmd/compilergen
+package main
+
+import (
+	"compiler/parser"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	lexerOutput := flag.String("lexer", "", "Output file of the lexer, lexer.go next to the spec by default")
+	parserOutput := flag.String("parser", "", "Output file of the parser, parser.go next to the spec by default")
+	packageName := flag.String("p", "", "Package of the generated files, $GOPACKAGE or main by default")
+	report := flag.String("report", "", "Also write the LALR(1) automaton and its conflicts as JSON to this file, see parser/jsonreport.go")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: compilergen [-lexer lexer.go] [-parser parser.go] [-p package] [-report report.json] spec.cspec")
+		os.Exit(2)
+	}
+	path := flag.Arg(0)
+	if *lexerOutput == "" {
+		*lexerOutput = filepath.Join(filepath.Dir(path), "lexer.go")
+	}
+	if *parserOutput == "" {
+		*parserOutput = filepath.Join(filepath.Dir(path), "parser.go")
+	}
+	if *packageName == "" {
+		*packageName = os.Getenv("GOPACKAGE")
+	}
+	if *packageName == "" {
+		*packageName = "main"
+	}
+
+	source, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	spec, err := parser.ParseYacc(string(source))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, path+": "+err.Error())
+		os.Exit(1)
+	}
+	lexerCode, err := spec.GenerateLexer(*packageName, filepath.Base(path), "compilergen")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, path+": "+err.Error())
+		os.Exit(1)
+	}
+	parserCode, conflicts, err := spec.GenerateGo(*packageName, filepath.Base(path), "compilergen")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, path+": "+err.Error())
+		os.Exit(1)
+	}
+	for _, warning := range spec.Grammar.Warnings() {
+		fmt.Fprintln(os.Stderr, path+": warning: "+warning)
+	}
+	// Like yacc: Conflicts are resolved and only reported
+	for _, conflict := range conflicts {
+		fmt.Fprintln(os.Stderr, path+": "+conflict.String())
+	}
+	for output, code := range map[string][]byte{*lexerOutput: lexerCode, *parserOutput: parserCode} {
+		if err := os.WriteFile(output, code, 0o644); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if *report != "" {
+		if err := os.WriteFile(*report, []byte(spec.Table().Report().JSON()), 0o644); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+// Generates reference documentation with railroad diagrams from a grammar, see parser/doc.go.
+//
+//	grammardoc -format=html -o grammar.html calc.y
+//
+// -format=report writes the parsing table instead, see parser/report.go: the LR(0) automaton as SVG and the states
+// with their items, actions and gotos, in one HTML page which needs no Graphviz. -format=json writes the same as JSON
+// for tools, see parser/jsonreport.go.
+// Reads BNF (parser/bnf.go), yacc (.y or .cspec, parser/yacc.go) or S-expression (.sexpr, parser/sexpr.go) grammars.
+// The comment lines in front of a rule document its non terminal. Without a file it documents the language of the compiler
+package main
+
+import (
+	"compiler/parser"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	format := flag.String("format", "md", "md, html, report or json")
+	lalr := flag.Bool("lalr", false, "Report of the LALR(1) table instead of the SLR(1) table, with -format=report or json")
+	output := flag.String("o", "", "Output file, standard output by default")
+	title := flag.String("title", "", "Title of the HTML page, the name of the grammar file by default")
+	flag.Parse()
+
+	if flag.NArg() > 1 || (*format != "md" && *format != "html" && *format != "report" && *format != "json") {
+		fmt.Fprintln(os.Stderr, "Usage: grammardoc [-format=md|html|report|json] [-lalr] [-title title] [-o output] [grammar.bnf|grammar.y|grammar.sexpr]")
+		os.Exit(2)
+	}
+
+	grammar := parser.Language(true)
+	name := "Language"
+	if flag.NArg() == 1 {
+		path := flag.Arg(0)
+		var err error
+		grammar, err = parser.ReadGrammarFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, path+": "+err.Error())
+			os.Exit(1)
+		}
+		name = filepath.Base(path)
+	}
+	if *title == "" && *format == "report" {
+		*title = name + " parsing table"
+	}
+	if *title == "" {
+		*title = name + " grammar reference"
+	}
+
+	doc := ""
+	switch *format {
+	case "md":
+		doc = grammar.Markdown()
+	case "html":
+		doc = grammar.HTML(*title)
+	case "report", "json":
+		table := grammar.CreateSLRParser()
+		if *lalr {
+			table = grammar.CreateLALRParser()
+		}
+		if *format == "json" {
+			doc = table.Report().JSON()
+		} else {
+			doc = table.HTMLReport(*title)
+		}
+	}
+	if *output == "" {
+		fmt.Print(doc)
+		return
+	}
+	if err := os.WriteFile(*output, []byte(doc), 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
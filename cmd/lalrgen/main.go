@@ -0,0 +1,71 @@
+// Generates an LALR(1) parser in Go from a yacc like grammar file, see parser/yacc.go for the format.
+//
+//	//go:generate lalrgen calc.y
+//
+// writes calc.go into the package of the file with the go:generate line. Install it with go install ./cmd/lalrgen
+package main
+
+import (
+	"compiler/parser"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	output := flag.String("o", "", "Output file, the grammar file with .go instead of .y by default")
+	packageName := flag.String("p", "", "Package of the generated file, $GOPACKAGE or main by default")
+	report := flag.String("report", "", "Also write the LALR(1) automaton and its conflicts as JSON to this file, see parser/jsonreport.go")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: lalrgen [-o output.go] [-p package] [-report report.json] grammar.y")
+		os.Exit(2)
+	}
+	path := flag.Arg(0)
+	if *output == "" {
+		*output = strings.TrimSuffix(path, filepath.Ext(path)) + ".go"
+	}
+	if *packageName == "" {
+		*packageName = os.Getenv("GOPACKAGE")
+	}
+	if *packageName == "" {
+		*packageName = "main"
+	}
+
+	source, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	yacc, err := parser.ParseYacc(string(source))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, path+": "+err.Error())
+		os.Exit(1)
+	}
+	code, conflicts, err := yacc.GenerateGo(*packageName, filepath.Base(path), "lalrgen")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, path+": "+err.Error())
+		os.Exit(1)
+	}
+	for _, warning := range yacc.Grammar.Warnings() {
+		fmt.Fprintln(os.Stderr, path+": warning: "+warning)
+	}
+	// Like yacc: Conflicts are resolved and only reported
+	for _, conflict := range conflicts {
+		fmt.Fprintln(os.Stderr, path+": "+conflict.String())
+	}
+	err = os.WriteFile(*output, code, 0o644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *report != "" {
+		if err := os.WriteFile(*report, []byte(yacc.Table().Report().JSON()), 0o644); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+// Language server for the C# subset over standard input and output, see lsp/server.go.
+//
+//	langserver
+//
+// Editors start it for .cs files, e.g. in VS Code with a client extension or in Neovim with
+// vim.lsp.start({name = "neon", cmd = {"langserver"}}). Errors are written to standard error
+package main
+
+import (
+	"compiler/lsp"
+	"fmt"
+	"os"
+)
+
+func main() {
+	if err := lsp.MakeServer(os.Stdin, os.Stdout).Run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
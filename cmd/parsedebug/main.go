@@ -0,0 +1,184 @@
+// Steps through a parse interactively, see debug/session.go.
+//
+//	parsedebug program.cs
+//	parsedebug -grammar calc.y [-lalr] "NUM + NUM"
+//
+// Without -grammar it parses a C# file with the grammar of the compiler. With a grammar file (BNF, .y or .sexpr)
+// the input is a list of terminals separated by whitespace. Type help for the commands
+package main
+
+import (
+	"bufio"
+	"compiler/debug"
+	"compiler/lexer"
+	"compiler/parser"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const help = `s, step          do the next action of the parser
+n, next          go on until the next terminal is shifted
+c, continue      go on until a state with a breakpoint is on top of the stack
+b, break [N]     set a breakpoint on state N, list the breakpoints without N
+d, delete N      remove the breakpoint on state N
+r, back          undo the last action
+restart          go back to the start
+p, stack         show the stack, the lookahead and the next action
+t, trace         show the whole parse
+q, quit`
+
+func main() {
+	grammarFile := flag.String("grammar", "", "Grammar file (BNF, .y or .sexpr), the input is then a list of terminals")
+	lalr := flag.Bool("lalr", false, "Build an LALR(1) table instead of an SLR(1) table, with -grammar")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: parsedebug program.cs | parsedebug -grammar file [-lalr] \"terminals\"")
+		os.Exit(2)
+	}
+
+	var table *parser.SLR_parsing_Table
+	var tokens []lexer.Token
+	if *grammarFile == "" {
+		grammar := parser.Language(true)
+		table = grammar.CreateSLRParser()
+		tokens = lexer.Tokens(flag.Arg(0), lexer.Options{Output: os.Stderr})
+	} else {
+		grammar, err := parser.ReadGrammarFile(*grammarFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, *grammarFile+": "+err.Error())
+			os.Exit(1)
+		}
+		if *lalr {
+			table = grammar.CreateLALRParser()
+		} else {
+			table = grammar.CreateSLRParser()
+		}
+		for _, terminal := range strings.Fields(flag.Arg(0)) {
+			tokens = append(tokens, lexer.Token{Identifier: terminal, Value: terminal})
+		}
+	}
+
+	session, err := debug.MakeSession(table, tokens)
+	if err != nil {
+		// The steps up to the error can still be looked at
+		fmt.Fprintln(os.Stderr, err)
+	}
+	fmt.Println("Type help for the commands")
+	show(os.Stdout, session)
+	input := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Print("(parsedebug) ")
+		if !input.Scan() {
+			fmt.Println()
+			return
+		}
+		fields := strings.Fields(input.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if !run(os.Stdout, session, fields) {
+			return
+		}
+	}
+}
+
+// Runs one command, false to quit
+func run(out io.Writer, session *debug.Session, fields []string) bool {
+	state := -1
+	if len(fields) > 1 {
+		var err error
+		if state, err = strconv.Atoi(fields[1]); err != nil {
+			fmt.Fprintln(out, "Not a state: "+fields[1])
+			return true
+		}
+	}
+	switch fields[0] {
+	case "s", "step":
+		if step, ok := session.Step(); ok {
+			fmt.Fprintln(out, describe(session, step))
+		}
+		show(out, session)
+	case "n", "next":
+		for _, step := range session.StepSymbol() {
+			fmt.Fprintln(out, describe(session, step))
+		}
+		show(out, session)
+	case "c", "continue":
+		for _, step := range session.Continue() {
+			fmt.Fprintln(out, describe(session, step))
+		}
+		if top, _ := session.Top(); !session.Done() {
+			fmt.Fprintln(out, "Breakpoint on state "+strconv.Itoa(top))
+		}
+		show(out, session)
+	case "b", "break":
+		if state == -1 {
+			fmt.Fprintln(out, "Breakpoints:", session.Breakpoints())
+			break
+		}
+		session.Break(state)
+	case "d", "delete":
+		if state == -1 {
+			fmt.Fprintln(out, "Which state?")
+			break
+		}
+		session.Clear(state)
+	case "r", "back":
+		if !session.Back() {
+			fmt.Fprintln(out, "At the start")
+		}
+		show(out, session)
+	case "restart":
+		session.Restart()
+		show(out, session)
+	case "p", "stack":
+		show(out, session)
+	case "t", "trace":
+		fmt.Fprint(out, session.Trace().String())
+	case "q", "quit":
+		return false
+	case "h", "help":
+		fmt.Fprintln(out, help)
+	default:
+		fmt.Fprintln(out, "Unknown command "+fields[0]+", type help for the commands")
+	}
+	return true
+}
+
+// The stack, the rest of the input and what happens next
+func show(out io.Writer, session *debug.Session) {
+	states, symbols := session.Stack()
+	stack := []string{strconv.Itoa(states[0])}
+	for i, symbol := range symbols {
+		stack = append(stack, symbol, strconv.Itoa(states[i+1]))
+	}
+	fmt.Fprintln(out, "  stack: "+strings.Join(stack, " "))
+	fmt.Fprintln(out, "  input: "+strings.Join(session.Trace().Input[session.Position():], " "))
+	if step, ok := session.Next(); ok {
+		fmt.Fprintln(out, "  next:  "+describe(session, step))
+		return
+	}
+	if session.Trace().Accepted {
+		fmt.Fprintln(out, "  accepted")
+	} else {
+		fmt.Fprintln(out, "  failed")
+	}
+}
+
+func describe(session *debug.Session, step parser.TraceStep) string {
+	switch step.Action {
+	case "Shift":
+		return "shift " + step.Lookahead + ", goto " + strconv.Itoa(step.Next)
+	case "Reduce":
+		return "reduce " + step.Rule + ", goto " + strconv.Itoa(step.Next)
+	case "Accept":
+		return "accept"
+	}
+	trace := session.Trace()
+	return "error at \"" + step.Lookahead + "\", expected " + strings.Join(trace.Expected, " ")
+}
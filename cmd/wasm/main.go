@@ -0,0 +1,186 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"compiler/ast"
+	"compiler/diag"
+	"compiler/flow"
+	"compiler/lexer"
+	"compiler/parser"
+	"compiler/symtab"
+	"compiler/types"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"syscall/js"
+)
+
+/*
+The front end in the browser. Build with
+
+	GOOS=js GOARCH=wasm go build -o neon.wasm ./cmd/wasm
+
+and load it with wasm_exec.js from $(go env GOROOT)/lib/wasm (misc/wasm before Go 1.24). It sets a global object neon,
+every function returns a JSON string:
+
+	neon.lex(source)          [{"kind": "name", "value": "a", "line": 3}, ...]
+	neon.parse(source)        {"ok": true, "tree": {...}, "diagnostics": [...], "output": "..."}
+	neon.match(grammar, input) {"match": true, "ambiguous": false}, grammar in BNF, input are terminals separated by whitespace
+
+Diagnostics are {"code": "E0101", "message": "...", "line": 3, "suggestions": [...]}.
+parse runs the name and type checks as well, if the source has no syntax errors.
+*/
+
+type tokenJSON struct {
+	Kind  string `json:"kind"`
+	Value any    `json:"value"`
+	Line  int    `json:"line"`
+}
+
+type treeJSON struct {
+	Symbol   string     `json:"symbol"`
+	Value    any        `json:"value,omitempty"`
+	Line     int        `json:"line,omitempty"`
+	Children []treeJSON `json:"children,omitempty"`
+}
+
+type diagnosticJSON struct {
+	Code        diag.Code `json:"code"`
+	Message     string    `json:"message"`
+	Severity    string    `json:"severity"`
+	Line        int       `json:"line"`
+	Col         int       `json:"col"`
+	Suggestions []string  `json:"suggestions,omitempty"`
+}
+
+type parseJSON struct {
+	Ok          bool             `json:"ok"`
+	Tree        *treeJSON        `json:"tree,omitempty"`
+	Diagnostics []diagnosticJSON `json:"diagnostics"`
+	Output      string           `json:"output"`
+}
+
+type matchJSON struct {
+	Match     bool   `json:"match"`
+	Ambiguous bool   `json:"ambiguous"`
+	Error     string `json:"error,omitempty"`
+}
+
+func main() {
+	js.Global().Set("neon", js.ValueOf(map[string]any{
+		"lex":   js.FuncOf(lex),
+		"parse": js.FuncOf(parse),
+		"match": js.FuncOf(match),
+	}))
+	// The functions are called from JavaScript, main must not return
+	select {}
+}
+
+func lex(this js.Value, args []js.Value) any {
+	tokenChannel := make(chan lexer.Token)
+	go lexer.LexReader(context.Background(), strings.NewReader(argument(args, 0)), tokenChannel, lexer.Options{Output: &strings.Builder{}})
+	tokens := []tokenJSON{}
+	for token := range tokenChannel {
+		if token.Identifier == "LINE" {
+			continue
+		}
+		tokens = append(tokens, tokenJSON{Kind: token.Identifier, Value: value(token.Value), Line: token.Line})
+	}
+	return marshal(tokens)
+}
+
+func parse(this js.Value, args []js.Value) any {
+	var output strings.Builder
+	result := parseJSON{Diagnostics: []diagnosticJSON{}}
+	// wasm has only one thread, so the lexer and the parser can not report at the same time
+	report := func(diagnostic *diag.Diagnostic) {
+		result.Diagnostics = append(result.Diagnostics, makeDiagnosticJSON(diagnostic))
+	}
+	tree, ok, _ := parser.ParseSource(context.Background(), argument(args, 0), true, lexer.Options{Output: &output, Report: report})
+	result.Output = output.String()
+	if !ok {
+		return marshal(result)
+	}
+	node := makeTreeJSON(tree)
+	result.Tree = &node
+
+	program, err := ast.Build(tree)
+	if err != nil {
+		result.Output += err.Error() + "\n"
+		return marshal(result)
+	}
+	diagnostics := symtab.Bind(program)
+	if len(diagnostics) == 0 {
+		_, diagnostics = types.Check(program)
+	}
+	if len(diagnostics) == 0 {
+		diagnostics = flow.Check(program)
+	}
+	for _, diagnostic := range diagnostics {
+		report(diagnostic)
+	}
+	result.Ok = !diag.HasErrors(diagnostics)
+	return marshal(result)
+}
+
+func match(this js.Value, args []js.Value) any {
+	grammar, err := parser.ParseBNF(argument(args, 0))
+	if err != nil {
+		return marshal(matchJSON{Error: err.Error()})
+	}
+	tokens := []lexer.Token{}
+	for _, word := range strings.Fields(argument(args, 1)) {
+		tokens = append(tokens, lexer.Token{Identifier: word, Value: word})
+	}
+	forest, err := grammar.CreateEarleyParser().Parse(tokens)
+	if err != nil {
+		return marshal(matchJSON{})
+	}
+	return marshal(matchJSON{Match: true, Ambiguous: len(forest.Ambiguities()) > 0})
+}
+
+func argument(args []js.Value, i int) string {
+	if i >= len(args) || args[i].Type() != js.TypeString {
+		return ""
+	}
+	return args[i].String()
+}
+
+// Interpolated strings carry their segments, JavaScript gets the text of the token instead
+func value(v any) any {
+	switch v.(type) {
+	case []lexer.StringSegment, []parser.InterpolationSegment:
+		return "interpolated string"
+	}
+	return v
+}
+
+func makeTreeJSON(tree parser.ParseTree) treeJSON {
+	node := treeJSON{Symbol: tree.Leaf.Name, Line: tree.Leaf.Line}
+	// Non terminals have the value 0
+	if len(tree.Branches) == 0 && tree.Leaf.Value != 0 {
+		node.Value = value(tree.Leaf.Value)
+	}
+	for _, branch := range tree.Branches {
+		node.Children = append(node.Children, makeTreeJSON(branch))
+	}
+	return node
+}
+
+func makeDiagnosticJSON(diagnostic *diag.Diagnostic) diagnosticJSON {
+	result := diagnosticJSON{Code: diagnostic.Code, Severity: string(diagnostic.Severity), Message: diagnostic.Message, Line: diagnostic.Line, Col: diagnostic.Col}
+	for _, suggestion := range diagnostic.Suggestions {
+		result.Suggestions = append(result.Suggestions, suggestion.Message+" ("+suggestion.Edit.String()+")")
+	}
+	return result
+}
+
+func marshal(v any) string {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf(`{"error": %q}`, err.Error())
+	}
+	return string(encoded)
+}
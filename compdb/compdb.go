@@ -0,0 +1,41 @@
+package compdb
+
+import (
+	"encoding/json"
+	"path/filepath"
+)
+
+/*
+Exports for build systems and IDE indexers:
+	Commands  the compile command of every file, in the format of compile_commands.json from clang
+	Analyze   which file needs which other file, from the using directives and the namespaces of the files
+Every file is compiled on its own, so the dependencies only tell which files belong together.
+*/
+
+// One entry of compile_commands.json
+type Command struct {
+	Directory string   `json:"directory"`
+	File      string   `json:"file"`
+	Arguments []string `json:"arguments"`
+}
+
+// The command of every file: the compiler, the flags and the file. Relative paths are relative to directory
+func Commands(directory string, compiler string, flags []string, paths []string) []Command {
+	commands := []Command{}
+	for _, path := range paths {
+		arguments := append([]string{compiler}, flags...)
+		arguments = append(arguments, path)
+		file := path
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(directory, file)
+		}
+		commands = append(commands, Command{Directory: directory, File: file, Arguments: arguments})
+	}
+	return commands
+}
+
+// Indented JSON, like the files clang writes
+func CommandsJSON(commands []Command) string {
+	encoded, _ := json.MarshalIndent(commands, "", "  ")
+	return string(encoded) + "\n"
+}
@@ -0,0 +1,102 @@
+package compdb
+
+import (
+	"compiler/ast"
+	"compiler/lexer"
+	"compiler/parser"
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+type Graph struct {
+	Files []File `json:"files"`
+	// Namespaces which are used, but declared by none of the files, e.g. System
+	External []string `json:"external"`
+	// The files with every file after the files it depends on. Files in a cycle are in the order of the paths
+	Order []string `json:"order"`
+}
+
+type File struct {
+	Path      string   `json:"path"`
+	Namespace string   `json:"namespace,omitempty"`
+	Class     string   `json:"class,omitempty"`
+	Usings    []string `json:"usings"`
+	// Files which declare a namespace of the usings
+	Dependencies []string `json:"dependencies"`
+	// The diagnostics if the file does not parse, it has no namespace then
+	Error string `json:"error,omitempty"`
+}
+
+// Parses the files with at most workers at the same time and connects them by their usings and namespaces
+func Analyze(paths []string, options lexer.Options, workers int) *Graph {
+	graph := &Graph{Files: []File{}, External: []string{}}
+	declaring := make(map[string][]string)
+	for _, result := range parser.ParseFiles(paths, true, options, workers) {
+		file := File{Path: result.Path, Usings: []string{}, Dependencies: []string{}}
+		if !result.Ok {
+			// Only the diagnostics, there is no tree
+			file.Error = strings.TrimSpace(result.Output)
+			graph.Files = append(graph.Files, file)
+			continue
+		}
+		program, err := ast.Build(result.Tree)
+		if err != nil {
+			file.Error = err.Error()
+			graph.Files = append(graph.Files, file)
+			continue
+		}
+		file.Namespace = program.Namespace
+		file.Class = program.Class
+		file.Usings = append(file.Usings, program.Usings...)
+		declaring[program.Namespace] = append(declaring[program.Namespace], result.Path)
+		graph.Files = append(graph.Files, file)
+	}
+
+	for i, file := range graph.Files {
+		for _, using := range file.Usings {
+			paths, found := declaring[using]
+			if !found && !slices.Contains(graph.External, using) {
+				graph.External = append(graph.External, using)
+			}
+			for _, path := range paths {
+				if path != file.Path && !slices.Contains(file.Dependencies, path) {
+					graph.Files[i].Dependencies = append(graph.Files[i].Dependencies, path)
+				}
+			}
+		}
+	}
+	slices.Sort(graph.External)
+	graph.Order = order(graph.Files)
+	return graph
+}
+
+// Depth first, dependencies before the file. Edges back into the path are left out
+func order(files []File) []string {
+	byPath := make(map[string]File)
+	for _, file := range files {
+		byPath[file.Path] = file
+	}
+	visited := make(map[string]bool)
+	ordered := []string{}
+	var visit func(path string)
+	visit = func(path string) {
+		if visited[path] {
+			return
+		}
+		visited[path] = true
+		for _, dependency := range byPath[path].Dependencies {
+			visit(dependency)
+		}
+		ordered = append(ordered, path)
+	}
+	for _, file := range files {
+		visit(file.Path)
+	}
+	return ordered
+}
+
+func (graph *Graph) JSON() string {
+	encoded, _ := json.MarshalIndent(graph, "", "  ")
+	return string(encoded) + "\n"
+}
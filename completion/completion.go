@@ -0,0 +1,181 @@
+package completion
+
+import (
+	"compiler/interp"
+	"compiler/lexer"
+	"compiler/parser"
+	"compiler/symtab"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+/*
+Code completion from the grammar. The source in front of the cursor is parsed and every terminal
+the parser can shift next becomes a candidate. Where the grammar expects a name, the candidates come
+from the symbol index of the last program which compiled, since the program is usually broken while typing:
+	after Console.     the built in functions of the receiver
+	after the class.   the functions of the program
+	after a type       nothing, a new name is declared
+	else               the parameters, variables and functions visible at the line, and the receivers of built in functions
+A word which is being typed at the cursor filters the candidates.
+*/
+
+type Kind string
+
+const (
+	Keyword  Kind = "keyword"
+	Operator Kind = "operator"
+	Literal  Kind = "literal"
+	Function Kind = "function"
+	Param    Kind = "parameter"
+	Variable Kind = "variable"
+	// Built in functions and their receivers, e.g. Console.WriteLine
+	Builtin Kind = "builtin"
+)
+
+type Item struct {
+	Label string
+	Kind  Kind
+	// Type of the parameter or variable, return type of the function
+	Detail string
+}
+
+// Values of the terminals which stand for several tokens
+var tokenClasses = map[string][]string{
+	"logicaloperator": {"==", "!=", "<", "<=", ">", ">=", "&&", "||"},
+	"unaryoperator":   {"+", "-"},
+	"multoperator":    {"*", "/", "%"},
+	"boolliteral":     {"true", "false"},
+}
+
+// Names after these tokens are declared, not used
+var declaring = []string{"using", "namespace", "class", "void", "int", "double", "bool", "string", "]"}
+
+// The candidates at the cursor, the line starts at 1 and the column is the number of characters in front of the cursor.
+// index may be nil, then there are no names from the program
+func Complete(source string, line int, column int, index *symtab.Index) ([]Item, error) {
+	prefix, err := cut(source, line, column)
+	if err != nil {
+		return nil, err
+	}
+	word := prefix[len(strings.TrimRightFunc(prefix, isNameRune)):]
+	tokens, expected, err := parser.ExpectedAfter(prefix[:len(prefix)-len(word)], true)
+	if err != nil {
+		return nil, errors.New("Completion Error: " + err.Error())
+	}
+
+	names := []Item{}
+	others := []Item{}
+	for _, terminal := range expected {
+		switch terminal {
+		case "$", "intliteral", "doubleliteral", "stringliteral", "interpolatedstring":
+		case "name":
+			names = candidates(tokens, index, line)
+		case "logicaloperator", "unaryoperator", "multoperator":
+			for _, operator := range tokenClasses[terminal] {
+				others = append(others, Item{Label: operator, Kind: Operator})
+			}
+		case "boolliteral":
+			for _, value := range tokenClasses[terminal] {
+				others = append(others, Item{Label: value, Kind: Literal})
+			}
+		default:
+			kind := Operator
+			if isNameRune(rune(terminal[0])) {
+				kind = Keyword
+			}
+			others = append(others, Item{Label: terminal, Kind: kind})
+		}
+	}
+	slices.SortFunc(others, func(a Item, b Item) int {
+		return strings.Compare(a.Label, b.Label)
+	})
+
+	items := []Item{}
+	for _, item := range append(names, others...) {
+		if strings.HasPrefix(item.Label, word) {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// The source in front of the cursor
+func cut(source string, line int, column int) (string, error) {
+	lines := strings.SplitAfter(source, "\n")
+	if line < 1 || line > len(lines) {
+		return "", errors.New("Completion Error: The source has no line " + strconv.Itoa(line))
+	}
+	current := []rune(strings.TrimRight(lines[line-1], "\r\n"))
+	if column < 0 || column > len(current) {
+		return "", errors.New("Completion Error: Line " + strconv.Itoa(line) + " has no column " + strconv.Itoa(column))
+	}
+	return strings.Join(lines[:line-1], "") + string(current[:column]), nil
+}
+
+// The names which can be used after the tokens
+func candidates(tokens []lexer.Token, index *symtab.Index, line int) []Item {
+	items := []Item{}
+	last := ""
+	if len(tokens) > 0 {
+		last = tokens[len(tokens)-1].Identifier
+	}
+	if slices.Contains(declaring, last) {
+		return items
+	}
+
+	builtins := []string{}
+	for name := range interp.Builtins() {
+		builtins = append(builtins, name)
+	}
+	slices.Sort(builtins)
+
+	if last == "." && len(tokens) > 1 {
+		receiver, _ := tokens[len(tokens)-2].Value.(string)
+		if index != nil && receiver == index.Class() {
+			for _, symbol := range index.Functions() {
+				items = append(items, item(symbol))
+			}
+			return items
+		}
+		for _, name := range builtins {
+			if member, ok := strings.CutPrefix(name, receiver+"."); ok {
+				items = append(items, Item{Label: member, Kind: Builtin})
+			}
+		}
+		return items
+	}
+
+	if index != nil {
+		for _, symbol := range index.Visible(line) {
+			items = append(items, item(symbol))
+		}
+	}
+	receivers := []string{}
+	for _, name := range builtins {
+		receiver, _, _ := strings.Cut(name, ".")
+		if !slices.Contains(receivers, receiver) {
+			receivers = append(receivers, receiver)
+			items = append(items, Item{Label: receiver, Kind: Builtin})
+		}
+	}
+	return items
+}
+
+func item(symbol *symtab.Symbol) Item {
+	kind := Variable
+	switch symbol.Kind {
+	case symtab.Function:
+		kind = Function
+	case symtab.Param:
+		kind = Param
+	}
+	return Item{Label: symbol.Name, Kind: kind, Detail: symbol.Type}
+}
+
+func isNameRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
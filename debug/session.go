@@ -0,0 +1,153 @@
+package debug
+
+import (
+	"compiler/lexer"
+	"compiler/parser"
+	"slices"
+)
+
+/*
+Step debugger for the LR parser, for teaching and for finding out why a grammar does not take an input.
+The parse is run once with parser.Trace, the session moves through its steps, so going back is as cheap as going on:
+
+	session, err := debug.MakeSession(table, tokens)
+	session.Break(7)
+	session.Continue()    // stops when state 7 is on top of the stack
+	states, symbols := session.Stack()
+	session.Back()
+*/
+
+type Session struct {
+	trace parser.Trace
+	// Index of the next step in the trace. All steps before it are done
+	next        int
+	breakpoints map[int]bool
+}
+
+// The error is the one of parser.Trace, the session has the steps up to it then
+func MakeSession(table *parser.SLR_parsing_Table, tokens []lexer.Token) (*Session, error) {
+	session := new(Session)
+	trace, err := table.Trace(tokens)
+	session.trace = trace
+	session.breakpoints = make(map[int]bool)
+	return session, err
+}
+
+// Does the next action of the parser. false if the parser already accepted or failed
+func (session *Session) Step() (parser.TraceStep, bool) {
+	if session.Done() {
+		return parser.TraceStep{}, false
+	}
+	step := session.trace.Steps[session.next]
+	session.next++
+	return step, true
+}
+
+// Goes on until the next terminal is shifted, with the reductions in front of it. The steps which were done
+func (session *Session) StepSymbol() []parser.TraceStep {
+	steps := []parser.TraceStep{}
+	for {
+		step, ok := session.Step()
+		if !ok {
+			return steps
+		}
+		steps = append(steps, step)
+		if step.Action != "Reduce" {
+			return steps
+		}
+	}
+}
+
+// Goes on until a state with a breakpoint is on top of the stack or the parser is done. The steps which were done
+func (session *Session) Continue() []parser.TraceStep {
+	steps := []parser.TraceStep{}
+	for {
+		step, ok := session.Step()
+		if !ok {
+			return steps
+		}
+		steps = append(steps, step)
+		if state, _ := session.Top(); session.breakpoints[state] {
+			return steps
+		}
+	}
+}
+
+// Undoes the last step. false at the start
+func (session *Session) Back() bool {
+	if session.next == 0 {
+		return false
+	}
+	session.next--
+	return true
+}
+
+// Goes back to the start
+func (session *Session) Restart() {
+	session.next = 0
+}
+
+func (session *Session) Break(state int) {
+	session.breakpoints[state] = true
+}
+
+func (session *Session) Clear(state int) {
+	delete(session.breakpoints, state)
+}
+
+// The states with a breakpoint, sorted
+func (session *Session) Breakpoints() []int {
+	states := []int{}
+	for state := range session.breakpoints {
+		states = append(states, state)
+	}
+	slices.Sort(states)
+	return states
+}
+
+// The stack after the steps done so far, the top is the last one. symbols[i] leads to states[i+1]
+func (session *Session) Stack() (states []int, symbols []string) {
+	if session.next < len(session.trace.Steps) {
+		step := session.trace.Steps[session.next]
+		return step.States, step.Symbols
+	}
+	// After accept or an error the stack is the one of the last step
+	last := session.trace.Steps[len(session.trace.Steps)-1]
+	return last.States, last.Symbols
+}
+
+// The state on top of the stack and the terminal the parser looks at
+func (session *Session) Top() (int, string) {
+	states, _ := session.Stack()
+	return states[len(states)-1], session.Lookahead()
+}
+
+func (session *Session) Lookahead() string {
+	return session.trace.Input[session.Position()]
+}
+
+// Index of the next terminal of the input
+func (session *Session) Position() int {
+	if session.next < len(session.trace.Steps) {
+		return session.trace.Steps[session.next].Position
+	}
+	return session.trace.Steps[len(session.trace.Steps)-1].Position
+}
+
+// The next action, false once the parser is done
+func (session *Session) Next() (parser.TraceStep, bool) {
+	if session.Done() {
+		return parser.TraceStep{}, false
+	}
+	return session.trace.Steps[session.next], true
+}
+
+// Whether the parser accepted or failed
+func (session *Session) Done() bool {
+	return session.next == len(session.trace.Steps)
+}
+
+// The whole parse, with the input and where it failed
+func (session *Session) Trace() parser.Trace {
+	return session.trace
+}
@@ -0,0 +1,290 @@
+package diag
+
+import (
+	"slices"
+)
+
+// Stable code of a diagnostic. Errors start with E, warnings with W
+type Code string
+
+type Explanation struct {
+	Code  Code
+	Title string
+	Text  string
+}
+
+const (
+	FileNotOpened          Code = "E0001"
+	TimedOut               Code = "E0002"
+	NotNormalized          Code = "W0001"
+	MixedScript            Code = "W0002"
+	UnexpectedToken        Code = "E0101"
+	UnexpectedEOF          Code = "E0102"
+	ActionConflict         Code = "E0201"
+	GotoConflict           Code = "E0202"
+	TransitionConflict     Code = "E0203"
+	ASTBroken              Code = "E0301"
+	RuntimeError           Code = "E0401"
+	Redeclared             Code = "E0501"
+	NotDeclared            Code = "E0502"
+	UsedBeforeDeclared     Code = "E0503"
+	HidesName              Code = "E0504"
+	RecursionMayNotEnd     Code = "W0601"
+	UnreachableCode        Code = "W0602"
+	MissingReturn          Code = "E0601"
+	NotAssigned            Code = "E0602"
+	TypeMismatch           Code = "E0701"
+	ArgumentCount          Code = "E0702"
+	ConstantOverflow       Code = "E0801"
+	ConstantDivisionByZero Code = "E0802"
+	ParseTreeBroken        Code = "E0901"
+	RuleNotFound           Code = "E0902"
+)
+
+var catalog = map[Code]Explanation{}
+
+func init() {
+	Register(FileNotOpened, "Source file could not be opened",
+		`The lexer could not open the file given to -compile.
+
+Check that the path is correct and that the file is readable. Relative paths
+are resolved from the directory the compiler is started in.
+
+    ./neon -compile testcode/HelloWorld.cs`)
+
+	Register(TimedOut, "Compilation phase took too long",
+		`A phase of the compiler (parse, check or optimize) did not finish within
+its time limit, or the compilation was cancelled. The limits are set with
+-timeout for the whole compilation and -budget for single phases:
+
+    ./neon -compile -timeout 5s -budget parse=2s,check=500ms testcode/Test.cs
+
+Raise the limit, or split the program into smaller files.`)
+
+	Register(NotNormalized, "Identifier is not NFC normalized",
+		`The identifier contains characters in a decomposed form, e.g. "e" followed
+by a combining accent instead of the single character "é". Both look the same,
+but are different identifiers for the compiler.
+
+Compile with -normalize to NFC-normalize the source before lexing, or save the
+file with a normalizing editor.`)
+
+	Register(MixedScript, "Identifier mixes Latin, Greek or Cyrillic letters",
+		`Latin, Greek and Cyrillic contain letters which look the same but are
+different characters, e.g. the Latin "a" and the Cyrillic "а". An identifier
+which mixes these scripts is very likely a typo, which leads to a second
+variable instead of the one you meant.
+
+Retype the identifier using only one script.`)
+
+	Register(UnexpectedToken, "Syntax error: unexpected token",
+		`The parser found a token, which is not allowed at this position of the
+program. The message lists the tokens which would have been accepted.
+
+A common cause is a missing ";" at the end of the previous statement:
+
+    int a = 1
+    a = 2;     <- "a" is unexpected, the parser expects ";"`)
+
+	Register(UnexpectedEOF, "Syntax error: unexpected end of file",
+		`The file ended before the program was complete. Most of the time a
+closing "}" of a block, function, class or namespace is missing.
+
+    namespace Test {
+        class Program {
+            static void Main(string[] args) {
+            }
+        }
+                   <- "}" of the namespace is missing`)
+
+	Register(ActionConflict, "Grammar is not SLR parsable: action table conflict",
+		`Two different actions (shift/reduce or reduce/reduce) were found for the
+same state and terminal while building the SLR parsing table. The grammar in
+parser/grammarConstructor.go has to be changed, so every state has at most one
+action per terminal.`)
+
+	Register(GotoConflict, "Grammar is not SLR parsable: goto table conflict",
+		`Two different goto states were found for the same state and non terminal
+while building the SLR parsing table. This points to an error in the
+construction of the SLR automata.`)
+
+	Register(TransitionConflict, "Conflicting transitions in the SLR automata",
+		`A state of the SLR automata got two different transitions for the same
+symbol. This points to an error in the construction of the SLR automata.`)
+
+	Register(ASTBroken, "Internal error: AST could not be built",
+		`The parse tree did not have the shape the AST builder expects for the
+grammar. This happens when the grammar in parser/grammarConstructor.go was
+changed without changing ast/build.go as well.`)
+
+	Register(RuntimeError, "Runtime error",
+		`The interpreter (-run) stopped the program. Common causes are a division by
+zero, a variable that is used before it is declared, calling a function with
+the wrong number of arguments, a non void function that ends without return,
+or endless recursion:
+
+    static int Fib(int n) {
+        return Fib(n - 1) + Fib(n - 2);   <- never stops
+    }`)
+
+	Register(Redeclared, "Name is declared twice",
+		`A function, parameter or variable was declared a second time in the same
+scope. Rename one of them:
+
+    int a = 1;
+    int a = 2;   <- a is declared already`)
+
+	Register(NotDeclared, "Name is not declared",
+		`A variable or function is used, but there is no declaration for it in this
+scope or an enclosing one. Check the spelling, or declare it first:
+
+    a = 1;       <- missing int a;`)
+
+	Register(UsedBeforeDeclared, "Variable is used before it is declared",
+		`The variable is declared in this block, but further down than where it is
+used. Like in C#, the declaration has to come first:
+
+    Console.WriteLine(a);   <- a is declared in the next line
+    int a = 1;`)
+
+	Register(HidesName, "Name hides a parameter or variable",
+		`A parameter or variable of an enclosing scope has the same name. C# does not
+allow this, because it is easy to mix up the two:
+
+    static void F(int a) {
+        if (a > 0) {
+            int a = 2;   <- hides the parameter a
+        }
+    }`)
+
+	Register(RecursionMayNotEnd, "Recursion might not end",
+		`A function calls itself (or a function which calls it back), but none of the
+arguments gets smaller. A recursion ends when a parameter which is tested in a
+condition (the base case) gets smaller with every call:
+
+    static int Fib(int n) {
+        if (n <= 2) {
+            return 1;
+        }
+        return Fib(n - 1) + Fib(n - 2);   <- n gets smaller, fine
+    }
+
+    static int Loop(int n) {
+        return Loop(n);                   <- never ends
+    }
+
+The check only knows n - c and n / c, so other recursions can be fine as well.`)
+
+	Register(UnreachableCode, "Statement is never reached",
+		`No path through the function leads to the statement, it never runs. The
+statements after a return, after while (true) and in branches of constant
+conditions are never reached:
+
+    return a;
+    a = 2;              <- never reached
+    if (false) {
+        a = 3;          <- never reached
+    }
+
+Only the first statement of the unreachable code is reported. Remove the code,
+or fix the condition which skips it.`)
+
+	Register(MissingReturn, "Function does not return a value on every path",
+		`The function has a return type, but it can reach the end of its body
+without a return statement:
+
+    static int Sign(int a) {
+        if (a < 0) {
+            return -1;
+        }
+        if (a > 0) {
+            return 1;
+        }
+    }                   <- a == 0 ends here
+
+The compiler does not know which values the conditions can have, only constant
+conditions like while (true) are followed. Add a return at the end.`)
+
+	Register(NotAssigned, "Variable is used before it is assigned",
+		`A variable which is declared without a value is read, but there is a path to
+the read on which it is never assigned:
+
+    int a;
+    if (b) {
+        a = 1;
+    }
+    Console.WriteLine(a);   <- a is not assigned if b is false
+
+A loop may not run at all, so assignments inside it do not count after the
+loop. Assign a value in the declaration, or on every path.`)
+
+	Register(TypeMismatch, "Types do not match",
+		`A value has a type which can not be used here. An int can be used where a
+double is expected, every other type has to match exactly:
+
+    int a = 2.5;        <- a double can not be stored in an int
+    if (a) { }          <- conditions have to be bool
+    bool b = 1 + true;  <- + needs numbers or a string`)
+
+	Register(ArgumentCount, "Wrong number of arguments",
+		`The function is called with more or less arguments than it has parameters:
+
+    static int Add(int a, int b) { return a + b; }
+    Add(1);             <- Add takes 2 arguments`)
+
+	Register(ConstantOverflow, "Constant expression overflows int",
+		`An operation on int constants has a result which does not fit into an int
+(-2147483648 to 2147483647). C# computes constant expressions when compiling,
+so this is an error and not a value which wraps around at runtime:
+
+    int a = 2147483647 + 1;    <- overflows
+    int b = 3000000000;        <- the literal is too large for int
+    double c = 2147483647.0 + 1;   <- fine, doubles have a larger range
+
+Compute with doubles, or split the value.`)
+
+	Register(ConstantDivisionByZero, "Constant division by zero",
+		`An int constant is divided by the constant 0 with / or %. This would fail
+at runtime every time:
+
+    int a = 1 / 0;
+    int b = 10 % (2 - 2);
+
+Doubles divided by 0 are infinity or NaN, which is not an error.`)
+
+	Register(ParseTreeBroken, "Internal error: parse tree could not be built",
+		`A reduction needed more parse trees than were on the tree stack. This is
+an error in the compiler, not in the program being compiled.`)
+
+	Register(RuleNotFound, "Internal error: rule not found in grammar",
+		`An item of the SLR automata refers to a rule, which is not part of the
+grammar. This is an error in the compiler, not in the program being compiled.`)
+}
+
+// Adds an explanation to the catalog. Codes have to be unique
+func Register(code Code, title string, text string) {
+	if _, ok := catalog[code]; ok {
+		panic("Diagnostic code registered twice: " + string(code))
+	}
+	catalog[code] = Explanation{Code: code, Title: title, Text: text}
+}
+
+func Lookup(code Code) (Explanation, bool) {
+	explanation, ok := catalog[code]
+	return explanation, ok
+}
+
+// All registered codes, sorted
+func Codes() []Code {
+	codes := []Code{}
+	for code := range catalog {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	return codes
+}
+
+// Prefixes the message with the code, e.g. "[E0101] Syntax Error. ..."
+func (code Code) Format(message string) string {
+	return "[" + string(code) + "] " + message
+}
@@ -0,0 +1,103 @@
+package diag
+
+import (
+	"strconv"
+	"strings"
+)
+
+type Diagnostic struct {
+	Position
+	Code        Code
+	Severity    Severity
+	Message     string
+	Suggestions []Suggestion
+	// Other places which belong to the diagnostic, e.g. the first declaration of a name. Printed as notes
+	Related []Related
+}
+
+type Severity string
+
+const (
+	Error   Severity = "error"
+	Warning Severity = "warning"
+	Note    Severity = "note"
+)
+
+type Related struct {
+	Position
+	Message string
+}
+
+// A fix for the diagnostic, which can be applied without asking the user
+type Suggestion struct {
+	Message string
+	Edit    Edit
+}
+
+// Inserts text in front of the first token on the line matching Before. Empty Before inserts at the end of the line.
+// With Replace set, Insert replaces Before, e.g. a misspelled name. Col is the column of Before, 0 if it is not known
+type Edit struct {
+	Line    int
+	Col     int
+	Before  string
+	Insert  string
+	Replace bool
+}
+
+func MakeDiagnostic(code Code, message string, line int) *Diagnostic {
+	return MakeDiagnosticAt(code, message, Position{Line: line})
+}
+
+func MakeDiagnosticAt(code Code, message string, position Position) *Diagnostic {
+	newDiagnostic := new(Diagnostic)
+	newDiagnostic.Code = code
+	newDiagnostic.Severity = code.Severity()
+	newDiagnostic.Message = message
+	newDiagnostic.Position = position
+	return newDiagnostic
+}
+
+// Warnings start with W, everything else is an error
+func (code Code) Severity() Severity {
+	if strings.HasPrefix(string(code), "W") {
+		return Warning
+	}
+	return Error
+}
+
+// Whether one of the diagnostics is an error, warnings do not stop the compilation
+func HasErrors(diagnostics []*Diagnostic) bool {
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+func (diagnostic *Diagnostic) AddNote(message string, position Position) {
+	diagnostic.Related = append(diagnostic.Related, Related{Position: position, Message: message})
+}
+
+func (diagnostic *Diagnostic) Suggest(message string, edit Edit) {
+	diagnostic.Suggestions = append(diagnostic.Suggestions, Suggestion{Message: message, Edit: edit})
+}
+
+func (diagnostic *Diagnostic) String() string {
+	returnString := diagnostic.Code.Format(diagnostic.Message)
+	for _, suggestion := range diagnostic.Suggestions {
+		returnString += "\nHelp: " + suggestion.Message + " (" + suggestion.Edit.String() + ")"
+	}
+	return returnString
+}
+
+func (edit Edit) String() string {
+	lineString := strconv.Itoa(edit.Line)
+	if edit.Replace {
+		return "replace \"" + edit.Before + "\" with \"" + edit.Insert + "\" at line " + lineString
+	}
+	if edit.Before == "" {
+		return "insert \"" + edit.Insert + "\" at the end of line " + lineString
+	}
+	return "insert \"" + edit.Insert + "\" before \"" + edit.Before + "\" at line " + lineString
+}
@@ -0,0 +1,6 @@
+package diag
+
+import "compiler/source"
+
+// Where a diagnostic points to. The file set of the printer maps it to the position of //line directives
+type Position = source.Position
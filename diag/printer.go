@@ -0,0 +1,152 @@
+package diag
+
+import (
+	"compiler/source"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+/*
+Prints diagnostics like gcc, with the line of the source and a caret under the column:
+
+	Test.cs:5:9: error: [E0502] Name Error: The variable b at line 5 is not declared
+	    5 |         b = 1;
+	      |         ^
+	Help: ...
+	Test.cs:3:13: note: a is declared here
+
+The positions go through the file set, so //line directives are followed. Sources the lexer did not add to it
+are read from the file of the diagnostic the first time they are needed, the excerpts always show the real line.
+After Limit errors the rest is left out. The lexer and the parser print at the same time into their own buffers,
+so Print takes the writer and can be called from several go routines.
+*/
+
+type Printer struct {
+	// Errors to print before stopping, 0 for no limit
+	Limit int
+	Files *source.FileSet
+
+	mutex sync.Mutex
+	// Files which could not be read
+	missing map[string]bool
+	errors  int
+	stopped bool
+}
+
+func MakePrinter(limit int) *Printer {
+	newPrinter := new(Printer)
+	newPrinter.Limit = limit
+	newPrinter.Files = source.MakeFileSet()
+	newPrinter.missing = make(map[string]bool)
+	return newPrinter
+}
+
+// Source of a file which is not on disk, e.g. from an editor. The empty file name is for sources without a file
+func (printer *Printer) AddSource(file string, content string) {
+	printer.Files.AddFile(file, []byte(content))
+}
+
+// Number of errors printed so far
+func (printer *Printer) Errors() int {
+	printer.mutex.Lock()
+	defer printer.mutex.Unlock()
+	return printer.errors
+}
+
+func (printer *Printer) Print(output io.Writer, diagnostic *Diagnostic) {
+	printer.mutex.Lock()
+	defer printer.mutex.Unlock()
+	if printer.stopped {
+		return
+	}
+	if diagnostic.Severity == Error {
+		if printer.Limit > 0 && printer.errors == printer.Limit {
+			printer.stopped = true
+			fmt.Fprintln(output, "note: too many errors, only the first "+strconv.Itoa(printer.Limit)+" are shown")
+			return
+		}
+		printer.errors++
+	}
+
+	severity := diagnostic.Severity
+	if severity == "" {
+		severity = diagnostic.Code.Severity()
+	}
+	printer.printAt(output, diagnostic.Position, severity, diagnostic.Code.Format(diagnostic.Message))
+	for _, suggestion := range diagnostic.Suggestions {
+		fmt.Fprintln(output, "Help: "+suggestion.Message+" ("+suggestion.Edit.String()+")")
+	}
+	for _, related := range diagnostic.Related {
+		if related.File == "" {
+			related.File = diagnostic.File
+		}
+		printer.printAt(output, related.Position, Note, related.Message)
+	}
+}
+
+func (printer *Printer) printAt(output io.Writer, position Position, severity Severity, message string) {
+	location := printer.Files.Adjust(position).String()
+	if location != "" {
+		location += ": "
+	}
+	fmt.Fprintln(output, location+string(severity)+": "+message)
+
+	line, ok := printer.line(position)
+	if !ok {
+		return
+	}
+	number := strconv.Itoa(position.Line)
+	margin := strings.Repeat(" ", len(number)+4)
+	fmt.Fprintln(output, "    "+number+" | "+line)
+	if position.Col == 0 {
+		return
+	}
+	fmt.Fprintln(output, margin+" | "+underline(line, position.Col))
+}
+
+// The line of the position, if the source is known
+func (printer *Printer) line(position Position) (string, bool) {
+	if position.Line == 0 {
+		return "", false
+	}
+	file := printer.Files.Lookup(position.File)
+	if file == nil && position.File != "" && !printer.missing[position.File] {
+		var err error
+		file, err = printer.Files.Load(position.File)
+		printer.missing[position.File] = err != nil
+	}
+	if file == nil {
+		return "", false
+	}
+	return file.Line(position.Line)
+}
+
+// Spaces up to the column, then a caret and a tilde for every further character of the word at the column.
+// Tabs are kept, so the caret lines up with the source
+func underline(line string, col int) string {
+	runes := []rune(line)
+	if col > len(runes)+1 {
+		col = len(runes) + 1
+	}
+	marks := ""
+	for _, r := range runes[:col-1] {
+		if r == '\t' {
+			marks += "\t"
+		} else {
+			marks += " "
+		}
+	}
+	marks += "^"
+	for i := col; i < len(runes) && isWordRune(runes[col-1]) && isWordRune(runes[i]); i++ {
+		marks += "~"
+	}
+	return marks
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
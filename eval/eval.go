@@ -0,0 +1,154 @@
+package eval
+
+import (
+	"compiler/ast"
+	"compiler/diag"
+	"compiler/interp"
+	"math"
+	"strconv"
+)
+
+/*
+Evaluation of constant expressions of the AST, for the checks which need the value at compile time and for constant
+folding. An expression is constant if it is built from literals, names with a known value, operators and interpolated
+strings; calls are never constant. The values are the ones of the interpreter (interp.Value) and the operators compute
+what they compute at runtime, with one difference: int is the 32 bit int of C#, like in the generated code.
+C# does not allow constant int operations which overflow, so they are errors here:
+
+	2147483647 + 1      overflows
+	-2147483648         fine, the literal is only too large without the minus
+	1 / 0, 1 % 0        division by zero
+	1.0 / 0             fine, doubles overflow to infinity
+
+Operands with types that do not fit the operator make the expression not constant, the type checker reports them.
+*/
+
+// The value of the expression. ok is false if the expression is not constant. The diagnostic is set if it is
+// constant, but can not be computed; it is at the innermost operation which fails
+func Eval(expr ast.Expr, names map[string]interp.Value) (interp.Value, bool, *diag.Diagnostic) {
+	switch e := expr.(type) {
+	case *ast.IntLit:
+		if e.Value > math.MaxInt32 {
+			return nil, true, overflow("Integral constant "+strconv.Itoa(e.Value)+" is too large for int", e.Position)
+		}
+		return e.Value, true, nil
+	case *ast.DoubleLit:
+		return e.Value, true, nil
+	case *ast.BoolLit:
+		return e.Value, true, nil
+	case *ast.StringLit:
+		return e.Value, true, nil
+	case *ast.InterpolatedString:
+		text := ""
+		for _, part := range e.Parts {
+			if part.Expr == nil {
+				text += part.Text
+				continue
+			}
+			v, ok, d := Eval(part.Expr, names)
+			if !ok || d != nil {
+				return nil, ok, d
+			}
+			text += interp.Format(v)
+		}
+		return text, true, nil
+	case *ast.Ident:
+		v, ok := names[e.Name]
+		return v, ok, nil
+	case *ast.Unary:
+		// The literal 2147483648 only fits with the minus in front of it
+		if literal, ok := e.Operand.(*ast.IntLit); ok && e.Op == "-" && literal.Value == -math.MinInt32 {
+			return math.MinInt32, true, nil
+		}
+		operand, ok, d := Eval(e.Operand, names)
+		if !ok || d != nil {
+			return nil, ok, d
+		}
+		return unary(e, operand)
+	case *ast.Binary:
+		left, ok, d := Eval(e.Left, names)
+		if !ok || d != nil {
+			return nil, ok, d
+		}
+		right, ok, d := Eval(e.Right, names)
+		if !ok || d != nil {
+			return nil, ok, d
+		}
+		return binary(e, left, right)
+	}
+	return nil, false, nil
+}
+
+func unary(e *ast.Unary, operand interp.Value) (interp.Value, bool, *diag.Diagnostic) {
+	if i, ok := operand.(int); ok && e.Op == "-" && i == math.MinInt32 {
+		return nil, true, overflow("The operation -("+strconv.Itoa(i)+") overflows int", e.Position)
+	}
+	v, err := interp.UnaryOperation(e.Op, operand)
+	if err != nil {
+		return nil, false, nil
+	}
+	return v, true, nil
+}
+
+func binary(e *ast.Binary, left interp.Value, right interp.Value) (interp.Value, bool, *diag.Diagnostic) {
+	switch e.Op {
+	case "&&", "||":
+		l, lok := left.(bool)
+		r, rok := right.(bool)
+		if !lok || !rok {
+			return nil, false, nil
+		}
+		if e.Op == "&&" {
+			return l && r, true, nil
+		}
+		return l || r, true, nil
+	}
+
+	l, lok := left.(int)
+	r, rok := right.(int)
+	if lok && rok {
+		switch e.Op {
+		case "+", "-", "*", "/", "%":
+			return arithmetic(e, l, r)
+		}
+	}
+	v, err := interp.BinaryOperation(e.Op, left, right)
+	if err != nil {
+		return nil, false, nil
+	}
+	return v, true, nil
+}
+
+// + - * / % of two ints, computed in 64 bits and checked against the range of int
+func arithmetic(e *ast.Binary, l int, r int) (interp.Value, bool, *diag.Diagnostic) {
+	var result int64
+	switch e.Op {
+	case "+":
+		result = int64(l) + int64(r)
+	case "-":
+		result = int64(l) - int64(r)
+	case "*":
+		result = int64(l) * int64(r)
+	case "/", "%":
+		if r == 0 {
+			return nil, true, diag.MakeDiagnosticAt(diag.ConstantDivisionByZero, "Constant Error: Division by zero at line "+strconv.Itoa(e.Line), position(e.Position))
+		}
+		if e.Op == "/" {
+			result = int64(l) / int64(r)
+		} else {
+			result = int64(l) % int64(r)
+		}
+	}
+	if result > math.MaxInt32 || result < math.MinInt32 {
+		return nil, true, overflow("The operation "+strconv.Itoa(l)+" "+e.Op+" "+strconv.Itoa(r)+" overflows int", e.Position)
+	}
+	return int(result), true, nil
+}
+
+func overflow(message string, pos ast.Position) *diag.Diagnostic {
+	return diag.MakeDiagnosticAt(diag.ConstantOverflow, "Constant Error: "+message+" at line "+strconv.Itoa(pos.Line), position(pos))
+}
+
+func position(pos ast.Position) diag.Position {
+	return diag.Position{Line: pos.Line, Col: pos.Col}
+}
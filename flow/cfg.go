@@ -0,0 +1,161 @@
+package flow
+
+import (
+	"compiler/ast"
+	"compiler/eval"
+)
+
+/*
+Control flow graph of a function, built from the AST. A basic block holds statements which run one after the
+other; if and while end a block with their condition:
+
+	if (c) { A } else { B } C      [... c] -> [A] -> [C]
+	                                       -> [B] ->
+	while (c) { A } B              [...] -> [c] -> [A] -> back to [c]
+	                                           -> [B]
+	return                         -> exit, the statements after it start a block without predecessors
+
+Conditions which are constant (see the eval package) only get the edge which is taken, like C# decides
+reachability: the code after while (true) is never reached, the language has no break.
+The end of the body has an edge to the exit as well, for the functions which fall off their end.
+*/
+
+type Block struct {
+	// Position in Graph.Blocks, the entry is 0
+	Index int
+	// Statements in the order they run. The last one can be an If or While whose condition ends the block
+	Statements []ast.Stmt
+	Succs      []*Block
+	Preds      []*Block
+}
+
+type Graph struct {
+	Function *ast.Function
+	Entry    *Block
+	// Reached by every return and by the end of the body, has no statements
+	Exit   *Block
+	Blocks []*Block
+	// The block at the end of the body. The function falls off its end if this block is reachable
+	End *Block
+	// The block of every statement. For a while it is the block of its condition, which starts a block
+	blockOf map[ast.Stmt]*Block
+}
+
+type builder struct {
+	graph   *Graph
+	current *Block
+}
+
+// The graph of a function with a body, nil for extern functions
+func Build(function *ast.Function) *Graph {
+	if function.Body == nil {
+		return nil
+	}
+	graph := &Graph{Function: function, blockOf: make(map[ast.Stmt]*Block)}
+	b := &builder{graph: graph}
+	graph.Entry = b.newBlock()
+	graph.Exit = &Block{}
+	b.current = graph.Entry
+	b.block(function.Body)
+	graph.End = b.current
+	connect(b.current, graph.Exit)
+	graph.Exit.Index = len(graph.Blocks)
+	graph.Blocks = append(graph.Blocks, graph.Exit)
+	return graph
+}
+
+// The block the statement is in
+func (graph *Graph) BlockOf(statement ast.Stmt) *Block {
+	return graph.blockOf[statement]
+}
+
+// The blocks which can be reached from the entry, the entry included
+func (graph *Graph) Reachable() map[*Block]bool {
+	reached := map[*Block]bool{graph.Entry: true}
+	work := []*Block{graph.Entry}
+	for len(work) > 0 {
+		block := work[len(work)-1]
+		work = work[:len(work)-1]
+		for _, succ := range block.Succs {
+			if !reached[succ] {
+				reached[succ] = true
+				work = append(work, succ)
+			}
+		}
+	}
+	return reached
+}
+
+func (b *builder) newBlock() *Block {
+	block := &Block{Index: len(b.graph.Blocks)}
+	b.graph.Blocks = append(b.graph.Blocks, block)
+	return block
+}
+
+func connect(from *Block, to *Block) {
+	from.Succs = append(from.Succs, to)
+	to.Preds = append(to.Preds, from)
+}
+
+func (b *builder) block(block *ast.Block) {
+	for _, statement := range block.Statements {
+		b.statement(statement)
+	}
+}
+
+func (b *builder) statement(statement ast.Stmt) {
+	switch s := statement.(type) {
+	case *ast.Return:
+		b.add(s)
+		connect(b.current, b.graph.Exit)
+		b.current = b.newBlock()
+	case *ast.If:
+		b.add(s)
+		cond := b.current
+		value, constant := condition(s.Cond)
+		after := b.newBlock()
+		b.branch(cond, s.Then, after, !constant || value)
+		if s.Else != nil {
+			b.branch(cond, s.Else, after, !constant || !value)
+		} else if !constant || !value {
+			connect(cond, after)
+		}
+		b.current = after
+	case *ast.While:
+		head := b.newBlock()
+		connect(b.current, head)
+		b.current = head
+		b.add(s)
+		value, constant := condition(s.Cond)
+		after := b.newBlock()
+		b.branch(head, s.Body, head, !constant || value)
+		if !constant || !value {
+			connect(head, after)
+		}
+		b.current = after
+	default:
+		b.add(s)
+	}
+}
+
+// Builds the block of a branch and connects it to next. taken is false if the condition never leads to it
+func (b *builder) branch(from *Block, body *ast.Block, next *Block, taken bool) {
+	b.current = b.newBlock()
+	if taken {
+		connect(from, b.current)
+	}
+	b.block(body)
+	connect(b.current, next)
+}
+
+func (b *builder) add(statement ast.Stmt) {
+	b.current.Statements = append(b.current.Statements, statement)
+	b.graph.blockOf[statement] = b.current
+}
+
+// The value of a constant bool condition
+func condition(cond ast.Expr) (bool, bool) {
+	value, ok, d := eval.Eval(cond, nil)
+	b, isBool := value.(bool)
+	return b, ok && d == nil && isBool
+}
@@ -0,0 +1,188 @@
+package flow
+
+import (
+	"compiler/ast"
+	"compiler/diag"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+/*
+Checks of the control flow of the functions, on their graphs:
+	Unreachable statements: a warning at the first statement of every part of a function which is never reached.
+	Missing return: a function which does not return void must not reach the end of its body.
+	Definite assignment: a variable declared without a value has to be assigned on every path before it is read.
+	Like in C# a loop may not run, so an assignment in it does not count after the loop.
+The variables are followed by their declarations, so the program has to be bound by symtab.Bind first.
+Nothing is reported for code which is never reached, except that it is never reached.
+*/
+
+// Variables which are assigned on every path to a point. nil stands for all variables, the value of the
+// points which are only reached over paths the analysis has not seen yet
+type assigned map[*ast.VarDecl]bool
+
+type checker struct {
+	graph       *Graph
+	reached     map[*Block]bool
+	diagnostics []*diag.Diagnostic
+}
+
+// The diagnostics of all functions, ordered by line
+func Check(program *ast.Program) []*diag.Diagnostic {
+	diagnostics := []*diag.Diagnostic{}
+	for _, function := range program.Functions {
+		diagnostics = append(diagnostics, CheckFunction(function)...)
+	}
+	slices.SortStableFunc(diagnostics, func(a *diag.Diagnostic, b *diag.Diagnostic) int {
+		return a.Line - b.Line
+	})
+	return diagnostics
+}
+
+func CheckFunction(function *ast.Function) []*diag.Diagnostic {
+	graph := Build(function)
+	if graph == nil {
+		return nil
+	}
+	c := &checker{graph: graph, reached: graph.Reachable(), diagnostics: []*diag.Diagnostic{}}
+	c.unreachable(function.Body.Statements, true)
+	if c.reached[graph.End] && function.ReturnType != "void" {
+		c.report(diag.MissingReturn, "Function "+function.Name+" at line "+strconv.Itoa(function.Line)+" does not return a value on every path", function.Pos())
+	}
+	c.assignments()
+	return c.diagnostics
+}
+
+// Reports the unreachable statements which follow a reachable one in the source. Returns whether the last statement is reachable
+func (c *checker) unreachable(statements []ast.Stmt, previous bool) bool {
+	for _, statement := range statements {
+		reached := c.reached[c.graph.BlockOf(statement)]
+		if !reached && previous {
+			c.report(diag.UnreachableCode, "The statement at line "+strconv.Itoa(statement.Pos().Line)+" is never reached", statement.Pos())
+		}
+		previous = reached
+		switch s := statement.(type) {
+		case *ast.If:
+			previous = c.unreachable(s.Then.Statements, previous)
+			if s.Else != nil {
+				previous = c.unreachable(s.Else.Statements, previous)
+			}
+		case *ast.While:
+			previous = c.unreachable(s.Body.Statements, previous)
+		}
+	}
+	return previous
+}
+
+// Computes the assigned variables at the start of every block until nothing changes, then reports the reads
+func (c *checker) assignments() {
+	in := make([]assigned, len(c.graph.Blocks))
+	out := make([]assigned, len(c.graph.Blocks))
+	for changed := true; changed; {
+		changed = false
+		for _, block := range c.graph.Blocks {
+			if !c.reached[block] {
+				continue
+			}
+			in[block.Index] = c.meet(block, out)
+			result := c.transfer(block, in[block.Index], false)
+			if !equal(result, out[block.Index]) {
+				out[block.Index] = result
+				changed = true
+			}
+		}
+	}
+	for _, block := range c.graph.Blocks {
+		if c.reached[block] && in[block.Index] != nil {
+			c.transfer(block, in[block.Index], true)
+		}
+	}
+}
+
+// The variables assigned at the end of all reachable predecessors
+func (c *checker) meet(block *Block, out []assigned) assigned {
+	if block == c.graph.Entry {
+		return assigned{}
+	}
+	var result assigned
+	for _, pred := range block.Preds {
+		if !c.reached[pred] || out[pred.Index] == nil {
+			continue
+		}
+		if result == nil {
+			result = maps.Clone(out[pred.Index])
+			continue
+		}
+		maps.DeleteFunc(result, func(decl *ast.VarDecl, _ bool) bool {
+			return !out[pred.Index][decl]
+		})
+	}
+	return result
+}
+
+// The variables assigned after the statements of the block
+func (c *checker) transfer(block *Block, in assigned, report bool) assigned {
+	if in == nil {
+		return nil
+	}
+	set := maps.Clone(in)
+	for _, statement := range block.Statements {
+		switch s := statement.(type) {
+		case *ast.VarDecl:
+			c.reads(s.Value, set, report)
+			// A declaration in a loop is a new variable in every iteration
+			if s.Value != nil {
+				set[s] = true
+			} else {
+				delete(set, s)
+			}
+		case *ast.Assign:
+			c.reads(s.Value, set, report)
+			if decl, ok := s.Decl.(*ast.VarDecl); ok {
+				set[decl] = true
+			}
+		case *ast.CallStmt:
+			c.reads(s.Call, set, report)
+		case *ast.Return:
+			c.reads(s.Value, set, report)
+		case *ast.If:
+			c.reads(s.Cond, set, report)
+		case *ast.While:
+			c.reads(s.Cond, set, report)
+		}
+	}
+	return set
+}
+
+// Reports the variables the expression reads which are not assigned
+func (c *checker) reads(expr ast.Expr, set assigned, report bool) {
+	if expr == nil || !report {
+		return
+	}
+	ast.Inspect(expr, func(node ast.Node) bool {
+		ident, ok := node.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		if decl, ok := ident.Decl.(*ast.VarDecl); ok && !set[decl] {
+			c.report(diag.NotAssigned, "Variable "+ident.Name+" is used at line "+strconv.Itoa(ident.Line)+" before it is assigned a value", ident.Pos())
+		}
+		return true
+	})
+}
+
+func equal(a assigned, b assigned) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return maps.Equal(a, b)
+}
+
+func (c *checker) report(code diag.Code, message string, pos ast.Position) {
+	prefix := "Flow Error: "
+	if code.Severity() == diag.Warning {
+		prefix = "Flow Warning: "
+	}
+	c.diagnostics = append(c.diagnostics, diag.MakeDiagnosticAt(code, prefix+message, diag.Position{Line: pos.Line, Col: pos.Col}))
+}
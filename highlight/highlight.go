@@ -0,0 +1,160 @@
+package highlight
+
+import (
+	"compiler/ast"
+	"compiler/interp"
+	"compiler/lexer"
+	"compiler/symtab"
+	"context"
+	"io"
+	"slices"
+	"strings"
+)
+
+/*
+Highlight kinds for every token of a source. The lexer tells keywords, literals and operators apart,
+names get their kind from the tokens around them:
+	after namespace or using    namespace
+	after class, before a .     class (the program or a built in receiver like Console)
+	before a (                  function
+	after a type                the declared function, parameter or variable
+	else                        the parameter or variable the name resolves to in the program
+Without a program (the source does not compile) every other name is a variable.
+*/
+
+// The names are the token types of the Language Server Protocol, constant is an addition of this package
+type Kind string
+
+const (
+	Keyword   Kind = "keyword"
+	Type      Kind = "type"
+	Namespace Kind = "namespace"
+	Class     Kind = "class"
+	Function  Kind = "function"
+	Parameter Kind = "parameter"
+	Variable  Kind = "variable"
+	// true and false
+	Constant Kind = "constant"
+	Number   Kind = "number"
+	String   Kind = "string"
+	Operator Kind = "operator"
+	// ; , . ( ) { } [ ], not in the semantic tokens
+	Punctuation Kind = "punctuation"
+	// Only in HTML, the lexer drops comments
+	Comment Kind = "comment"
+)
+
+type Token struct {
+	// Line and Col start at 1, Length and Col count characters
+	Line   int
+	Col    int
+	Length int
+	Kind   Kind
+}
+
+var keywords = []string{"using", "namespace", "class", "static", "extern", "if", "else", "while", "return"}
+
+var types = []string{"void", "int", "double", "bool", "string"}
+
+// The kinds of the tokens of the source in their order.
+// program is the AST of the source and may be nil
+func Classify(source string, program *ast.Program) []Token {
+	tokens := lex(source)
+	uses := map[ast.Position]symtab.Kind{}
+	className := ""
+	if program != nil {
+		className = program.Class
+		for node, symbol := range symtab.Resolve(program) {
+			uses[node.Pos()] = symbol.Kind
+		}
+	}
+	receivers := []string{}
+	for name := range interp.Builtins() {
+		receiver, _, _ := strings.Cut(name, ".")
+		receivers = append(receivers, receiver)
+	}
+
+	classified := []Token{}
+	// Inside using or namespace until ; or {
+	inPath := false
+	// Depth of the ( of a function declaration, 0 outside of the parameters
+	paramDepth := 0
+	depth := 0
+	for i, token := range tokens {
+		previous, next := "", ""
+		if i > 0 {
+			previous = tokens[i-1].Identifier
+		}
+		if i+1 < len(tokens) {
+			next = tokens[i+1].Identifier
+		}
+		switch token.Identifier {
+		case "(":
+			depth++
+		case ")":
+			if depth == paramDepth {
+				paramDepth = 0
+			}
+			depth--
+		case "using", "namespace":
+			inPath = true
+		case ";", "{":
+			inPath = false
+		}
+
+		var kind Kind
+		switch {
+		case token.Identifier == "name":
+			name, _ := token.Value.(string)
+			switch {
+			case inPath:
+				kind = Namespace
+			case previous == "class" || next == "." && (name == className || slices.Contains(receivers, name)):
+				kind = Class
+			case next == "(":
+				kind = Function
+				if slices.Contains(types, previous) {
+					paramDepth = depth + 1
+				}
+			case slices.Contains(types, previous) || previous == "]":
+				kind = Variable
+				if paramDepth != 0 {
+					kind = Parameter
+				}
+			case uses[ast.Position{Line: token.Line, Col: token.Col}] == symtab.Param:
+				kind = Parameter
+			default:
+				kind = Variable
+			}
+		case slices.Contains(keywords, token.Identifier):
+			kind = Keyword
+		case slices.Contains(types, token.Identifier):
+			kind = Type
+		case token.Identifier == "boolliteral":
+			kind = Constant
+		case token.Identifier == "intliteral" || token.Identifier == "doubleliteral":
+			kind = Number
+		case token.Identifier == "stringliteral" || token.Identifier == "interpolatedstring":
+			kind = String
+		case strings.HasSuffix(token.Identifier, "operator") || token.Identifier == "=":
+			kind = Operator
+		default:
+			kind = Punctuation
+		}
+		classified = append(classified, Token{Line: token.Line, Col: token.Col, Length: token.Length, Kind: kind})
+	}
+	return classified
+}
+
+// The tokens of the source which are in the source, without line ends
+func lex(source string) []lexer.Token {
+	tokenChannel := make(chan lexer.Token)
+	go lexer.LexReader(context.Background(), strings.NewReader(source), tokenChannel, lexer.Options{Output: io.Discard})
+	tokens := []lexer.Token{}
+	for token := range tokenChannel {
+		if token.Col != 0 {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
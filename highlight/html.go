@@ -0,0 +1,62 @@
+package highlight
+
+import (
+	"compiler/source"
+	"html"
+	"strings"
+)
+
+// The colors of the kinds in the HTML page
+const style = `body { background: #1e1e1e; color: #d4d4d4; }
+pre { font-family: monospace; font-size: 14px; }
+.keyword { color: #569cd6; }
+.type { color: #4ec9b0; }
+.namespace, .class { color: #4ec9b0; }
+.function { color: #dcdcaa; }
+.parameter { color: #9cdcfe; font-style: italic; }
+.variable { color: #9cdcfe; }
+.constant { color: #569cd6; }
+.number { color: #b5cea8; }
+.string { color: #ce9178; }
+.operator { color: #d4d4d4; }
+.comment { color: #6a9955; }`
+
+// A standalone HTML page with the highlighted source. Text between the tokens which is not white space is a comment
+func HTML(title string, text string, tokens []Token) string {
+	file := source.MakeFileSet().AddFile(title, []byte(text))
+	var body strings.Builder
+	offset := 0
+	for _, token := range tokens {
+		start := file.OffsetOf(token.Line, token.Col)
+		end := file.OffsetOf(token.Line, token.Col+token.Length)
+		if start < offset || end < start {
+			continue
+		}
+		writeGap(&body, text[offset:start])
+		if token.Kind == Punctuation {
+			body.WriteString(html.EscapeString(text[start:end]))
+		} else {
+			writeSpan(&body, text[start:end], token.Kind)
+		}
+		offset = end
+	}
+	writeGap(&body, text[offset:])
+
+	return "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>" + html.EscapeString(title) + "</title>\n" +
+		"<style>\n" + style + "\n</style>\n</head>\n<body>\n<pre>" + body.String() + "</pre>\n</body>\n</html>\n"
+}
+
+// White space stays as it is, the rest is a comment
+func writeGap(body *strings.Builder, gap string) {
+	trimmed := strings.TrimLeft(gap, " \t\r\n")
+	body.WriteString(gap[:len(gap)-len(trimmed)])
+	comment := strings.TrimRight(trimmed, " \t\r\n")
+	if comment != "" {
+		writeSpan(body, comment, Comment)
+	}
+	body.WriteString(trimmed[len(comment):])
+}
+
+func writeSpan(body *strings.Builder, text string, kind Kind) {
+	body.WriteString("<span class=\"" + string(kind) + "\">" + html.EscapeString(text) + "</span>")
+}
@@ -0,0 +1,29 @@
+package highlight
+
+import "slices"
+
+// The token types in the order Encode numbers them, for the legend of the semantic tokens provider
+func Legend() []Kind {
+	return []Kind{Keyword, Type, Namespace, Class, Function, Parameter, Variable, Constant, Number, String, Operator, Comment}
+}
+
+// The tokens as data of a semantic tokens response: five numbers per token, the line and the start relative to
+// the token before, the length, the index in the Legend and no modifiers. Punctuation is left out. Lines and starts count from 0.
+// Columns count characters, which is the same as UTF-16 code units outside of emojis and other rare characters
+func Encode(tokens []Token) []int {
+	legend := Legend()
+	data := []int{}
+	line, col := 1, 1
+	for _, token := range tokens {
+		if token.Kind == Punctuation {
+			continue
+		}
+		start := token.Col - 1
+		if token.Line == line {
+			start = token.Col - col
+		}
+		data = append(data, token.Line-line, start, token.Length, slices.Index(legend, token.Kind), 0)
+		line, col = token.Line, token.Col
+	}
+	return data
+}
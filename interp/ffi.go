@@ -0,0 +1,117 @@
+package interp
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/*
+Foreign function interface: Programs declare host functions with
+
+	static extern double Sqrt(double x);
+
+and the interpreter binds them by name to a Builtin. The adapters below turn
+typed Go functions into Builtins without reflection, by checking every argument
+with a type assertion:
+
+	interpreter.Bind("Sqrt", interp.Func1(math.Sqrt))
+
+Go types for the C# types: int -> int, double -> float64, bool -> bool, string -> string
+*/
+
+// Functions bound to extern declarations by default
+func HostFunctions() map[string]Builtin {
+	return map[string]Builtin{
+		"Sqrt":  Func1(math.Sqrt),
+		"Pow":   Func2(math.Pow),
+		"Floor": Func1(math.Floor),
+		"ReadLine": Func0(func() string {
+			line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			return strings.TrimRight(line, "\r\n")
+		}),
+		"ParseInt": Func1(func(s string) int {
+			i, _ := strconv.Atoi(s)
+			return i
+		}),
+	}
+}
+
+func Func0[R any](f func() R) Builtin {
+	return func(out io.Writer, args []Value) (Value, error) {
+		if err := checkArgCount(args, 0); err != nil {
+			return nil, err
+		}
+		return f(), nil
+	}
+}
+
+func Func1[A, R any](f func(A) R) Builtin {
+	return func(out io.Writer, args []Value) (Value, error) {
+		if err := checkArgCount(args, 1); err != nil {
+			return nil, err
+		}
+		a, err := arg[A](args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return f(a), nil
+	}
+}
+
+func Func2[A, B, R any](f func(A, B) R) Builtin {
+	return func(out io.Writer, args []Value) (Value, error) {
+		if err := checkArgCount(args, 2); err != nil {
+			return nil, err
+		}
+		a, err := arg[A](args, 0)
+		if err != nil {
+			return nil, err
+		}
+		b, err := arg[B](args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return f(a, b), nil
+	}
+}
+
+// For host functions without result (void)
+func Proc1[A any](f func(A)) Builtin {
+	return func(out io.Writer, args []Value) (Value, error) {
+		if err := checkArgCount(args, 1); err != nil {
+			return nil, err
+		}
+		a, err := arg[A](args, 0)
+		if err != nil {
+			return nil, err
+		}
+		f(a)
+		return nil, nil
+	}
+}
+
+func checkArgCount(args []Value, count int) error {
+	if len(args) != count {
+		return runtimeError("Host function takes " + strconv.Itoa(count) + " arguments, but got " + strconv.Itoa(len(args)))
+	}
+	return nil
+}
+
+func arg[T any](args []Value, i int) (T, error) {
+	var zero T
+	// int to double is the only implicit conversion
+	if _, isDouble := any(zero).(float64); isDouble {
+		if v, isInt := args[i].(int); isInt {
+			return any(float64(v)).(T), nil
+		}
+	}
+	v, ok := args[i].(T)
+	if !ok {
+		return zero, runtimeError("Argument " + strconv.Itoa(i+1) + " of host function has the wrong type: " + Format(args[i]))
+	}
+	return v, nil
+}
@@ -0,0 +1,331 @@
+package interp
+
+import (
+	"compiler/ast"
+	"compiler/diag"
+	"errors"
+	"io"
+	"strconv"
+)
+
+/*
+Tree walking interpreter for the AST. Runs programs without any code generation,
+and gives the expected output for checking the later compiler stages.
+
+Values are Go values:
+	int -> int, double -> float64, bool -> bool, string -> string, string[] -> []string
+	void functions return nil
+*/
+
+type Value any
+
+// Built in function, e.g. Console.WriteLine. Gets the output of the interpreter to write to
+type Builtin func(out io.Writer, args []Value) (Value, error)
+
+type Interpreter struct {
+	program   *ast.Program
+	functions map[string]*ast.Function
+	builtins  map[string]Builtin
+	// Host functions bound to the extern functions of the program
+	externs map[string]Builtin
+	out     io.Writer
+	depth   int
+}
+
+// Recursion deeper than this is reported as runtime error, instead of crashing the interpreter
+const maxCallDepth = 10000
+
+type environment struct {
+	variables map[string]*variable
+	parent    *environment
+}
+
+type variable struct {
+	typ   string
+	value Value
+}
+
+func New(program *ast.Program, out io.Writer) *Interpreter {
+	newInterpreter := new(Interpreter)
+	newInterpreter.program = program
+	newInterpreter.out = out
+	newInterpreter.functions = make(map[string]*ast.Function)
+	for _, function := range program.Functions {
+		newInterpreter.functions[function.Name] = function
+	}
+	newInterpreter.builtins = make(map[string]Builtin)
+	for name, builtin := range Builtins() {
+		newInterpreter.builtins[name] = builtin
+	}
+	newInterpreter.externs = make(map[string]Builtin)
+	for name, host := range HostFunctions() {
+		newInterpreter.externs[name] = host
+	}
+	return newInterpreter
+}
+
+// Binds a host function to the extern function with the name, replacing the default one
+func (interpreter *Interpreter) Bind(name string, host Builtin) {
+	interpreter.externs[name] = host
+}
+
+// Runs Main (or main) with the arguments
+func (interpreter *Interpreter) Run(args []string) error {
+	// Unbound extern functions are reported before the program starts, not when they are called
+	for _, function := range interpreter.program.Functions {
+		if _, ok := interpreter.externs[function.Name]; function.Extern && !ok {
+			return runtimeError("Extern function " + function.Name + " is not bound to a host function")
+		}
+	}
+	for _, name := range []string{"Main", "main"} {
+		if function, ok := interpreter.functions[name]; ok {
+			// Main can be declared without the parameter for the arguments
+			mainArgs := []Value{}
+			if len(function.Params) == 1 {
+				mainArgs = []Value{args}
+			}
+			_, err := interpreter.Call(name, mainArgs)
+			return err
+		}
+	}
+	return runtimeError("No Main function found")
+}
+
+// Calls a function of the program
+func (interpreter *Interpreter) Call(name string, args []Value) (Value, error) {
+	function, ok := interpreter.functions[name]
+	if !ok {
+		return nil, runtimeError("Function " + name + " does not exist")
+	}
+	if len(args) != len(function.Params) {
+		return nil, runtimeError("Function " + name + " takes " + strconv.Itoa(len(function.Params)) + " arguments, but got " + strconv.Itoa(len(args)))
+	}
+
+	interpreter.depth++
+	defer func() { interpreter.depth-- }()
+	if interpreter.depth > maxCallDepth {
+		return nil, runtimeError("Stack overflow in function " + name + ", the recursion does not seem to end")
+	}
+
+	if function.Extern {
+		for i, param := range function.Params {
+			args[i] = convert(args[i], param.Type)
+		}
+		value, err := interpreter.externs[name](interpreter.out, args)
+		if err != nil {
+			return nil, err
+		}
+		return convert(value, function.ReturnType), nil
+	}
+
+	env := newEnvironment(nil)
+	for i, param := range function.Params {
+		env.declare(param.Name, param.Type, convert(args[i], param.Type))
+	}
+	returned, value, err := interpreter.execBlock(function.Body, env)
+	if err != nil {
+		return nil, err
+	}
+	if !returned && function.ReturnType != "void" {
+		return nil, runtimeError("Function " + name + " ended without returning a value")
+	}
+	return convert(value, function.ReturnType), nil
+}
+
+func newEnvironment(parent *environment) *environment {
+	newEnv := new(environment)
+	newEnv.variables = make(map[string]*variable)
+	newEnv.parent = parent
+	return newEnv
+}
+
+func (env *environment) declare(name string, typ string, value Value) {
+	env.variables[name] = &variable{typ: typ, value: value}
+}
+
+func (env *environment) lookup(name string) *variable {
+	for current := env; current != nil; current = current.parent {
+		if v, ok := current.variables[name]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// Returns whether a return statement was executed, and its value
+func (interpreter *Interpreter) execBlock(block *ast.Block, parent *environment) (bool, Value, error) {
+	env := newEnvironment(parent)
+	for _, statement := range block.Statements {
+		returned, value, err := interpreter.exec(statement, env)
+		if err != nil || returned {
+			return returned, value, err
+		}
+	}
+	return false, nil, nil
+}
+
+func (interpreter *Interpreter) exec(statement ast.Stmt, env *environment) (bool, Value, error) {
+	switch s := statement.(type) {
+	case *ast.VarDecl:
+		if _, ok := env.variables[s.Name]; ok {
+			return false, nil, runtimeError("Variable " + s.Name + " is declared twice")
+		}
+		value := ZeroValue(s.Type)
+		if s.Value != nil {
+			var err error
+			value, err = interpreter.eval(s.Value, env)
+			if err != nil {
+				return false, nil, err
+			}
+		}
+		env.declare(s.Name, s.Type, convert(value, s.Type))
+	case *ast.Assign:
+		v := env.lookup(s.Name)
+		if v == nil {
+			return false, nil, runtimeError("Variable " + s.Name + " is not declared")
+		}
+		value, err := interpreter.eval(s.Value, env)
+		if err != nil {
+			return false, nil, err
+		}
+		v.value = convert(value, v.typ)
+	case *ast.CallStmt:
+		_, err := interpreter.eval(s.Call, env)
+		return false, nil, err
+	case *ast.Return:
+		if s.Value == nil {
+			return true, nil, nil
+		}
+		value, err := interpreter.eval(s.Value, env)
+		return err == nil, value, err
+	case *ast.If:
+		cond, err := interpreter.evalBool(s.Cond, env)
+		if err != nil {
+			return false, nil, err
+		}
+		if cond {
+			return interpreter.execBlock(s.Then, env)
+		}
+		if s.Else != nil {
+			return interpreter.execBlock(s.Else, env)
+		}
+	case *ast.While:
+		for {
+			cond, err := interpreter.evalBool(s.Cond, env)
+			if err != nil || !cond {
+				return false, nil, err
+			}
+			returned, value, err := interpreter.execBlock(s.Body, env)
+			if err != nil || returned {
+				return returned, value, err
+			}
+		}
+	}
+	return false, nil, nil
+}
+
+func (interpreter *Interpreter) evalBool(expression ast.Expr, env *environment) (bool, error) {
+	value, err := interpreter.eval(expression, env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, runtimeError("Condition is not a bool: " + Format(value))
+	}
+	return b, nil
+}
+
+func (interpreter *Interpreter) eval(expression ast.Expr, env *environment) (Value, error) {
+	switch e := expression.(type) {
+	case *ast.IntLit:
+		return e.Value, nil
+	case *ast.DoubleLit:
+		return e.Value, nil
+	case *ast.BoolLit:
+		return e.Value, nil
+	case *ast.StringLit:
+		return e.Value, nil
+	case *ast.InterpolatedString:
+		text := ""
+		for _, part := range e.Parts {
+			if part.Expr == nil {
+				text += part.Text
+				continue
+			}
+			value, err := interpreter.eval(part.Expr, env)
+			if err != nil {
+				return nil, err
+			}
+			text += Format(value)
+		}
+		return text, nil
+	case *ast.Ident:
+		v := env.lookup(e.Name)
+		if v == nil {
+			return nil, runtimeError("Variable " + e.Name + " is not declared")
+		}
+		return v.value, nil
+	case *ast.Unary:
+		operand, err := interpreter.eval(e.Operand, env)
+		if err != nil {
+			return nil, err
+		}
+		return UnaryOperation(e.Op, operand)
+	case *ast.Binary:
+		return interpreter.evalBinary(e, env)
+	case *ast.Call:
+		return interpreter.evalCall(e, env)
+	}
+	return nil, runtimeError("Unknown expression")
+}
+
+func (interpreter *Interpreter) evalBinary(e *ast.Binary, env *environment) (Value, error) {
+	left, err := interpreter.eval(e.Left, env)
+	if err != nil {
+		return nil, err
+	}
+	// && and || only evaluate the right side if needed
+	if e.Op == "&&" || e.Op == "||" {
+		l, ok := left.(bool)
+		if !ok {
+			return nil, runtimeError("Operator " + e.Op + " needs bools, got " + Format(left))
+		}
+		if (e.Op == "&&" && !l) || (e.Op == "||" && l) {
+			return l, nil
+		}
+		right, err := interpreter.evalBool(e.Right, env)
+		return right, err
+	}
+	right, err := interpreter.eval(e.Right, env)
+	if err != nil {
+		return nil, err
+	}
+	return BinaryOperation(e.Op, left, right)
+}
+
+func (interpreter *Interpreter) evalCall(call *ast.Call, env *environment) (Value, error) {
+	args := []Value{}
+	for _, arg := range call.Args {
+		value, err := interpreter.eval(arg, env)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value)
+	}
+	// Program.Fib() is the same as Fib()
+	if call.Receiver == "" || call.Receiver == interpreter.program.Class {
+		if _, ok := interpreter.functions[call.Name]; ok {
+			return interpreter.Call(call.Name, args)
+		}
+	}
+	builtin, ok := interpreter.builtins[call.FullName()]
+	if !ok {
+		return nil, runtimeError("Function " + call.FullName() + " does not exist")
+	}
+	return builtin(interpreter.out, args)
+}
+
+func runtimeError(message string) error {
+	return errors.New(diag.RuntimeError.Format("Runtime Error: " + message))
+}
@@ -0,0 +1,65 @@
+package interp_test
+
+import (
+	"compiler/ast"
+	"compiler/interp"
+	"compiler/lexer"
+	"compiler/parser"
+	"compiler/symtab"
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+func run(t *testing.T, source string, args []string) string {
+	t.Helper()
+	tree, ok, err := parser.ParseSource(context.Background(), source, true, lexer.Options{Output: io.Discard})
+	if !ok {
+		t.Fatal("parse failed", err)
+	}
+	program, err := ast.Build(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diagnostics := symtab.Bind(program); len(diagnostics) > 0 {
+		t.Fatal(diagnostics[0])
+	}
+	var out strings.Builder
+	if err := interp.New(program, &out).Run(args); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestMainWithoutParameter(t *testing.T) {
+	source := `using System;
+
+namespace NoArgs {
+    class Program {
+        static void Main() {
+            Console.WriteLine("no arguments");
+        }
+    }
+}
+`
+	if got := run(t, source, []string{"ignored"}); got != "no arguments\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestMainWithArguments(t *testing.T) {
+	source := `using System;
+
+namespace Args {
+    class Program {
+        static void Main(string[] args) {
+            Console.WriteLine("with arguments");
+        }
+    }
+}
+`
+	if got := run(t, source, nil); got != "with arguments\n" {
+		t.Errorf("got %q", got)
+	}
+}
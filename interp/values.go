@@ -0,0 +1,211 @@
+package interp
+
+import (
+	"io"
+	"math"
+	"strconv"
+)
+
+// Built in functions by their full name, e.g. Console.WriteLine
+func Builtins() map[string]Builtin {
+	return map[string]Builtin{
+		"Console.WriteLine": func(out io.Writer, args []Value) (Value, error) {
+			return write(out, args, "\n")
+		},
+		"Console.Write": func(out io.Writer, args []Value) (Value, error) {
+			return write(out, args, "")
+		},
+	}
+}
+
+func write(out io.Writer, args []Value, end string) (Value, error) {
+	if len(args) > 1 {
+		return nil, runtimeError("Console.Write and Console.WriteLine take at most one argument")
+	}
+	text := ""
+	if len(args) == 1 {
+		text = Format(args[0])
+	}
+	io.WriteString(out, text+end)
+	return nil, nil
+}
+
+// Formats the value like C# ToString() does
+func Format(value Value) string {
+	switch v := value.(type) {
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		if math.IsInf(v, 1) {
+			return "∞"
+		} else if math.IsInf(v, -1) {
+			return "-∞"
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		if v {
+			return "True"
+		}
+		return "False"
+	case string:
+		return v
+	case []string:
+		return "System.String[]"
+	}
+	return ""
+}
+
+// Value of a variable declared without one
+func ZeroValue(typ string) Value {
+	switch typ {
+	case "int":
+		return 0
+	case "double":
+		return 0.0
+	case "bool":
+		return false
+	case "string":
+		return ""
+	}
+	return nil
+}
+
+// The only implicit conversion: int to double
+func convert(value Value, typ string) Value {
+	if i, ok := value.(int); ok && typ == "double" {
+		return float64(i)
+	}
+	return value
+}
+
+// Applies a unary operator (+ or -) to a number
+func UnaryOperation(op string, operand Value) (Value, error) {
+	switch v := operand.(type) {
+	case int:
+		if op == "-" {
+			return wrap(-v), nil
+		}
+		return v, nil
+	case float64:
+		if op == "-" {
+			return -v, nil
+		}
+		return v, nil
+	}
+	return nil, runtimeError("Operator " + op + " needs a number, got " + Format(operand))
+}
+
+// Applies a binary operator (except && and ||, which need short circuiting) to two values
+func BinaryOperation(op string, left Value, right Value) (Value, error) {
+	// string + anything is a concatenation
+	if op == "+" {
+		_, leftIsString := left.(string)
+		_, rightIsString := right.(string)
+		if leftIsString || rightIsString {
+			return Format(left) + Format(right), nil
+		}
+	}
+
+	switch op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	}
+
+	l, lok := left.(int)
+	r, rok := right.(int)
+	if lok && rok {
+		return intOperation(op, l, r)
+	}
+
+	lf, lok := toDouble(left)
+	rf, rok := toDouble(right)
+	if !lok || !rok {
+		return nil, runtimeError("Operator " + op + " can not be used with " + Format(left) + " and " + Format(right))
+	}
+	return doubleOperation(op, lf, rf)
+}
+
+// int is the 32 bit int of C#, results which do not fit wrap around like in the generated code
+func intOperation(op string, l int, r int) (Value, error) {
+	switch op {
+	case "+":
+		return wrap(l + r), nil
+	case "-":
+		return wrap(l - r), nil
+	case "*":
+		return wrap(l * r), nil
+	case "/", "%":
+		if r == 0 {
+			return nil, runtimeError("Division by zero")
+		}
+		if op == "/" {
+			return wrap(l / r), nil
+		}
+		return wrap(l % r), nil
+	case "<":
+		return l < r, nil
+	case ">":
+		return l > r, nil
+	case "<=":
+		return l <= r, nil
+	case ">=":
+		return l >= r, nil
+	}
+	return nil, runtimeError("Operator " + op + " can not be used with numbers")
+}
+
+// The int with the lower 32 bits of the value, e.g. 2147483648 is -2147483648
+func wrap(value int) int {
+	return int(int32(value))
+}
+
+func doubleOperation(op string, l float64, r float64) (Value, error) {
+	switch op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		return l / r, nil
+	case "%":
+		return math.Mod(l, r), nil
+	case "<":
+		return l < r, nil
+	case ">":
+		return l > r, nil
+	case "<=":
+		return l <= r, nil
+	case ">=":
+		return l >= r, nil
+	}
+	return nil, runtimeError("Operator " + op + " can not be used with numbers")
+}
+
+func toDouble(value Value) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func equal(left Value, right Value) bool {
+	// Arrays are compared by reference in C#, args is the only array
+	_, leftIsArray := left.([]string)
+	_, rightIsArray := right.([]string)
+	if leftIsArray || rightIsArray {
+		return false
+	}
+	l, lok := toDouble(left)
+	r, rok := toDouble(right)
+	if lok && rok {
+		return l == r
+	}
+	return left == right
+}
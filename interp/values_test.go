@@ -0,0 +1,46 @@
+package interp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestIntOverflowWraps(t *testing.T) {
+	tests := []struct {
+		op    string
+		left  int
+		right int
+		want  int
+	}{
+		{"+", math.MaxInt32, 0, math.MaxInt32},
+		{"+", math.MaxInt32, 1, math.MinInt32},
+		{"-", math.MinInt32, 1, math.MaxInt32},
+		{"-", 0, math.MinInt32, math.MinInt32},
+		{"*", math.MaxInt32, 2, -2},
+		{"*", 65536, 65536, 0},
+		{"/", math.MinInt32, -1, math.MinInt32},
+		{"%", math.MinInt32, -1, 0},
+		{"/", math.MaxInt32, -1, -math.MaxInt32},
+	}
+	for _, test := range tests {
+		got, err := BinaryOperation(test.op, test.left, test.right)
+		if err != nil {
+			t.Errorf("%d %s %d: %v", test.left, test.op, test.right, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%d %s %d = %v, want %d", test.left, test.op, test.right, got, test.want)
+		}
+	}
+}
+
+func TestNegateMinInt(t *testing.T) {
+	got, err := UnaryOperation("-", math.MinInt32)
+	if err != nil || got != math.MinInt32 {
+		t.Errorf("-(%d) = %v, %v", math.MinInt32, got, err)
+	}
+	got, _ = UnaryOperation("-", math.MaxInt32)
+	if got != -math.MaxInt32 {
+		t.Errorf("-(%d) = %v", math.MaxInt32, got)
+	}
+}
@@ -0,0 +1,74 @@
+package lexer
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Seeds of the fuzz targets: the test programs and snippets with the tricky parts of the lexer
+func addSeeds(f *testing.F) {
+	paths, _ := filepath.Glob("../testcode/*.cs")
+	for _, path := range paths {
+		if source, err := os.ReadFile(path); err == nil {
+			f.Add(string(source))
+		}
+	}
+	for _, seed := range []string{
+		"",
+		"int a = 1;\r\nint b = 2.5;",
+		`string s = $"a {b + 1} {{c}} {"d"}";`,
+		"/* open comment\n int a = 1;",
+		"a /* */ b // c\n\"unterminated",
+		"int ａ = 1; int а = 2;",
+		"x = 99999999999999999999;",
+		"$\"{",
+	} {
+		f.Add(seed)
+	}
+}
+
+// The lexer never panics or hangs, the token stream always ends with "$" and the positions are in the source
+func FuzzLexReader(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, source string) {
+		lines := strings.Count(source, "\n") + 1
+		tokenChannel := make(chan Token)
+		go LexReader(context.Background(), strings.NewReader(source), tokenChannel, Options{Output: io.Discard, Normalize: true})
+		var last Token
+		for token := range tokenChannel {
+			if token.Line < 0 || token.Line > lines || token.Col < 0 {
+				t.Fatalf("token %v is not in the %d lines of the source", token, lines)
+			}
+			last = token
+		}
+		if last.Identifier != "$" {
+			t.Fatalf("the tokens end with %v instead of $", last)
+		}
+	})
+}
+
+// Lexing a changed line again gives the same tokens as lexing the changed source
+func FuzzDocumentEdit(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, source string) {
+		document := MakeDocument(source, Options{Output: io.Discard})
+		document.Edit(Position{Line: 1, Col: 1}, Position{Line: 1, Col: 1}, "x")
+		if document.Text() != "x"+source {
+			t.Fatalf("text after the edit is %q", document.Text())
+		}
+		fresh := MakeDocument("x"+source, Options{Output: io.Discard})
+		got, want := document.Tokens(), fresh.Tokens()
+		if len(got) != len(want) {
+			t.Fatalf("%d tokens after the edit, %d when lexed again", len(got), len(want))
+		}
+		for i := range got {
+			if got[i].Identifier != want[i].Identifier || got[i].Line != want[i].Line || got[i].Col != want[i].Col {
+				t.Fatalf("token %d is %v after the edit, %v when lexed again", i, got[i], want[i])
+			}
+		}
+	})
+}
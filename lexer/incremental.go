@@ -0,0 +1,182 @@
+package lexer
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+Incremental lexing for editors. A Document keeps the tokens of every line with a checkpoint: whether a multi line
+comment is open at the start of the line, the only state the lexer carries from one line to the next.
+After an edit the changed lines are lexed again, then the lines after them until one starts in the same state as
+before, the rest of the tokens are kept. Lines after the edit move if it adds or removes lines, only their line
+numbers change then:
+
+	document := lexer.MakeDocument(source, options)
+	change := document.Edit(lexer.Position{Line: 3, Col: 5}, lexer.Position{Line: 3, Col: 5}, "x")
+	tokens := document.Tokens()    // the same as Tokens would give for the new text
+
+Diagnostics of the lexer go to the options again for the lines which are lexed again.
+*/
+
+// Line and column in the text, both starting at 1 like the ones of the tokens. Columns count characters
+type Position struct {
+	Line int
+	Col  int
+}
+
+type Document struct {
+	options Options
+	lines   []documentLine
+}
+
+type documentLine struct {
+	// Without the newline
+	text string
+	// The checkpoint: a multi line comment is open at the start of the line
+	inComment bool
+	// ... and at its end
+	endsInComment bool
+	tokens        []Token
+}
+
+// The lines which were lexed again by an edit, in the new text
+type Change struct {
+	First int
+	Last  int
+	// Number of lines added, negative if lines were removed. The lines after Last moved by it
+	Delta int
+}
+
+func MakeDocument(source string, options Options) *Document {
+	document := &Document{options: options}
+	for _, line := range strings.Split(source, "\n") {
+		document.lines = append(document.lines, documentLine{text: line})
+	}
+	document.relex(0, len(document.lines))
+	return document
+}
+
+// Replaces the text from start up to end, end is not part of it. Positions out of the text are moved into it
+func (document *Document) Edit(start Position, end Position, text string) Change {
+	start, end = document.clamp(start), document.clamp(end)
+	if end.Line < start.Line || (end.Line == start.Line && end.Col < start.Col) {
+		start, end = end, start
+	}
+	first, last := document.lines[start.Line-1].text, document.lines[end.Line-1].text
+	edited := first[:columnOffset(first, start.Col)] + text + last[columnOffset(last, end.Col):]
+
+	replaced := []documentLine{}
+	for _, line := range strings.Split(edited, "\n") {
+		replaced = append(replaced, documentLine{text: line})
+	}
+	document.lines = slices.Replace(document.lines, start.Line-1, end.Line, replaced...)
+	delta := len(replaced) - (end.Line - start.Line + 1)
+
+	relexed := document.relex(start.Line-1, start.Line-1+len(replaced))
+	if delta != 0 {
+		for i := relexed; i < len(document.lines); i++ {
+			document.lines[i].tokens = renumber(document.lines[i].tokens, i+1)
+		}
+	}
+	return Change{First: start.Line, Last: relexed, Delta: delta}
+}
+
+// Lexes the lines from first up to at least end, and on while the state at the start of the next line changed.
+// The index of the line after the last one it lexed
+func (document *Document) relex(first int, end int) int {
+	inComment := first > 0 && document.lines[first-1].endsInComment
+	i := first
+	for ; i < len(document.lines); i++ {
+		line := &document.lines[i]
+		if i >= end && line.inComment == inComment && line.tokens != nil {
+			break
+		}
+		line.inComment = inComment
+		line.tokens = lexLine(strings.TrimSuffix(line.text, "\r"), i+1, &inComment, document.options)
+		line.endsInComment = inComment
+	}
+	return i
+}
+
+// All tokens with the "$" at the end, like Tokens
+func (document *Document) Tokens() []Token {
+	lines := document.lexedLines()
+	tokens := []Token{}
+	for _, line := range lines {
+		tokens = append(tokens, line.tokens...)
+	}
+	return append(tokens, makeToken("$", "$", len(lines), 0, 0))
+}
+
+// The tokens of a line, starting at 1, with its LINE token unless the line is in a comment
+func (document *Document) LineTokens(line int) []Token {
+	if line < 1 || line > len(document.lines) {
+		return nil
+	}
+	return document.lines[line-1].tokens
+}
+
+func (document *Document) Text() string {
+	texts := []string{}
+	for _, line := range document.lines {
+		texts = append(texts, line.text)
+	}
+	return strings.Join(texts, "\n")
+}
+
+// Number of lines, the empty line after a newline at the end of the text is counted
+func (document *Document) Lines() int {
+	return len(document.lines)
+}
+
+// The lexer does not see the empty line after a newline at the end of the text
+func (document *Document) lexedLines() []documentLine {
+	if last := len(document.lines) - 1; document.lines[last].text == "" {
+		return document.lines[:last]
+	}
+	return document.lines
+}
+
+func (document *Document) clamp(position Position) Position {
+	position.Line = min(max(position.Line, 1), len(document.lines))
+	position.Col = min(max(position.Col, 1), utf8.RuneCountInString(document.lines[position.Line-1].text)+1)
+	return position
+}
+
+// Byte offset of the column in the line
+func columnOffset(line string, col int) int {
+	offset := 0
+	for range col - 1 {
+		_, size := utf8.DecodeRuneInString(line[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// The tokens with their line changed, also the ones in interpolated strings
+func renumber(tokens []Token, line int) []Token {
+	renumbered := []Token{}
+	for _, token := range tokens {
+		token.Line = line
+		switch value := token.Value.(type) {
+		case []StringSegment:
+			segments := []StringSegment{}
+			for _, segment := range value {
+				if segment.Expression != nil {
+					segment.Expression = renumber(segment.Expression, line)
+				}
+				segments = append(segments, segment)
+			}
+			token.Value = segments
+		default:
+			if token.Identifier == "LINE" {
+				token.Value = strconv.Itoa(line)
+			}
+		}
+		renumbered = append(renumbered, token)
+	}
+	return renumbered
+}
@@ -2,15 +2,81 @@ package lexer
 
 import (
 	"bufio"
-	//"fmt"
+	"bytes"
+	"compiler/diag"
+	"context"
+	"fmt"
+	"io"
 	"os"
 	"strconv"
+	"strings"
 	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
 )
 
+type Options struct {
+	// NFC-normalize the source, so identifiers that look the same are the same
+	Normalize bool
+	// Where warnings are written to, standard output if nil
+	Output io.Writer
+	// Gets every diagnostic of the lexer and the parser, in addition to Output.
+	// The lexer and the parser run at the same time, so it can be called from two go routines
+	Report func(*diag.Diagnostic)
+	// Path of the source in diagnostics, empty if it is not from a file
+	File string
+	// Prints the diagnostics with source excerpts to Output, if set
+	Printer *diag.Printer
+	// Keep the whitespace and comments as trivia of the tokens, see trivia.go
+	Trivia bool
+}
+
+func (options Options) Writer() io.Writer {
+	if options.Output == nil {
+		return os.Stdout
+	}
+	return options.Output
+}
+
+// Writes the diagnostic to the output, with the printer if there is one, and hands it to Report
+func (options Options) Emit(diagnostic *diag.Diagnostic) {
+	if options.Printer != nil {
+		options.Printer.Print(options.Writer(), diagnostic)
+	} else {
+		fmt.Fprintln(options.Writer(), diagnostic)
+	}
+	if options.Report != nil {
+		options.Report(diagnostic)
+	}
+}
+
 type Token struct {
 	Identifier string
 	Value      any
+	// Line in the source file, 0 for tokens which are not from a file
+	Line int
+	// Column of the first character, starting at 1. 0 if unknown
+	Col int
+	// Number of characters in the source, with the quotes of strings. 0 if unknown
+	Length int
+	// The text of the token and the whitespace and comments around it, only with Options.Trivia
+	Trivia *Trivia
+}
+
+// Part of an interpolated string ($"..."). Either plain text or the tokens of an embedded {expression}
+type StringSegment struct {
+	Text       string
+	Expression []Token
+}
+
+// Token as split from the line, before its identifier is determined
+type rawToken struct {
+	text           string
+	isString       bool
+	isInterpolated bool
+	// Column of the first character in the line, starting at 1
+	column int
 }
 
 // Runs as go routine; called by the parser
@@ -22,230 +88,424 @@ func GetNext(tokenChannel chan Token) *Token {
 	}
 }
 
-func Lex(path string, tokenChannel chan Token) {
+// Lexes the whole file at once, for parsers which need all tokens up front
+func Tokens(path string, options Options) []Token {
+	tokenChannel := make(chan Token)
+	go Lex(path, tokenChannel, options)
+	tokens := []Token{}
+	for token := range tokenChannel {
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+func Lex(path string, tokenChannel chan Token, options Options) {
+	LexContext(context.Background(), path, tokenChannel, options)
+}
+
+// Lex, which stops after the line it is in when the context is done. The channel is closed without "$" then
+func LexContext(ctx context.Context, path string, tokenChannel chan Token, options Options) {
 	//fmt.Println("Started Lexing...")
 	//fmt.Println()
 	// Open File
 	file, err := os.Open(path)
 	if err != nil {
-		panic("Lexer Error: File not able to be opened. Likely to be the wrong path. Path given: " + path)
+		panic(diag.FileNotOpened.Format("Lexer Error: File not able to be opened. Likely to be the wrong path. Path given: " + path))
 	}
 	defer file.Close()
+	LexReader(ctx, file, tokenChannel, options)
+}
 
+// Lexes source code which is not in a file, e.g. from an editor or the browser
+func LexReader(ctx context.Context, source io.Reader, tokenChannel chan Token, options Options) {
+	// The printer shows excerpts of exactly the source which was lexed
+	if options.Printer != nil {
+		content, _ := io.ReadAll(source)
+		options.Printer.Files.AddFile(options.File, content)
+		source = bytes.NewReader(content)
+	}
 	// Scan over the file
-	scanner := bufio.NewScanner(file)
-	scanner.Split(bufio.ScanLines)
+	scanner := bufio.NewScanner(source)
+	scanner.Split(scanLinesWithEnd)
 
 	lineNumber := 1
 
 	isMultiLineComment := false
+	trivia := new(triviaState)
 
 	for scanner.Scan() {
-		line := scanner.Text()
-		// Split String, removing whitespace etc.
-		tokens := []string{"\n"}
-		buffer := ""
-		isString := false
-		isSymbolString := false
-		isSingleLineComment := false
-
-		for _, c := range line {
-			switch {
-			case isSingleLineComment:
-				continue
+		if ctx.Err() != nil {
+			close(tokenChannel)
+			return
+		}
+		line, ending := splitLineEnd(scanner.Text())
+		tokens := lexLine(line, lineNumber, &isMultiLineComment, options)
+		if options.Trivia {
+			trivia.attach(line, ending, tokens, options)
+		}
+		for _, token := range tokens {
+			tokenChannel <- token
+		}
+		lineNumber++
+	}
+	if options.Trivia {
+		tokenChannel <- trivia.end(makeToken("$", "$", lineNumber-1, 0, 0))
+	} else {
+		sendToken("$", "$", lineNumber-1, 0, 0, tokenChannel)
+	}
+	close(tokenChannel)
+	//fmt.Println()
+	//fmt.Println("Lexer finished")
+}
 
-			case isMultiLineComment:
-				buf := string(c)
-				if buf == "*" {
-					buffer = buf
-				} else if buf == "/" && buffer == "*" {
-					buffer = ""
-					isMultiLineComment = false
-				} else {
-					buffer = ""
-					continue
-				}
+// The tokens of one line, starting with its LINE token, unless the line is in a comment. The only state carried from one
+// line to the next is whether a multi line comment is open, so lines can be lexed again on their own (see incremental.go)
+func lexLine(line string, lineNumber int, isMultiLineComment *bool, options Options) []Token {
+	if options.Normalize {
+		line = norm.NFC.String(line)
+	}
+	// Split String, removing whitespace etc.
+	rawTokens, isSingleLineComment := splitLine(line, isMultiLineComment)
+	rawTokens = append([]rawToken{{text: "\n"}}, rawTokens...)
 
-			case c == '"':
-				if !isString {
-					isString = true
-				} else {
-					isString = false
-					tokens = append(tokens, buffer)
-					buffer = ""
-				}
+	// Determine Identifier
+	tokens := []Token{}
+	for _, token := range rawTokens {
+		var identifier string
+		var tokenVal any
+		if token.text == "\n" && !token.isString {
+			identifier = "LINE"
+			tokenVal = strconv.Itoa(lineNumber)
+		} else {
+			identifier, tokenVal = classifyToken(token, lineNumber)
+			if identifier == "name" {
+				warnConfusable(token.text, diag.Position{File: options.File, Line: lineNumber, Col: token.column}, options)
+			}
+		}
+		if *isMultiLineComment || isSingleLineComment {
+			isSingleLineComment = false
+		} else {
+			tokens = append(tokens, makeToken(identifier, tokenVal, lineNumber, token.column, token.length()))
+		}
+	}
+	return tokens
+}
 
-			case isString:
-				buffer = buffer + string(c)
+// Splits a line into its raw tokens, removing whitespace and comments
+func splitLine(line string, isMultiLineComment *bool) ([]rawToken, bool) {
+	tokens := []rawToken{}
+	buffer := ""
+	// Index of the first rune of the buffer in the line
+	bufferStart := 0
+	isString := false
+	isInterpolated := false
+	braceDepth := 0
+	isSymbolString := false
+	isSingleLineComment := false
 
-			case isSymbolString:
-				if !isSymbol(c) {
-					tokens = append(tokens, buffer)
-					buffer = ""
-					if !unicode.IsSpace(c) {
-						buffer = string(c)
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case isSingleLineComment:
+			continue
+
+		case *isMultiLineComment:
+			buf := string(c)
+			if buf == "*" {
+				buffer = buf
+			} else if buf == "/" && buffer == "*" {
+				buffer = ""
+				*isMultiLineComment = false
+			} else {
+				buffer = ""
+				continue
+			}
+
+		case c == '"' && braceDepth == 0:
+			if !isString {
+				// $"..." starts an interpolated string, anything else left in the buffer is its own token
+				if buffer == "$" {
+					isInterpolated = true
+				} else {
+					if buffer != "" {
+						tokens = append(tokens, rawToken{text: buffer, column: bufferStart + 1})
 					}
-					isSymbolString = false
-					continue
+					bufferStart = i
 				}
+				buffer = ""
+				isSymbolString = false
+				isString = true
+			} else {
+				tokens = append(tokens, rawToken{text: buffer, isString: true, isInterpolated: isInterpolated, column: bufferStart + 1})
+				buffer = ""
+				isString = false
+				isInterpolated = false
+			}
 
-				// Is symbol -> Can be concatonated to // /* etc.
-				concSymbol := concatonateSymbols([]rune(buffer)[0], c)
+		case isString:
+			if isInterpolated {
+				// Escaped braces stay in the text, the rest keeps track of the embedded expressions
+				next := rune(0)
+				if i+1 < len(runes) {
+					next = runes[i+1]
+				}
+				switch {
+				case braceDepth == 0 && (c == '{' || c == '}') && next == c:
+					buffer = buffer + string(c)
+					i++
+				case c == '{':
+					braceDepth++
+				case c == '}' && braceDepth > 0:
+					braceDepth--
+				}
+			}
+			buffer = buffer + string(c)
 
-				// Symbols cannot be concatonated
-				if concSymbol == "" {
-					tokens = append(tokens, buffer)
+		case isSymbolString:
+			if !isSymbol(c) {
+				tokens = append(tokens, rawToken{text: buffer, column: bufferStart + 1})
+				buffer = ""
+				if !unicode.IsSpace(c) {
 					buffer = string(c)
-					continue
+					bufferStart = i
 				}
-
-				// Symbols can be concatonated -> check for if comment
 				isSymbolString = false
-				if concSymbol == "//" {
-					isSingleLineComment = true
-				} else if concSymbol == "/*" {
-					isMultiLineComment = true
-				} else {
-					tokens = append(tokens, concSymbol)
-					buffer = ""
-					continue
-				}
+				continue
+			}
 
-				// If this line is reached a comment has started
-				buffer = ""
+			// Is symbol -> Can be concatonated to // /* etc.
+			concSymbol := concatonateSymbols([]rune(buffer)[0], c)
 
-			case isSymbol(c):
-				if buffer != "" {
-					tokens = append(tokens, buffer)
-				}
-				isSymbolString = true
+			// Symbols cannot be concatonated
+			if concSymbol == "" {
+				tokens = append(tokens, rawToken{text: buffer, column: bufferStart + 1})
 				buffer = string(c)
+				bufferStart = i
+				continue
+			}
 
-			case unicode.IsSpace(c):
-				if buffer != "" {
-					tokens = append(tokens, buffer)
-				}
+			// Symbols can be concatonated -> check for if comment
+			isSymbolString = false
+			if concSymbol == "//" {
+				isSingleLineComment = true
+			} else if concSymbol == "/*" {
+				*isMultiLineComment = true
+			} else {
+				tokens = append(tokens, rawToken{text: concSymbol, column: bufferStart + 1})
 				buffer = ""
+				continue
+			}
+
+			// If this line is reached a comment has started
+			buffer = ""
+
+		case c == '.' && isNumber(buffer):
+			// Decimal point of a double literal
+			buffer = buffer + string(c)
 
-			default:
-				buffer = buffer + string(c)
+		case isSymbol(c):
+			if buffer != "" {
+				tokens = append(tokens, rawToken{text: buffer, column: bufferStart + 1})
 			}
-		}
+			isSymbolString = true
+			buffer = string(c)
+			bufferStart = i
+
+		case unicode.IsSpace(c):
+			if buffer != "" {
+				tokens = append(tokens, rawToken{text: buffer, column: bufferStart + 1})
+			}
+			buffer = ""
 
-		if buffer != "" {
-			tokens = append(tokens, buffer)
+		default:
+			if buffer == "" {
+				bufferStart = i
+			}
+			buffer = buffer + string(c)
 		}
+	}
 
-		// Determine Identifier
-		for _, token := range tokens {
-			identifier := ""
-			var tokenVal any
+	if buffer != "" {
+		tokens = append(tokens, rawToken{text: buffer, column: bufferStart + 1})
+	}
+	return tokens, isSingleLineComment
+}
 
-			tmpdigit, intConvErr := strconv.Atoi(token)
+// Number of characters of the token in the line. The lexer has no escapes, so only the quotes and the $ are missing in the text
+func (token rawToken) length() int {
+	length := utf8.RuneCountInString(token.text)
+	if token.isString {
+		length += 2
+	}
+	if token.isInterpolated {
+		length++
+	}
+	return length
+}
 
-			// If could be converted to int
-			if intConvErr == nil {
-				sendToken("intliteral", tmpdigit, tokenChannel)
-				continue
-			}
+func classifyToken(token rawToken, line int) (string, any) {
+	if token.isInterpolated {
+		return "interpolatedstring", splitInterpolation(token.text, line)
+	}
+	if token.isString {
+		return "stringliteral", token.text
+	}
 
-			tmpbool, boolConvErr := strconv.ParseBool(token)
+	tmpdigit, intConvErr := strconv.Atoi(token.text)
 
-			// If could be converted to bool
-			if boolConvErr == nil {
-				sendToken("boolliteral", tmpbool, tokenChannel)
-				continue
-			}
+	// If could be converted to int
+	if intConvErr == nil {
+		return "intliteral", tmpdigit
+	}
+
+	tmpdouble, doubleConvErr := strconv.ParseFloat(token.text, 64)
+
+	// If could be converted to double. Only digits with a decimal point, "1e5" or "NaN" are names
+	if doubleConvErr == nil && isNumber(strings.Replace(token.text, ".", "", 1)) {
+		return "doubleliteral", tmpdouble
+	}
+
+	tmpbool, boolConvErr := strconv.ParseBool(token.text)
 
-			// Check for the different symbols
-			switch token {
-			case "\n":
-				identifier = "LINE"
-				tokenVal = strconv.Itoa(lineNumber)
-				lineNumber++
-			case "namespace":
-				identifier = "namespace"
-			case "using":
-				identifier = "using"
-			case "class":
-				identifier = "class"
-			case "void":
-				identifier = "void"
-			case "static":
-				identifier = "static"
-			case "int":
-				identifier = "int"
-			case "bool":
-				identifier = "bool"
-			case "string":
-				identifier = "string"
-			case "double":
-				identifier = "double"
-			case "if":
-				identifier = "if"
-			case "else":
-				identifier = "else"
-			case "while":
-				identifier = "while"
-			case "return":
-				identifier = "return"
-			case ".":
-				identifier = "."
-			case ",":
-				identifier = ","
-			case "=":
-				identifier = "="
-			case ">", "<", ">=", "<=", "||", "&&", "==", "!=":
-				identifier = "logicaloperator"
-				tokenVal = token
-			case "+", "-":
-				identifier = "unaryoperator"
-				tokenVal = token
-			case "*", "/", "%":
-				identifier = "multoperator"
-				tokenVal = token
-			case ";":
-				identifier = ";"
-			case "{":
-				identifier = "{"
-			case "}":
-				identifier = "}"
-			case "(":
-				identifier = "("
-			case ")":
-				identifier = ")"
-			case "[":
-				identifier = "["
-			case "]":
-				identifier = "]"
-			default:
-				identifier = "name"
-				tokenVal = token
+	// If could be converted to bool. ParseBool also takes "T", "f", "1" etc., which are names or numbers in C#
+	if boolConvErr == nil && (token.text == "true" || token.text == "false") {
+		return "boolliteral", tmpbool
+	}
+
+	// Check for the different symbols
+	switch token.text {
+	case "namespace", "using", "class", "void", "static", "extern", "int", "bool", "string", "double",
+		"if", "else", "while", "return", ".", ",", "=", ";", "{", "}", "(", ")", "[", "]":
+		return token.text, nil
+	case ">", "<", ">=", "<=", "||", "&&", "==", "!=":
+		return "logicaloperator", token.text
+	case "+", "-":
+		return "unaryoperator", token.text
+	case "*", "/", "%":
+		return "multoperator", token.text
+	default:
+		return "name", token.text
+	}
+}
+
+// Splits the content of an interpolated string into text and expression segments. {{ and }} are literal braces
+func splitInterpolation(text string, line int) []StringSegment {
+	segments := []StringSegment{}
+	literal := ""
+	expression := ""
+	braceDepth := 0
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		if braceDepth == 0 {
+			if (c == '{' || c == '}') && i+1 < len(runes) && runes[i+1] == c {
+				literal = literal + string(c)
+				i++
+				continue
 			}
-			if isMultiLineComment || isSingleLineComment {
-				if isSingleLineComment {
+			if c == '{' {
+				if literal != "" {
+					segments = append(segments, StringSegment{Text: literal})
+					literal = ""
 				}
-				isSingleLineComment = false
-			} else {
-				sendToken(identifier, tokenVal, tokenChannel)
+				braceDepth++
+				continue
+			}
+			literal = literal + string(c)
+			continue
+		}
+
+		if c == '{' {
+			braceDepth++
+		} else if c == '}' {
+			braceDepth--
+			if braceDepth == 0 {
+				segments = append(segments, StringSegment{Text: expression, Expression: lexExpression(expression, line)})
+				expression = ""
+				continue
 			}
 		}
+		expression = expression + string(c)
 	}
-	sendToken("$", "$", tokenChannel)
-	close(tokenChannel)
-	//fmt.Println()
-	//fmt.Println("Lexer finished")
+
+	// Unclosed expressions are kept as text
+	literal = literal + expression
+	if literal != "" {
+		segments = append(segments, StringSegment{Text: literal})
+	}
+	return segments
 }
 
-func sendToken(identifier string, value any, channel chan Token) {
-	// Make return token and add to channel
+// Tokenizes the source of an embedded expression. All tokens are in the line of the string
+func lexExpression(source string, line int) []Token {
+	isMultiLineComment := false
+	rawTokens, _ := splitLine(source, &isMultiLineComment)
+	tokens := []Token{}
+	for _, raw := range rawTokens {
+		identifier, value := classifyToken(raw, line)
+		tokens = append(tokens, makeToken(identifier, value, line, 0, 0))
+	}
+	return tokens
+}
+
+func makeToken(identifier string, value any, line int, col int, length int) Token {
 	returnToken := new(Token)
 	returnToken.Identifier = identifier
 	returnToken.Value = value
+	returnToken.Line = line
+	returnToken.Col = col
+	returnToken.Length = length
 	if returnToken.Value == nil {
 		returnToken.Value = 0
 	}
-	channel <- *returnToken
+	return *returnToken
+}
+
+func sendToken(identifier string, value any, line int, col int, length int, channel chan Token) {
+	// Make return token and add to channel
+	channel <- makeToken(identifier, value, line, col, length)
+}
+
+// Warns about identifiers which can look like another identifier, but are not equal to it
+func warnConfusable(name string, position diag.Position, options Options) {
+	lineString := strconv.Itoa(position.Line)
+	if !options.Normalize && !norm.NFC.IsNormalString(name) {
+		options.Emit(diag.MakeDiagnosticAt(diag.NotNormalized, "Lexer Warning: Identifier \""+name+"\" at line "+lineString+" is not NFC normalized. Use -normalize to normalize the source", position))
+	}
+	if isMixedScript(name) {
+		options.Emit(diag.MakeDiagnosticAt(diag.MixedScript, "Lexer Warning: Identifier \""+name+"\" at line "+lineString+" mixes Latin, Greek or Cyrillic letters", position))
+	}
+}
+
+// Latin, Greek and Cyrillic share letters which look the same (a and а), so mixing them is likely a mistake
+func isMixedScript(name string) bool {
+	scripts := []*unicode.RangeTable{unicode.Latin, unicode.Greek, unicode.Cyrillic}
+	found := -1
+	for _, r := range name {
+		for i, script := range scripts {
+			if unicode.Is(script, r) {
+				if found != -1 && found != i {
+					return true
+				}
+				found = i
+			}
+		}
+	}
+	return false
+}
+
+func isNumber(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
 }
 
 func isSymbol(r rune) bool {
@@ -0,0 +1,96 @@
+package lexer
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+/*
+Trivia for lossless syntax trees (see parser/cst.go). With Options.Trivia every token which is in the source gets the
+text it was lexed from and the whitespace and comments around it, like Roslyn splits them:
+
+	Trailing  after the token up to the next token in the line, or up to the end of the line with the line end
+	Leading   everything else in front of the token: the indentation and the lines without tokens in front of the
+	          first token of a line
+
+The leading trivia of "$" is the rest of the file. The Leading, Text and Trailing of the tokens in their order give back
+the source, with the line ends it had ("\r\n" stays). With Options.Normalize it is the normalized source.
+Tokens the lexer drops (the ones in front of a multi line comment which goes on in the next line) are trivia as well.
+LINE tokens and the tokens of interpolated expressions have no trivia, Document does not keep any.
+*/
+
+type Trivia struct {
+	Leading string
+	// The token as it is in the source, e.g. with the quotes of a string
+	Text     string
+	Trailing string
+}
+
+// The leading trivia collected for the next token, over the lines without tokens
+type triviaState struct {
+	pending strings.Builder
+}
+
+// Sets the trivia of the tokens of a line. ending is the line end, "" for a last line without one
+func (state *triviaState) attach(line string, ending string, tokens []Token, options Options) {
+	if options.Normalize {
+		line = norm.NFC.String(line)
+	}
+	runes := []rune(line)
+	// Characters of the line which belong to a token already
+	offset := 0
+	var last *Trivia
+	for i := range tokens {
+		if tokens[i].Col == 0 {
+			continue
+		}
+		start := min(max(tokens[i].Col-1, offset), len(runes))
+		end := min(start+tokens[i].Length, len(runes))
+		trivia := &Trivia{Text: string(runes[start:end])}
+		if last == nil {
+			state.pending.WriteString(string(runes[offset:start]))
+			trivia.Leading = state.pending.String()
+			state.pending.Reset()
+		} else {
+			last.Trailing = string(runes[offset:start])
+		}
+		tokens[i].Trivia = trivia
+		last = trivia
+		offset = end
+	}
+	rest := string(runes[offset:]) + ending
+	if last == nil {
+		state.pending.WriteString(rest)
+	} else {
+		last.Trailing = rest
+	}
+}
+
+// The "$" at the end of the source with the rest of the file as its trivia
+func (state *triviaState) end(token Token) Token {
+	token.Trivia = &Trivia{Leading: state.pending.String()}
+	return token
+}
+
+// bufio.ScanLines, which keeps the line end in the token. The trivia needs it, the lexer gets the line without it
+func scanLinesWithEnd(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i+1], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// The line without its end, like bufio.ScanLines gives it, and the end
+func splitLineEnd(text string) (string, string) {
+	line := strings.TrimSuffix(text, "\n")
+	line = strings.TrimSuffix(line, "\r")
+	return line, text[len(line):]
+}
@@ -0,0 +1,569 @@
+package llvm
+
+import (
+	"compiler/ast"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/*
+LLVM IR in text form (.ll) of a checked program. The project has no IR of its own, so it is generated from the AST.
+LLVM optimizes it and generates code for every architecture it supports:
+
+	opt -O2 program.ll -S -o program.opt.ll
+	clang program.ll -o program -lm
+
+Pointers are opaque (ptr), the default since LLVM 15, LLVM 14 needs -opaque-pointers.
+
+Types: int is i32, bool is i1, double is double, string is a pointer to a NUL terminated string.
+Every variable gets an alloca in the entry block, opt -passes=mem2reg turns them into registers.
+Functions of the program get the prefix neon_, extern functions are called by their own name,
+Sqrt, Pow, Floor, ParseInt and ReadLine are included. main calls Main with null for the arguments.
+*/
+
+func Generate(program *ast.Program) (string, error) {
+	g := &generator{program: program, functions: make(map[string]*ast.Function), strings: make(map[string]string)}
+	for _, function := range program.Functions {
+		g.functions[function.Name] = function
+	}
+	main := g.functions["Main"]
+	if main == nil {
+		main = g.functions["main"]
+	}
+	if main == nil || main.Extern {
+		return "", errors.New("LLVM Error: No Main function found")
+	}
+
+	var text strings.Builder
+	for _, function := range program.Functions {
+		if function.Extern {
+			if host, ok := hostFunctions[function.Name]; ok && host.signature == signature(function) {
+				text.WriteString(host.body)
+			} else {
+				text.WriteString("\ndeclare " + irType(function.ReturnType) + " " + global(function.Name) + "(" + paramTypes(function) + ")\n")
+			}
+			continue
+		}
+		code, err := g.generateFunction(function)
+		if err != nil {
+			return "", err
+		}
+		text.WriteString(code)
+	}
+	text.WriteString("\ndefine i32 @main() {\n")
+	if len(main.Params) == 1 {
+		text.WriteString("  call " + irType(main.ReturnType) + " " + symbol(main) + "(ptr null)\n")
+	} else {
+		text.WriteString("  call " + irType(main.ReturnType) + " " + symbol(main) + "()\n")
+	}
+	text.WriteString("  ret i32 0\n}\n")
+	text.WriteString(runtime)
+
+	if len(g.literals) > 0 {
+		text.WriteString("\n")
+	}
+	for _, literal := range g.literals {
+		text.WriteString(g.strings[literal] + " = private unnamed_addr constant [" + strconv.Itoa(len(literal)+1) + " x i8] c\"" + escape(literal) + "\\00\"\n")
+	}
+	return text.String(), nil
+}
+
+type generator struct {
+	program   *ast.Program
+	functions map[string]*ast.Function
+	// Names of the string constants, in the order they were found
+	strings  map[string]string
+	literals []string
+
+	// Of the function being generated
+	function *ast.Function
+	scopes   []map[string]local
+	// Allocas of the entry block
+	allocas strings.Builder
+	code    strings.Builder
+	values  int
+	labels  int
+	// The block instructions are added to, for phi
+	block string
+	// The block ends with ret, br or unreachable, the next instruction needs a new block
+	terminated bool
+}
+
+type local struct {
+	// Pointer from the alloca
+	pointer string
+	typ     string
+}
+
+// Unsupported programs panic with a generateError, only possible if they did not pass the checks
+type generateError struct {
+	message string
+}
+
+func (g *generator) generateFunction(function *ast.Function) (code string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			generateErr, ok := r.(generateError)
+			if !ok {
+				panic(r)
+			}
+			err = errors.New("LLVM Error: " + generateErr.message)
+		}
+	}()
+
+	g.function = function
+	g.scopes = []map[string]local{{}}
+	g.allocas.Reset()
+	g.code.Reset()
+	g.values = 0
+	g.labels = 0
+	g.block = "entry"
+	g.terminated = false
+
+	params := []string{}
+	for _, param := range function.Params {
+		argument := localName("arg." + param.Name)
+		params = append(params, irType(param.Type)+" "+argument)
+		g.emit("store " + irType(param.Type) + " " + argument + ", ptr " + g.declare(param.Name, param.Type).pointer)
+	}
+	g.statements(function.Body)
+	if function.ReturnType == "void" {
+		g.terminate("ret void")
+	} else {
+		g.emit("call void @neon_no_return(ptr " + g.stringLiteral(function.Name) + ")")
+		g.terminate("unreachable")
+	}
+
+	header := "\ndefine " + irType(function.ReturnType) + " " + symbol(function) + "(" + strings.Join(params, ", ") + ") {\nentry:\n"
+	return header + g.allocas.String() + g.code.String() + "}\n", nil
+}
+
+func (g *generator) statements(block *ast.Block) {
+	g.scopes = append(g.scopes, map[string]local{})
+	for _, statement := range block.Statements {
+		g.statement(statement)
+	}
+	g.scopes = g.scopes[:len(g.scopes)-1]
+}
+
+func (g *generator) statement(statement ast.Stmt) {
+	switch s := statement.(type) {
+	case *ast.VarDecl:
+		value := g.zero(s.Type)
+		if s.Value != nil {
+			value = g.convert(g.expr(s.Value), s.Type)
+		}
+		g.store(g.declare(s.Name, s.Type), value)
+	case *ast.Assign:
+		variable := g.lookup(s.Name)
+		g.store(variable, g.convert(g.expr(s.Value), variable.typ))
+	case *ast.CallStmt:
+		g.call(s.Call)
+	case *ast.Return:
+		if s.Value == nil {
+			g.terminate("ret void")
+		} else {
+			value := g.convert(g.expr(s.Value), g.function.ReturnType)
+			g.terminate("ret " + irType(g.function.ReturnType) + " " + value)
+		}
+		// Statements after the return are unreachable, but still need a block
+		g.startBlock(g.label())
+	case *ast.If:
+		thenLabel, elseLabel, endLabel := g.label(), g.label(), g.label()
+		g.terminate("br i1 " + g.condition(s.Cond) + ", label %" + thenLabel + ", label %" + elseLabel)
+		g.startBlock(thenLabel)
+		g.statements(s.Then)
+		g.terminate("br label %" + endLabel)
+		g.startBlock(elseLabel)
+		if s.Else != nil {
+			g.statements(s.Else)
+		}
+		g.startBlock(endLabel)
+	case *ast.While:
+		startLabel, bodyLabel, endLabel := g.label(), g.label(), g.label()
+		g.startBlock(startLabel)
+		g.terminate("br i1 " + g.condition(s.Cond) + ", label %" + bodyLabel + ", label %" + endLabel)
+		g.startBlock(bodyLabel)
+		g.statements(s.Body)
+		g.terminate("br label %" + startLabel)
+		g.startBlock(endLabel)
+	}
+}
+
+func (g *generator) condition(cond ast.Expr) string {
+	value := g.expr(cond)
+	if value.typ != "bool" {
+		panic(generateError{"Condition is not a bool, at line " + strconv.Itoa(cond.Pos().Line)})
+	}
+	return value.name
+}
+
+// Result of an expression: a register or a constant
+type value struct {
+	name string
+	typ  string
+}
+
+func (g *generator) expr(expr ast.Expr) value {
+	switch e := expr.(type) {
+	case *ast.IntLit:
+		return value{strconv.Itoa(int(int32(e.Value))), "int"}
+	case *ast.DoubleLit:
+		return value{doubleConstant(e.Value), "double"}
+	case *ast.BoolLit:
+		return value{strconv.FormatBool(e.Value), "bool"}
+	case *ast.StringLit:
+		return value{g.stringLiteral(e.Value), "string"}
+	case *ast.InterpolatedString:
+		result := g.stringLiteral("")
+		for _, part := range e.Parts {
+			text := g.stringLiteral(part.Text)
+			if part.Expr != nil {
+				text = g.toString(g.expr(part.Expr))
+			}
+			result = g.assign("call ptr @neon_concat(ptr " + result + ", ptr " + text + ")")
+		}
+		return value{result, "string"}
+	case *ast.Ident:
+		variable := g.lookup(e.Name)
+		return value{g.assign("load " + irType(variable.typ) + ", ptr " + variable.pointer), variable.typ}
+	case *ast.Unary:
+		operand := g.expr(e.Operand)
+		if e.Op != "-" {
+			return operand
+		}
+		if operand.typ == "double" {
+			return value{g.assign("fneg double " + operand.name), "double"}
+		}
+		return value{g.assign("sub i32 0, " + operand.name), operand.typ}
+	case *ast.Binary:
+		return g.binary(e)
+	case *ast.Call:
+		result := g.call(e)
+		if result.typ == "void" {
+			panic(generateError{"The void function " + e.FullName() + " has no value, at line " + strconv.Itoa(e.Pos().Line)})
+		}
+		return result
+	}
+	panic(generateError{"Unknown expression at line " + strconv.Itoa(expr.Pos().Line)})
+}
+
+var intInstructions = map[string]string{"+": "add i32", "-": "sub i32", "*": "mul i32", "/": "sdiv i32", "%": "srem i32",
+	"<": "icmp slt i32", ">": "icmp sgt i32", "<=": "icmp sle i32", ">=": "icmp sge i32", "==": "icmp eq i32", "!=": "icmp ne i32"}
+
+// Ordered comparisons are false with NaN, une is true like != in C#
+var doubleInstructions = map[string]string{"+": "fadd double", "-": "fsub double", "*": "fmul double", "/": "fdiv double", "%": "frem double",
+	"<": "fcmp olt double", ">": "fcmp ogt double", "<=": "fcmp ole double", ">=": "fcmp oge double", "==": "fcmp oeq double", "!=": "fcmp une double"}
+
+func (g *generator) binary(e *ast.Binary) value {
+	left, right := g.typeOf(e.Left), g.typeOf(e.Right)
+	switch {
+	case e.Op == "&&" || e.Op == "||":
+		// Short circuit: the right side is only evaluated if needed
+		rightLabel, endLabel := g.label(), g.label()
+		leftValue := g.condition(e.Left)
+		leftBlock := g.block
+		if e.Op == "&&" {
+			g.terminate("br i1 " + leftValue + ", label %" + rightLabel + ", label %" + endLabel)
+		} else {
+			g.terminate("br i1 " + leftValue + ", label %" + endLabel + ", label %" + rightLabel)
+		}
+		g.startBlock(rightLabel)
+		rightValue := g.condition(e.Right)
+		rightBlock := g.block
+		g.startBlock(endLabel)
+		short := strconv.FormatBool(e.Op == "||")
+		return value{g.assign("phi i1 [ " + short + ", %" + leftBlock + " ], [ " + rightValue + ", %" + rightBlock + " ]"), "bool"}
+	case e.Op == "+" && (left == "string" || right == "string"):
+		leftString := g.toString(g.expr(e.Left))
+		rightString := g.toString(g.expr(e.Right))
+		return value{g.assign("call ptr @neon_concat(ptr " + leftString + ", ptr " + rightString + ")"), "string"}
+	case (e.Op == "==" || e.Op == "!=") && left == "string":
+		leftValue, rightValue := g.expr(e.Left), g.expr(e.Right)
+		equal := g.assign("call i1 @neon_string_equals(ptr " + leftValue.name + ", ptr " + rightValue.name + ")")
+		if e.Op == "!=" {
+			return value{g.assign("xor i1 " + equal + ", true"), "bool"}
+		}
+		return value{equal, "bool"}
+	case (e.Op == "==" || e.Op == "!=") && left == "bool":
+		leftValue, rightValue := g.expr(e.Left), g.expr(e.Right)
+		return value{g.assign(map[string]string{"==": "icmp eq i1 ", "!=": "icmp ne i1 "}[e.Op] + leftValue.name + ", " + rightValue.name), "bool"}
+	case left == "double" || right == "double":
+		leftValue := g.convert(g.expr(e.Left), "double")
+		rightValue := g.convert(g.expr(e.Right), "double")
+		return value{g.assign(doubleInstructions[e.Op] + " " + leftValue + ", " + rightValue), g.typeOf(e)}
+	}
+	leftValue, rightValue := g.expr(e.Left), g.expr(e.Right)
+	if e.Op == "/" || e.Op == "%" {
+		g.checkDivisor(rightValue.name)
+		return value{g.divide(e.Op, leftValue.name, rightValue.name), "int"}
+	}
+	return value{g.assign(intInstructions[e.Op] + " " + leftValue.name + ", " + rightValue.name), g.typeOf(e)}
+}
+
+// int.MinValue / -1 is undefined in LLVM, like the interpreter x / -1 is -x (which wraps around) and x % -1 is 0
+func (g *generator) divide(op string, dividend string, divisor string) string {
+	minusOneLabel, divideLabel, endLabel := g.label(), g.label(), g.label()
+	isMinusOne := g.assign("icmp eq i32 " + divisor + ", -1")
+	g.terminate("br i1 " + isMinusOne + ", label %" + minusOneLabel + ", label %" + divideLabel)
+	g.startBlock(minusOneLabel)
+	negated := "0"
+	if op == "/" {
+		negated = g.assign("sub i32 0, " + dividend)
+	}
+	minusOneBlock := g.block
+	g.terminate("br label %" + endLabel)
+	g.startBlock(divideLabel)
+	quotient := g.assign(intInstructions[op] + " " + dividend + ", " + divisor)
+	divideBlock := g.block
+	g.terminate("br label %" + endLabel)
+	g.startBlock(endLabel)
+	return g.assign("phi i32 [ " + negated + ", %" + minusOneBlock + " ], [ " + quotient + ", %" + divideBlock + " ]")
+}
+
+func (g *generator) checkDivisor(divisor string) {
+	zeroLabel, okLabel := g.label(), g.label()
+	isZero := g.assign("icmp eq i32 " + divisor + ", 0")
+	g.terminate("br i1 " + isZero + ", label %" + zeroLabel + ", label %" + okLabel)
+	g.startBlock(zeroLabel)
+	g.emit("call void @neon_division_by_zero()")
+	g.terminate("unreachable")
+	g.startBlock(okLabel)
+}
+
+func (g *generator) call(call *ast.Call) value {
+	if function, ok := g.functions[call.Name]; ok && (call.Receiver == "" || call.Receiver == g.program.Class) {
+		args := []string{}
+		for i, arg := range call.Args {
+			typ := function.Params[i].Type
+			args = append(args, irType(typ)+" "+g.convert(g.expr(arg), typ))
+		}
+		name := symbol(function)
+		if function.Extern {
+			name = global(function.Name)
+		}
+		instruction := "call " + irType(function.ReturnType) + " " + name + "(" + strings.Join(args, ", ") + ")"
+		if function.ReturnType == "void" {
+			g.emit(instruction)
+			return value{"", "void"}
+		}
+		return value{g.assign(instruction), function.ReturnType}
+	}
+	switch call.FullName() {
+	case "Console.WriteLine", "Console.Write":
+		text := g.stringLiteral("")
+		if len(call.Args) > 0 {
+			text = g.toString(g.expr(call.Args[0]))
+		}
+		g.emit("call void @neon_print(ptr " + text + ", i1 " + strconv.FormatBool(call.Name == "WriteLine") + ")")
+		return value{"", "void"}
+	}
+	panic(generateError{"Function " + call.FullName() + " does not exist, at line " + strconv.Itoa(call.Pos().Line)})
+}
+
+// Type of the expression without generating anything, the same rules as in package types
+func (g *generator) typeOf(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.IntLit:
+		return "int"
+	case *ast.DoubleLit:
+		return "double"
+	case *ast.BoolLit:
+		return "bool"
+	case *ast.StringLit, *ast.InterpolatedString:
+		return "string"
+	case *ast.Ident:
+		return g.lookup(e.Name).typ
+	case *ast.Unary:
+		return g.typeOf(e.Operand)
+	case *ast.Binary:
+		switch e.Op {
+		case "&&", "||", "<", ">", "<=", ">=", "==", "!=":
+			return "bool"
+		}
+		left, right := g.typeOf(e.Left), g.typeOf(e.Right)
+		switch {
+		case e.Op == "+" && (left == "string" || right == "string"):
+			return "string"
+		case left == "double" || right == "double":
+			return "double"
+		}
+		return "int"
+	case *ast.Call:
+		if function, ok := g.functions[e.Name]; ok && (e.Receiver == "" || e.Receiver == g.program.Class) {
+			return function.ReturnType
+		}
+		return "void"
+	}
+	return "void"
+}
+
+func (g *generator) toString(v value) string {
+	switch v.typ {
+	case "int":
+		return g.assign("call ptr @neon_int_to_string(i32 " + v.name + ")")
+	case "double":
+		return g.assign("call ptr @neon_double_to_string(double " + v.name + ")")
+	case "bool":
+		return g.assign("call ptr @neon_bool_to_string(i1 " + v.name + ")")
+	}
+	return v.name
+}
+
+// The only implicit conversion: int to double
+func (g *generator) convert(v value, to string) string {
+	if v.typ == "int" && to == "double" {
+		return g.assign("sitofp i32 " + v.name + " to double")
+	}
+	return v.name
+}
+
+func (g *generator) zero(typ string) string {
+	switch typ {
+	case "double":
+		return "0.0"
+	case "string":
+		return g.stringLiteral("")
+	case "bool":
+		return "false"
+	}
+	return "0"
+}
+
+func (g *generator) store(variable local, value string) {
+	g.emit("store " + irType(variable.typ) + " " + value + ", ptr " + variable.pointer)
+}
+
+func (g *generator) declare(name string, typ string) local {
+	g.values++
+	variable := local{pointer: localName(name + "." + strconv.Itoa(g.values)), typ: typ}
+	g.allocas.WriteString("  " + variable.pointer + " = alloca " + irType(typ) + "\n")
+	g.scopes[len(g.scopes)-1][name] = variable
+	return variable
+}
+
+func (g *generator) lookup(name string) local {
+	for i := len(g.scopes) - 1; i >= 0; i-- {
+		if variable, ok := g.scopes[i][name]; ok {
+			return variable
+		}
+	}
+	panic(generateError{"Variable " + name + " is not declared"})
+}
+
+func (g *generator) stringLiteral(text string) string {
+	if name, ok := g.strings[text]; ok {
+		return name
+	}
+	name := "@.str." + strconv.Itoa(len(g.literals))
+	g.strings[text] = name
+	g.literals = append(g.literals, text)
+	return name
+}
+
+// Emits the instruction into a new register and returns the register. Registers have no ".", so they never clash with variables
+func (g *generator) assign(instruction string) string {
+	g.values++
+	register := "%t" + strconv.Itoa(g.values)
+	g.emit(register + " = " + instruction)
+	return register
+}
+
+func (g *generator) emit(instruction string) {
+	g.code.WriteString("  " + instruction + "\n")
+}
+
+func (g *generator) terminate(instruction string) {
+	if !g.terminated {
+		g.emit(instruction)
+	}
+	g.terminated = true
+}
+
+// Starts a new block, the block before falls through into it
+func (g *generator) startBlock(label string) {
+	g.terminate("br label %" + label)
+	g.code.WriteString(label + ":\n")
+	g.block = label
+	g.terminated = false
+}
+
+func (g *generator) label() string {
+	g.labels++
+	return "L" + strconv.Itoa(g.labels)
+}
+
+func irType(typ string) string {
+	switch typ {
+	case "int":
+		return "i32"
+	case "bool":
+		return "i1"
+	case "double":
+		return "double"
+	case "void":
+		return "void"
+	}
+	// string and string[]
+	return "ptr"
+}
+
+func paramTypes(function *ast.Function) string {
+	types := []string{}
+	for _, param := range function.Params {
+		types = append(types, irType(param.Type))
+	}
+	return strings.Join(types, ", ")
+}
+
+// Return type and parameter types as in hostFunctions
+func signature(function *ast.Function) string {
+	types := []string{}
+	for _, param := range function.Params {
+		types = append(types, param.Type)
+	}
+	return function.ReturnType + "(" + strings.Join(types, ",") + ")"
+}
+
+// Doubles are written as the hexadecimal bits, which LLVM reads back exactly
+func doubleConstant(value float64) string {
+	return fmt.Sprintf("0x%016X", math.Float64bits(value))
+}
+
+func symbol(function *ast.Function) string {
+	return global("neon_" + function.Name)
+}
+
+func global(name string) string {
+	return "@" + identifier(name)
+}
+
+func localName(name string) string {
+	return "%" + identifier(name)
+}
+
+// Names with other characters than letters, digits, $, . and _ are quoted, bytes which are not printable ASCII are escaped
+func identifier(name string) string {
+	for _, c := range name {
+		if !(c == '_' || c == '.' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			return "\"" + escape(name) + "\""
+		}
+	}
+	return name
+}
+
+// Bytes which are not printable ASCII, " and \ are written as \XX
+func escape(text string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(text); i++ {
+		if c := text[i]; c >= 0x20 && c < 0x7f && c != '"' && c != '\\' {
+			escaped.WriteByte(c)
+		} else {
+			fmt.Fprintf(&escaped, "\\%02X", c)
+		}
+	}
+	return escaped.String()
+}
@@ -0,0 +1,183 @@
+package llvm
+
+// Runtime functions every module contains, on top of the C library. Strings are NUL terminated and never freed
+const runtime = `
+declare i64 @strlen(ptr)
+declare ptr @malloc(i64)
+declare ptr @strcpy(ptr, ptr)
+declare ptr @strcat(ptr, ptr)
+declare i32 @strcmp(ptr, ptr)
+declare i64 @strcspn(ptr, ptr)
+declare i32 @atoi(ptr)
+declare i32 @snprintf(ptr, i64, ptr, ...)
+declare double @strtod(ptr, ptr)
+declare i32 @printf(ptr, ...)
+declare i32 @putchar(i32)
+declare i64 @getline(ptr, ptr, ptr)
+declare void @exit(i32) noreturn
+declare double @llvm.sqrt.f64(double)
+declare double @llvm.pow.f64(double, double)
+declare double @llvm.floor.f64(double)
+
+@stdin = external global ptr
+
+@.int_format = private unnamed_addr constant [3 x i8] c"%d\00"
+@.double_format = private unnamed_addr constant [5 x i8] c"%.*f\00"
+@.string_format = private unnamed_addr constant [3 x i8] c"%s\00"
+@.true = private unnamed_addr constant [5 x i8] c"True\00"
+@.false = private unnamed_addr constant [6 x i8] c"False\00"
+@.nan = private unnamed_addr constant [4 x i8] c"NaN\00"
+@.infinity = private unnamed_addr constant [4 x i8] c"\E2\88\9E\00"
+@.minus_infinity = private unnamed_addr constant [5 x i8] c"-\E2\88\9E\00"
+@.empty = private unnamed_addr constant [1 x i8] c"\00"
+@.line_end = private unnamed_addr constant [3 x i8] c"\0D\0A\00"
+@.no_return_format = private unnamed_addr constant [68 x i8] c"[E0401] Runtime Error: Function %s ended without returning a value\0A\00"
+@.division_by_zero = private unnamed_addr constant [41 x i8] c"[E0401] Runtime Error: Division by zero\0A\00"
+
+; a + b in a new string
+define internal ptr @neon_concat(ptr %a, ptr %b) {
+  %a_length = call i64 @strlen(ptr %a)
+  %b_length = call i64 @strlen(ptr %b)
+  %length = add i64 %a_length, %b_length
+  %size = add i64 %length, 1
+  %result = call ptr @malloc(i64 %size)
+  call ptr @strcpy(ptr %result, ptr %a)
+  call ptr @strcat(ptr %result, ptr %b)
+  ret ptr %result
+}
+
+define internal i1 @neon_string_equals(ptr %a, ptr %b) {
+  %compared = call i32 @strcmp(ptr %a, ptr %b)
+  %equal = icmp eq i32 %compared, 0
+  ret i1 %equal
+}
+
+define internal ptr @neon_int_to_string(i32 %value) {
+  %buffer = call ptr @malloc(i64 16)
+  call i32 (ptr, i64, ptr, ...) @snprintf(ptr %buffer, i64 16, ptr @.int_format, i32 %value)
+  ret ptr %buffer
+}
+
+; True or False, like C#
+define internal ptr @neon_bool_to_string(i1 %value) {
+  %string = select i1 %value, ptr @.true, ptr @.false
+  ret ptr %string
+}
+
+; The fewest decimals which read back as the same double, without exponent like C#.
+; Very large numbers can show different digits after the 17th
+define internal ptr @neon_double_to_string(double %value) {
+entry:
+  %is_nan = fcmp uno double %value, %value
+  br i1 %is_nan, label %nan, label %number
+nan:
+  ret ptr @.nan
+number:
+  %is_infinity = fcmp oeq double %value, 0x7FF0000000000000
+  br i1 %is_infinity, label %infinity, label %not_infinity
+infinity:
+  ret ptr @.infinity
+not_infinity:
+  %is_minus_infinity = fcmp oeq double %value, 0xFFF0000000000000
+  br i1 %is_minus_infinity, label %minus_infinity, label %finite
+minus_infinity:
+  ret ptr @.minus_infinity
+finite:
+  %buffer = call ptr @malloc(i64 720)
+  br label %format
+format:
+  %decimals = phi i32 [ 0, %finite ], [ %more_decimals, %more ]
+  call i32 (ptr, i64, ptr, ...) @snprintf(ptr %buffer, i64 720, ptr @.double_format, i32 %decimals, double %value)
+  %at_most = icmp sge i32 %decimals, 340
+  br i1 %at_most, label %formatted, label %read_back
+read_back:
+  %read = call double @strtod(ptr %buffer, ptr null)
+  %same = fcmp oeq double %read, %value
+  br i1 %same, label %formatted, label %more
+more:
+  %more_decimals = add i32 %decimals, 1
+  br label %format
+formatted:
+  ret ptr %buffer
+}
+
+define internal void @neon_print(ptr %string, i1 %newline) {
+  call i32 (ptr, ...) @printf(ptr @.string_format, ptr %string)
+  br i1 %newline, label %line, label %done
+line:
+  call i32 @putchar(i32 10)
+  br label %done
+done:
+  ret void
+}
+
+; Like the interpreter: a function which should return a value must not end
+define internal void @neon_no_return(ptr %function) noreturn {
+  call i32 (ptr, ...) @printf(ptr @.no_return_format, ptr %function)
+  call void @exit(i32 1)
+  unreachable
+}
+
+; Integer division by zero is undefined in LLVM, the interpreter stops with an error
+define internal void @neon_division_by_zero() noreturn {
+  call i32 (ptr, ...) @printf(ptr @.string_format, ptr @.division_by_zero)
+  call void @exit(i32 1)
+  unreachable
+}
+`
+
+type hostFunction struct {
+	// Return type and parameter types the extern declaration must have
+	signature string
+	body      string
+}
+
+// Host functions bound to extern declarations by default, like interp.HostFunctions.
+// Other extern functions are only declared and have to be linked
+var hostFunctions = map[string]hostFunction{
+	"Sqrt": {"double(double)", `
+define double @Sqrt(double %x) {
+  %result = call double @llvm.sqrt.f64(double %x)
+  ret double %result
+}
+`},
+	"Pow": {"double(double,double)", `
+define double @Pow(double %x, double %y) {
+  %result = call double @llvm.pow.f64(double %x, double %y)
+  ret double %result
+}
+`},
+	"Floor": {"double(double)", `
+define double @Floor(double %x) {
+  %result = call double @llvm.floor.f64(double %x)
+  ret double %result
+}
+`},
+	"ParseInt": {"int(string)", `
+define i32 @ParseInt(ptr %text) {
+  %result = call i32 @atoi(ptr %text)
+  ret i32 %result
+}
+`},
+	"ReadLine": {"string()", `
+define ptr @ReadLine() {
+entry:
+  %line = alloca ptr
+  %size = alloca i64
+  store ptr null, ptr %line
+  store i64 0, ptr %size
+  %stdin = load ptr, ptr @stdin
+  %read = call i64 @getline(ptr %line, ptr %size, ptr %stdin)
+  %ok = icmp sgt i64 %read, 0
+  br i1 %ok, label %strip, label %empty
+empty:
+  ret ptr @.empty
+strip:
+  %text = load ptr, ptr %line
+  %length = call i64 @strcspn(ptr %text, ptr @.line_end)
+  %end = getelementptr i8, ptr %text, i64 %length
+  store i8 0, ptr %end
+  ret ptr %text
+}
+`},
+}
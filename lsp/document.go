@@ -0,0 +1,334 @@
+package lsp
+
+import (
+	"compiler/ast"
+	"compiler/diag"
+	"compiler/flow"
+	"compiler/highlight"
+	"compiler/lexer"
+	"compiler/parser"
+	"compiler/symtab"
+	"compiler/types"
+	"context"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf16"
+)
+
+// An open file with the results of the compiler for its text. Everything is computed again after a change
+type document struct {
+	uri     string
+	version int
+	text    string
+	lines   []string
+	// The tokens which are in the text, without line ends
+	tokens      []lexer.Token
+	diagnostics []*diag.Diagnostic
+	// nil if no AST could be built
+	program *ast.Program
+	info    *types.Info
+	// The declaration or use every name token stands for, by the index in tokens
+	names map[int]*symtab.Symbol
+}
+
+// Lexes and parses the text with the tolerant parser, so there is an AST for broken code as well, and checks the
+// names and types like the compiler. Type errors are only reported if the names are fine, like in the compiler
+func analyze(table *parser.SLR_parsing_Table, uri string, version int, text string) *document {
+	doc := &document{uri: uri, version: version, text: text, names: make(map[int]*symtab.Symbol)}
+	for _, line := range strings.Split(text, "\n") {
+		doc.lines = append(doc.lines, strings.TrimSuffix(line, "\r"))
+	}
+
+	// The lexer runs in its own go routine
+	var mutex sync.Mutex
+	options := lexer.Options{Output: io.Discard, Report: func(diagnostic *diag.Diagnostic) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		doc.diagnostics = append(doc.diagnostics, diagnostic)
+	}}
+	tokenChannel := make(chan lexer.Token)
+	go lexer.LexReader(context.Background(), strings.NewReader(text), tokenChannel, options)
+	tokens := []lexer.Token{}
+	for token := range tokenChannel {
+		tokens = append(tokens, token)
+		if token.Col != 0 {
+			doc.tokens = append(doc.tokens, token)
+		}
+	}
+	tree, _ := table.ParseTolerant(tokens, options)
+
+	program, err := ast.Build(tree)
+	if err == nil {
+		doc.program = program
+		nameDiagnostics := symtab.Bind(program)
+		info, typeDiagnostics := types.Check(program)
+		doc.info = info
+		doc.diagnostics = append(doc.diagnostics, nameDiagnostics...)
+		if len(nameDiagnostics) == 0 {
+			doc.diagnostics = append(doc.diagnostics, typeDiagnostics...)
+		}
+		if len(nameDiagnostics) == 0 && len(typeDiagnostics) == 0 {
+			doc.diagnostics = append(doc.diagnostics, flow.Check(program)...)
+		}
+		doc.resolve()
+	}
+	slices.SortStableFunc(doc.diagnostics, func(a *diag.Diagnostic, b *diag.Diagnostic) int {
+		return a.Line - b.Line
+	})
+	return doc
+}
+
+// Finds the name tokens of the declarations and of the names which resolve to them
+func (doc *document) resolve() {
+	symbols := make(map[ast.Decl]*symtab.Symbol)
+	for node, symbol := range symtab.Resolve(doc.program) {
+		symbols[symbol.Decl] = symbol
+		if i := doc.nameToken(node.Pos(), symbol.Name); i != -1 {
+			doc.names[i] = symbol
+		}
+	}
+	declare := func(decl ast.Decl, symbol *symtab.Symbol) {
+		if existing, ok := symbols[decl]; ok {
+			symbol = existing
+		}
+		if i := doc.nameToken(decl.Pos(), symbol.Name); i != -1 {
+			doc.names[i] = symbol
+		}
+	}
+	ast.Inspect(doc.program, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.Function:
+			declare(n, &symtab.Symbol{Name: n.Name, Kind: symtab.Function, Type: n.ReturnType, Decl: n})
+		case *ast.Param:
+			declare(n, &symtab.Symbol{Name: n.Name, Kind: symtab.Param, Type: n.Type, Decl: n})
+		case *ast.VarDecl:
+			declare(n, &symtab.Symbol{Name: n.Name, Kind: symtab.Variable, Type: n.Type, Decl: n})
+		}
+		return true
+	})
+}
+
+// Index of the first name token with the name at or after the position, the position of a node is the one of its
+// first token: the type of a declaration, the receiver of a call. -1 if there is none, e.g. in interpolated strings
+func (doc *document) nameToken(pos ast.Position, name string) int {
+	if pos.Col == 0 {
+		return -1
+	}
+	for i, token := range doc.tokens {
+		if token.Line < pos.Line || token.Line == pos.Line && token.Col < pos.Col {
+			continue
+		}
+		if token.Identifier == "name" && token.Value == name {
+			return i
+		}
+		if token.Identifier == "{" || token.Identifier == ";" {
+			break
+		}
+	}
+	return -1
+}
+
+// Index of the token at the position of the editor, -1 if there is none. The end of a token counts, the cursor is
+// often right after a name
+func (doc *document) tokenAt(at position) int {
+	if at.Line >= len(doc.lines) {
+		return -1
+	}
+	line, col := at.Line+1, column(doc.lines[at.Line], at.Character)
+	for i, token := range doc.tokens {
+		if token.Line == line && token.Col <= col && col <= token.Col+token.Length {
+			// A name right after another token, e.g. (a
+			if col == token.Col+token.Length && i+1 < len(doc.tokens) && doc.tokens[i+1].Line == line && doc.tokens[i+1].Col == col {
+				continue
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// The range of the token in the editor
+func (doc *document) tokenSpan(token lexer.Token) span {
+	return doc.span(token.Line, token.Col, token.Col+token.Length)
+}
+
+// The range of the columns from up to end of the line, lines and columns start at 1
+func (doc *document) span(line int, from int, end int) span {
+	text := ""
+	if line >= 1 && line <= len(doc.lines) {
+		text = doc.lines[line-1]
+	}
+	return span{Start: position{Line: line - 1, Character: character(text, from)}, End: position{Line: line - 1, Character: character(text, end)}}
+}
+
+// The range of the diagnostic: the token at its position, the line without indentation if it has no column
+func (doc *document) diagnosticSpan(pos diag.Position) span {
+	if pos.Line < 1 || pos.Line > len(doc.lines) {
+		return doc.span(max(pos.Line, 1), 1, 1)
+	}
+	if pos.Col == 0 {
+		text := doc.lines[pos.Line-1]
+		indent := len([]rune(text)) - len([]rune(strings.TrimLeft(text, " \t")))
+		return doc.span(pos.Line, indent+1, len([]rune(text))+1)
+	}
+	for _, token := range doc.tokens {
+		if token.Line == pos.Line && token.Col == pos.Col {
+			return doc.tokenSpan(token)
+		}
+	}
+	return doc.span(pos.Line, pos.Col, pos.Col+1)
+}
+
+func (doc *document) lspDiagnostics() []lspDiagnostic {
+	severities := map[diag.Severity]int{diag.Error: 1, diag.Warning: 2, diag.Note: 3}
+	result := []lspDiagnostic{}
+	for _, diagnostic := range doc.diagnostics {
+		converted := lspDiagnostic{Range: doc.diagnosticSpan(diagnostic.Position), Severity: severities[diagnostic.Severity],
+			Code: string(diagnostic.Code), Source: "neon", Message: diagnostic.Message}
+		for _, related := range diagnostic.Related {
+			converted.RelatedInformation = append(converted.RelatedInformation,
+				relatedInformation{Location: location{URI: doc.uri, Range: doc.diagnosticSpan(related.Position)}, Message: related.Message})
+		}
+		result = append(result, converted)
+	}
+	return result
+}
+
+// The suggestions of the diagnostics whose range overlaps the range of the editor, as quick fixes
+func (doc *document) codeActions(at span) []codeAction {
+	actions := []codeAction{}
+	// One lspDiagnostic for every diagnostic, in the same order
+	converted := doc.lspDiagnostics()
+	for i, diagnostic := range doc.diagnostics {
+		if !overlaps(converted[i].Range, at) {
+			continue
+		}
+		for _, suggestion := range diagnostic.Suggestions {
+			edit, ok := doc.textEdit(suggestion.Edit)
+			if !ok {
+				continue
+			}
+			actions = append(actions, codeAction{Title: suggestion.Message + " (" + suggestion.Edit.String() + ")", Kind: "quickfix",
+				Diagnostics: []lspDiagnostic{converted[i]}, Edit: workspaceEdit{Changes: map[string][]textEdit{doc.uri: {edit}}}})
+		}
+	}
+	return actions
+}
+
+// The change of the text an edit of a suggestion makes. false if the text the edit refers to is not on its line
+func (doc *document) textEdit(edit diag.Edit) (textEdit, bool) {
+	if edit.Line < 1 || edit.Line > len(doc.lines) {
+		return textEdit{}, false
+	}
+	line := []rune(doc.lines[edit.Line-1])
+	if edit.Before == "" {
+		return textEdit{Range: doc.span(edit.Line, len(line)+1, len(line)+1), NewText: edit.Insert}, true
+	}
+	// The first Before at or after the column
+	from := max(edit.Col, 1)
+	if from > len(line) {
+		return textEdit{}, false
+	}
+	rest := string(line[from-1:])
+	index := strings.Index(rest, edit.Before)
+	if index == -1 {
+		return textEdit{}, false
+	}
+	col := from + len([]rune(rest[:index]))
+	end := col
+	if edit.Replace {
+		end += len([]rune(edit.Before))
+	}
+	return textEdit{Range: doc.span(edit.Line, col, end), NewText: edit.Insert}, true
+}
+
+func overlaps(a span, b span) bool {
+	return !before(a.End, b.Start) && !before(b.End, a.Start)
+}
+
+func before(a position, b position) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Character < b.Character
+}
+
+// The declaration of the name at the position
+func (doc *document) definition(at position) *location {
+	symbol := doc.names[doc.tokenAt(at)]
+	if symbol == nil {
+		return nil
+	}
+	i := doc.nameToken(symbol.Pos(), symbol.Name)
+	if i == -1 {
+		return nil
+	}
+	return &location{URI: doc.uri, Range: doc.tokenSpan(doc.tokens[i])}
+}
+
+// The kind, type and name of the name at the position, like a declaration:
+//
+//	(parameter) int n
+//	(function) static int fib(int n)
+func (doc *document) hover(at position) *hover {
+	i := doc.tokenAt(at)
+	symbol := doc.names[i]
+	if symbol == nil {
+		return nil
+	}
+	text := ""
+	switch decl := symbol.Decl.(type) {
+	case *ast.Function:
+		params := []string{}
+		for _, param := range decl.Params {
+			params = append(params, doc.typeName(param, param.Type)+" "+param.Name)
+		}
+		modifiers := "static "
+		if decl.Extern {
+			modifiers += "extern "
+		}
+		text = modifiers + decl.ReturnType + " " + decl.Name + "(" + strings.Join(params, ", ") + ")"
+	default:
+		text = doc.typeName(decl, symbol.Type) + " " + symbol.Name
+	}
+	return &hover{Contents: markupContent{Kind: "markdown", Value: "```csharp\n(" + string(symbol.Kind) + ") " + text + "\n```"}, Range: doc.tokenSpan(doc.tokens[i])}
+}
+
+// The type of the declaration from the type checker, the type name of the source if it has none
+func (doc *document) typeName(decl ast.Decl, name string) string {
+	if doc.info != nil {
+		if t, ok := doc.info.Decls[decl]; ok && t != types.Invalid {
+			return types.Resolve(t).String()
+		}
+	}
+	return name
+}
+
+func (doc *document) semanticTokens() semanticTokens {
+	return semanticTokens{Data: highlight.Encode(highlight.Classify(doc.text, doc.program))}
+}
+
+// The column of the UTF-16 offset in the line, columns count characters and start at 1
+func column(line string, character int) int {
+	col, units := 1, 0
+	for _, r := range line {
+		if units >= character {
+			break
+		}
+		units += utf16.RuneLen(r)
+		col++
+	}
+	return col
+}
+
+// The UTF-16 offset of the column in the line
+func character(line string, col int) int {
+	units := 0
+	for i, r := range []rune(line) {
+		if i >= col-1 {
+			return units
+		}
+		units += utf16.RuneLen(r)
+	}
+	// Columns after the end of the line, like the end of the last token
+	return units + max(col-1-len([]rune(line)), 0)
+}
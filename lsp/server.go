@@ -0,0 +1,194 @@
+package lsp
+
+import (
+	"bufio"
+	"compiler/highlight"
+	"compiler/parser"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+/*
+Language server for the C# subset, speaking the Language Server Protocol over a pair of streams (standard input and
+output in cmd/langserver). Every open file goes through the front end of the compiler after each change:
+
+	textDocument/publishDiagnostics  the diagnostics of the lexer, the tolerant parser and the name, type and flow checks
+	textDocument/hover               the kind and type of the name under the cursor, e.g. (parameter) int n
+	textDocument/definition          the declaration of the name under the cursor, from the symbol table
+	textDocument/semanticTokens/full the highlighting of the highlight package
+	textDocument/codeAction          the suggestions of the diagnostics as quick fixes, e.g. a missing ; or a misspelled name
+
+Documents are synced in full, every change sends the whole text. Files are checked on their own, usings are not followed.
+*/
+
+type Server struct {
+	in  *bufio.Reader
+	out io.Writer
+	// The parsing table of the language, built once
+	table *parser.SLR_parsing_Table
+	// The open documents by URI
+	documents   map[string]*document
+	initialized bool
+	shutdown    bool
+}
+
+func MakeServer(in io.Reader, out io.Writer) *Server {
+	server := new(Server)
+	server.in = bufio.NewReader(in)
+	server.out = out
+	server.table = parser.Language(true).CreateSLRParser()
+	server.documents = make(map[string]*document)
+	return server
+}
+
+// Handles messages until the exit notification. The error is nil if the client asked for a shutdown before
+func (server *Server) Run() error {
+	for {
+		body, err := readMessage(server.in)
+		if err == io.EOF {
+			return errors.New("LSP Error: the input ended without exit")
+		}
+		if err != nil {
+			return err
+		}
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			if err := writeMessage(server.out, errorResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: responseError{Code: parseError, Message: err.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+		if msg.Method == "exit" {
+			if !server.shutdown {
+				return errors.New("LSP Error: exit without shutdown")
+			}
+			return nil
+		}
+		if err := server.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// Answers requests and handles notifications, the error is one of writing to the client
+func (server *Server) handle(msg message) error {
+	isRequest := len(msg.ID) > 0
+	if !server.initialized && msg.Method != "initialize" {
+		if isRequest {
+			return server.fail(msg.ID, serverNotInitialized, "The server is not initialized")
+		}
+		return nil
+	}
+	if server.shutdown && isRequest {
+		return server.fail(msg.ID, invalidRequest, "The server is shut down")
+	}
+
+	switch msg.Method {
+	case "initialize":
+		server.initialized = true
+		return server.reply(msg.ID, server.capabilities())
+	case "shutdown":
+		server.shutdown = true
+		return server.reply(msg.ID, nil)
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if json.Unmarshal(msg.Params, &params) != nil {
+			return nil
+		}
+		return server.update(params.TextDocument.URI, params.TextDocument.Version, params.TextDocument.Text)
+	case "textDocument/didChange":
+		var params didChangeParams
+		if json.Unmarshal(msg.Params, &params) != nil || len(params.ContentChanges) == 0 {
+			return nil
+		}
+		return server.update(params.TextDocument.URI, params.TextDocument.Version, params.ContentChanges[len(params.ContentChanges)-1].Text)
+	case "textDocument/didClose":
+		var params didCloseParams
+		if json.Unmarshal(msg.Params, &params) != nil {
+			return nil
+		}
+		delete(server.documents, params.TextDocument.URI)
+		// The editor keeps the diagnostics of closed files otherwise
+		return server.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: params.TextDocument.URI, Diagnostics: []lspDiagnostic{}})
+	case "textDocument/hover", "textDocument/definition":
+		var params textDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return server.fail(msg.ID, invalidParams, err.Error())
+		}
+		doc, ok := server.documents[params.TextDocument.URI]
+		switch {
+		case !ok:
+			return server.reply(msg.ID, nil)
+		case msg.Method == "textDocument/hover":
+			// nil pointers are sent as null
+			return server.reply(msg.ID, doc.hover(params.Position))
+		}
+		return server.reply(msg.ID, doc.definition(params.Position))
+	case "textDocument/semanticTokens/full":
+		var params semanticTokensParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return server.fail(msg.ID, invalidParams, err.Error())
+		}
+		doc, ok := server.documents[params.TextDocument.URI]
+		if !ok {
+			return server.reply(msg.ID, nil)
+		}
+		return server.reply(msg.ID, doc.semanticTokens())
+	case "textDocument/codeAction":
+		var params codeActionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return server.fail(msg.ID, invalidParams, err.Error())
+		}
+		doc, ok := server.documents[params.TextDocument.URI]
+		if !ok {
+			return server.reply(msg.ID, []codeAction{})
+		}
+		return server.reply(msg.ID, doc.codeActions(params.Range))
+	}
+	if isRequest {
+		return server.fail(msg.ID, methodNotFound, "Unknown method "+msg.Method)
+	}
+	// Other notifications like initialized or $/cancelRequest need nothing
+	return nil
+}
+
+func (server *Server) capabilities() map[string]any {
+	legend := []string{}
+	for _, kind := range highlight.Legend() {
+		legend = append(legend, string(kind))
+	}
+	return map[string]any{
+		"capabilities": map[string]any{
+			// Full sync
+			"textDocumentSync":   map[string]any{"openClose": true, "change": 1},
+			"hoverProvider":      true,
+			"definitionProvider": true,
+			"codeActionProvider": true,
+			"semanticTokensProvider": map[string]any{
+				"legend": map[string]any{"tokenTypes": legend, "tokenModifiers": []string{}},
+				"full":   true,
+			},
+		},
+		"serverInfo": map[string]any{"name": "neon-langserver"},
+	}
+}
+
+// Checks the new text of the document and sends its diagnostics
+func (server *Server) update(uri string, version int, text string) error {
+	doc := analyze(server.table, uri, version, text)
+	server.documents[uri] = doc
+	return server.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: uri, Version: version, Diagnostics: doc.lspDiagnostics()})
+}
+
+func (server *Server) reply(id json.RawMessage, result any) error {
+	return writeMessage(server.out, response{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+func (server *Server) fail(id json.RawMessage, code int, text string) error {
+	return writeMessage(server.out, errorResponse{JSONRPC: "2.0", ID: id, Error: responseError{Code: code, Message: text}})
+}
+
+func (server *Server) notify(method string, params any) error {
+	return writeMessage(server.out, notification{JSONRPC: "2.0", Method: method, Params: params})
+}
@@ -0,0 +1,117 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+const uri = "file:///program.cs"
+
+const source = `using System;
+
+namespace N {
+    class Program {
+        static void Main(string[] args) {
+            int count = 2;
+            Console.WriteLine(cout)
+        }
+    }
+}
+`
+
+// Runs the server on the requests and returns the responses by their id
+func serve(t *testing.T, requests ...any) map[string]json.RawMessage {
+	t.Helper()
+	var in bytes.Buffer
+	for _, request := range requests {
+		if err := writeMessage(&in, request); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var out bytes.Buffer
+	if err := MakeServer(&in, &out).Run(); err != nil {
+		t.Fatal(err)
+	}
+	results := map[string]json.RawMessage{}
+	reader := bufio.NewReader(&out)
+	for {
+		body, err := readMessage(reader)
+		if err == io.EOF {
+			return results
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result struct {
+			ID     json.RawMessage `json:"id"`
+			Result json.RawMessage `json:"result"`
+		}
+		if err := json.Unmarshal(body, &result); err != nil {
+			t.Fatal(err)
+		}
+		results[string(result.ID)] = result.Result
+	}
+}
+
+func request(id int, method string, params any) map[string]any {
+	return map[string]any{"jsonrpc": "2.0", "id": id, "method": method, "params": params}
+}
+
+func notify(method string, params any) map[string]any {
+	return map[string]any{"jsonrpc": "2.0", "method": method, "params": params}
+}
+
+// The quick fixes of the lines, the first line is 0
+func codeActions(t *testing.T, from int, to int) []codeAction {
+	t.Helper()
+	results := serve(t,
+		request(1, "initialize", map[string]any{}),
+		notify("textDocument/didOpen", map[string]any{"textDocument": map[string]any{"uri": uri, "version": 1, "text": source}}),
+		request(2, "textDocument/codeAction", map[string]any{"textDocument": map[string]any{"uri": uri},
+			"range": span{Start: position{Line: from}, End: position{Line: to, Character: 100}}}),
+		request(3, "shutdown", nil),
+		notify("exit", nil),
+	)
+	var actions []codeAction
+	if err := json.Unmarshal(results["2"], &actions); err != nil {
+		t.Fatal(err)
+	}
+	return actions
+}
+
+func TestCodeActionRenamesToClosestName(t *testing.T) {
+	actions := codeActions(t, 6, 6)
+	for _, action := range actions {
+		edit := action.Edit.Changes[uri]
+		if strings.Contains(action.Title, "count") && len(edit) == 1 {
+			want := span{Start: position{Line: 6, Character: 30}, End: position{Line: 6, Character: 34}}
+			if edit[0].NewText != "count" || edit[0].Range != want || action.Kind != "quickfix" {
+				t.Errorf("rename edit %+v, want count at %+v", edit[0], want)
+			}
+			return
+		}
+	}
+	t.Errorf("no rename to count in %+v", actions)
+}
+
+func TestCodeActionInsertsSemicolon(t *testing.T) {
+	found := false
+	for _, action := range codeActions(t, 0, 9) {
+		for _, edit := range action.Edit.Changes[uri] {
+			found = found || edit.NewText == ";" && edit.Range.Start == edit.Range.End
+		}
+	}
+	if !found {
+		t.Error("no insertion of the missing semicolon")
+	}
+}
+
+func TestCodeActionOutsideOfDiagnostics(t *testing.T) {
+	if actions := codeActions(t, 0, 2); len(actions) != 0 {
+		t.Errorf("got %+v", actions)
+	}
+}
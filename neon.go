@@ -1,66 +1,575 @@
 package main
 
 import (
+	"bytes"
+	"compiler/amd64"
+	"compiler/ast"
+	"compiler/budget"
+	"compiler/callgraph"
+	"compiler/compdb"
+	"compiler/diag"
+	"compiler/flow"
+	"compiler/highlight"
+	"compiler/interp"
+	"compiler/lexer"
+	"compiler/llvm"
+	"compiler/opt"
 	"compiler/parser"
+	"compiler/playground"
+	"compiler/regalloc"
+	"compiler/symtab"
+	"compiler/types"
+	"compiler/vm"
+	"compiler/wat"
+	"context"
 	"flag"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
 )
 
+// How -run executes the program
+type backend struct {
+	vm     bool
+	disasm bool
+	// Path to write the bytecode to, "" for none
+	save string
+}
+
+// What checkNames does after the checks
+type analyses struct {
+	prune    bool
+	pipeline opt.Pipeline
+	stack    bool
+	liveness bool
+	// Number of registers for the register allocation, 0 for none
+	registers int
+}
+
 func main() {
-	fmt.Println()
 	if len(os.Args) < 2 {
-		fmt.Println("Please provide a code path and a flag")
 		fmt.Println()
-		return
-	}
-	if len(os.Args) > 3 {
-		fmt.Println("To many arguments")
+		fmt.Println("Please provide a code path and a flag")
 		fmt.Println()
 		return
 	}
 
 	compile := flag.Bool("compile", false, "Compile the code")
-	liveness := flag.Bool("liveness", false, "Start liveness analysis")
-	constants := flag.Bool("constants", false, "Start constant propagation analysis")
+	run := flag.Bool("run", false, "Run the program with the interpreter. Arguments after the path are passed to Main")
+	asmFlag := flag.Bool("asm", false, "Print the program as x86-64 assembly (AT&T syntax), build it with gcc program.s -lm")
+	outFlag := flag.String("o", "", "Write the code of -asm, -llvm or -wat to this file instead of the standard output")
+	llvmFlag := flag.Bool("llvm", false, "Print the program as LLVM IR (.ll), build it with clang program.ll -lm")
+	watFlag := flag.Bool("wat", false, "Print the program as WebAssembly text format (WAT)")
+	useVM := flag.Bool("vm", false, "Run the program with the bytecode VM instead of the interpreter, with -run")
+	disasm := flag.Bool("disasm", false, "Print the bytecode of the program before running it with the VM, with -run")
+	save := flag.String("save", "", "Write the bytecode of the program to the file, with -run. Run it later with -run file.nbc")
+	liveness := flag.Bool("liveness", false, "Show which variables are live after every statement")
+	registers := flag.Int("registers", 0, "Allocate the variables of every function to this many registers and show which ones are spilled")
+	constants := flag.Bool("constants", false, "Fold constant expressions and propagate constants, reports how much smaller the functions get")
+	dce := flag.Bool("dce", false, "Remove unreachable code and variables which are never read, reports how much smaller the functions get")
+	normalize := flag.Bool("normalize", false, "NFC-normalize the source before lexing")
+	jobs := flag.Int("j", runtime.NumCPU(), "Number of files compiled at the same time")
+	prune := flag.Bool("prune", false, "Remove functions which are never called from Main and report them")
+	stack := flag.Bool("stack", false, "Estimate the worst case stack usage and warn about recursion which might not end")
+	timeout := flag.Duration("timeout", 0, "Time limit for the whole compilation, e.g. 5s. 0 means no limit")
+	budgetFlag := flag.String("budget", "", "Time limits of the phases, e.g. parse=2s,check=500ms. Phases are parse, check and optimize")
+	serve := flag.String("serve", "", "Start the grammar playground server on the address, e.g. -serve :8080")
+	compdbFlag := flag.Bool("compdb", false, "Print the compile commands of the files as compile_commands.json")
+	deps := flag.Bool("deps", false, "Print which files depend on which other files through their usings, as JSON")
+	highlightFlag := flag.Bool("highlight", false, "Print the source as a highlighted HTML page")
+	maxErrors := flag.Int("max-errors", 0, "Stop printing the diagnostics of a file after this many errors. 0 means no limit")
+	explain := flag.String("explain", "", "Explain an error code, e.g. -explain E0101. Use -explain list for all codes")
 
 	flag.Parse()
 
-	if !*compile && !*liveness && !*constants {
-		fmt.Println("Please specify what the program should do. Use -help if needed")
+	var generator func(*ast.Program) (string, error)
+	if *asmFlag {
+		generator = amd64.Generate
+	} else if *llvmFlag {
+		generator = llvm.Generate
+	} else if *watFlag {
+		generator = wat.Generate
+	}
+	// The generated code is the only output then, so it can be redirected into a file
+	if generator == nil {
+		fmt.Println()
+	}
+
+	if *explain != "" {
+		explainCode(*explain)
 		fmt.Println()
 		return
 	}
 
-	var path string
+	if *compdbFlag || *deps {
+		if flag.NArg() == 0 {
+			fmt.Println("No path provided")
+			fmt.Println()
+			return
+		}
+		if *compdbFlag {
+			directory, _ := os.Getwd()
+			flags := []string{"-compile"}
+			if *normalize {
+				flags = append(flags, "-normalize")
+			}
+			fmt.Print(compdb.CommandsJSON(compdb.Commands(directory, os.Args[0], flags, flag.Args())))
+		}
+		if *deps {
+			fmt.Print(compdb.Analyze(flag.Args(), lexer.Options{Normalize: *normalize}, *jobs).JSON())
+		}
+		return
+	}
 
-	if *compile {
-		if len(os.Args) != 3 {
+	if *highlightFlag {
+		if flag.NArg() == 0 {
 			fmt.Println("No path provided")
 			fmt.Println()
 			return
 		}
-		path = os.Args[2]
-		// Send code to tokenizer
-		_, parsingSuccesful := parser.Parse(path, true)
+		if err := highlightFile(flag.Arg(0)); err != nil {
+			fmt.Println(err)
+		}
+		return
+	}
+
+	if *serve != "" {
+		fmt.Println("Grammar playground listening on " + *serve)
+		fmt.Println(http.ListenAndServe(*serve, playground.Handler()))
+		return
+	}
+
+	budgets, err := budget.ParseFlag(*budgetFlag)
+	if err != nil {
+		fmt.Println(err)
 		fmt.Println()
+		return
+	}
+	// The order matters, constant propagation turns conditions and variables into literals the other passes can remove
+	pipeline := opt.Pipeline{}
+	if *constants {
+		pipeline = pipeline.Add(opt.Constants{})
+	}
+	if *dce {
+		pipeline = pipeline.Add(opt.Unreachable{}).Add(opt.UnusedCode{})
+	}
+
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
 
-		if !parsingSuccesful{
+	if *run {
+		if flag.NArg() == 0 {
+			fmt.Println("No path provided")
+			fmt.Println()
 			return
 		}
+		runProgram(ctx, budgets, flag.Arg(0), flag.Args()[1:], lexer.Options{Normalize: *normalize, Printer: diag.MakePrinter(*maxErrors)}, *prune, pipeline, backend{vm: *useVM || *disasm || *save != "", disasm: *disasm, save: *save})
+		return
+	}
 
-		
+	if generator != nil {
+		if flag.NArg() == 0 {
+			fmt.Fprintln(os.Stderr, "No path provided")
+			os.Exit(2)
+		}
+		options := lexer.Options{Normalize: *normalize, Printer: diag.MakePrinter(*maxErrors)}
+		if !generateFile(ctx, budgets, flag.Arg(0), options, *prune, pipeline, generator, *outFlag) {
+			os.Exit(1)
+		}
+		return
 	}
 
-	if *liveness {
-		fmt.Println("Not implemented")
+	after := analyses{prune: *prune, pipeline: pipeline, stack: *stack, liveness: *liveness, registers: *registers}
+	if !*compile && !*prune && !*stack && !*liveness && *registers == 0 && pipeline.Empty() {
+		fmt.Println("Please specify what the program should do. Use -help if needed")
 		fmt.Println()
 		return
 	}
 
-	if *constants {
-		fmt.Println("Not implemented")
+	if *compile || *prune || *stack || *liveness || *registers > 0 || !pipeline.Empty() {
+		if flag.NArg() == 0 {
+			fmt.Println("No path provided")
+			fmt.Println()
+			return
+		}
+		paths := flag.Args()
+		options := lexer.Options{Normalize: *normalize, Printer: diag.MakePrinter(*maxErrors)}
+		parsingSuccesful := true
+		// Send code to tokenizer
+		parseCtx, cancel := budgets.Start(ctx, budget.Parsing)
+		if len(paths) == 1 {
+			tree, ok, err := parser.ParseContext(parseCtx, paths[0], true, options)
+			cancel()
+			if err != nil {
+				fmt.Println(budgets.Error(budget.Parsing, err))
+			}
+			parsingSuccesful = ok && checkNames(ctx, budgets, tree, paths[0], options.Printer, after)
+			fmt.Println()
+		} else {
+			results := parser.ParseFilesContext(parseCtx, paths, true, options, *jobs)
+			cancel()
+			for _, result := range results {
+				fmt.Println(result.Path + ":")
+				fmt.Print(result.Output)
+				if result.Err != nil {
+					fmt.Println(budgets.Error(budget.Parsing, result.Err))
+				}
+				ok := result.Ok && checkNames(ctx, budgets, result.Tree, result.Path, result.Printer, after)
+				fmt.Println()
+				parsingSuccesful = parsingSuccesful && ok
+			}
+		}
+
+		if !parsingSuccesful {
+			return
+		}
+	}
+}
+
+// Writes the code the generator makes of the program to the file, or to the standard output for "".
+// Everything else, the output of the parser and the diagnostics, goes to the standard error
+func generateFile(ctx context.Context, budgets budget.Budget, path string, options lexer.Options, prune bool, pipeline opt.Pipeline, generator func(*ast.Program) (string, error), file string) bool {
+	var out bytes.Buffer
+	if !generateCode(ctx, budgets, path, options, prune, pipeline, generator, &out, os.Stderr) {
+		return false
+	}
+	if file == "" {
+		os.Stdout.Write(out.Bytes())
+		return true
+	}
+	if err := os.WriteFile(file, out.Bytes(), 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return false
+	}
+	return true
+}
+
+// Parses and checks the file and writes the generated code to out. Warnings are written to errOut as well, errors stop before the code is generated
+func generateCode(ctx context.Context, budgets budget.Budget, path string, options lexer.Options, prune bool, pipeline opt.Pipeline, generator func(*ast.Program) (string, error), out io.Writer, errOut io.Writer) bool {
+	var parserOutput strings.Builder
+	options.Output = &parserOutput
+	parseCtx, cancel := budgets.Start(ctx, budget.Parsing)
+	tree, ok, err := parser.ParseContext(parseCtx, path, true, options)
+	cancel()
+	if err != nil {
+		fmt.Fprintln(errOut, budgets.Error(budget.Parsing, err))
+		return false
+	}
+	if !ok {
+		fmt.Fprint(errOut, parserOutput.String())
+		return false
+	}
+	program, err := ast.Build(tree)
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return false
+	}
+	checkCtx, cancel := budgets.Start(ctx, budget.Checking)
+	diagnostics, err := symtab.BindContext(checkCtx, program)
+	if err == nil && len(diagnostics) == 0 {
+		_, diagnostics, err = types.CheckContext(checkCtx, program)
+	}
+	if err == nil && len(diagnostics) == 0 {
+		diagnostics = flow.Check(program)
+	}
+	cancel()
+	if err != nil {
+		fmt.Fprintln(errOut, budgets.Error(budget.Checking, err))
+		return false
+	}
+	for _, diagnostic := range diagnostics {
+		diagnostic.File = path
+		options.Printer.Print(errOut, diagnostic)
+	}
+	if diag.HasErrors(diagnostics) {
+		return false
+	}
+	if prune {
+		callgraph.RemoveDead(program)
+	}
+	for _, function := range program.Functions {
+		pipeline.Run(function)
+	}
+	text, err := generator(program)
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return false
+	}
+	io.WriteString(out, text)
+	return true
+}
+
+// The budgets only limit the compilation, the program itself runs as long as it needs
+func runProgram(ctx context.Context, budgets budget.Budget, path string, args []string, options lexer.Options, prune bool, pipeline opt.Pipeline, execution backend) {
+	if strings.HasSuffix(path, ".nbc") {
+		if err := runSaved(path, args, execution.disasm); err != nil {
+			fmt.Println(err)
+		}
+		fmt.Println()
+		return
+	}
+	// Only show the parser output if something went wrong
+	var parserOutput strings.Builder
+	options.Output = &parserOutput
+	parseCtx, cancel := budgets.Start(ctx, budget.Parsing)
+	tree, parsingSuccesful, err := parser.ParseContext(parseCtx, path, true, options)
+	cancel()
+	if err != nil {
+		fmt.Println(budgets.Error(budget.Parsing, err))
 		fmt.Println()
 		return
 	}
+	if !parsingSuccesful {
+		fmt.Print(parserOutput.String())
+		fmt.Println()
+		return
+	}
+	program, err := ast.Build(tree)
+	if err != nil {
+		fmt.Println(err)
+		fmt.Println()
+		return
+	}
+	checkCtx, cancel := budgets.Start(ctx, budget.Checking)
+	diagnostics, err := symtab.BindContext(checkCtx, program)
+	if err == nil && len(diagnostics) == 0 {
+		_, diagnostics, err = types.CheckContext(checkCtx, program)
+	}
+	if err == nil && len(diagnostics) == 0 {
+		diagnostics = flow.Check(program)
+	}
+	cancel()
+	if err != nil {
+		fmt.Println(budgets.Error(budget.Checking, err))
+		fmt.Println()
+		return
+	}
+	printDiagnostics(options.Printer, path, diagnostics)
+	if diag.HasErrors(diagnostics) {
+		fmt.Println()
+		return
+	}
+	if prune {
+		callgraph.RemoveDead(program)
+	}
+	for _, function := range program.Functions {
+		pipeline.Run(function)
+	}
+	if execution.vm {
+		err = runBytecode(program, args, execution)
+	} else {
+		err = interp.New(program, os.Stdout).Run(args)
+	}
+	if err != nil {
+		fmt.Println(err)
+	}
+	fmt.Println()
+}
+
+func runBytecode(program *ast.Program, args []string, execution backend) error {
+	bytecode, err := vm.Compile(program)
+	if err != nil {
+		return err
+	}
+	if execution.save != "" {
+		data, err := bytecode.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(execution.save, data, 0644); err != nil {
+			return err
+		}
+	}
+	if execution.disasm {
+		fmt.Println(bytecode.Disassemble())
+	}
+	return vm.New(bytecode, os.Stdout).Run(args)
+}
+
+// Runs bytecode written with -save, without the source
+func runSaved(path string, args []string, disasm bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	bytecode, err := vm.Unmarshal(data)
+	if err != nil {
+		return err
+	}
+	if disasm {
+		fmt.Println(bytecode.Disassemble())
+	}
+	return vm.New(bytecode, os.Stdout).Run(args)
+}
+
+// Builds the AST and checks the names and types. Prints the errors and the results of the analyses, in the order of the fields
+func checkNames(ctx context.Context, budgets budget.Budget, tree parser.ParseTree, path string, printer *diag.Printer, after analyses) bool {
+	program, err := ast.Build(tree)
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+	checkCtx, cancel := budgets.Start(ctx, budget.Checking)
+	defer cancel()
+	diagnostics, err := symtab.BindContext(checkCtx, program)
+	if err != nil {
+		fmt.Println(budgets.Error(budget.Checking, err))
+		return false
+	}
+	printDiagnostics(printer, path, diagnostics)
+	if len(diagnostics) > 0 {
+		return false
+	}
+	_, diagnostics, err = types.CheckContext(checkCtx, program)
+	if err != nil {
+		fmt.Println(budgets.Error(budget.Checking, err))
+		return false
+	}
+	printDiagnostics(printer, path, diagnostics)
+	if len(diagnostics) > 0 {
+		return false
+	}
+	// Unreachable code is only a warning
+	diagnostics = flow.Check(program)
+	printDiagnostics(printer, path, diagnostics)
+	if diag.HasErrors(diagnostics) {
+		return false
+	}
+	// Pruning and the stack analysis are quick, the budget is only checked before them and between the optimized functions
+	optimizeCtx, cancelOptimize := budgets.Start(ctx, budget.Optimizing)
+	defer cancelOptimize()
+	if (after.prune || after.stack || !after.pipeline.Empty()) && optimizeCtx.Err() != nil {
+		fmt.Println(budgets.Error(budget.Optimizing, optimizeCtx.Err()))
+		return false
+	}
+	if after.prune {
+		reportDead(callgraph.RemoveDead(program))
+	}
+	if !after.pipeline.Empty() {
+		for _, function := range program.Functions {
+			if optimizeCtx.Err() != nil {
+				fmt.Println(budgets.Error(budget.Optimizing, optimizeCtx.Err()))
+				return false
+			}
+			reportOptimized(function, after.pipeline.Run(function))
+		}
+	}
+	if after.stack {
+		reportStack(callgraph.AnalyzeStack(program), program, path, printer)
+	}
+	for _, function := range program.Functions {
+		if after.liveness {
+			reportLiveness(function, regalloc.Analyze(function))
+		}
+		if after.registers > 0 {
+			reportAllocation(function, regalloc.Allocate(function, after.registers))
+		}
+	}
+	return true
+}
+
+func reportLiveness(function *ast.Function, live *regalloc.Liveness) {
+	fmt.Println("Liveness of " + function.Name + ", live at the start: " + strings.Join(live.LiveIn, ", "))
+	ast.Inspect(function, func(node ast.Node) bool {
+		if statement, ok := node.(ast.Stmt); ok {
+			fmt.Println("  line " + strconv.Itoa(statement.Pos().Line) + ": " + strings.Join(live.LiveOut[statement], ", "))
+		}
+		return true
+	})
+}
+
+func reportAllocation(function *ast.Function, allocation *regalloc.Allocation) {
+	assigned := []string{}
+	for name, register := range allocation.Assigned {
+		assigned = append(assigned, name+" r"+strconv.Itoa(register))
+	}
+	slices.Sort(assigned)
+	fmt.Println("Registers of " + function.Name + ": " + strings.Join(assigned, ", "))
+	if len(allocation.Spilled) > 0 {
+		fmt.Println("  spilled: " + strings.Join(allocation.Spilled, ", ") + " (" + strconv.Itoa(allocation.SpillCost) + " loads and stores)")
+	}
+}
+
+func reportStack(report *callgraph.StackReport, program *ast.Program, path string, printer *diag.Printer) {
+	frames := []string{}
+	for _, function := range program.Functions {
+		frames = append(frames, function.Name+" "+strconv.Itoa(report.Frames[function.Name]))
+	}
+	fmt.Println("Stack frames (JVM slots): " + strings.Join(frames, ", "))
+	if len(report.Path) > 0 {
+		fmt.Println("Worst case stack: " + strconv.Itoa(report.MaxSlots) + " slots (" + strings.Join(report.Path, " -> ") + ")")
+	}
+	for _, cycle := range report.Recursive {
+		fmt.Println("Recursion in " + strings.Join(cycle, ", ") + ": the stack depth depends on the input, the cycle is counted once")
+	}
+	printDiagnostics(printer, path, report.Diagnostics)
+}
+
+// The checks only know the AST, the diagnostics get the path of the file here
+func printDiagnostics(printer *diag.Printer, path string, diagnostics []*diag.Diagnostic) {
+	for _, diagnostic := range diagnostics {
+		if diagnostic.File == "" {
+			diagnostic.File = path
+		}
+		printer.Print(os.Stdout, diagnostic)
+	}
+}
+
+func reportOptimized(function *ast.Function, stats opt.Stats) {
+	changes := []string{}
+	for name, count := range stats.Changes {
+		changes = append(changes, name+" "+strconv.Itoa(count))
+	}
+	slices.Sort(changes)
+	fmt.Println("Optimized " + function.Name + ": " + strconv.Itoa(stats.Before) + " -> " + strconv.Itoa(stats.After) + " AST nodes (" + strings.Join(changes, ", ") + ")")
+}
+
+func reportDead(removed []callgraph.Removed) {
+	nodes := 0
+	for _, r := range removed {
+		fmt.Println("Removed unused function " + r.Function.Name + " (" + strconv.Itoa(r.Nodes) + " AST nodes)")
+		nodes += r.Nodes
+	}
+	fmt.Println("Removed " + strconv.Itoa(len(removed)) + " functions, " + strconv.Itoa(nodes) + " AST nodes in total")
+}
+
+// Names are only resolved if the source compiles, else the page is highlighted from the tokens
+func highlightFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	text := string(content)
+	// Broken code is highlighted too, names in the regions which could not be parsed stay plain
+	tree, _, _ := parser.ParseSourceTolerant(context.Background(), text, true, lexer.Options{Output: io.Discard})
+	program, _ := ast.Build(tree)
+	fmt.Print(highlight.HTML(path, text, highlight.Classify(text, program)))
+	return nil
+}
+
+func explainCode(code string) {
+	if code == "list" {
+		for _, c := range diag.Codes() {
+			explanation, _ := diag.Lookup(c)
+			fmt.Println(string(c) + "  " + explanation.Title)
+		}
+		return
+	}
+	explanation, ok := diag.Lookup(diag.Code(code))
+	if !ok {
+		fmt.Println("Unknown error code " + code + ". Use -explain list for all codes")
+		return
+	}
+	fmt.Println(string(explanation.Code) + ": " + explanation.Title)
+	fmt.Println()
+	fmt.Println(explanation.Text)
 }
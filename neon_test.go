@@ -0,0 +1,357 @@
+package main
+
+import (
+	"compiler/amd64"
+	"compiler/ast"
+	"compiler/budget"
+	"compiler/diag"
+	"compiler/interp"
+	"compiler/lexer"
+	"compiler/llvm"
+	"compiler/opt"
+	"compiler/wat"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Programs the backends have to run like the interpreter does
+var programs = map[string]string{
+	"fibonacci": `using System;
+
+namespace Backend {
+    class Program {
+        static int Fib(int n) {
+            if (n <= 2) {
+                return 1;
+            }
+            return Fib(n - 1) + Fib(n - 2);
+        }
+
+        static void Main(string[] args) {
+            int i = 1;
+            while (i <= 10) {
+                Console.Write(Fib(i));
+                Console.Write(" ");
+                i = i + 1;
+            }
+            Console.WriteLine();
+        }
+    }
+}
+`,
+	"values": `using System;
+
+namespace Backend {
+    class Program {
+        static double Half(int a) {
+            return a / 2.0;
+        }
+
+        static bool Even(int a) {
+            return a % 2 == 0;
+        }
+
+        static void Main(string[] args) {
+            int max = 2147483647;
+            Console.WriteLine(max + 1);
+            Console.WriteLine(Half(7));
+            Console.WriteLine(Half(8));
+            Console.WriteLine(Even(3));
+            Console.WriteLine(Even(4) && true);
+            string name = "neon";
+            Console.WriteLine("Hello " + name + "!");
+            Console.WriteLine($"{name} has {4 * 1} letters");
+            Console.WriteLine(-17 / 5);
+            Console.WriteLine(-17 % 5);
+        }
+    }
+}
+`,
+	"division by zero": `using System;
+
+namespace Backend {
+    class Program {
+        static int Zero() {
+            return 0;
+        }
+
+        static void Main(string[] args) {
+            Console.WriteLine("before");
+            Console.Write("no newline ");
+            Console.WriteLine(5 / Zero());
+            Console.WriteLine("after");
+        }
+    }
+}
+`,
+	"minimum divided by minus one": `using System;
+
+namespace Backend {
+    class Program {
+        static int MinusOne() {
+            return -1;
+        }
+
+        static void Main(string[] args) {
+            int min = -2147483648;
+            Console.WriteLine(min / MinusOne());
+            Console.WriteLine(min % MinusOne());
+            Console.WriteLine(7 / MinusOne());
+            Console.WriteLine(7 % MinusOne());
+            Console.WriteLine(min / 2 % 7);
+            Console.WriteLine(-7 / 2);
+        }
+    }
+}
+`,
+}
+
+// Set for the test binary started by neon, which then runs main instead of the tests
+const mainEnv = "NEON_TEST_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(mainEnv) != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// Runs neon with the arguments and returns everything it printed
+func neon(t *testing.T, args ...string) string {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), mainEnv+"=1")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatal(err.Error() + "\n" + string(out))
+	}
+	return string(out)
+}
+
+// Writes the source to a file in a temporary directory and returns its path
+func write(t *testing.T, source string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "program.cs")
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// The output of the generator for the program, fails with the diagnostics
+func generate(t *testing.T, source string, generator func(*ast.Program) (string, error)) string {
+	t.Helper()
+	path := write(t, source)
+	budgets, _ := budget.ParseFlag("")
+	var out, errOut strings.Builder
+	if !generateCode(context.Background(), budgets, path, lexer.Options{Printer: diag.MakePrinter(0)}, false, opt.Pipeline{}, generator, &out, &errOut) {
+		t.Fatal(errOut.String())
+	}
+	return out.String()
+}
+
+// What neon -run prints for the program, a runtime error included
+func interpret(t *testing.T, source string) string {
+	return generate(t, source, func(program *ast.Program) (string, error) {
+		var out strings.Builder
+		if err := interp.New(program, &out).Run(nil); err != nil {
+			out.WriteString(err.Error() + "\n")
+		}
+		return out.String(), nil
+	})
+}
+
+// Runs the command and returns its standard output, fails with its error output
+func command(t *testing.T, name string, args ...string) string {
+	t.Helper()
+	var stderr strings.Builder
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(name + ": " + err.Error() + "\n" + stderr.String())
+	}
+	return string(out)
+}
+
+// Runs the compiled program and returns its standard output. Programs stop with exit code 1 after a runtime error,
+// everything else, like a crash, fails
+func execute(t *testing.T, name string, args ...string) string {
+	t.Helper()
+	var stderr strings.Builder
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if exit, ok := err.(*exec.ExitError); ok && exit.ExitCode() == 1 && strings.Contains(string(out), "Runtime Error") {
+		err = nil
+	}
+	if err != nil {
+		t.Fatal(name + ": " + err.Error() + "\n" + stderr.String())
+	}
+	return string(out)
+}
+
+func need(t *testing.T, tool string) {
+	if _, err := exec.LookPath(tool); err != nil {
+		t.Skip(tool + " is not installed")
+	}
+}
+
+func TestAsmRunsLikeTheInterpreter(t *testing.T) {
+	need(t, "gcc")
+	for name, source := range programs {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			assembly := filepath.Join(dir, "program.s")
+			binary := filepath.Join(dir, "program")
+			if err := os.WriteFile(assembly, []byte(generate(t, source, amd64.Generate)), 0644); err != nil {
+				t.Fatal(err)
+			}
+			command(t, "gcc", assembly, "-o", binary, "-lm")
+			want := interpret(t, source)
+			if got := execute(t, binary); got != want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+// Only the code is written, nothing of the parser
+func TestAsmOutputIsOnlyCode(t *testing.T) {
+	text := generate(t, programs["fibonacci"], amd64.Generate)
+	if strings.Contains(text, "Code passed parser") || !strings.HasPrefix(strings.TrimSpace(text), ".") {
+		t.Errorf("output does not start with the assembly:\n%.200s", text)
+	}
+}
+
+// The IR has to pass the verifier of llvm-as and run like the interpreter with lli.
+// LLVM 14 only reads opaque pointers with -opaque-pointers, newer versions use them by default
+func TestLLVMRunsLikeTheInterpreter(t *testing.T) {
+	need(t, "llvm-as")
+	need(t, "lli")
+	for name, source := range programs {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			ir := filepath.Join(dir, "program.ll")
+			text := generate(t, source, llvm.Generate)
+			if strings.Contains(text, "Code passed parser") {
+				t.Fatalf("output contains the parser output:\n%.200s", text)
+			}
+			if err := os.WriteFile(ir, []byte(text), 0644); err != nil {
+				t.Fatal(err)
+			}
+			flags := opaquePointers()
+			command(t, "llvm-as", append(flags, ir, "-o", filepath.Join(dir, "program.bc"))...)
+			want := interpret(t, source)
+			if got := execute(t, "lli", append(flags, ir)...); got != want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func opaquePointers() []string {
+	version, _ := exec.Command("lli", "--version").Output()
+	if strings.Contains(string(version), "LLVM version 14.") {
+		return []string{"-opaque-pointers"}
+	}
+	return nil
+}
+
+// The output is one module and nothing else. With wabt installed it also has to assemble and validate
+func TestWatOutputIsOneModule(t *testing.T) {
+	for name, source := range programs {
+		t.Run(name, func(t *testing.T) {
+			text := strings.TrimSpace(generate(t, source, wat.Generate))
+			if !strings.HasPrefix(text, "(module") || !strings.Contains(text, `(export "main"`) {
+				t.Fatalf("output does not start with the module:\n%.200s", text)
+			}
+			if end := moduleEnd(text); end != len(text) {
+				t.Fatalf("text after the end of the module:\n%.200s", text[end:])
+			}
+			if _, err := exec.LookPath("wat2wasm"); err != nil {
+				return
+			}
+			dir := t.TempDir()
+			path := filepath.Join(dir, "program.wat")
+			if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+				t.Fatal(err)
+			}
+			command(t, "wat2wasm", path, "-o", filepath.Join(dir, "program.wasm"))
+		})
+	}
+}
+
+// The index after the parenthesis which closes the first one, outside of strings and comments
+func moduleEnd(text string) int {
+	depth := 0
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] == '"':
+			for i++; i < len(text) && text[i] != '"'; i++ {
+				if text[i] == '\\' {
+					i++
+				}
+			}
+		case strings.HasPrefix(text[i:], ";;"):
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+		case text[i] == '(':
+			depth++
+		case text[i] == ')':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+const unused = `using System;
+
+namespace Unused {
+    class Program {
+        static int Unused(int a) {
+            return a;
+        }
+
+        static void Main(string[] args) {
+            Console.WriteLine(1);
+        }
+    }
+}
+`
+
+func TestPruneAlone(t *testing.T) {
+	out := neon(t, "-prune", write(t, unused))
+	if strings.Contains(out, "Please specify") || !strings.Contains(out, "Removed unused function Unused") {
+		t.Errorf("-prune on its own did not prune:\n%s", out)
+	}
+}
+
+func TestStackAlone(t *testing.T) {
+	out := neon(t, "-stack", write(t, programs["fibonacci"]))
+	if strings.Contains(out, "Please specify") || !strings.Contains(out, "Stack frames (JVM slots): ") || !strings.Contains(out, "Recursion in Fib") {
+		t.Errorf("-stack on its own did not estimate the stack:\n%s", out)
+	}
+}
+
+// div_s and rem_s trap, the programs have to divide through the runtime functions which check the divisor
+func TestWatChecksDivisors(t *testing.T) {
+	for _, name := range []string{"division by zero", "minimum divided by minus one"} {
+		text := generate(t, programs[name], wat.Generate)
+		if strings.Count(text, "i32.div_s") != 1 || strings.Count(text, "i32.rem_s") != 1 {
+			t.Errorf("%s: div_s or rem_s used outside of $divide and $remainder", name)
+		}
+		if !strings.Contains(text, "call $divide") || !strings.Contains(text, `(import "env" "error"`) {
+			t.Errorf("%s: the division does not go through $divide or error is not imported", name)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package opt
+
+import (
+	"compiler/ast"
+	"compiler/eval"
+	"compiler/interp"
+	"maps"
+)
+
+/*
+Constant folding and propagation. Operators on literals are computed by the eval package with the operators of the
+interpreter, so the result is the same as at runtime. Operations which fail (1 / 0) or overflow int stay.
+
+Variables which hold a known value are replaced by the value. The values are followed through the
+statements of the function:
+	if: both branches start with the values before the if, afterwards only values both agree on are kept
+	while: variables assigned in the loop are unknown in the condition, the body and after the loop
+	return: the code after it is never reached, so the values of that branch are dropped
+Calls can not change local variables, there are no globals and no ref parameters.
+*/
+
+type Constants struct{}
+
+// Variable -> value it holds at this point of the function
+type values map[string]interp.Value
+
+type propagator struct {
+	// Variable -> declared type, to convert int values assigned to doubles
+	types   map[string]string
+	changes int
+}
+
+func (Constants) Name() string {
+	return "constants"
+}
+
+func (Constants) Run(function *ast.Function) int {
+	if function.Body == nil {
+		return 0
+	}
+	p := &propagator{types: make(map[string]string)}
+	for _, param := range function.Params {
+		p.types[param.Name] = param.Type
+	}
+	p.block(function.Body, values{})
+	return p.changes
+}
+
+// Returns the values at the end of the block and whether the end is reached
+func (p *propagator) block(block *ast.Block, env values) (values, bool) {
+	for _, statement := range block.Statements {
+		reached := true
+		if ifStatement, ok := statement.(*ast.If); ok {
+			env, reached = p.branches(ifStatement, env)
+		} else {
+			reached = p.statement(statement, env)
+		}
+		if !reached {
+			return env, false
+		}
+	}
+	return env, true
+}
+
+// Changes env for the statement (except if, see branches), returns false after a return
+func (p *propagator) statement(statement ast.Stmt, env values) bool {
+	switch s := statement.(type) {
+	case *ast.VarDecl:
+		p.types[s.Name] = s.Type
+		delete(env, s.Name)
+		if s.Value != nil {
+			s.Value = p.expr(s.Value, env)
+			p.assign(s.Name, s.Value, env)
+		}
+	case *ast.Assign:
+		s.Value = p.expr(s.Value, env)
+		p.assign(s.Name, s.Value, env)
+	case *ast.CallStmt:
+		p.args(s.Call, env)
+	case *ast.Return:
+		if s.Value != nil {
+			s.Value = p.expr(s.Value, env)
+		}
+		return false
+	case *ast.While:
+		for name := range assigned(s.Body) {
+			delete(env, name)
+		}
+		s.Cond = p.expr(s.Cond, env)
+		p.block(s.Body, maps.Clone(env))
+	}
+	return true
+}
+
+func (p *propagator) branches(s *ast.If, env values) (values, bool) {
+	s.Cond = p.expr(s.Cond, env)
+	thenValues, thenReached := p.block(s.Then, maps.Clone(env))
+	elseValues, elseReached := env, true
+	if s.Else != nil {
+		elseValues, elseReached = p.block(s.Else, maps.Clone(env))
+	}
+
+	// With a constant condition only one branch runs
+	if cond, ok := s.Cond.(*ast.BoolLit); ok {
+		if cond.Value {
+			return thenValues, thenReached
+		}
+		return elseValues, elseReached
+	}
+	switch {
+	case !thenReached && !elseReached:
+		return values{}, false
+	case !thenReached:
+		return elseValues, true
+	case !elseReached:
+		return thenValues, true
+	}
+	merged := values{}
+	for name, value := range thenValues {
+		if other, ok := elseValues[name]; ok && other == value {
+			merged[name] = value
+		}
+	}
+	return merged, true
+}
+
+func (p *propagator) assign(name string, value ast.Expr, env values) {
+	if v, ok := constant(value); ok {
+		env[name] = convert(v, p.types[name])
+		return
+	}
+	delete(env, name)
+}
+
+func (p *propagator) args(call *ast.Call, env values) {
+	for i, arg := range call.Args {
+		call.Args[i] = p.expr(arg, env)
+	}
+}
+
+// Returns the expression with known variables replaced and constant operations folded
+func (p *propagator) expr(expr ast.Expr, env values) ast.Expr {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if v, ok := env[e.Name]; ok {
+			p.changes++
+			return literal(v, e.Position)
+		}
+	case *ast.Unary:
+		e.Operand = p.expr(e.Operand, env)
+		return p.fold(e)
+	case *ast.Binary:
+		e.Left = p.expr(e.Left, env)
+		e.Right = p.expr(e.Right, env)
+		if e.Op == "&&" || e.Op == "||" {
+			return p.logical(e)
+		}
+		return p.fold(e)
+	case *ast.Call:
+		p.args(e, env)
+	case *ast.InterpolatedString:
+		return p.interpolated(e, env)
+	}
+	return expr
+}
+
+// The literal of an operation on literals. Operations which fail (1 / 0) or overflow int stay
+func (p *propagator) fold(e ast.Expr) ast.Expr {
+	v, ok, d := eval.Eval(e, nil)
+	if !ok || d != nil {
+		return e
+	}
+	p.changes++
+	return literal(v, e.Pos())
+}
+
+// true && x is x, false && x is false. The right side is not run in the second case anyway
+func (p *propagator) logical(e *ast.Binary) ast.Expr {
+	left, ok := e.Left.(*ast.BoolLit)
+	if !ok {
+		return e
+	}
+	p.changes++
+	if left.Value == (e.Op == "&&") {
+		return e.Right
+	}
+	return literal(left.Value, e.Position)
+}
+
+// Constant parts become text. Without expressions left the string is a plain string literal
+func (p *propagator) interpolated(e *ast.InterpolatedString, env values) ast.Expr {
+	parts := []ast.InterpolationPart{}
+	for _, part := range e.Parts {
+		if part.Expr != nil {
+			part.Expr = p.expr(part.Expr, env)
+			if v, ok := constant(part.Expr); ok {
+				p.changes++
+				part = ast.InterpolationPart{Text: interp.Format(v)}
+			}
+		}
+		// Texts next to each other are joined
+		if part.Expr == nil && len(parts) > 0 && parts[len(parts)-1].Expr == nil {
+			parts[len(parts)-1].Text += part.Text
+			continue
+		}
+		parts = append(parts, part)
+	}
+	e.Parts = parts
+	if len(parts) == 0 {
+		return &ast.StringLit{Position: e.Position}
+	}
+	if len(parts) == 1 && parts[0].Expr == nil {
+		p.changes++
+		return &ast.StringLit{Position: e.Position, Value: parts[0].Text}
+	}
+	return e
+}
+
+// Variables assigned or declared anywhere in the node
+func assigned(node ast.Node) map[string]bool {
+	names := make(map[string]bool)
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.Assign:
+			names[s.Name] = true
+		case *ast.VarDecl:
+			names[s.Name] = true
+		}
+		return true
+	})
+	return names
+}
+
+// Value of a literal, nil for everything else
+func literalValue(expr ast.Expr) interp.Value {
+	switch e := expr.(type) {
+	case *ast.IntLit:
+		return e.Value
+	case *ast.DoubleLit:
+		return e.Value
+	case *ast.BoolLit:
+		return e.Value
+	case *ast.StringLit:
+		return e.Value
+	}
+	return nil
+}
+
+func constant(expr ast.Expr) (interp.Value, bool) {
+	v := literalValue(expr)
+	return v, v != nil
+}
+
+func literal(value interp.Value, position ast.Position) ast.Expr {
+	switch v := value.(type) {
+	case int:
+		return &ast.IntLit{Position: position, Value: v}
+	case float64:
+		return &ast.DoubleLit{Position: position, Value: v}
+	case bool:
+		return &ast.BoolLit{Position: position, Value: v}
+	case string:
+		return &ast.StringLit{Position: position, Value: v}
+	}
+	panic("No literal for value")
+}
+
+// An int stored in a double variable is a double from then on
+func convert(value interp.Value, typ string) interp.Value {
+	if i, ok := value.(int); ok && typ == "double" {
+		return float64(i)
+	}
+	return value
+}
@@ -0,0 +1,223 @@
+package opt
+
+import "compiler/ast"
+
+/*
+Dead code elimination. Unreachable removes statements which never run:
+	statements after return and after while (true), the language has no break
+	if and while with a constant false condition, if (true) is replaced by its then block
+UnusedCode removes statements whose results are never used:
+	variables which are never read, if computing their values has no effect
+	if statements with empty branches
+
+Both work best after Constants, which turns conditions and variables into literals.
+*/
+
+type Unreachable struct{}
+
+type UnusedCode struct{}
+
+type remover struct {
+	// Variable -> number of declarations in the function, parameters included
+	declared map[string]int
+	changes  int
+}
+
+func (Unreachable) Name() string {
+	return "unreachable"
+}
+
+func (Unreachable) Run(function *ast.Function) int {
+	if function.Body == nil {
+		return 0
+	}
+	r := &remover{declared: make(map[string]int)}
+	ast.Inspect(function, func(n ast.Node) bool {
+		switch d := n.(type) {
+		case *ast.Param:
+			r.declared[d.Name]++
+		case *ast.VarDecl:
+			r.declared[d.Name]++
+		}
+		return true
+	})
+	r.block(function.Body)
+	return r.changes
+}
+
+// Removes the unreachable statements of the block and the blocks inside it
+func (r *remover) block(block *ast.Block) {
+	statements := []ast.Stmt{}
+	ended := false
+	for _, statement := range block.Statements {
+		if ended {
+			r.changes++
+			continue
+		}
+		switch s := statement.(type) {
+		case *ast.If:
+			r.block(s.Then)
+			if s.Else != nil {
+				r.block(s.Else)
+			}
+			cond, ok := s.Cond.(*ast.BoolLit)
+			if !ok {
+				break
+			}
+			branch := s.Else
+			if cond.Value {
+				branch = s.Then
+			}
+			if branch == nil {
+				r.changes++
+				continue
+			}
+			if r.inlinable(branch) {
+				r.changes++
+				for _, inlined := range branch.Statements {
+					statements = append(statements, inlined)
+					ended = ended || terminates(inlined)
+				}
+				continue
+			}
+			// The branch has to keep its scope, so it stays an if (true)
+			if !cond.Value || s.Else != nil {
+				r.changes++
+				*s = ast.If{Position: s.Position, Cond: &ast.BoolLit{Position: cond.Position, Value: true}, Then: branch}
+			}
+		case *ast.While:
+			r.block(s.Body)
+			if cond, ok := s.Cond.(*ast.BoolLit); ok && !cond.Value {
+				r.changes++
+				continue
+			}
+		}
+		statements = append(statements, statement)
+		ended = terminates(statement)
+	}
+	block.Statements = statements
+}
+
+// The statements of the branch can move into the enclosing block, if their names are declared nowhere else
+func (r *remover) inlinable(branch *ast.Block) bool {
+	for _, statement := range branch.Statements {
+		if declaration, ok := statement.(*ast.VarDecl); ok && r.declared[declaration.Name] > 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// Whether the code after the statement is never reached
+func terminates(statement ast.Stmt) bool {
+	switch s := statement.(type) {
+	case *ast.Return:
+		return true
+	case *ast.If:
+		if cond, ok := s.Cond.(*ast.BoolLit); ok && cond.Value {
+			return blockTerminates(s.Then)
+		}
+		return s.Else != nil && blockTerminates(s.Then) && blockTerminates(s.Else)
+	case *ast.While:
+		cond, ok := s.Cond.(*ast.BoolLit)
+		return ok && cond.Value
+	}
+	return false
+}
+
+func blockTerminates(block *ast.Block) bool {
+	for _, statement := range block.Statements {
+		if terminates(statement) {
+			return true
+		}
+	}
+	return false
+}
+
+func (UnusedCode) Name() string {
+	return "unused"
+}
+
+func (UnusedCode) Run(function *ast.Function) int {
+	if function.Body == nil {
+		return 0
+	}
+	// Names are counted over the whole function, a name used in one scope keeps the variables of all scopes
+	read := make(map[string]bool)
+	impure := make(map[string]bool)
+	ast.Inspect(function.Body, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.Ident:
+			read[s.Name] = true
+		case *ast.VarDecl:
+			impure[s.Name] = impure[s.Name] || (s.Value != nil && !pure(s.Value))
+		case *ast.Assign:
+			impure[s.Name] = impure[s.Name] || !pure(s.Value)
+		}
+		return true
+	})
+	unused := func(name string) bool {
+		return !read[name] && !impure[name]
+	}
+	return removeUnused(function.Body, unused)
+}
+
+func removeUnused(block *ast.Block, unused func(string) bool) int {
+	changes := 0
+	statements := []ast.Stmt{}
+	for _, statement := range block.Statements {
+		switch s := statement.(type) {
+		case *ast.VarDecl:
+			if unused(s.Name) {
+				changes++
+				continue
+			}
+		case *ast.Assign:
+			if unused(s.Name) {
+				changes++
+				continue
+			}
+		case *ast.If:
+			changes += removeUnused(s.Then, unused)
+			if s.Else != nil {
+				changes += removeUnused(s.Else, unused)
+				if len(s.Else.Statements) == 0 {
+					changes++
+					s.Else = nil
+				}
+			}
+			if len(s.Then.Statements) == 0 && s.Else == nil && pure(s.Cond) {
+				changes++
+				continue
+			}
+		case *ast.While:
+			changes += removeUnused(s.Body, unused)
+		}
+		statements = append(statements, statement)
+	}
+	block.Statements = statements
+	return changes
+}
+
+// Whether computing the expression has no effect: no calls and no integer division which might fail
+func pure(expr ast.Expr) bool {
+	result := true
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch e := n.(type) {
+		case *ast.Call:
+			result = false
+		case *ast.Binary:
+			if e.Op == "/" || e.Op == "%" {
+				switch divisor := e.Right.(type) {
+				case *ast.IntLit:
+					result = result && divisor.Value != 0
+				case *ast.DoubleLit:
+				default:
+					result = false
+				}
+			}
+		}
+		return result
+	})
+	return result
+}
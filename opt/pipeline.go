@@ -0,0 +1,76 @@
+package opt
+
+import "compiler/ast"
+
+/*
+Optimizations on the AST. There is no IR, so a pass changes the AST of a function in place:
+
+	stats := opt.Pipeline{}.Add(opt.Constants{}).Run(function)
+
+The passes run in the order they were added. The whole pipeline is repeated until no pass changes
+anything anymore, because one pass can give the next one new work.
+*/
+
+// A pass changes the function in place and returns how many changes it made
+type Pass interface {
+	Name() string
+	Run(function *ast.Function) int
+}
+
+type Pipeline struct {
+	passes []Pass
+}
+
+// What a pipeline did to one function
+type Stats struct {
+	// Number of AST nodes of the function before and after the pipeline
+	Before int
+	After  int
+	// Pass name -> changes made by the pass
+	Changes map[string]int
+	// How often the pipeline was repeated
+	Rounds int
+}
+
+// A pipeline which stops without reaching a fixed point has a pass which keeps changing the same code
+const maxRounds = 10
+
+// Returns a new pipeline, so pipelines can be built in one expression
+func (pipeline Pipeline) Add(pass Pass) Pipeline {
+	passes := append([]Pass{}, pipeline.passes...)
+	return Pipeline{passes: append(passes, pass)}
+}
+
+func (pipeline Pipeline) Empty() bool {
+	return len(pipeline.passes) == 0
+}
+
+func (pipeline Pipeline) Run(function *ast.Function) Stats {
+	stats := Stats{Before: Count(function), Changes: make(map[string]int)}
+	for stats.Rounds < maxRounds {
+		stats.Rounds++
+		changed := false
+		for _, pass := range pipeline.passes {
+			changes := pass.Run(function)
+			stats.Changes[pass.Name()] += changes
+			changed = changed || changes > 0
+		}
+		if !changed {
+			break
+		}
+	}
+	stats.After = Count(function)
+	return stats
+}
+
+// Number of AST nodes below and including the node
+func Count(node ast.Node) int {
+	count := 0
+	ast.Inspect(node, func(n ast.Node) bool {
+		if n != nil {
+			count++
+		}
+		return true
+	})
+	return count
+}
@@ -0,0 +1,150 @@
+package opt_test
+
+import (
+	"compiler/ast"
+	"compiler/interp"
+	"compiler/lexer"
+	"compiler/opt"
+	"compiler/parser"
+	"compiler/symtab"
+	"compiler/types"
+	"compiler/vm"
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+// Constant conditions, variables which are never read and code after return, next to code which has to stay
+const source = `using System;
+
+namespace Optimize {
+    class Program {
+        static int Area(int width) {
+            int height = 4 * 2 + 1;
+            int unused = height * 3;
+            if (height > 100) {
+                Console.WriteLine("never");
+            }
+            return width * height;
+            Console.WriteLine("after return");
+        }
+
+        static string Name(bool formal) {
+            string first = "Ada";
+            string last = "Lovelace";
+            if (formal) {
+                return $"{last}, {first}";
+            }
+            return first + " " + last;
+        }
+
+        static void Main(string[] args) {
+            int i = 0;
+            double scale = 1.5 * 2;
+            bool debug = 1 > 2;
+            while (i < 3) {
+                Console.WriteLine(Area(i) * scale);
+                if (debug) {
+                    Console.WriteLine("debug");
+                }
+                i = i + 1;
+            }
+            Console.WriteLine(Name(true));
+            Console.WriteLine(Name(false));
+            Console.WriteLine(-(7 % 3) + 10 / 4);
+        }
+    }
+}
+`
+
+var pipeline = opt.Pipeline{}.Add(opt.Constants{}).Add(opt.Unreachable{}).Add(opt.UnusedCode{})
+
+func program(t *testing.T) *ast.Program {
+	t.Helper()
+	tree, ok, err := parser.ParseSource(context.Background(), source, true, lexer.Options{Output: io.Discard})
+	if !ok {
+		t.Fatal("parse failed", err)
+	}
+	program, err := ast.Build(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diagnostics := symtab.Bind(program); len(diagnostics) > 0 {
+		t.Fatal(diagnostics[0])
+	}
+	if _, diagnostics := types.Check(program); len(diagnostics) > 0 {
+		t.Fatal(diagnostics[0])
+	}
+	return program
+}
+
+// The program after the pipeline ran on all functions, and the stats
+func optimized(t *testing.T) (*ast.Program, map[string]opt.Stats) {
+	program := program(t)
+	stats := map[string]opt.Stats{}
+	for _, function := range program.Functions {
+		stats[function.Name] = pipeline.Run(function)
+	}
+	return program, stats
+}
+
+func compile(t *testing.T, program *ast.Program) *vm.Program {
+	t.Helper()
+	bytecode, err := vm.Compile(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytecode
+}
+
+// Number of instructions of every function
+func instructions(bytecode *vm.Program) map[string]int {
+	counts := map[string]int{}
+	for _, function := range bytecode.Functions {
+		counts[function.Name] = len(function.Code)
+	}
+	return counts
+}
+
+func TestPipelineShrinksFunctions(t *testing.T) {
+	unoptimized := program(t)
+	optimizedProgram, stats := optimized(t)
+	for name, s := range stats {
+		if s.After >= s.Before {
+			t.Errorf("%s: %d AST nodes before and %d after the pipeline", name, s.Before, s.After)
+		}
+		if s.Rounds < 2 {
+			t.Errorf("%s: the pipeline stopped after %d round, before it reached a fixed point", name, s.Rounds)
+		}
+	}
+	before := instructions(compile(t, unoptimized))
+	after := instructions(compile(t, optimizedProgram))
+	for name, count := range before {
+		if after[name] >= count {
+			t.Errorf("%s: %d instructions before and %d after the pipeline", name, count, after[name])
+		}
+	}
+}
+
+// The optimized program prints what the original one prints, with the interpreter and with the VM
+func TestPipelineKeepsOutput(t *testing.T) {
+	var want strings.Builder
+	if err := interp.New(program(t), &want).Run(nil); err != nil {
+		t.Fatal(err)
+	}
+	optimizedProgram, _ := optimized(t)
+	var interpreted, executed strings.Builder
+	if err := interp.New(optimizedProgram, &interpreted).Run(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := vm.New(compile(t, optimizedProgram), &executed).Run(nil); err != nil {
+		t.Fatal(err)
+	}
+	if interpreted.String() != want.String() {
+		t.Errorf("interpreter got:\n%s\nwant:\n%s", interpreted.String(), want.String())
+	}
+	if executed.String() != want.String() {
+		t.Errorf("VM got:\n%s\nwant:\n%s", executed.String(), want.String())
+	}
+}
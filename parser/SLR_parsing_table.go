@@ -1,13 +1,50 @@
 package parser
 
 import (
+	"compiler/diag"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
 )
 
+// Never changed after it is built or loaded, so one table can parse in many go routines at the same time (see ParseFiles)
 type SLR_parsing_Table struct {
 	actionTable map[int]map[string]*Action
 	gotoToTable map[int]map[string]*GoTo
+	grammar     *Grammar
+	conflicts   []SLRConflict
+	// The LR(0) automaton the table was built from
+	automata *SLR_automata
+	// The terminals each rule is reduced on, by state and rule, before conflicts are resolved. Empty for loaded tables
+	lookaheads map[int]map[int][]string
+}
+
+// Two actions for the same state and terminal. Resolved like yacc does:
+// shift wins over reduce, the earlier rule wins between two reduces
+type SLRConflict struct {
+	State  int
+	Symbol string
+	// shift/reduce or reduce/reduce
+	Kind string
+	// The rules that are reduced by
+	Rules []Rule
+	// Input which runs into the conflict, the last terminal is Symbol. See ConflictExample
+	Example []string
+}
+
+func (conflict SLRConflict) String() string {
+	rules := []string{}
+	for _, r := range conflict.Rules {
+		rules = append(rules, r.String())
+	}
+	text := conflict.Kind + " conflict in state " + strconv.Itoa(conflict.State) + " on \"" + conflict.Symbol + "\": " + strings.Join(rules, " | ")
+	if len(conflict.Example) > 0 {
+		text += ", e.g. \"" + strings.Join(conflict.Example, " ") + "\""
+	}
+	return text
 }
 
 type Action struct {
@@ -22,6 +59,8 @@ type GoTo struct {
 
 func (automata *SLR_automata) CreateSLRTable(grammar *Grammar) *SLR_parsing_Table {
 	table := makeSlrParsingTable()
+	table.grammar = grammar
+	table.automata = automata
 
 	for _, state := range automata.states {
 		for _, itemrule := range state.rules {
@@ -31,7 +70,7 @@ func (automata *SLR_automata) CreateSLRTable(grammar *Grammar) *SLR_parsing_Tabl
 			} else {
 				// The dot is at the end of the production
 				for _, terminal := range grammar.follow[itemrule.rule.nonTerminal] {
-					if terminal == "$" && itemrule.rule.nonTerminal == "S" {
+					if terminal == "$" && itemrule.rule.nonTerminal == grammar.start {
 						table.AddAction(state.id, "$", "Accept", 0)
 					} else {
 						ruleID := detRuleId(grammar, itemrule)
@@ -57,6 +96,7 @@ func makeSlrParsingTable() *SLR_parsing_Table {
 	newTable := new(SLR_parsing_Table)
 	newTable.actionTable = make(map[int]map[string]*Action)
 	newTable.gotoToTable = make(map[int]map[string]*GoTo)
+	newTable.lookaheads = make(map[int]map[int][]string)
 	return newTable
 }
 
@@ -83,15 +123,89 @@ func MakeAction(ty string, value int) Action {
 
 func (table *SLR_parsing_Table) AddAction(state int, terminal string, actionType string, ActionValue int) {
 	newAction := MakeAction(actionType, ActionValue)
+	if actionType != "Shift" {
+		table.addLookahead(state, newAction, terminal)
+	}
 	if table.actionTable[state] == nil {
 		table.actionTable[state] = make(map[string]*Action)
 	}
-	if table.actionTable[state][terminal] != nil && table.actionTable[state][terminal].value != ActionValue {
-		fmt.Println("Grammar does not seem to be SLR Parsable, Action Table Error")
+	old := table.actionTable[state][terminal]
+	if old != nil && *old != newAction {
+		newAction = table.resolveConflict(state, terminal, *old, newAction)
 	}
 	table.actionTable[state][terminal] = &newAction
 }
 
+// Records the conflict and returns the action which is kept
+func (table *SLR_parsing_Table) resolveConflict(state int, terminal string, old Action, new Action) Action {
+	conflict := SLRConflict{State: state, Symbol: terminal, Kind: "reduce/reduce"}
+	keep := old
+	switch {
+	case old.actionType == "Shift" && new.actionType == "Shift":
+		// Only one state can follow a terminal, so this cannot happen
+		panic(diag.ActionConflict.Format("Two shifts for the same terminal"))
+	case old.actionType == "Shift" || new.actionType == "Shift":
+		conflict.Kind = "shift/reduce"
+		if new.actionType == "Shift" {
+			keep = new
+			old, new = new, old
+		}
+		conflict.Rules = []Rule{table.reducedRule(new)}
+		// Operators with a precedence are no conflict, like %left and %right in yacc
+		if new.actionType == "Reduce" {
+			if shift, ok := table.grammar.resolveByPrecedence(terminal, conflict.Rules[0]); ok {
+				if shift {
+					return old
+				}
+				return new
+			}
+		}
+	default:
+		if new.value < old.value {
+			keep = new
+		}
+		conflict.Rules = []Rule{table.reducedRule(old), table.reducedRule(new)}
+	}
+	table.conflicts = append(table.conflicts, conflict)
+	return keep
+}
+
+func (table *SLR_parsing_Table) addLookahead(state int, action Action, terminal string) {
+	ruleID := action.value
+	if action.actionType == "Accept" {
+		ruleID = slices.IndexFunc(table.grammar.rules, func(r Rule) bool { return r.nonTerminal == table.grammar.start })
+	}
+	if table.lookaheads[state] == nil {
+		table.lookaheads[state] = make(map[int][]string)
+	}
+	if !slices.Contains(table.lookaheads[state][ruleID], terminal) {
+		table.lookaheads[state][ruleID] = append(table.lookaheads[state][ruleID], terminal)
+	}
+}
+
+// The rule of a reduce, or the augmented start rule for accept
+func (table *SLR_parsing_Table) reducedRule(action Action) Rule {
+	if action.actionType == "Accept" {
+		for _, r := range table.grammar.rules {
+			if r.nonTerminal == table.grammar.start {
+				return r
+			}
+		}
+	}
+	return table.grammar.rules[action.value]
+}
+
+// The conflicts found while building the table, empty if the grammar is SLR(1). Each with an example input
+func (table *SLR_parsing_Table) Conflicts() []SLRConflict {
+	yields := table.grammar.shortestYields()
+	conflicts := []SLRConflict{}
+	for _, conflict := range table.conflicts {
+		conflict.Example, _ = table.conflictExample(conflict, yields)
+		conflicts = append(conflicts, conflict)
+	}
+	return conflicts
+}
+
 func MakeGoto(val int) *GoTo {
 	newGoto := new(GoTo)
 	newGoto.val = val
@@ -103,16 +217,18 @@ func (table *SLR_parsing_Table) AddGoTo(state int, symbol string, newstate int)
 		table.gotoToTable[state] = make(map[string]*GoTo)
 	}
 	if table.gotoToTable[state][symbol] != nil && table.gotoToTable[state][symbol].val != newstate {
-		panic("Grammar does not seem to be SLR Parsable, GoTo Table error")
+		panic(diag.GotoConflict.Format("Grammar does not seem to be SLR Parsable, GoTo Table error"))
 	}
 	table.gotoToTable[state][symbol] = MakeGoto(newstate)
 }
 
 func (table *SLR_parsing_Table) PrintTable(grammar *Grammar) {
 	fmt.Println("Action: ")
-	for i, m := range table.actionTable {
+	for _, i := range slices.Sorted(maps.Keys(table.actionTable)) {
 		fmt.Println(i)
-		for str, action := range m {
+		m := table.actionTable[i]
+		for _, str := range sortedKeys(m) {
+			action := m[str]
 			fmt.Print(str + " ")
 			fmt.Print(action.actionType + " ")
 			switch action.actionType {
@@ -127,10 +243,12 @@ func (table *SLR_parsing_Table) PrintTable(grammar *Grammar) {
 	}
 
 	fmt.Println("GoTo: ")
-	for stateId, othermap := range table.gotoToTable {
+	for _, stateId := range slices.Sorted(maps.Keys(table.gotoToTable)) {
+		othermap := table.gotoToTable[stateId]
 		fmt.Print(stateId)
 		fmt.Print(" ")
-		for symbol, state := range othermap {
+		for _, symbol := range sortedKeys(othermap) {
+			state := othermap[symbol]
 			fmt.Print(symbol + " ")
 			fmt.Print(state)
 			fmt.Print(" ")
@@ -152,6 +270,8 @@ func (table SLR_parsing_Table) getNextExpectedTokens(state int) []string {
 			retString = append(retString, i)
 		}
 	}
+	// Map order is random, sorted the error messages stay the same between runs
+	slices.Sort(retString)
 	return retString
 }
 
@@ -164,5 +284,5 @@ func detRuleId(grammar *Grammar, itemrule ItemRule) int {
 			return i
 		}
 	}
-	panic("Rule not found in grammar for ID verfication")
+	panic(diag.RuleNotFound.Format("Rule not found in grammar for ID verfication"))
 }
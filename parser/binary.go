@@ -0,0 +1,129 @@
+package parser
+
+import (
+	"compiler/artifact"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// Payload of MarshalBinary. The version is raised when the layout changes, with a migration for the old one
+var tableFormat = artifact.Format{Kind: artifact.ParserTable, Version: 1, Compatible: 1}
+
+var actionTypes = []string{"Shift", "Reduce", "Accept"}
+
+// The table and its grammar in the artifact format, enough to parse without building the table again.
+// The automaton is not included, Dot of a loaded table is empty
+func (table *SLR_parsing_Table) MarshalBinary() ([]byte, error) {
+	var encoder artifact.Encoder
+	grammar := table.grammar
+	encoder.String(grammar.start)
+	encoder.Strings(grammar.nonTerminals)
+	encoder.Strings(grammar.terminals)
+	encodeRules(&encoder, grammar.rules)
+
+	// Sorted, the same table always gives the same bytes
+	states := slices.Sorted(maps.Keys(table.actionTable))
+	encoder.Uint(len(states))
+	for _, state := range states {
+		encoder.Uint(state)
+		symbols := slices.Sorted(maps.Keys(table.actionTable[state]))
+		encoder.Uint(len(symbols))
+		for _, symbol := range symbols {
+			action := table.actionTable[state][symbol]
+			encoder.String(symbol)
+			encoder.Uint(slices.Index(actionTypes, action.actionType))
+			encoder.Uint(action.value)
+		}
+	}
+	states = slices.Sorted(maps.Keys(table.gotoToTable))
+	encoder.Uint(len(states))
+	for _, state := range states {
+		encoder.Uint(state)
+		symbols := slices.Sorted(maps.Keys(table.gotoToTable[state]))
+		encoder.Uint(len(symbols))
+		for _, symbol := range symbols {
+			encoder.String(symbol)
+			encoder.Uint(table.gotoToTable[state][symbol].val)
+		}
+	}
+
+	encoder.Uint(len(table.conflicts))
+	for _, conflict := range table.conflicts {
+		encoder.Uint(conflict.State)
+		encoder.String(conflict.Symbol)
+		encoder.String(conflict.Kind)
+		encodeRules(&encoder, conflict.Rules)
+	}
+	return tableFormat.Seal(encoder.Bytes()), nil
+}
+
+// Reads a table written by MarshalBinary
+func UnmarshalTable(data []byte) (*SLR_parsing_Table, error) {
+	decoder, err := tableFormat.Open(data)
+	if err != nil {
+		return nil, err
+	}
+
+	table := makeSlrParsingTable()
+	grammar := new(Grammar)
+	grammar.start = decoder.String()
+	grammar.nonTerminals = decoder.Strings()
+	grammar.terminals = decoder.Strings()
+	grammar.rules = decodeRules(decoder)
+	table.grammar = grammar
+
+	for range decoder.Len() {
+		state := decoder.Uint()
+		for range decoder.Len() {
+			symbol := decoder.String()
+			actionType := decoder.Uint()
+			value := decoder.Uint()
+			if actionType >= len(actionTypes) || (actionTypes[actionType] == "Reduce" && value >= len(grammar.rules)) {
+				decoder.Fail("Invalid action in state " + strconv.Itoa(state))
+				break
+			}
+			action := MakeAction(actionTypes[actionType], value)
+			if table.actionTable[state] == nil {
+				table.actionTable[state] = make(map[string]*Action)
+			}
+			table.actionTable[state][symbol] = &action
+		}
+	}
+	for range decoder.Len() {
+		state := decoder.Uint()
+		for range decoder.Len() {
+			if table.gotoToTable[state] == nil {
+				table.gotoToTable[state] = make(map[string]*GoTo)
+			}
+			symbol := decoder.String()
+			table.gotoToTable[state][symbol] = MakeGoto(decoder.Uint())
+		}
+	}
+
+	for range decoder.Len() {
+		conflict := SLRConflict{State: decoder.Uint(), Symbol: decoder.String(), Kind: decoder.String()}
+		conflict.Rules = decodeRules(decoder)
+		table.conflicts = append(table.conflicts, conflict)
+	}
+	if err := decoder.Finish(); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+func encodeRules(encoder *artifact.Encoder, rules []Rule) {
+	encoder.Uint(len(rules))
+	for _, rule := range rules {
+		encoder.String(rule.nonTerminal)
+		encoder.Strings(rule.production)
+	}
+}
+
+func decodeRules(decoder *artifact.Decoder) []Rule {
+	rules := []Rule{}
+	for range decoder.Len() {
+		rules = append(rules, MakeRule(decoder.String(), decoder.Strings()))
+	}
+	return rules
+}
@@ -0,0 +1,144 @@
+package parser
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+/*
+Grammar from a BNF string:
+
+	EXPRESSION -> EXPRESSION + TERM | TERM
+	TERM -> ( EXPRESSION )
+	      | name
+	LIST -> item LIST | ε
+
+Symbols are separated by whitespace. "::=" can be used instead of "->".
+Every symbol with a rule is a non terminal, everything else is a terminal.
+Quote symbols ('|', "->", 'ε') to use them as plain symbols.
+An empty alternative or ε is the empty word. Lines starting with # or // are comments,
+the comment lines right in front of a rule are the doc comment of its non terminal.
+The non terminal of the first rule is the start symbol.
+*/
+func ParseBNF(source string) (*Grammar, error) {
+	rules := []Rule{}
+	start := ""
+	current := ""
+	docs := make(map[string]string)
+	// Comment lines since the last line which was not a comment
+	comment := []string{}
+
+	for i, line := range strings.Split(source, "\n") {
+		lineString := strconv.Itoa(i + 1)
+		// Comments may have quotes which are not closed, so they are not split
+		trimmed := strings.TrimSpace(line)
+		if text, found := strings.CutPrefix(trimmed, "#"); found {
+			comment = append(comment, strings.TrimSpace(text))
+			continue
+		}
+		if text, found := strings.CutPrefix(trimmed, "//"); found {
+			comment = append(comment, strings.TrimSpace(text))
+			continue
+		}
+		symbols, err := splitBNFLine(line)
+		if err != nil {
+			return nil, errors.New("BNF Error at line " + lineString + ": " + err.Error())
+		}
+		if len(symbols) == 0 {
+			comment = []string{}
+			continue
+		}
+
+		var alternatives []string
+		switch {
+		case len(symbols) >= 2 && (symbols[1] == "->" || symbols[1] == "::="):
+			current = symbols[0]
+			if current == "|" || current == "ε" {
+				return nil, errors.New("BNF Error at line " + lineString + ": \"" + current + "\" can not be a non terminal")
+			}
+			if start == "" {
+				start = current
+			}
+			if len(comment) > 0 {
+				docs[current] = strings.TrimSpace(docs[current] + "\n" + strings.Join(comment, "\n"))
+			}
+			alternatives = symbols[2:]
+		case symbols[0] == "|":
+			// Continues the rule of the line before
+			if current == "" {
+				return nil, errors.New("BNF Error at line " + lineString + ": Alternative without a rule")
+			}
+			alternatives = symbols
+		default:
+			return nil, errors.New("BNF Error at line " + lineString + ": Expected \"NONTERMINAL -> ...\"")
+		}
+
+		production := []string{}
+		for j, s := range alternatives {
+			if s == "|" {
+				// Leading | of a continued line does not end an alternative
+				if j != 0 || symbols[0] != "|" {
+					rules = append(rules, MakeRule(current, production))
+				}
+				production = []string{}
+				continue
+			}
+			if s != "ε" {
+				production = append(production, unquoteBNF(s))
+			}
+		}
+		rules = append(rules, MakeRule(current, production))
+		comment = []string{}
+	}
+
+	if start == "" {
+		return nil, errors.New("BNF Error: Grammar has no rules")
+	}
+	grammar := MakeGrammar(rules, start)
+	for nonTerminal, doc := range docs {
+		grammar.SetDoc(nonTerminal, doc)
+	}
+	return grammar, nil
+}
+
+// Splits on whitespace. Quoted symbols are kept together and keep their quotes
+func splitBNFLine(line string) ([]string, error) {
+	symbols := []string{}
+	buffer := ""
+	var quote rune
+	for _, c := range line {
+		switch {
+		case quote != 0:
+			buffer += string(c)
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			buffer += string(c)
+			quote = c
+		case c == ' ' || c == '\t' || c == '\r':
+			if buffer != "" {
+				symbols = append(symbols, buffer)
+			}
+			buffer = ""
+		default:
+			buffer += string(c)
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("Unclosed quote")
+	}
+	if buffer != "" {
+		symbols = append(symbols, buffer)
+	}
+	return symbols, nil
+}
+
+// Quoted symbols are always terminals: 'a' -> a
+func unquoteBNF(symbol string) string {
+	if len(symbol) >= 2 && (symbol[0] == '\'' || symbol[0] == '"') && symbol[len(symbol)-1] == symbol[0] {
+		return symbol[1 : len(symbol)-1]
+	}
+	return symbol
+}
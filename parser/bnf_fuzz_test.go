@@ -0,0 +1,35 @@
+package parser
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// Grammars which ParseBNF accepts give a table, which parses and traces any input without panicking or hanging
+func FuzzParseBNF(f *testing.F) {
+	f.Add("S -> a S b\nS ->", "a a b b")
+	f.Add("E -> E + T | T\nT -> T * F | F\nF -> ( E ) | id", "id + id * ( id )")
+	f.Add("S -> T\nT -> a b\nT -> ab", "ab")
+	f.Add("A -> S\nA ->\nB -> S C", "S C")
+	f.Add("# doc\nS -> \"x y\" | ε", "x y")
+	f.Add("S -> A\nA -> B\nB -> A", "")
+	f.Fuzz(func(t *testing.T, bnf string, input string) {
+		// Large grammars only make the fuzzer slow
+		if len(bnf) > 300 || len(input) > 100 {
+			return
+		}
+		grammar, err := ParseBNF(bnf)
+		if err != nil {
+			return
+		}
+		slr := grammar.CreateSLRParser()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		slr.ParseContext(ctx, words(input))
+		slr.TraceContext(ctx, words(input))
+		if ctx.Err() != nil {
+			t.Fatal("parsing did not finish in time")
+		}
+	})
+}
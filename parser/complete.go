@@ -0,0 +1,117 @@
+package parser
+
+import (
+	"compiler/lexer"
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+/*
+Parser state for code completion. The tokens in front of the cursor are run through the table,
+then every terminal is tried as the next token. A terminal is legal if the parser shifts or accepts it
+after the reductions it causes, so a reduction on the FOLLOW set alone does not count.
+*/
+
+// The terminals which can come after the tokens, sorted. The tokens are the start of an input without "$",
+// LINE tokens are skipped. "$" is in the list if the tokens are already a whole input
+func (table *SLR_parsing_Table) Expected(tokens []lexer.Token) ([]string, error) {
+	states, err := table.run(tokens)
+	if err != nil {
+		return nil, err
+	}
+	return table.expectedAfter(states), nil
+}
+
+// The terminals the parser shifts or accepts after the states, sorted
+func (table *SLR_parsing_Table) expectedAfter(states []int) []string {
+	expected := []string{}
+	for terminal := range table.actionTable[states[len(states)-1]] {
+		if table.accepts(states, terminal) {
+			expected = append(expected, terminal)
+		}
+	}
+	slices.Sort(expected)
+	return expected
+}
+
+// Lexes the source and returns its tokens (without LINE and "$") and the terminals which can follow them.
+// The source is everything in front of the cursor
+func ExpectedAfter(source string, test bool) ([]lexer.Token, []string, error) {
+	tokenChannel := make(chan lexer.Token)
+	go lexer.LexReader(context.Background(), strings.NewReader(source), tokenChannel, lexer.Options{Output: io.Discard})
+	tokens := []lexer.Token{}
+	for token := range tokenChannel {
+		if token.Identifier != "LINE" && token.Identifier != "$" {
+			tokens = append(tokens, token)
+		}
+	}
+
+	slrTable, _ := createParser(test)
+	expected, err := slrTable.Expected(tokens)
+	return tokens, expected, err
+}
+
+// The stack of states after the tokens, the top is the last one
+func (table *SLR_parsing_Table) run(tokens []lexer.Token) ([]int, error) {
+	states := []int{0}
+	for _, token := range tokens {
+		if token.Identifier == "LINE" {
+			continue
+		}
+		for {
+			action, err := table.GetAction(states[len(states)-1], token.Identifier)
+			if err != nil || action.actionType == "Accept" {
+				return nil, errors.New("Syntax Error. Unexpected: \"" + formatToken(token) + "\" at line " + strconv.Itoa(token.Line))
+			}
+			if action.actionType == "Shift" {
+				states = append(states, action.value)
+				break
+			}
+			if states, err = table.reduce(states, action.value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return states, nil
+}
+
+// Whether the terminal is shifted or accepted after the states, the states are not changed
+func (table *SLR_parsing_Table) accepts(states []int, terminal string) bool {
+	_, ok := table.shift(states, terminal)
+	return ok
+}
+
+// The states after the reductions the terminal causes and its shift. On accept the states are the ones before "$".
+// ok is false if the parser fails on the terminal, the states are not changed
+func (table *SLR_parsing_Table) shift(states []int, terminal string) ([]int, bool) {
+	states = slices.Clone(states)
+	for {
+		action, err := table.GetAction(states[len(states)-1], terminal)
+		if err != nil {
+			return nil, false
+		}
+		switch action.actionType {
+		case "Shift":
+			return append(states, action.value), true
+		case "Accept":
+			return states, true
+		}
+		if states, err = table.reduce(states, action.value); err != nil {
+			return nil, false
+		}
+	}
+}
+
+func (table *SLR_parsing_Table) reduce(states []int, ruleID int) ([]int, error) {
+	rule := table.grammar.rules[ruleID]
+	states = states[:len(states)-len(rule.production)]
+	gotoVal, err := table.GetGoto(states[len(states)-1], rule.nonTerminal)
+	if err != nil {
+		return nil, err
+	}
+	return append(states, gotoVal.val), nil
+}
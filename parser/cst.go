@@ -0,0 +1,91 @@
+package parser
+
+import (
+	"compiler/lexer"
+	"context"
+	"strings"
+)
+
+/*
+Lossless concrete syntax trees for formatters and refactoring tools. The source is lexed with trivia
+(lexer.Options.Trivia), so every leaf of the parse tree has the text of its token and the whitespace and comments
+around it, and parsed like ParseSource. Nothing of the source is lost, Source gives it back exactly:
+
+	cst, ok, err := parser.ParseSourceCST(ctx, source, true, options)
+	cst.Source() == source
+
+A tool changes the tree, e.g. the Text of a name for a rename, and prints it with Source.
+Leaves without trivia print nothing, these are tokens which are not in the source, like the ones the tolerant
+parser inserts.
+*/
+
+type CST struct {
+	Tree ParseTree
+	// The whitespace and comments after the last token
+	End string
+}
+
+// Parses the source with trivia. The tree and the messages are the ones of ParseSource
+func ParseSourceCST(ctx context.Context, source string, test bool, options lexer.Options) (*CST, bool, error) {
+	slrTable, grammar := createParser(test)
+	options.Trivia = true
+	end := ""
+	tree, ok, err := parseLexed(ctx, func(tokenChannel chan lexer.Token, lexerOptions lexer.Options) {
+		// The parser does not keep the "$", its trivia is taken on the way
+		lexed := make(chan lexer.Token)
+		go lexer.LexReader(ctx, strings.NewReader(source), lexed, lexerOptions)
+		for token := range lexed {
+			if token.Identifier == "$" && token.Trivia != nil {
+				end = token.Trivia.Leading
+			}
+			tokenChannel <- token
+		}
+		close(tokenChannel)
+	}, slrTable, grammar, options)
+	if !ok {
+		return nil, false, err
+	}
+	return &CST{Tree: tree, End: end}, true, nil
+}
+
+// The source the tree was parsed from
+func (cst *CST) Source() string {
+	var out strings.Builder
+	writeSource(&out, cst.Tree)
+	out.WriteString(cst.End)
+	return out.String()
+}
+
+// The text of the leaves of the tree with their trivia
+func (tree ParseTree) Source() string {
+	var out strings.Builder
+	writeSource(&out, tree)
+	return out.String()
+}
+
+// The leaves of the tree which are in the source, in their order. Changes to their trivia show up in Source
+func (tree *ParseTree) Tokens() []*ParseLeaf {
+	leaves := []*ParseLeaf{}
+	if len(tree.Branches) == 0 {
+		if tree.Leaf.Trivia != nil {
+			leaves = append(leaves, &tree.Leaf)
+		}
+		return leaves
+	}
+	for i := range tree.Branches {
+		leaves = append(leaves, tree.Branches[i].Tokens()...)
+	}
+	return leaves
+}
+
+func writeSource(out *strings.Builder, tree ParseTree) {
+	if trivia := tree.Leaf.Trivia; len(tree.Branches) == 0 && trivia != nil {
+		out.WriteString(trivia.Leading)
+		out.WriteString(trivia.Text)
+		out.WriteString(trivia.Trailing)
+		return
+	}
+	for _, branch := range tree.Branches {
+		writeSource(out, branch)
+	}
+}
@@ -0,0 +1,284 @@
+package parser
+
+import (
+	"html"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+/*
+Reference documentation of a grammar. Every non terminal gets a section with its doc comment (see Doc),
+a railroad diagram of its rules, the rules as text and the non terminals which use it.
+Non terminals link to their sections, in the text and in the diagrams. The start symbol comes first,
+the others in the order of their rules. A table at the end lists the terminals.
+
+The diagrams are inline SVG, in the Markdown too. Viewers which strip HTML from Markdown only show the rules as text.
+*/
+
+// Layout of the railroad diagrams, in pixels
+const (
+	railCharWidth = 8
+	railBoxHeight = 24
+	railRowHeight = 40
+	railGap       = 16
+	// Space for the start and end marks and the curves between the rows
+	railMargin = 20
+)
+
+// The documentation as a Markdown document
+func (grammar *Grammar) Markdown() string {
+	var md strings.Builder
+	md.WriteString("# Grammar reference\n\n")
+	md.WriteString("The start symbol is " + grammar.markdownSymbol(grammar.start) + ".\n\n")
+	for _, nonTerminal := range grammar.documented() {
+		md.WriteString("- [" + nonTerminal + "](#" + docAnchor(nonTerminal) + ")\n")
+	}
+	md.WriteString("- [Terminals](#terminals)\n")
+
+	for _, nonTerminal := range grammar.documented() {
+		md.WriteString("\n<a id=\"" + docAnchor(nonTerminal) + "\"></a>\n\n## " + nonTerminal + "\n\n")
+		if doc := grammar.Doc(nonTerminal); doc != "" {
+			md.WriteString(doc + "\n\n")
+		}
+		md.WriteString(grammar.railroad(nonTerminal) + "\n\n")
+		for _, rule := range grammar.rules {
+			if rule.nonTerminal != nonTerminal {
+				continue
+			}
+			symbols := []string{}
+			for _, symbol := range rule.production {
+				symbols = append(symbols, grammar.markdownSymbol(symbol))
+			}
+			if len(symbols) == 0 {
+				symbols = append(symbols, "*empty*")
+			}
+			md.WriteString("- " + strings.Join(symbols, " ") + "\n")
+		}
+		if users := grammar.users(nonTerminal); len(users) > 0 {
+			links := []string{}
+			for _, user := range users {
+				links = append(links, grammar.markdownSymbol(user))
+			}
+			md.WriteString("\nUsed by " + strings.Join(links, ", ") + "\n")
+		}
+	}
+
+	md.WriteString("\n<a id=\"terminals\"></a>\n\n## Terminals\n\n| Terminal | Used by |\n| --- | --- |\n")
+	for _, terminal := range grammar.terminals {
+		links := []string{}
+		for _, user := range grammar.users(terminal) {
+			links = append(links, grammar.markdownSymbol(user))
+		}
+		md.WriteString("| " + strings.ReplaceAll(markdownCode(terminal), "|", "\\|") + " | " + strings.Join(links, ", ") + " |\n")
+	}
+	return md.String()
+}
+
+// The documentation as a standalone HTML page
+func (grammar *Grammar) HTML(title string) string {
+	var page strings.Builder
+	page.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>" + html.EscapeString(title) + "</title>\n")
+	page.WriteString("<style>\nbody { font-family: sans-serif; max-width: 60em; margin: auto; }\ncode { background: #f0f0f0; }\n" +
+		"table { border-collapse: collapse; }\ntd, th { border: 1px solid #ccc; padding: 2px 8px; text-align: left; }\n</style>\n</head>\n<body>\n")
+	page.WriteString("<h1>" + html.EscapeString(title) + "</h1>\n<p>The start symbol is " + grammar.htmlSymbol(grammar.start) + ".</p>\n<ul>\n")
+	for _, nonTerminal := range grammar.documented() {
+		page.WriteString("<li><a href=\"#" + docAnchor(nonTerminal) + "\">" + html.EscapeString(nonTerminal) + "</a></li>\n")
+	}
+	page.WriteString("<li><a href=\"#terminals\">Terminals</a></li>\n</ul>\n")
+
+	for _, nonTerminal := range grammar.documented() {
+		page.WriteString("<h2 id=\"" + docAnchor(nonTerminal) + "\">" + html.EscapeString(nonTerminal) + "</h2>\n")
+		if doc := grammar.Doc(nonTerminal); doc != "" {
+			page.WriteString("<p>" + strings.ReplaceAll(html.EscapeString(doc), "\n", "<br>\n") + "</p>\n")
+		}
+		page.WriteString(grammar.railroad(nonTerminal) + "\n<ul>\n")
+		for _, rule := range grammar.rules {
+			if rule.nonTerminal != nonTerminal {
+				continue
+			}
+			symbols := []string{}
+			for _, symbol := range rule.production {
+				symbols = append(symbols, grammar.htmlSymbol(symbol))
+			}
+			if len(symbols) == 0 {
+				symbols = append(symbols, "<em>empty</em>")
+			}
+			page.WriteString("<li>" + strings.Join(symbols, " ") + "</li>\n")
+		}
+		page.WriteString("</ul>\n")
+		if users := grammar.users(nonTerminal); len(users) > 0 {
+			links := []string{}
+			for _, user := range users {
+				links = append(links, grammar.htmlSymbol(user))
+			}
+			page.WriteString("<p>Used by " + strings.Join(links, ", ") + "</p>\n")
+		}
+	}
+
+	page.WriteString("<h2 id=\"terminals\">Terminals</h2>\n<table>\n<tr><th>Terminal</th><th>Used by</th></tr>\n")
+	for _, terminal := range grammar.terminals {
+		links := []string{}
+		for _, user := range grammar.users(terminal) {
+			links = append(links, grammar.htmlSymbol(user))
+		}
+		page.WriteString("<tr><td>" + grammar.htmlSymbol(terminal) + "</td><td>" + strings.Join(links, ", ") + "</td></tr>\n")
+	}
+	page.WriteString("</table>\n</body>\n</html>\n")
+	return page.String()
+}
+
+// The start symbol first, then the other non terminals in the order of their rules
+func (grammar *Grammar) documented() []string {
+	nonTerminals := []string{}
+	if grammar.isNonTerminal(grammar.start) {
+		nonTerminals = append(nonTerminals, grammar.start)
+	}
+	for _, nonTerminal := range grammar.nonTerminals {
+		if nonTerminal != grammar.start {
+			nonTerminals = append(nonTerminals, nonTerminal)
+		}
+	}
+	return nonTerminals
+}
+
+// The non terminals with a rule which has the symbol, in the order of their rules
+func (grammar *Grammar) users(symbol string) []string {
+	users := []string{}
+	for _, rule := range grammar.rules {
+		if contains(rule.production, symbol) != -1 && contains(users, rule.nonTerminal) == -1 {
+			users = append(users, rule.nonTerminal)
+		}
+	}
+	return users
+}
+
+func (grammar *Grammar) markdownSymbol(symbol string) string {
+	if grammar.isNonTerminal(symbol) {
+		return "[" + symbol + "](#" + docAnchor(symbol) + ")"
+	}
+	return markdownCode(symbol)
+}
+
+func (grammar *Grammar) htmlSymbol(symbol string) string {
+	if grammar.isNonTerminal(symbol) {
+		return "<a href=\"#" + docAnchor(symbol) + "\">" + html.EscapeString(symbol) + "</a>"
+	}
+	return "<code>" + html.EscapeString(symbol) + "</code>"
+}
+
+// Code span which works for symbols with backticks too
+func markdownCode(symbol string) string {
+	if strings.Contains(symbol, "`") {
+		return "`` " + symbol + " ``"
+	}
+	return "`" + symbol + "`"
+}
+
+// Id of the section of the non terminal. Characters other than letters, digits, - and _ are written as their code
+func docAnchor(nonTerminal string) string {
+	anchor := "rule-"
+	for _, r := range nonTerminal {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_') {
+			anchor += string(r)
+		} else {
+			anchor += "." + strconv.FormatInt(int64(r), 16)
+		}
+	}
+	return anchor
+}
+
+// Railroad diagram of the rules of the non terminal as SVG. Every rule is one row of boxes,
+// rounded boxes are terminals, square ones are non terminals and link to their section
+func (grammar *Grammar) railroad(nonTerminal string) string {
+	rows := [][]string{}
+	for _, rule := range grammar.rules {
+		if rule.nonTerminal == nonTerminal {
+			rows = append(rows, rule.production)
+		}
+	}
+	inner := 0
+	for _, row := range rows {
+		inner = max(inner, railRowWidth(row))
+	}
+	// Main line, left and right rail and where the boxes start and end
+	y := railBoxHeight/2 + 8
+	left := railMargin
+	start := left + railMargin
+	end := start + inner
+	right := end + railMargin
+	width := right + railMargin
+	height := y + (len(rows)-1)*railRowHeight + railBoxHeight/2 + 8
+
+	var svg strings.Builder
+	svg.WriteString("<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"" + strconv.Itoa(width) + "\" height=\"" + strconv.Itoa(height) + "\" font-family=\"monospace\" font-size=\"13\">")
+	line := " fill=\"none\" stroke=\"#333\" stroke-width=\"1.5\""
+	// Start and end marks
+	svg.WriteString(railPath("M"+railPoint(4, y-8)+" V"+strconv.Itoa(y+8)+" M"+railPoint(4, y)+" H"+strconv.Itoa(left), line))
+	svg.WriteString(railPath("M"+railPoint(right, y)+" H"+strconv.Itoa(width-4)+" M"+railPoint(width-4, y-8)+" V"+strconv.Itoa(y+8), line))
+
+	for i, row := range rows {
+		rowY := y + i*railRowHeight
+		half := railMargin / 2
+		if i == 0 {
+			svg.WriteString(railPath("M"+railPoint(left, y)+" H"+strconv.Itoa(start), line))
+			svg.WriteString(railPath("M"+railPoint(end, y)+" H"+strconv.Itoa(right), line))
+		} else {
+			// Down from the main line on the left, back up on the right
+			svg.WriteString(railPath("M"+railPoint(left, y)+" Q"+railPoint(left+half, y)+" "+railPoint(left+half, y+half)+
+				" V"+strconv.Itoa(rowY-half)+" Q"+railPoint(left+half, rowY)+" "+railPoint(start, rowY), line))
+			svg.WriteString(railPath("M"+railPoint(end, rowY)+" Q"+railPoint(right-half, rowY)+" "+railPoint(right-half, rowY-half)+
+				" V"+strconv.Itoa(y+half)+" Q"+railPoint(right-half, y)+" "+railPoint(right, y), line))
+		}
+
+		x := start
+		for j, symbol := range row {
+			if j > 0 {
+				svg.WriteString(railPath("M"+railPoint(x, rowY)+" H"+strconv.Itoa(x+railGap), line))
+				x += railGap
+			}
+			boxWidth := railBoxWidth(symbol)
+			svg.WriteString(grammar.railBox(symbol, x, rowY, boxWidth))
+			x += boxWidth
+		}
+		// The rest of the row up to the right rail, the whole row for empty rules
+		if x < end {
+			svg.WriteString(railPath("M"+railPoint(x, rowY)+" H"+strconv.Itoa(end), line))
+		}
+	}
+	svg.WriteString("</svg>")
+	return svg.String()
+}
+
+func (grammar *Grammar) railBox(symbol string, x int, y int, width int) string {
+	top := y - railBoxHeight/2
+	rect := "<rect x=\"" + strconv.Itoa(x) + "\" y=\"" + strconv.Itoa(top) + "\" width=\"" + strconv.Itoa(width) + "\" height=\"" + strconv.Itoa(railBoxHeight) + "\""
+	text := "<text x=\"" + strconv.Itoa(x+width/2) + "\" y=\"" + strconv.Itoa(y+4) + "\" text-anchor=\"middle\">" + html.EscapeString(symbol) + "</text>"
+	if grammar.isNonTerminal(symbol) {
+		return "<a href=\"#" + docAnchor(symbol) + "\">" + rect + " fill=\"#e8f0ff\" stroke=\"#333\"/>" + text + "</a>"
+	}
+	return rect + " rx=\"" + strconv.Itoa(railBoxHeight/2) + "\" fill=\"#fff8e0\" stroke=\"#333\"/>" + text
+}
+
+func railBoxWidth(symbol string) int {
+	return len([]rune(symbol))*railCharWidth + 2*railCharWidth
+}
+
+func railRowWidth(row []string) int {
+	width := 0
+	for i, symbol := range row {
+		if i > 0 {
+			width += railGap
+		}
+		width += railBoxWidth(symbol)
+	}
+	return width
+}
+
+func railPoint(x int, y int) string {
+	return strconv.Itoa(x) + " " + strconv.Itoa(y)
+}
+
+func railPath(d string, style string) string {
+	return "<path d=\"" + d + "\"" + style + "/>"
+}
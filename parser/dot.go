@@ -0,0 +1,90 @@
+package parser
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Graphviz DOT of the LR(0) automaton the table was built from. Every state is a box with its items
+func (table *SLR_parsing_Table) Dot() string {
+	var dot strings.Builder
+	dot.WriteString("digraph automaton {\n\trankdir=LR;\n\tnode [shape=box, fontname=monospace];\n")
+	// Tables read with UnmarshalTable have no automaton
+	if table.automata == nil {
+		dot.WriteString("}\n")
+		return dot.String()
+	}
+	for _, state := range table.automata.states {
+		label := "State " + strconv.Itoa(state.id) + "\\l"
+		for _, item := range state.rules {
+			label += dotEscape(item.String()) + "\\l"
+		}
+		dot.WriteString("\t" + strconv.Itoa(state.id) + " [label=\"" + label + "\"];\n")
+		symbols := []string{}
+		for symbol := range state.transitions {
+			symbols = append(symbols, symbol)
+		}
+		slices.Sort(symbols)
+		for _, symbol := range symbols {
+			dot.WriteString("\t" + strconv.Itoa(state.id) + " -> " + strconv.Itoa(state.transitions[symbol]) + " [label=\"" + dotEscape(symbol) + "\"];\n")
+		}
+	}
+	dot.WriteString("}\n")
+	return dot.String()
+}
+
+// Graphviz DOT of the parse tree. Terminals are shown with their value
+func TreeDot(tree ParseTree) string {
+	var dot strings.Builder
+	dot.WriteString("digraph tree {\n\tnode [shape=plaintext];\n")
+	count := 0
+	var write func(tree ParseTree) int
+	write = func(tree ParseTree) int {
+		id := count
+		count++
+		label := dotEscape(tree.Leaf.Name)
+		// Non terminals have the value 0
+		if len(tree.Branches) == 0 && tree.Leaf.Value != 0 {
+			if value := dotValue(tree.Leaf.Value); value != "" && value != tree.Leaf.Name {
+				label += "\\n" + dotEscape(value)
+			}
+		}
+		dot.WriteString("\t" + strconv.Itoa(id) + " [label=\"" + label + "\"];\n")
+		for _, branch := range tree.Branches {
+			child := write(branch)
+			dot.WriteString("\t" + strconv.Itoa(id) + " -> " + strconv.Itoa(child) + ";\n")
+		}
+		return id
+	}
+	write(tree)
+	dot.WriteString("}\n")
+	return dot.String()
+}
+
+// A -> α . β
+func (item ItemRule) String() string {
+	symbols := append([]string{}, item.rule.production[:item.dot]...)
+	symbols = append(symbols, ".")
+	symbols = append(symbols, item.rule.production[item.dot:]...)
+	return item.rule.nonTerminal + " -> " + strings.Join(symbols, " ")
+}
+
+func dotValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
+
+// Quotes, backslashes and line breaks have to be escaped in DOT strings
+func dotEscape(text string) string {
+	return strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n").Replace(text)
+}
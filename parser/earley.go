@@ -0,0 +1,326 @@
+package parser
+
+import (
+	"compiler/lexer"
+	"slices"
+	"strconv"
+)
+
+/*
+Earley parser, works with every context free grammar, including ambiguous and left recursive ones:
+	Set k holds the items [A -> α.β, i]: α derives the tokens i to k.
+	Set 0 starts with [S -> .γ, 0] for every start rule.
+	For every item in set k:
+		Predict: [A -> α.Bβ, i]: add [B -> .γ, k] for every rule of B. If B is nullable, add [A -> αB.β, i] as well
+		Scan: [A -> α.aβ, i] and token k is a: add [A -> αa.β, i] to set k+1
+		Complete: [B -> γ., j]: add [A -> αB.β, i] for every [A -> α.Bβ, i] in set j
+	The tokens are accepted if the last set has a completed start rule from 0.
+
+The result is a shared packed parse forest: There is one node for every symbol and the tokens it derives,
+every way to derive them is a family of the node. Every tree of the forest is a parse tree of the tokens.
+*/
+
+type EarleyParser struct {
+	grammar  *Grammar
+	nullable map[string]bool
+	// Non terminal -> indices of its rules
+	rulesOf map[string][]int
+}
+
+type earleyItem struct {
+	rule   int
+	dot    int
+	origin int
+}
+
+// Node of the forest for a symbol which derives the tokens from Start to End (exclusive)
+type ForestNode struct {
+	Symbol string
+	Start  int
+	End    int
+	// Value, line and column of the token for terminals
+	Value any
+	Line  int
+	Col   int
+	// Each family is one way to derive the tokens. Terminals have none
+	Families []ForestFamily
+}
+
+type ForestFamily struct {
+	Rule     Rule
+	Children []*ForestNode
+}
+
+type Forest struct {
+	Root *ForestNode
+}
+
+type forestKey struct {
+	symbol string
+	start  int
+	end    int
+}
+
+type splitKey struct {
+	rule   int
+	origin int
+	dot    int
+	start  int
+	end    int
+}
+
+type forestBuilder struct {
+	parser *EarleyParser
+	tokens []lexer.Token
+	sets   []map[earleyItem]bool
+	nodes  map[forestKey]*ForestNode
+	splits map[splitKey][][]*ForestNode
+}
+
+// No table is needed, the grammar is used as it is
+func (grammar *Grammar) CreateEarleyParser() *EarleyParser {
+	parser := new(EarleyParser)
+	parser.grammar = grammar
+	parser.nullable = grammar.Nullable()
+	parser.rulesOf = make(map[string][]int)
+	for i, rule := range grammar.rules {
+		parser.rulesOf[rule.nonTerminal] = append(parser.rulesOf[rule.nonTerminal], i)
+	}
+	return parser
+}
+
+// Parses the tokens and returns the forest of all parse trees. LINE tokens only count the lines, a "$" ends the input
+func (parser *EarleyParser) Parse(tokens []lexer.Token) (*Forest, error) {
+	input := []lexer.Token{}
+	lines := []int{}
+	linecount := 0
+	for _, token := range tokens {
+		if token.Identifier == "LINE" {
+			linecount, _ = strconv.Atoi(token.Value.(string))
+			continue
+		}
+		if token.Identifier == "$" {
+			break
+		}
+		input = append(input, token)
+		lines = append(lines, linecount)
+	}
+	lines = append(lines, linecount)
+
+	sets := make([]map[earleyItem]bool, len(input)+1)
+	items := make([][]earleyItem, len(input)+1)
+	// Set -> non terminal -> items with the dot before the non terminal
+	waiting := make([]map[string][]earleyItem, len(input)+1)
+	for k := range sets {
+		sets[k] = make(map[earleyItem]bool)
+		waiting[k] = make(map[string][]earleyItem)
+	}
+	add := func(k int, item earleyItem) {
+		if sets[k][item] {
+			return
+		}
+		sets[k][item] = true
+		items[k] = append(items[k], item)
+		production := parser.grammar.rules[item.rule].production
+		if item.dot < len(production) {
+			waiting[k][production[item.dot]] = append(waiting[k][production[item.dot]], item)
+		}
+	}
+
+	for _, ruleID := range parser.rulesOf[parser.grammar.start] {
+		add(0, earleyItem{ruleID, 0, 0})
+	}
+	for k := 0; k <= len(input); k++ {
+		for i := 0; i < len(items[k]); i++ {
+			item := items[k][i]
+			rule := parser.grammar.rules[item.rule]
+			switch {
+			case item.dot == len(rule.production):
+				for _, parent := range waiting[item.origin][rule.nonTerminal] {
+					add(k, earleyItem{parent.rule, parent.dot + 1, parent.origin})
+				}
+			case parser.rulesOf[rule.production[item.dot]] != nil:
+				for _, ruleID := range parser.rulesOf[rule.production[item.dot]] {
+					add(k, earleyItem{ruleID, 0, k})
+				}
+				if parser.nullable[rule.production[item.dot]] {
+					add(k, earleyItem{item.rule, item.dot + 1, item.origin})
+				}
+			case k < len(input) && input[k].Identifier == rule.production[item.dot]:
+				add(k+1, earleyItem{item.rule, item.dot + 1, item.origin})
+			}
+		}
+
+		if k < len(input) && len(items[k+1]) == 0 {
+			return nil, syntaxError(input[k], lines[k], parser.expected(items[k]))
+		}
+	}
+
+	builder := forestBuilder{parser: parser, tokens: input, sets: sets, nodes: make(map[forestKey]*ForestNode), splits: make(map[splitKey][][]*ForestNode)}
+	if !builder.spans(parser.grammar.start, 0, len(input)) {
+		return nil, syntaxError(lexer.Token{Identifier: "$", Value: "$"}, lines[len(input)], parser.expected(items[len(input)]))
+	}
+	return &Forest{Root: builder.node(parser.grammar.start, 0, len(input))}, nil
+}
+
+// Terminals after the dot of the items
+func (parser *EarleyParser) expected(items []earleyItem) []string {
+	expected := []string{}
+	for _, item := range items {
+		production := parser.grammar.rules[item.rule].production
+		if item.dot < len(production) && parser.rulesOf[production[item.dot]] == nil && contains(expected, production[item.dot]) == -1 {
+			expected = append(expected, production[item.dot])
+		}
+	}
+	return expected
+}
+
+// Whether the non terminal derives the tokens from start to end
+func (builder *forestBuilder) spans(nonTerminal string, start int, end int) bool {
+	for _, ruleID := range builder.parser.rulesOf[nonTerminal] {
+		if builder.sets[end][earleyItem{ruleID, len(builder.parser.grammar.rules[ruleID].production), start}] {
+			return true
+		}
+	}
+	return false
+}
+
+// Nodes are shared: every symbol and range has only one node. A cyclic grammar (A -> A) gives a cyclic forest
+func (builder *forestBuilder) node(symbol string, start int, end int) *ForestNode {
+	key := forestKey{symbol, start, end}
+	if node, ok := builder.nodes[key]; ok {
+		return node
+	}
+	node := &ForestNode{Symbol: symbol, Start: start, End: end}
+	builder.nodes[key] = node
+	if builder.parser.rulesOf[symbol] == nil {
+		node.Value = builder.tokens[start].Value
+		node.Line = builder.tokens[start].Line
+		node.Col = builder.tokens[start].Col
+		return node
+	}
+	for _, ruleID := range builder.parser.rulesOf[symbol] {
+		rule := builder.parser.grammar.rules[ruleID]
+		if !builder.sets[end][earleyItem{ruleID, len(rule.production), start}] {
+			continue
+		}
+		for _, children := range builder.split(ruleID, start, 0, start, end) {
+			node.Families = append(node.Families, ForestFamily{Rule: rule, Children: children})
+		}
+	}
+	return node
+}
+
+// All ways the symbols of the rule from dot on derive the tokens from start to end
+func (builder *forestBuilder) split(ruleID int, origin int, dot int, start int, end int) [][]*ForestNode {
+	key := splitKey{ruleID, origin, dot, start, end}
+	if splits, ok := builder.splits[key]; ok {
+		return splits
+	}
+	production := builder.parser.grammar.rules[ruleID].production
+	if dot == len(production) {
+		if start == end {
+			return [][]*ForestNode{{}}
+		}
+		return nil
+	}
+
+	splits := [][]*ForestNode{}
+	symbol := production[dot]
+	for middle := start; middle <= end; middle++ {
+		// The item after the symbol has to exist, else the symbol can not end here
+		if !builder.sets[middle][earleyItem{ruleID, dot + 1, origin}] {
+			continue
+		}
+		if builder.parser.rulesOf[symbol] == nil {
+			if middle != start+1 || builder.tokens[start].Identifier != symbol {
+				continue
+			}
+		} else if !builder.spans(symbol, start, middle) {
+			continue
+		}
+		for _, rest := range builder.split(ruleID, origin, dot+1, middle, end) {
+			splits = append(splits, append([]*ForestNode{builder.node(symbol, start, middle)}, rest...))
+		}
+	}
+	builder.splits[key] = splits
+	return splits
+}
+
+// Whether there is more than one way to derive the tokens of the node
+func (node *ForestNode) Ambiguous() bool {
+	return len(node.Families) > 1
+}
+
+// The ambiguous nodes of the forest, ordered by their position
+func (forest *Forest) Ambiguities() []*ForestNode {
+	ambiguous := []*ForestNode{}
+	visited := make(map[*ForestNode]bool)
+	var visit func(node *ForestNode)
+	visit = func(node *ForestNode) {
+		if visited[node] {
+			return
+		}
+		visited[node] = true
+		if node.Ambiguous() {
+			ambiguous = append(ambiguous, node)
+		}
+		for _, family := range node.Families {
+			for _, child := range family.Children {
+				visit(child)
+			}
+		}
+	}
+	visit(forest.Root)
+	slices.SortStableFunc(ambiguous, func(a *ForestNode, b *ForestNode) int {
+		if a.Start != b.Start {
+			return a.Start - b.Start
+		}
+		return b.End - a.End
+	})
+	return ambiguous
+}
+
+// At most limit parse trees of the forest. Cycles are not followed, so every tree is finite
+func (forest *Forest) Trees(limit int) []ParseTree {
+	return forestTrees(forest.Root, make(map[*ForestNode]bool), limit)
+}
+
+func forestTrees(node *ForestNode, onPath map[*ForestNode]bool, limit int) []ParseTree {
+	if node.Families == nil {
+		return []ParseTree{{Leaf: ParseLeaf{Name: node.Symbol, Value: node.Value, Line: node.Line, Col: node.Col}, Branches: []ParseTree{}}}
+	}
+	onPath[node] = true
+	defer delete(onPath, node)
+
+	trees := []ParseTree{}
+	for _, family := range node.Families {
+		// Every combination of the trees of the children
+		combinations := [][]ParseTree{{}}
+		for _, child := range family.Children {
+			if onPath[child] {
+				combinations = nil
+				break
+			}
+			childTrees := forestTrees(child, onPath, limit)
+			next := [][]ParseTree{}
+			for _, combination := range combinations {
+				for _, childTree := range childTrees {
+					if len(next) == limit {
+						break
+					}
+					next = append(next, append(slices.Clone(combination), childTree))
+				}
+			}
+			combinations = next
+		}
+		for _, combination := range combinations {
+			if len(trees) == limit {
+				return trees
+			}
+			line, col := firstPosition(combination)
+			trees = append(trees, ParseTree{Leaf: ParseLeaf{Name: node.Symbol, Value: 0, Line: line, Col: col}, Branches: combination})
+		}
+	}
+	return trees
+}
@@ -0,0 +1,89 @@
+package parser
+
+import (
+	"iter"
+	"slices"
+	"strings"
+)
+
+// The sentences of the grammar with at most maxLen terminals, shortest first and sorted among the same length,
+// e.g. for test inputs. Their number grows exponentially with the length, so keep maxLen small
+func (grammar *Grammar) Sentences(maxLen int) iter.Seq[[]string] {
+	return func(yield func([]string) bool) {
+		// Non terminal -> the strings of each length it derives, keyed by the terminals joined with a 0 byte
+		derived := make(map[string][]map[string][]string)
+		for _, nonTerminal := range grammar.nonTerminals {
+			derived[nonTerminal] = make([]map[string][]string, maxLen+1)
+		}
+		for length := 0; length <= maxLen; length++ {
+			grammar.deriveLength(derived, length)
+			sentences := [][]string{}
+			for _, sentence := range derived[grammar.start][length] {
+				sentences = append(sentences, sentence)
+			}
+			slices.SortFunc(sentences, slices.Compare)
+			for _, sentence := range sentences {
+				if !yield(sentence) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Fills in the strings of the length for every non terminal. The shorter ones are done already,
+// the ones of the same length depend on each other through rules like A -> B or A -> B C with C nullable,
+// so it is a fixed point
+func (grammar *Grammar) deriveLength(derived map[string][]map[string][]string, length int) {
+	for _, nonTerminal := range grammar.nonTerminals {
+		derived[nonTerminal][length] = make(map[string][]string)
+	}
+	changed := true
+	for changed {
+		changed = false
+		for _, r := range grammar.rules {
+			for _, sentence := range grammar.deriveSequence(derived, r.production, length) {
+				key := strings.Join(sentence, "\x00")
+				if _, ok := derived[r.nonTerminal][length][key]; !ok {
+					derived[r.nonTerminal][length][key] = sentence
+					changed = true
+				}
+			}
+		}
+	}
+}
+
+// The strings of exactly the length the symbols derive, with what is known so far
+func (grammar *Grammar) deriveSequence(derived map[string][]map[string][]string, symbols []string, length int) [][]string {
+	if len(symbols) == 0 {
+		if length == 0 {
+			return [][]string{{}}
+		}
+		return nil
+	}
+	first, rest := symbols[0], symbols[1:]
+	if !grammar.isNonTerminal(first) {
+		if length == 0 {
+			return nil
+		}
+		sentences := [][]string{}
+		for _, sentence := range grammar.deriveSequence(derived, rest, length-1) {
+			sentences = append(sentences, append([]string{first}, sentence...))
+		}
+		return sentences
+	}
+	sentences := [][]string{}
+	for firstLength := 0; firstLength <= length; firstLength++ {
+		prefixes := derived[first][firstLength]
+		if len(prefixes) == 0 {
+			continue
+		}
+		suffixes := grammar.deriveSequence(derived, rest, length-firstLength)
+		for _, prefix := range prefixes {
+			for _, suffix := range suffixes {
+				sentences = append(sentences, append(slices.Clone(prefix), suffix...))
+			}
+		}
+	}
+	return sentences
+}
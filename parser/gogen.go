@@ -0,0 +1,332 @@
+package parser
+
+import (
+	"errors"
+	"go/format"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+/*
+Go source of an LALR(1) parser for a yacc grammar. The generated file has no imports and contains:
+	type Token struct { Kind string; Value any }
+	func Parse(tokens []Token) (any, error)
+	type SyntaxError, type SyntaxErrors
+Parse adds the "$" at the end itself. The value of a terminal is the Value of its token,
+the value of a non terminal is $$ of the rule it was reduced by. Parse returns the value of the start symbol.
+Syntax errors are recovered from with the error rules and %sync tokens of the grammar (see yacc.go), Parse returns
+all of them as SyntaxErrors.
+*/
+
+// $$ or $1, $2, ...
+var yaccValuePattern = regexp.MustCompile(`\$(\$|[0-9]+)`)
+
+// The LALR(1) table of the grammar, which GenerateGo writes out
+func (yacc *YaccGrammar) Table() *SLR_parsing_Table {
+	grammar := MakeGrammar(yacc.Grammar.rules, yacc.Grammar.start)
+	return grammar.CreateLALRParser()
+}
+
+// Generates the parser. The generator and the source are named in the "Code generated" comment.
+// The conflicts are resolved like for SLR and returned, so they can be reported
+func (yacc *YaccGrammar) GenerateGo(packageName string, source string, generator string) ([]byte, []SLRConflict, error) {
+	table := yacc.Table()
+	grammar := table.grammar
+	for _, terminal := range yacc.Sync {
+		if contains(grammar.terminals, terminal) == -1 {
+			return nil, nil, errors.New("Yacc Error: %sync " + terminal + " is not a terminal of the grammar")
+		}
+	}
+	states := table.stateCount()
+
+	var code strings.Builder
+	code.WriteString("// Code generated by " + generator + " from " + source + ". DO NOT EDIT.\n\n")
+	code.WriteString("package " + packageName + "\n\n")
+	if yacc.Prologue != "" {
+		code.WriteString(yacc.Prologue + "\n\n")
+	}
+
+	code.WriteString(`// Token given to Parse. Kind is a terminal of the grammar, Value is $n in the actions
+type Token struct {
+	Kind  string
+	Value any
+}
+
+// Returned by Parse if the tokens do not match the grammar
+type SyntaxError struct {
+	// Index of the token, len(tokens) for the end of the input
+	Position int
+	Found    string
+	Expected []string
+}
+
+func (err *SyntaxError) Error() string {
+	expected := ""
+	for i, e := range err.Expected {
+		if i > 0 {
+			expected += ", "
+		}
+		expected += e
+	}
+	return "Syntax Error. Unexpected: \"" + err.Found + "\" at token " + yyItoa(err.Position) + ". Expecting: " + expected
+}
+
+// All syntax errors of a parse. The parser recovers from errors with the error rules of the grammar or by
+// skipping to a %sync token, so there can be more than one. errors.As finds the first *SyntaxError
+type SyntaxErrors []*SyntaxError
+
+func (errs SyntaxErrors) Error() string {
+	message := ""
+	for i, err := range errs {
+		if i > 0 {
+			message += "\n"
+		}
+		message += err.Error()
+	}
+	return message
+}
+
+func (errs SyntaxErrors) Unwrap() []error {
+	unwrapped := []error{}
+	for _, err := range errs {
+		unwrapped = append(unwrapped, err)
+	}
+	return unwrapped
+}
+
+func yyItoa(i int) string {
+	if i == 0 {
+		return "0"
+	}
+	digits := ""
+	for ; i > 0; i /= 10 {
+		digits = string(rune('0'+i%10)) + digits
+	}
+	return digits
+}
+
+// kind: 1 shift, 2 reduce, 3 accept
+type yyAction struct {
+	kind  int
+	value int
+}
+
+`)
+
+	code.WriteString("var yyActions = []map[string]yyAction{\n")
+	for state := 0; state < states; state++ {
+		code.WriteString("\t{")
+		for i, terminal := range sortedKeys(table.actionTable[state]) {
+			if i > 0 {
+				code.WriteString(", ")
+			}
+			action := table.actionTable[state][terminal]
+			kind := map[string]string{"Shift": "1", "Reduce": "2", "Accept": "3"}[action.actionType]
+			code.WriteString(strconv.Quote(terminal) + ": {" + kind + ", " + strconv.Itoa(action.value) + "}")
+		}
+		code.WriteString("},\n")
+	}
+	code.WriteString("}\n\n")
+
+	code.WriteString("var yyGotos = []map[string]int{\n")
+	for state := 0; state < states; state++ {
+		code.WriteString("\t{")
+		for i, nonTerminal := range sortedKeys(table.gotoToTable[state]) {
+			if i > 0 {
+				code.WriteString(", ")
+			}
+			code.WriteString(strconv.Quote(nonTerminal) + ": " + strconv.Itoa(table.gotoToTable[state][nonTerminal].val))
+		}
+		code.WriteString("},\n")
+	}
+	code.WriteString("}\n\n")
+
+	code.WriteString("// Terminals which have an action, for the error message\nvar yyExpected = [][]string{\n")
+	for state := 0; state < states; state++ {
+		expected := []string{}
+		for _, terminal := range sortedKeys(table.actionTable[state]) {
+			if terminal != yaccError {
+				expected = append(expected, strconv.Quote(terminal))
+			}
+		}
+		code.WriteString("\t{" + strings.Join(expected, ", ") + "},\n")
+	}
+	code.WriteString("}\n\n")
+
+	code.WriteString("// Tokens to skip to after a syntax error which no error rule catches, from %sync\nvar yySync = map[string]bool{")
+	for i, terminal := range yacc.Sync {
+		if i > 0 {
+			code.WriteString(", ")
+		}
+		code.WriteString(strconv.Quote(terminal) + ": true")
+	}
+	code.WriteString("}\n\n")
+
+	code.WriteString("var yyRules = []struct {\n\tnonTerminal string\n\tlength      int\n}{\n")
+	for _, rule := range grammar.rules {
+		code.WriteString("\t{" + strconv.Quote(rule.nonTerminal) + ", " + strconv.Itoa(len(rule.production)) + "},\n")
+	}
+	code.WriteString("}\n\n")
+
+	code.WriteString(`// Parses the tokens and runs the actions of the rules, returns the value of the start symbol.
+// The error is SyntaxErrors. The value is nil if the parser could not recover from an error
+func Parse(tokens []Token) (any, error) {
+	tokens = append(tokens[:len(tokens):len(tokens)], Token{Kind: "$"})
+	states := []int{0}
+	values := []any{}
+	errs := SyntaxErrors{}
+	// Tokens to shift after a recovery until errors are reported again, like yacc
+	quiet := 0
+	position := 0
+	for {
+		token := tokens[position]
+		state := states[len(states)-1]
+		action, ok := yyActions[state][token.Kind]
+		if !ok {
+			if quiet == 3 {
+				// Nothing was shifted since the last recovery, so the token is dropped
+				if token.Kind == "$" {
+					return nil, errs
+				}
+				position++
+				continue
+			}
+			if quiet == 0 {
+				errs = append(errs, &SyntaxError{Position: position, Found: token.Kind, Expected: yyExpected[state]})
+			}
+			if states, values, position, ok = yyRecover(states, values, tokens, position); !ok {
+				return nil, errs
+			}
+			quiet = 3
+			continue
+		}
+		switch action.kind {
+		case 1:
+			states = append(states, action.value)
+			values = append(values, token.Value)
+			position++
+			if quiet > 0 {
+				quiet--
+			}
+		case 2:
+			rule := yyRules[action.value]
+			yyDollar := append([]any{}, values[len(values)-rule.length:]...)
+			states = states[:len(states)-rule.length]
+			values = values[:len(values)-rule.length]
+			states = append(states, yyGotos[states[len(states)-1]][rule.nonTerminal])
+			values = append(values, yyReduce(action.value, yyDollar))
+		case 3:
+			if len(errs) > 0 {
+				return values[len(values)-1], errs
+			}
+			return values[len(values)-1], nil
+		}
+	}
+}
+
+// With an error rule: pops states until one shifts error, shifts it and skips the tokens which can not come next.
+// Else skips past the next %sync token and pops states until one can go on with the token after it.
+// false if neither works
+func yyRecover(states []int, values []any, tokens []Token, position int) ([]int, []any, int, bool) {
+	for i := len(states) - 1; i >= 0; i-- {
+		action, ok := yyActions[states[i]]["error"]
+		if !ok || action.kind != 1 {
+			continue
+		}
+		states = append(states[:i+1], action.value)
+		values = append(values[:i], nil)
+		for tokens[position].Kind != "$" {
+			if _, ok := yyActions[action.value][tokens[position].Kind]; ok {
+				break
+			}
+			position++
+		}
+		return states, values, position, true
+	}
+	for len(yySync) > 0 {
+		for !yySync[tokens[position].Kind] {
+			if tokens[position].Kind == "$" {
+				return states, values, position, false
+			}
+			position++
+		}
+		position++
+		for i := len(states) - 1; i >= 0; i-- {
+			if _, ok := yyActions[states[i]][tokens[position].Kind]; ok {
+				return states[:i+1], values[:i], position, true
+			}
+		}
+	}
+	return states, values, position, false
+}
+
+// Runs the action of the rule. yyDollar are the values of the symbols of the rule
+func yyReduce(rule int, yyDollar []any) any {
+	var yyVal any
+	if len(yyDollar) > 0 {
+		yyVal = yyDollar[0]
+	}
+	switch rule {
+`)
+	for ruleID, action := range yacc.Actions {
+		if action == "" {
+			continue
+		}
+		rule := grammar.rules[ruleID]
+		action, err := substituteYaccValues(action, len(rule.production))
+		if err != nil {
+			return nil, nil, errors.New("Yacc Error in the action of " + rule.String() + ": " + err.Error())
+		}
+		code.WriteString("\tcase " + strconv.Itoa(ruleID) + ":\n\t\t// " + rule.String() + "\n\t\t" + action + "\n")
+	}
+	code.WriteString("\t}\n\treturn yyVal\n}\n")
+
+	if yacc.Epilogue != "" {
+		code.WriteString("\n" + yacc.Epilogue + "\n")
+	}
+
+	formatted, err := format.Source([]byte(code.String()))
+	if err != nil {
+		return nil, nil, errors.New("Yacc Error: Generated code is not valid Go, likely because of an action: " + err.Error())
+	}
+	return formatted, table.Conflicts(), nil
+}
+
+// $$ -> yyVal, $n -> yyDollar[n-1]
+func substituteYaccValues(action string, length int) (string, error) {
+	var err error
+	substituted := yaccValuePattern.ReplaceAllStringFunc(action, func(value string) string {
+		if value == "$$" {
+			return "yyVal"
+		}
+		n, _ := strconv.Atoi(value[1:])
+		if n < 1 || n > length {
+			err = errors.New(value + " is not a symbol of the rule")
+		}
+		return "yyDollar[" + strconv.Itoa(n-1) + "]"
+	})
+	return substituted, err
+}
+
+// Number of states, the states are numbered from 0
+func (table *SLR_parsing_Table) stateCount() int {
+	count := 0
+	for state := range table.actionTable {
+		count = max(count, state+1)
+	}
+	for state := range table.gotoToTable {
+		count = max(count, state+1)
+	}
+	return count
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
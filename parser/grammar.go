@@ -1,7 +1,8 @@
 package parser
 
 import (
-	"unicode"
+	"os"
+	"path/filepath"
 )
 
 type Grammar struct {
@@ -11,6 +12,10 @@ type Grammar struct {
 	rules        []Rule
 	follow       map[string][]string
 	closure      map[string][]Rule
+	// Of the operators of collapsed precedence chains, see CollapsePrecedence
+	precedence map[string]precedence
+	// Doc comments of the non terminals, from the comments in front of their rules
+	docs map[string]string
 }
 
 type Rule struct {
@@ -39,124 +44,167 @@ func MakeGrammar(rules []Rule, start string) *Grammar {
 	newGrammar := new(Grammar)
 	newGrammar.start = start
 	newGrammar.rules = rules
-	// Add Terminals/ Non terminals
+	// Add Terminals/ Non terminals. Every symbol with a rule is a non terminal
 	for _, r := range rules {
 		if contains(newGrammar.nonTerminals, r.nonTerminal) == -1 {
 			newGrammar.nonTerminals = append(newGrammar.nonTerminals, r.nonTerminal)
 		}
+	}
+	for _, r := range rules {
 		for _, s := range r.production {
-			if isNT(s) {
-				if contains(newGrammar.nonTerminals, s) == -1 {
-					newGrammar.nonTerminals = append(newGrammar.nonTerminals, s)
-				}
-			} else {
-				if contains(newGrammar.terminals, s) == -1 {
-					newGrammar.terminals = append(newGrammar.terminals, s)
-				}
+			if contains(newGrammar.nonTerminals, s) == -1 && contains(newGrammar.terminals, s) == -1 {
+				newGrammar.terminals = append(newGrammar.terminals, s)
 			}
 		}
 	}
 	return newGrammar
 }
 
-func isNT(input string) bool {
-	for _, r := range []rune(input) {
-		if !unicode.IsUpper(r) {
-			return false
+// Reads a grammar file by its extension: .y yacc (yacc.go), .sexpr S-expressions (sexpr.go), everything else BNF (bnf.go)
+func ReadGrammarFile(path string) (*Grammar, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch filepath.Ext(path) {
+	case ".y", ".cspec":
+		yacc, err := ParseYacc(string(source))
+		if err != nil {
+			return nil, err
 		}
+		return yacc.Grammar, nil
+	case ".sexpr":
+		return ParseSExpr(string(source))
 	}
-	return true
+	return ParseBNF(string(source))
 }
 
-func (grammar *Grammar) FIRST() map[string][]string {
-	firstMap := make(map[string][]string)
-	grammar.firstrecursive(grammar.start, firstMap)
-	return firstMap
+// Sets the doc comment of the non terminal, see Doc
+func (grammar *Grammar) SetDoc(nonTerminal string, doc string) {
+	if grammar.docs == nil {
+		grammar.docs = make(map[string]string)
+	}
+	grammar.docs[nonTerminal] = doc
+}
+
+// The doc comment of the non terminal, "" if it has none. ParseBNF and ParseYacc take the comment lines
+// right in front of a rule, the documentation generator (doc.go) shows them
+func (grammar *Grammar) Doc(nonTerminal string) string {
+	return grammar.docs[nonTerminal]
+}
+
+// Start symbol, S after Augment
+func (grammar *Grammar) Start() string {
+	return grammar.start
+}
+
+// Non terminals which can derive the empty word
+func (grammar *Grammar) Nullable() map[string]bool {
+	nullable := make(map[string]bool)
+	changed := true
+	for changed {
+		changed = false
+		for _, r := range grammar.rules {
+			if nullable[r.nonTerminal] {
+				continue
+			}
+			allNullable := true
+			for _, s := range r.production {
+				if !nullable[s] {
+					allNullable = false
+					break
+				}
+			}
+			if allNullable {
+				nullable[r.nonTerminal] = true
+				changed = true
+			}
+		}
+	}
+	return nullable
 }
 
-// Only works without epsilon and only specific kinds of left recursion ~ only with loop length == 1 :))
-func (grammar *Grammar) firstrecursive(input string, firstMap map[string][]string) {
-	_, ok := firstMap[input]
-	if !ok {
-		firstMap[input] = []string{}
-	} else {
-		return
+// Calculated as fixed point, so works with epsilon rules (empty productions) and any kind of left recursion.
+// The empty word is not part of the sets, use Nullable for that
+func (grammar *Grammar) FIRST() map[string][]string {
+	nullable := grammar.Nullable()
+	firstMap := make(map[string][]string)
+	for _, nt := range grammar.nonTerminals {
+		firstMap[nt] = []string{}
 	}
-	for _, r := range grammar.rules {
-		if r.nonTerminal == input {
-
-			if isNT(r.production[0]) {
-				grammar.firstrecursive(r.production[0], firstMap)
-				for _, s := range firstMap[r.production[0]] {
-					if contains(firstMap[input], s) == -1 {
-						firstMap[input] = append(firstMap[input], s)
+	changed := true
+	for changed {
+		changed = false
+		for _, r := range grammar.rules {
+			for _, s := range r.production {
+				for _, terminal := range grammar.firstOfSymbol(s, firstMap) {
+					if contains(firstMap[r.nonTerminal], terminal) == -1 {
+						firstMap[r.nonTerminal] = append(firstMap[r.nonTerminal], terminal)
+						changed = true
 					}
 				}
-			} else {
-				if contains(firstMap[input], r.production[0]) == -1 {
-					firstMap[input] = append(firstMap[input], r.production[0])
+				if !nullable[s] {
+					break
 				}
 			}
 		}
-		for _, p := range r.production {
-			if isNT(p) {
-				if p != r.nonTerminal {
-					grammar.firstrecursive(p, firstMap)
-				}
+	}
+	return firstMap
+}
+
+// FIRST of a sequence of symbols, and whether the whole sequence can derive the empty word
+func (grammar *Grammar) FirstOfSequence(symbols []string, first map[string][]string, nullable map[string]bool) ([]string, bool) {
+	firstSet := []string{}
+	for _, s := range symbols {
+		for _, terminal := range grammar.firstOfSymbol(s, first) {
+			if contains(firstSet, terminal) == -1 {
+				firstSet = append(firstSet, terminal)
 			}
 		}
+		if !nullable[s] {
+			return firstSet, false
+		}
 	}
+	return firstSet, true
+}
+
+func (grammar *Grammar) firstOfSymbol(symbol string, first map[string][]string) []string {
+	if contains(grammar.nonTerminals, symbol) != -1 {
+		return first[symbol]
+	}
+	return []string{symbol}
 }
 
 func (grammar *Grammar) FOLLOW(first map[string][]string) map[string][]string {
+	nullable := grammar.Nullable()
 	followMap := make(map[string][]string)
-	grammar.recursiveFollow("START", followMap, first)
 	for _, nt := range grammar.nonTerminals {
-		grammar.recursiveFollow(nt, followMap, first)
+		followMap[nt] = []string{}
 	}
-	return followMap
-}
+	followMap[grammar.start] = []string{"$"}
 
-// Right recursion
-// Normal follow one jump upwards
-func (grammar *Grammar) recursiveFollow(input string, followMap map[string][]string, first map[string][]string) {
-	if input == "S" {
-		followMap["S"] = []string{"$"}
-		return
-	}
-	_, ok := followMap[input]
-	if !ok {
-		followMap[input] = []string{}
-	} else {
-		return
-	}
-	for _, rule := range grammar.rules {
-		for i, symbol := range rule.production {
-			if symbol == input {
-				if i == len(rule.production)-1 {
-					if rule.nonTerminal != input {
-						grammar.recursiveFollow(rule.nonTerminal, followMap, first)
-					}
-					for _, newEntry := range followMap[rule.nonTerminal] {
-						if contains(followMap[input], newEntry) == -1 {
-							followMap[input] = append(followMap[input], newEntry)
-						}
-					}
-				} else {
-					next := rule.production[i+1]
-					if isNT(next) {
-						for _, newEntry := range first[next] {
-							if contains(followMap[input], newEntry) == -1 {
-								followMap[input] = append(followMap[input], newEntry)
-							}
-						}
-					} else {
-						if contains(followMap[input], next) == -1 {
-							followMap[input] = append(followMap[input], next)
-						}
+	changed := true
+	for changed {
+		changed = false
+		for _, rule := range grammar.rules {
+			for i, symbol := range rule.production {
+				if contains(grammar.nonTerminals, symbol) == -1 {
+					continue
+				}
+				// Everything that can start the rest of the rule follows the symbol.
+				// If the rest can be empty, everything following the rule follows the symbol as well
+				newEntries, restNullable := grammar.FirstOfSequence(rule.production[i+1:], first, nullable)
+				if restNullable {
+					newEntries = append(newEntries, followMap[rule.nonTerminal]...)
+				}
+				for _, newEntry := range newEntries {
+					if contains(followMap[symbol], newEntry) == -1 {
+						followMap[symbol] = append(followMap[symbol], newEntry)
+						changed = true
 					}
 				}
 			}
 		}
 	}
+	return followMap
 }
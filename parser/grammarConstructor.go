@@ -28,8 +28,9 @@ func testGrammar() []Rule {
 		MakeRule("LITERAL", []string{"stringliteral"}),
 		MakeRule("LITERAL", []string{"boolliteral"}),
 		MakeRule("LITERAL", []string{"NUMLITERAL"}),
+		MakeRule("LITERAL", []string{"interpolatedstring"}),
 		MakeRule("NUMLITERAL", []string{"intliteral"}),
-		MakeRule("NUMLITERAL", []string{"intliteral", ".", "intliteral"}),
+		MakeRule("NUMLITERAL", []string{"doubleliteral"}),
 
 		MakeRule("START", []string{"USINGBLOCK"}),
 		MakeRule("USINGBLOCK", []string{"using", "name", ";", "USINGBLOCK"}),
@@ -40,6 +41,7 @@ func testGrammar() []Rule {
 		MakeRule("FUNCBLOCK", []string{"}"}),
 
 		MakeRule("FUNC", []string{"static", "RETURNTYPE", "name", "(", "INPUTBLOCK", "{", "STATEMENTBLOCK"}),
+		MakeRule("FUNC", []string{"static", "extern", "RETURNTYPE", "name", "(", "INPUTBLOCK", ";"}),
 		MakeRule("STATEMENTBLOCK", []string{"}"}),
 		MakeRule("STATEMENTBLOCK", []string{"FUNCCALL", ";", "STATEMENTBLOCK"}),
 		MakeRule("STATEMENTBLOCK", []string{"RETURN", "STATEMENTBLOCK"}),
@@ -52,7 +54,7 @@ func testGrammar() []Rule {
 		MakeRule("INPUTBLOCK", []string{"string", "[", "]", "name", ")"}),
 		MakeRule("INPUTBLOCK", []string{"INPUTSTART"}),
 		MakeRule("INPUTSTART", []string{"TYPE", "name", "INPUTCONTINUED"}),
-		MakeRule("INPUTCONTINUED", []string{",", "TYPE", "name"}),
+		MakeRule("INPUTCONTINUED", []string{",", "TYPE", "name", "INPUTCONTINUED"}),
 		MakeRule("INPUTCONTINUED", []string{")"}),
 
 		MakeRule("ARGBLOCK", []string{")"}),
@@ -92,6 +94,43 @@ func testGrammar() []Rule {
 	return rules
 }
 
+// Doc comments of the non terminals of the test grammar, for the reference documentation
+var testGrammarDocs = map[string]string{
+	"START":               "A program is one file: the usings, then one namespace with one class.",
+	"USINGBLOCK":          "Any number of using directives in front of the namespace, e.g. using System;",
+	"NAMESPACE":           "The namespace around the class.",
+	"CLASS":               "The class with the functions of the program. Main is called when the program starts.",
+	"FUNCBLOCK":           "The functions of the class up to the closing brace of the class.",
+	"FUNC":                "A static function. Functions marked extern have no body, the host implements them.",
+	"RETURNTYPE":          "void for functions without a value, else the type of the value.",
+	"TYPE":                "The built in types.",
+	"INPUTBLOCK":          "The parameters of a function up to the closing parenthesis. Main may take string[] args.",
+	"STATEMENTBLOCK":      "The statements of a block up to its closing brace.",
+	"FUNCCALL":            "A call of a function of the class or of a built in function like Console.WriteLine.",
+	"ARGBLOCK":            "The arguments of a call up to the closing parenthesis.",
+	"RETURN":              "Leaves the function, with the value if the function has a return type.",
+	"VARIABLEDECLARATION": "Declares a variable, with or without a first value.",
+	"VARASSIGN":           "Assigns a new value to a declared variable.",
+	"IF":                  "Runs the block if the condition is true, else the else block if there is one.",
+	"WHILE":               "Runs the block as long as the condition is true.",
+	"EXPRESSION":          "Comparisons and logical operators, they bind weakest. All binary operators are left associative.",
+	"TERM":                "Addition and subtraction.",
+	"FACTOR":              "Multiplication, division and remainder.",
+	"PRIMARY":             "Operands: calls, literals, variables, unary + and - and parentheses.",
+	"LITERAL":             "Strings, booleans, numbers and interpolated strings like $\"x is {x}\".",
+}
+
+// The grammar of the language with its doc comments, not augmented
+func Language(test bool) *Grammar {
+	grammar := MakeGrammar(defGrammar(test), "START")
+	if test {
+		for nonTerminal, doc := range testGrammarDocs {
+			grammar.SetDoc(nonTerminal, doc)
+		}
+	}
+	return grammar
+}
+
 func compilerGrammar() []Rule {
 	return []Rule{}
 }
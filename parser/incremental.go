@@ -0,0 +1,282 @@
+package parser
+
+import (
+	"compiler/lexer"
+	"context"
+	"errors"
+	"sort"
+	"strings"
+)
+
+/*
+Incremental parsing for editors, on top of lexer.Document. Every subtree of the last parse remembers the state the
+parser was in in front of it, its tokens and the token after it. After an edit a subtree is reused as a whole when
+	- its tokens are all in front of the edit or all behind it, so they did not change,
+	- the parser is in the same state in front of it, and
+	- the token after it is the same terminal.
+The parser would then do exactly the same steps for it again, so it pushes the subtree with the goto of its non
+terminal instead. The outermost subtree which fits is taken, found by walking down the old tree.
+
+	parser := parser.MakeIncrementalParser(table)
+	tree, err := parser.Parse(document.Tokens())
+	change := document.Edit(start, end, text)
+	tree, err = parser.Edit(tree, change, document.Tokens())
+
+The trees are the same as Parse of the table builds. Subtrees behind an edit which adds or removes lines are copied
+with the new line numbers.
+*/
+
+type IncrementalParser struct {
+	table *SLR_parsing_Table
+}
+
+// Result of an incremental parse, to be handed to the next Edit
+type IncrementalTree struct {
+	Tree ParseTree
+	// Number of terminals in reused subtrees, for statistics
+	Reused int
+	// The tokens of the parse without LINE tokens, ending with "$"
+	terminals []lexer.Token
+	root      *reuseNode
+}
+
+// A subtree with what the parser needs to know to reuse it
+type reuseNode struct {
+	tree ParseTree
+	// The terminals of the subtree are terminals[start:end] of the parse
+	start int
+	end   int
+	// The state in front of the subtree and the terminal after it
+	state    int
+	follow   string
+	children []*reuseNode
+}
+
+func MakeIncrementalParser(table *SLR_parsing_Table) *IncrementalParser {
+	parser := new(IncrementalParser)
+	parser.table = table
+	return parser
+}
+
+// Parses the tokens without reusing anything. The tokens have to end with "$"
+func (parser *IncrementalParser) Parse(tokens []lexer.Token) (*IncrementalTree, error) {
+	return parser.parse(terminalsOf(tokens), nil)
+}
+
+// Parses the tokens after the change of a document and reuses the subtrees of the old tree which did not change.
+// Without an old tree, e.g. after a syntax error, it is Parse
+func (parser *IncrementalParser) Edit(old *IncrementalTree, change lexer.Change, tokens []lexer.Token) (*IncrementalTree, error) {
+	terminals := terminalsOf(tokens)
+	if old == nil {
+		return parser.parse(terminals, nil)
+	}
+	// The terminals in front of the changed lines and behind them, "$" is never reused
+	prefix := linesBefore(terminals, change.First)
+	suffix := len(terminals) - 1 - linesBefore(terminals, change.Last+1)
+	if oldPrefix := linesBefore(old.terminals, change.First); oldPrefix != prefix {
+		// The change does not fit the old tree, parse everything
+		return parser.parse(terminals, nil)
+	}
+	reuse := &reuseCursor{old: old, prefix: prefix, suffixStart: len(old.terminals) - 1 - suffix, shift: len(terminals) - len(old.terminals), delta: change.Delta}
+	if reuse.suffixStart < prefix {
+		return parser.parse(terminals, nil)
+	}
+	return parser.parse(terminals, reuse)
+}
+
+func (parser *IncrementalParser) parse(terminals []lexer.Token, reuse *reuseCursor) (*IncrementalTree, error) {
+	table := parser.table
+	type entry struct {
+		state int
+		node  *reuseNode
+	}
+	stack := []entry{{state: 0}}
+	result := &IncrementalTree{terminals: terminals}
+
+	for position := 0; ; {
+		state := stack[len(stack)-1].state
+		token := terminals[position]
+		if reuse != nil {
+			if node := reuse.find(position, state, terminals); node != nil {
+				next, err := table.GetGoto(state, node.tree.Leaf.Name)
+				if err == nil {
+					stack = append(stack, entry{state: next.val, node: node})
+					result.Reused += node.end - node.start
+					position = node.end
+					continue
+				}
+			}
+		}
+
+		action, err := table.GetAction(state, token.Identifier)
+		if err != nil {
+			return nil, errors.New(strings.TrimSpace(syntaxDiagnostic(token, token.Line, table.getNextExpectedTokens(state), "").String()))
+		}
+		switch action.actionType {
+		case "Shift":
+			leaf, err := parser.leaf(token)
+			if err != nil {
+				return nil, err
+			}
+			node := &reuseNode{tree: leaf, start: position, end: position + 1, state: state}
+			stack = append(stack, entry{state: action.value, node: node})
+			position++
+		case "Reduce":
+			rule := table.grammar.rules[action.value]
+			popped := stack[len(stack)-len(rule.production):]
+			stack = stack[:len(stack)-len(rule.production)]
+			node := &reuseNode{start: position, end: position, state: stack[len(stack)-1].state, follow: token.Identifier}
+			branches := []ParseTree{}
+			for i, e := range popped {
+				if i == 0 {
+					node.start = e.node.start
+				}
+				branches = append(branches, e.node.tree)
+				node.children = append(node.children, e.node)
+			}
+			node.tree = ParseTree{Leaf: ParseLeaf{Name: rule.nonTerminal, Value: 0}, Branches: branches}
+			node.tree.Leaf.Line, node.tree.Leaf.Col = firstPosition(branches)
+			next, err := table.GetGoto(node.state, rule.nonTerminal)
+			if err != nil {
+				return nil, errors.New(strings.TrimSpace(syntaxDiagnostic(token, token.Line, table.getNextExpectedTokens(state), "").String()))
+			}
+			stack = append(stack, entry{state: next.val, node: node})
+		case "Accept":
+			// Like Parse: the tree of the start symbol, the augmented start rule is never reduced
+			result.root = stack[1].node
+			result.Tree = result.root.tree
+			return result, nil
+		}
+	}
+}
+
+// The leaf of a token, interpolated strings get the trees of their expressions like in Parse
+func (parser *IncrementalParser) leaf(token lexer.Token) (ParseTree, error) {
+	if token.Identifier == "interpolatedstring" {
+		var out strings.Builder
+		segments, ok := parseInterpolation(context.Background(), token.Value.([]lexer.StringSegment), token.Line, lexer.Options{Output: &out})
+		if !ok {
+			return ParseTree{}, errors.New(strings.TrimSpace(out.String()))
+		}
+		token.Value = segments
+	}
+	return ParseTree{Leaf: ParseLeaf{Name: token.Identifier, Value: token.Value, Line: token.Line, Col: token.Col, Trivia: token.Trivia}, Branches: []ParseTree{}}, nil
+}
+
+// Finds the subtrees of the old tree which can be reused at a position of the new parse
+type reuseCursor struct {
+	old *IncrementalTree
+	// Old terminals in front of prefix are unchanged, so are the ones from suffixStart on. These moved by shift
+	prefix      int
+	suffixStart int
+	shift       int
+	// Lines the terminals from suffixStart on moved by
+	delta int
+}
+
+// The outermost old subtree of a non terminal which starts at the position and can be reused in the state.
+// The subtrees behind the edit are moved to the new position
+func (reuse *reuseCursor) find(position int, state int, terminals []lexer.Token) *reuseNode {
+	oldPosition := position
+	behind := position >= reuse.suffixStart+reuse.shift
+	switch {
+	case position < reuse.prefix:
+	case behind:
+		oldPosition = position - reuse.shift
+	default:
+		return nil
+	}
+
+	node := reuse.old.root
+	for node != nil {
+		if node.start == oldPosition && node.end > node.start && len(node.children) > 0 && node.state == state {
+			fits := node.end <= reuse.prefix || (behind && node.start >= reuse.suffixStart)
+			if fits && terminals[node.end+position-oldPosition].Identifier == node.follow {
+				if !behind || (reuse.shift == 0 && reuse.delta == 0) {
+					return node
+				}
+				return node.moved(reuse.shift, reuse.delta)
+			}
+		}
+		// Down to the child with the position
+		var next *reuseNode
+		for _, child := range node.children {
+			if child.start <= oldPosition && oldPosition < child.end {
+				next = child
+				break
+			}
+		}
+		node = next
+	}
+	return nil
+}
+
+// A copy of the subtree for terminals which moved by shift and lines which moved by delta
+func (node *reuseNode) moved(shift int, delta int) *reuseNode {
+	moved := &reuseNode{start: node.start + shift, end: node.end + shift, state: node.state, follow: node.follow}
+	for _, child := range node.children {
+		moved.children = append(moved.children, child.moved(shift, delta))
+	}
+	moved.tree = node.tree
+	if delta != 0 {
+		moved.tree.Leaf.Line += delta
+		if len(moved.children) > 0 {
+			moved.tree.Branches = []ParseTree{}
+			for _, child := range moved.children {
+				moved.tree.Branches = append(moved.tree.Branches, child.tree)
+			}
+		} else {
+			moved.tree.Leaf.Value = movedValue(moved.tree.Leaf.Value, delta)
+		}
+	}
+	return moved
+}
+
+// The lines in the trees of interpolated strings move as well
+func movedValue(value any, delta int) any {
+	segments, ok := value.([]InterpolationSegment)
+	if !ok {
+		return value
+	}
+	moved := []InterpolationSegment{}
+	for _, segment := range segments {
+		if segment.Expression != nil {
+			tree := movedTree(*segment.Expression, delta)
+			segment.Expression = &tree
+		}
+		moved = append(moved, segment)
+	}
+	return moved
+}
+
+func movedTree(tree ParseTree, delta int) ParseTree {
+	if tree.Leaf.Line != 0 {
+		tree.Leaf.Line += delta
+	}
+	tree.Leaf.Value = movedValue(tree.Leaf.Value, delta)
+	branches := []ParseTree{}
+	for _, branch := range tree.Branches {
+		branches = append(branches, movedTree(branch, delta))
+	}
+	tree.Branches = branches
+	return tree
+}
+
+// The tokens without LINE tokens, with "$" at the end
+func terminalsOf(tokens []lexer.Token) []lexer.Token {
+	terminals := []lexer.Token{}
+	for _, token := range tokens {
+		if token.Identifier != "LINE" {
+			terminals = append(terminals, token)
+		}
+	}
+	if len(terminals) == 0 || terminals[len(terminals)-1].Identifier != "$" {
+		terminals = append(terminals, lexer.Token{Identifier: "$", Value: "$"})
+	}
+	return terminals
+}
+
+// Number of terminals in lines in front of the line, "$" not counted
+func linesBefore(terminals []lexer.Token, line int) int {
+	return sort.Search(len(terminals)-1, func(i int) bool { return terminals[i].Line >= line })
+}
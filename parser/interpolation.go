@@ -0,0 +1,59 @@
+package parser
+
+import (
+	"compiler/lexer"
+	"context"
+	"sync"
+)
+
+// Text or parsed expression inside an interpolated string
+type InterpolationSegment struct {
+	Text       string
+	Expression *ParseTree
+}
+
+// The embedded expressions are parsed with their own table, which uses EXPRESSION as start symbol.
+// Only built once the first interpolated string is found
+var expressionParser struct {
+	once    sync.Once
+	table   *SLR_parsing_Table
+	grammar *Grammar
+}
+
+func getExpressionParser() (*SLR_parsing_Table, *Grammar) {
+	expressionParser.once.Do(func() {
+		expressionParser.table, expressionParser.grammar = createParserFor(defGrammar(true), "EXPRESSION")
+	})
+	return expressionParser.table, expressionParser.grammar
+}
+
+// Parses every embedded expression of an interpolated string into its own parse tree
+func parseInterpolation(ctx context.Context, segments []lexer.StringSegment, linecount int, options lexer.Options) ([]InterpolationSegment, bool) {
+	parsedSegments := []InterpolationSegment{}
+	for _, segment := range segments {
+		if segment.Expression == nil {
+			parsedSegments = append(parsedSegments, InterpolationSegment{Text: segment.Text})
+			continue
+		}
+
+		table, grammar := getExpressionParser()
+		tokenChannel := make(chan lexer.Token)
+		go func() {
+			for _, token := range segment.Expression {
+				tokenChannel <- token
+			}
+			tokenChannel <- lexer.Token{Identifier: "$", Value: "$"}
+			close(tokenChannel)
+		}()
+
+		tree, ok := parseTokenStream(ctx, tokenChannel, table, grammar, linecount, options)
+		if !ok {
+			// Drain the channel so the sending go routine can finish
+			for range tokenChannel {
+			}
+			return nil, false
+		}
+		parsedSegments = append(parsedSegments, InterpolationSegment{Text: segment.Text, Expression: &tree})
+	}
+	return parsedSegments, true
+}
@@ -0,0 +1,131 @@
+package parser
+
+import (
+	"encoding/json"
+	"slices"
+	"strconv"
+)
+
+/*
+Machine readable report of a parsing table, for IDEs and CI checks: the rules, the LR(0) item sets with the
+lookaheads of their complete items, the transitions and actions of every state, and every conflict with the items
+which cause it. Rules are referred to by their index in "rules". Tables read with UnmarshalTable have no automaton,
+their states have no items and their conflicts only the rules which are reduced.
+
+	{"start": "S", "rules": [{"id": 0, "nonTerminal": "<E>", "production": ["<E>", "+", "<E>"], ...}],
+	 "states": [{"id": 3, "items": [{"rule": 0, "dot": 3, "text": "<E> -> <E> + <E> .", "lookaheads": ["+", "$"]}, ...],
+	             "transitions": {"+": 2}, "actions": {"$": "reduce 0", "+": "shift 2"}}],
+	 "conflicts": [{"state": 3, "symbol": "+", "kind": "shift/reduce", "resolution": "shift 2", "items": [...], "example": ["id", "+", "id", "+"]}]}
+*/
+
+type LRReport struct {
+	Start        string           `json:"start"`
+	Terminals    []string         `json:"terminals"`
+	NonTerminals []string         `json:"nonTerminals"`
+	Rules        []ReportRule     `json:"rules"`
+	States       []ReportState    `json:"states"`
+	Conflicts    []ReportConflict `json:"conflicts"`
+}
+
+type ReportRule struct {
+	ID          int      `json:"id"`
+	NonTerminal string   `json:"nonTerminal"`
+	Production  []string `json:"production"`
+	Text        string   `json:"text"`
+}
+
+type ReportItem struct {
+	Rule int    `json:"rule"`
+	Dot  int    `json:"dot"`
+	Text string `json:"text"`
+	// The terminals the rule is reduced on, only for items with the dot at the end
+	Lookaheads []string `json:"lookaheads,omitempty"`
+}
+
+type ReportState struct {
+	ID    int          `json:"id"`
+	Items []ReportItem `json:"items,omitempty"`
+	// The next state for every terminal and non terminal
+	Transitions map[string]int `json:"transitions"`
+	// "shift N", "reduce N" (a rule) or "accept" for every terminal, after the conflicts are resolved
+	Actions map[string]string `json:"actions"`
+}
+
+type ReportConflict struct {
+	State  int    `json:"state"`
+	Symbol string `json:"symbol"`
+	Kind   string `json:"kind"`
+	// The action in the table
+	Resolution string `json:"resolution"`
+	// The items of the state which shift Symbol or are reduced on it
+	Items []ReportItem `json:"items"`
+	// Input which runs into the conflict, see ConflictExample
+	Example []string `json:"example"`
+}
+
+func (table *SLR_parsing_Table) Report() *LRReport {
+	grammar := table.grammar
+	report := &LRReport{Start: grammar.start, Terminals: slices.Clone(grammar.terminals), NonTerminals: slices.Clone(grammar.nonTerminals),
+		Rules: []ReportRule{}, States: []ReportState{}, Conflicts: []ReportConflict{}}
+	for i, rule := range grammar.rules {
+		report.Rules = append(report.Rules, ReportRule{ID: i, NonTerminal: rule.nonTerminal, Production: slices.Clone(rule.production), Text: rule.String()})
+	}
+
+	for state := range table.stateCount() {
+		reportState := ReportState{ID: state, Transitions: make(map[string]int), Actions: make(map[string]string)}
+		for terminal, action := range table.actionTable[state] {
+			switch action.actionType {
+			case "Shift":
+				reportState.Transitions[terminal] = action.value
+				reportState.Actions[terminal] = "shift " + strconv.Itoa(action.value)
+			case "Reduce":
+				reportState.Actions[terminal] = "reduce " + strconv.Itoa(action.value)
+			default:
+				reportState.Actions[terminal] = "accept"
+			}
+		}
+		for nonTerminal, goTo := range table.gotoToTable[state] {
+			reportState.Transitions[nonTerminal] = goTo.val
+		}
+		if table.automata != nil {
+			for _, item := range table.automata.states[state].rules {
+				reportState.Items = append(reportState.Items, table.reportItem(state, item))
+			}
+		}
+		report.States = append(report.States, reportState)
+	}
+
+	for _, conflict := range table.Conflicts() {
+		action := table.actionTable[conflict.State][conflict.Symbol]
+		reportConflict := ReportConflict{State: conflict.State, Symbol: conflict.Symbol, Kind: conflict.Kind,
+			Resolution: report.States[conflict.State].Actions[conflict.Symbol], Items: []ReportItem{}, Example: slices.Clone(conflict.Example)}
+		if action.actionType == "Shift" && table.automata != nil {
+			for _, item := range table.automata.states[conflict.State].rules {
+				if item.dot < len(item.rule.production) && item.rule.production[item.dot] == conflict.Symbol {
+					reportConflict.Items = append(reportConflict.Items, table.reportItem(conflict.State, item))
+				}
+			}
+		}
+		for _, rule := range conflict.Rules {
+			reportConflict.Items = append(reportConflict.Items, table.reportItem(conflict.State, ItemRule{rule, len(rule.production)}))
+		}
+		report.Conflicts = append(report.Conflicts, reportConflict)
+	}
+	return report
+}
+
+func (table *SLR_parsing_Table) reportItem(state int, item ItemRule) ReportItem {
+	ruleID := detRuleId(table.grammar, item)
+	reportItem := ReportItem{Rule: ruleID, Dot: item.dot, Text: item.String()}
+	if item.dot == len(item.rule.production) {
+		lookaheads := table.lookaheads[state][ruleID]
+		// Sorted like the maps of the report, which encoding/json sorts by key
+		reportItem.Lookaheads = slices.Sorted(slices.Values(lookaheads))
+	}
+	return reportItem
+}
+
+func (report *LRReport) JSON() string {
+	encoded, _ := json.MarshalIndent(report, "", "  ")
+	return string(encoded) + "\n"
+}
@@ -0,0 +1,179 @@
+package parser
+
+/*
+LALR(1) parsing table:
+	Start with the LR(0) automaton, the same one as for SLR.
+	Lookaheads of the kernel items (dragon book, "Efficient Construction of LALR Parsing Tables"):
+		For every kernel item K of every state:
+			LR(1) closure of [K, #], # being a lookahead which is not a terminal
+			For every item [B -> γ.Xδ, a] in the closure:
+				a != #: a is a lookahead of B -> γX.δ in GoTo(state, X) (spontaneous)
+				a == #: the lookaheads of K are lookaheads of B -> γX.δ in GoTo(state, X) as well (propagated)
+		$ is the lookahead of the start item. Propagate until nothing changes.
+	Reduce by a rule only on its lookaheads, instead of on the whole FOLLOW of the non terminal.
+	Shift, goto and accept are the same as for SLR.
+*/
+
+// Can not be a terminal, the lexer never returns a 0 byte
+const propagateLookahead = "\x00#"
+
+type lalrItem struct {
+	rule int
+	dot  int
+}
+
+type lr1Item struct {
+	item      lalrItem
+	lookahead string
+}
+
+// Item in a state of the automaton
+type stateItem struct {
+	state int
+	item  lalrItem
+}
+
+// Augments the grammar and builds the LR(0) automaton and the LALR(1) table for it.
+// Accepts every SLR(1) grammar and some more. Conflicts are resolved like for SLR and can be looked at with Conflicts
+func (grammar *Grammar) CreateLALRParser() *SLR_parsing_Table {
+	grammar.Augment()
+	first := grammar.FIRST()
+	grammar.follow = grammar.FOLLOW(first)
+	grammar.CalcClosure()
+
+	automata := grammar.CreateSLRAutomata()
+	lookaheads := automata.lalrLookaheads(grammar, first)
+	return automata.createLALRTable(grammar, first, lookaheads)
+}
+
+func (automata *SLR_automata) lalrLookaheads(grammar *Grammar, first map[string][]string) map[stateItem][]string {
+	nullable := grammar.Nullable()
+	lookaheads := make(map[stateItem][]string)
+	propagates := make(map[stateItem][]stateItem)
+	// The items of propagates in the order they were found, the lookaheads are then in the same order in every run
+	sources := []stateItem{}
+
+	for _, state := range automata.states {
+		for _, kernel := range grammar.kernelItems(state) {
+			from := stateItem{state.id, kernel}
+			if grammar.rules[kernel.rule].nonTerminal == grammar.start {
+				lookaheads[from] = []string{"$"}
+			}
+			for _, closed := range grammar.closureLR1([]lr1Item{{kernel, propagateLookahead}}, first, nullable) {
+				production := grammar.rules[closed.item.rule].production
+				if closed.item.dot >= len(production) {
+					continue
+				}
+				to := stateItem{state.transitions[production[closed.item.dot]], lalrItem{closed.item.rule, closed.item.dot + 1}}
+				if closed.lookahead == propagateLookahead {
+					if propagates[from] == nil {
+						sources = append(sources, from)
+					}
+					propagates[from] = append(propagates[from], to)
+				} else if contains(lookaheads[to], closed.lookahead) == -1 {
+					lookaheads[to] = append(lookaheads[to], closed.lookahead)
+				}
+			}
+		}
+	}
+
+	changed := true
+	for changed {
+		changed = false
+		for _, from := range sources {
+			for _, to := range propagates[from] {
+				for _, lookahead := range lookaheads[from] {
+					if contains(lookaheads[to], lookahead) == -1 {
+						lookaheads[to] = append(lookaheads[to], lookahead)
+						changed = true
+					}
+				}
+			}
+		}
+	}
+	return lookaheads
+}
+
+// Items with the dot not at the start, and the start item. Every other item of the state comes from the closure
+func (grammar *Grammar) kernelItems(state *State) []lalrItem {
+	kernel := []lalrItem{}
+	for _, itemrule := range state.rules {
+		if itemrule.dot > 0 || itemrule.rule.nonTerminal == grammar.start {
+			kernel = append(kernel, lalrItem{detRuleId(grammar, itemrule), itemrule.dot})
+		}
+	}
+	return kernel
+}
+
+// For [A -> α.Bβ, a] add [B -> .γ, b] for every rule B -> γ and every b in FIRST(βa)
+func (grammar *Grammar) closureLR1(items []lr1Item, first map[string][]string, nullable map[string]bool) []lr1Item {
+	done := make(map[lr1Item]bool)
+	for _, item := range items {
+		done[item] = true
+	}
+	for i := 0; i < len(items); i++ {
+		production := grammar.rules[items[i].item.rule].production
+		dot := items[i].item.dot
+		if dot >= len(production) || contains(grammar.nonTerminals, production[dot]) == -1 {
+			continue
+		}
+		followers, restNullable := grammar.FirstOfSequence(production[dot+1:], first, nullable)
+		if restNullable {
+			followers = append(followers, items[i].lookahead)
+		}
+		for ruleID, rule := range grammar.rules {
+			if rule.nonTerminal != production[dot] {
+				continue
+			}
+			for _, lookahead := range followers {
+				newItem := lr1Item{lalrItem{ruleID, 0}, lookahead}
+				if !done[newItem] {
+					done[newItem] = true
+					items = append(items, newItem)
+				}
+			}
+		}
+	}
+	return items
+}
+
+func (automata *SLR_automata) createLALRTable(grammar *Grammar, first map[string][]string, lookaheads map[stateItem][]string) *SLR_parsing_Table {
+	table := makeSlrParsingTable()
+	table.grammar = grammar
+	table.automata = automata
+	nullable := grammar.Nullable()
+
+	for _, state := range automata.states {
+		// Lookaheads of the items from the closure, epsilon rules are reduced from there
+		items := []lr1Item{}
+		for _, kernel := range grammar.kernelItems(state) {
+			for _, lookahead := range lookaheads[stateItem{state.id, kernel}] {
+				items = append(items, lr1Item{kernel, lookahead})
+			}
+		}
+		for _, item := range grammar.closureLR1(items, first, nullable) {
+			rule := grammar.rules[item.item.rule]
+			if item.item.dot < len(rule.production) {
+				continue
+			}
+			if item.lookahead == "$" && rule.nonTerminal == grammar.start {
+				table.AddAction(state.id, "$", "Accept", 0)
+			} else {
+				table.AddAction(state.id, item.lookahead, "Reduce", item.item.rule)
+			}
+		}
+
+		for _, itemrule := range state.rules {
+			if itemrule.dot >= len(itemrule.rule.production) {
+				continue
+			}
+			afterdot := itemrule.rule.production[itemrule.dot]
+			if contains(grammar.nonTerminals, afterdot) != -1 {
+				table.AddGoTo(state.id, afterdot, state.transitions[afterdot])
+			} else {
+				table.AddAction(state.id, afterdot, "Shift", state.transitions[afterdot])
+			}
+		}
+	}
+	return table
+}
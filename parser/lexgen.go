@@ -0,0 +1,132 @@
+package parser
+
+import (
+	"errors"
+	"go/format"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/*
+Go source of a lexer for a yacc grammar with token patterns (see yacc.go), to go with the parser from GenerateGo:
+	func Lex(input string) ([]Token, error)
+	type LexError
+The Value of a token is the text it matched. The longest match wins. Between matches of the same length
+the quoted terminals come first, so keywords win over identifiers, then the %token patterns in their order.
+Input matched by a %skip pattern is left out.
+*/
+
+// Generates the lexer. The generator and the source are named in the "Code generated" comment
+func (yacc *YaccGrammar) GenerateLexer(packageName string, source string, generator string) ([]byte, error) {
+	grammar := yacc.Grammar
+	patterns := make(map[string]string)
+	for _, pattern := range yacc.Patterns {
+		if grammar.isNonTerminal(pattern.Terminal) {
+			return nil, errors.New("Yacc Error: " + pattern.Terminal + " has rules, only terminals can have a regular expression")
+		}
+		if _, ok := patterns[pattern.Terminal]; ok {
+			return nil, errors.New("Yacc Error: " + pattern.Terminal + " has two regular expressions")
+		}
+		patterns[pattern.Terminal] = pattern.Pattern
+	}
+	for _, terminal := range grammar.terminals {
+		if _, ok := patterns[terminal]; !ok && contains(yacc.Literals, terminal) == -1 && terminal != yaccError {
+			return nil, errors.New("Yacc Error: Terminal " + terminal + " is not quoted and has no regular expression, declare it with \"%token " + terminal + " /regex/\"")
+		}
+	}
+
+	var code strings.Builder
+	code.WriteString("// Code generated by " + generator + " from " + source + ". DO NOT EDIT.\n\n")
+	code.WriteString("package " + packageName + "\n\n")
+	code.WriteString(`import (
+	"regexp"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Returned by Lex for input no token matches
+type LexError struct {
+	Line   int
+	Column int
+	Found  string
+}
+
+func (err *LexError) Error() string {
+	return "Lex Error at line " + strconv.Itoa(err.Line) + ", column " + strconv.Itoa(err.Column) + ": Unexpected " + strconv.Quote(err.Found)
+}
+
+type yyLexRule struct {
+	kind    string
+	skip    bool
+	pattern *regexp.Regexp
+}
+
+// Leftmost longest, so "a|ab" takes ab like the other rules
+func yyLexPattern(pattern string) *regexp.Regexp {
+	compiled := regexp.MustCompile("^(?:" + pattern + ")")
+	compiled.Longest()
+	return compiled
+}
+
+`)
+	code.WriteString("var yyLexRules = []yyLexRule{\n")
+	for _, literal := range yacc.Literals {
+		if contains(grammar.terminals, literal) != -1 {
+			code.WriteString("\t{kind: " + strconv.Quote(literal) + ", pattern: yyLexPattern(" + goRawString(regexp.QuoteMeta(literal)) + ")},\n")
+		}
+	}
+	for _, pattern := range yacc.Patterns {
+		code.WriteString("\t{kind: " + strconv.Quote(pattern.Terminal) + ", pattern: yyLexPattern(" + goRawString(pattern.Pattern) + ")},\n")
+	}
+	for _, skip := range yacc.Skip {
+		code.WriteString("\t{skip: true, pattern: yyLexPattern(" + goRawString(skip) + ")},\n")
+	}
+	code.WriteString("}\n\n")
+
+	code.WriteString(`// Splits the input into tokens for Parse
+func Lex(input string) ([]Token, error) {
+	tokens := []Token{}
+	line, column := 1, 1
+	for offset := 0; offset < len(input); {
+		var match *yyLexRule
+		length := 0
+		for i := range yyLexRules {
+			if end := yyLexRules[i].pattern.FindStringIndex(input[offset:]); end != nil && end[1] > length {
+				match, length = &yyLexRules[i], end[1]
+			}
+		}
+		if match == nil {
+			found, _ := utf8.DecodeRuneInString(input[offset:])
+			return nil, &LexError{Line: line, Column: column, Found: string(found)}
+		}
+		text := input[offset : offset+length]
+		if !match.skip {
+			tokens = append(tokens, Token{Kind: match.kind, Value: text})
+		}
+		for _, c := range text {
+			column++
+			if c == '\n' {
+				line, column = line+1, 1
+			}
+		}
+		offset += length
+	}
+	return tokens, nil
+}
+`)
+
+	formatted, err := format.Source([]byte(code.String()))
+	if err != nil {
+		return nil, errors.New("Yacc Error: Generated lexer is not valid Go: " + err.Error())
+	}
+	return formatted, nil
+}
+
+// Backquoted if possible, so the regular expression stays readable
+func goRawString(s string) string {
+	if strconv.CanBackquote(s) {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
@@ -0,0 +1,215 @@
+package parser
+
+import (
+	"compiler/diag"
+	"compiler/lexer"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+/*
+LL(1) parsing table:
+	For every rule A -> w:
+		For every terminal t in FIRST(w): table[A][t] = A -> w
+		If w can be empty: for every terminal t in FOLLOW(A): table[A][t] = A -> w
+	Two rules for the same entry are a conflict, the grammar is not LL(1).
+	Left recursive grammars (like the compiler grammar) always have conflicts.
+	Precedence chains can be left to a Pratt parser instead, see WithPrecedence and pratt.go.
+*/
+
+type LL1_parsing_Table struct {
+	grammar *Grammar
+	// Non terminal -> terminal -> index of the rule in grammar.rules
+	table     map[string]map[string]int
+	conflicts []LL1Conflict
+	// Top non terminals of the chains the Pratt parser does
+	precedence map[string]PrecedenceTable
+}
+
+// All rules which want the same table entry
+type LL1Conflict struct {
+	NonTerminal string
+	Terminal    string
+	Rules       []Rule
+}
+
+func (grammar *Grammar) CreateLL1Table() *LL1_parsing_Table {
+	newTable := new(LL1_parsing_Table)
+	newTable.grammar = grammar
+	newTable.table = make(map[string]map[string]int)
+
+	nullable := grammar.Nullable()
+	first := grammar.FIRST()
+	follow := grammar.FOLLOW(first)
+
+	for ruleID, rule := range grammar.rules {
+		terminals, ruleNullable := grammar.FirstOfSequence(rule.production, first, nullable)
+		if ruleNullable {
+			terminals = append(terminals, follow[rule.nonTerminal]...)
+		}
+		for _, terminal := range terminals {
+			newTable.add(rule.nonTerminal, terminal, ruleID)
+		}
+	}
+	return newTable
+}
+
+func (table *LL1_parsing_Table) add(nonTerminal string, terminal string, ruleID int) {
+	if table.table[nonTerminal] == nil {
+		table.table[nonTerminal] = make(map[string]int)
+	}
+	existing, ok := table.table[nonTerminal][terminal]
+	if !ok {
+		table.table[nonTerminal][terminal] = ruleID
+		return
+	}
+	if existing == ruleID {
+		return
+	}
+	// The first rule stays in the table, the conflict lists all of them
+	for i, conflict := range table.conflicts {
+		if conflict.NonTerminal == nonTerminal && conflict.Terminal == terminal {
+			table.conflicts[i].Rules = append(table.conflicts[i].Rules, table.grammar.rules[ruleID])
+			return
+		}
+	}
+	table.conflicts = append(table.conflicts, LL1Conflict{NonTerminal: nonTerminal, Terminal: terminal, Rules: []Rule{table.grammar.rules[existing], table.grammar.rules[ruleID]}})
+}
+
+func (table *LL1_parsing_Table) Conflicts() []LL1Conflict {
+	return table.conflicts
+}
+
+// A copy of the table which parses the chain of the precedence table with a Pratt parser, so the grammar can keep its
+// left recursive levels. The operands are parsed with the table. The conflicts of the levels are dropped
+func (table *LL1_parsing_Table) WithPrecedence(precedence PrecedenceTable) *LL1_parsing_Table {
+	newTable := new(LL1_parsing_Table)
+	*newTable = *table
+	newTable.precedence = make(map[string]PrecedenceTable)
+	for top, chain := range table.precedence {
+		newTable.precedence[top] = chain
+	}
+	newTable.precedence[precedence.Top] = precedence
+
+	levels := []string{}
+	for _, level := range precedence.Levels {
+		levels = append(levels, level.NonTerminal)
+	}
+	newTable.conflicts = []LL1Conflict{}
+	for _, conflict := range table.conflicts {
+		if contains(levels, conflict.NonTerminal) == -1 {
+			newTable.conflicts = append(newTable.conflicts, conflict)
+		}
+	}
+	return newTable
+}
+
+func (conflict LL1Conflict) String() string {
+	rules := []string{}
+	for _, rule := range conflict.Rules {
+		rules = append(rules, rule.String())
+	}
+	return "LL(1) conflict for " + conflict.NonTerminal + " on \"" + conflict.Terminal + "\": " + strings.Join(rules, " | ")
+}
+
+func (rule Rule) String() string {
+	if len(rule.production) == 0 {
+		return rule.nonTerminal + " -> ε"
+	}
+	return rule.nonTerminal + " -> " + strings.Join(rule.production, " ")
+}
+
+// Table driven top down parser. On conflicts the table contains the first conflicting rule of the grammar
+func (table *LL1_parsing_Table) Parse(tokens []lexer.Token) (*ParseTree, error) {
+	if len(tokens) == 0 || tokens[len(tokens)-1].Identifier != "$" {
+		tokens = append(tokens, lexer.Token{Identifier: "$", Value: "$"})
+	}
+	root, position, err := table.parseSymbol(table.grammar.start, tokens, 0)
+	if err != nil {
+		return nil, err
+	}
+	if position = skipLines(tokens, position); tokens[position].Identifier != "$" {
+		return nil, syntaxError(tokens[position], lineBefore(tokens, position), []string{"$"})
+	}
+	return root, nil
+}
+
+// Parses one symbol at tokens[position], returns it with the position after it
+func (table *LL1_parsing_Table) parseSymbol(symbol string, tokens []lexer.Token, position int) (*ParseTree, int, error) {
+	root := &ParseTree{Leaf: ParseLeaf{Name: symbol, Value: 0}}
+	type stackEntry struct {
+		symbol string
+		node   *ParseTree
+	}
+	stack := []stackEntry{{symbol: symbol, node: root}}
+
+	linecount := lineBefore(tokens, position)
+	for len(stack) > 0 {
+		token := tokens[position]
+		if token.Identifier == "LINE" {
+			linecount, _ = strconv.Atoi(token.Value.(string))
+			position++
+			continue
+		}
+
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if precedence, ok := table.precedence[top.symbol]; ok {
+			pratt := PrattParser{Table: precedence, Operand: func(tokens []lexer.Token, position int) (*ParseTree, int, error) {
+				return table.parseSymbol(precedence.Operand, tokens, position)
+			}}
+			tree, after, err := pratt.Parse(tokens, position)
+			if err != nil {
+				return nil, after, err
+			}
+			*top.node = *tree
+			position = after
+			linecount = lineBefore(tokens, position)
+			continue
+		}
+
+		if contains(table.grammar.nonTerminals, top.symbol) == -1 {
+			// Terminal has to match the input
+			if top.symbol != token.Identifier {
+				return nil, position, syntaxError(token, linecount, []string{top.symbol})
+			}
+			top.node.Leaf.Value = token.Value
+			top.node.Leaf.Line = token.Line
+			position++
+			continue
+		}
+
+		ruleID, ok := table.table[top.symbol][token.Identifier]
+		if !ok {
+			expected := []string{}
+			for terminal := range table.table[top.symbol] {
+				expected = append(expected, terminal)
+			}
+			return nil, position, syntaxError(token, linecount, expected)
+		}
+		rule := table.grammar.rules[ruleID]
+		// The rule starts with this token, unless it is empty
+		if len(rule.production) > 0 {
+			top.node.Leaf.Line = token.Line
+		}
+		top.node.Branches = make([]ParseTree, len(rule.production))
+		for i := len(rule.production) - 1; i >= 0; i-- {
+			top.node.Branches[i] = ParseTree{Leaf: ParseLeaf{Name: rule.production[i], Value: 0}, Branches: []ParseTree{}}
+			stack = append(stack, stackEntry{symbol: rule.production[i], node: &top.node.Branches[i]})
+		}
+	}
+	return root, position, nil
+}
+
+func syntaxError(token lexer.Token, linecount int, expected []string) error {
+	lineString := strconv.Itoa(linecount)
+	slices.Sort(expected)
+	nextString := formatNext(expected)
+	if token.Identifier == "$" {
+		return errors.New(diag.UnexpectedEOF.Format("Unexpected end of file reached. At line: " + lineString + ".\nExpecting: " + nextString))
+	}
+	return errors.New(diag.UnexpectedToken.Format("Syntax Error. Unexpected: \"" + formatToken(token) + "\" at line " + lineString + ".\nExpecting: " + nextString))
+}
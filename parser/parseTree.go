@@ -1,68 +1,90 @@
 package parser
 
 import (
+	"compiler/diag"
 	"compiler/lexer"
+	"fmt"
 	"slices"
-
-	"github.com/pterm/pterm"
 )
 
-type parseTree struct {
-	leaf     parseLeaf
-	branches []parseTree
+type ParseTree struct {
+	Leaf     ParseLeaf
+	Branches []ParseTree
 }
 
-type parseLeaf struct {
-	name  string
-	value any
+// Name is the terminal or non terminal. Terminals carry the value of their token
+type ParseLeaf struct {
+	Name  string
+	Value any
+	// Line of the token, or of the first token of a non terminal. 0 if unknown (empty rules)
+	Line int
+	// Column of the same token, 0 if unknown
+	Col int
+	// The source text of the token with the whitespace and comments around it, for tokens lexed with trivia (see cst.go)
+	Trivia *lexer.Trivia
 }
 
+// Builds the tree from the tokens and rules of the parser. A broken tree panics in this go routine, which would end
+// the program, so the panic is handed to the parser as error instead of the tree
 func createParseTree(parseChan chan any) {
-	Trees := []parseTree{}
+	var newItem any
+	defer func() {
+		if r := recover(); r != nil {
+			// The parser goes on sending until it is done, then waits for the tree if it accepted
+			for {
+				if done, ok := newItem.(bool); ok {
+					if done {
+						parseChan <- fmt.Errorf("%v", r)
+					}
+					return
+				}
+				newItem = <-parseChan
+			}
+		}
+	}()
+	Trees := []ParseTree{}
 	for true {
-		newItem := <-parseChan
+		newItem = <-parseChan
 		switch newItem.(type) {
 		case lexer.Token:
 			token := newItem.(lexer.Token)
-			newLeaf := parseLeaf{name: token.Identifier, value: token.Value}
-			newTree := parseTree{leaf: newLeaf, branches: []parseTree{}}
+			newLeaf := ParseLeaf{Name: token.Identifier, Value: token.Value, Line: token.Line, Col: token.Col, Trivia: token.Trivia}
+			newTree := ParseTree{Leaf: newLeaf, Branches: []ParseTree{}}
 			Trees = append(Trees, newTree)
 		case Rule:
 			rule := newItem.(Rule)
-			newTree := parseTree{}
-			newBranches := []parseTree{}
-			newTree.leaf = parseLeaf{name: rule.nonTerminal, value: 0}
+			newTree := ParseTree{}
+			newBranches := []ParseTree{}
+			newTree.Leaf = ParseLeaf{Name: rule.nonTerminal, Value: 0}
 			for i := range rule.production {
 				len := len(Trees)
 				if len == 0 {
-					panic("Parse Tree Error, no new node possible")
+					panic(diag.ParseTreeBroken.Format("Parse Tree Error, no new node possible"))
 				}
 
 				newBranches = append(newBranches, Trees[len-i-1])
 			}
 			Trees = Trees[:len(Trees)-len(rule.production)]
 			slices.Reverse(newBranches)
-			newTree.branches = newBranches
+			newTree.Branches = newBranches
+			newTree.Leaf.Line, newTree.Leaf.Col = firstPosition(newBranches)
 			Trees = append(Trees, newTree)
 		case bool:
-			parseChan <- Trees[0]
-			close(parseChan)
+			// true: parser accepted, hand back the tree. false: parser failed, nothing to hand back
+			if newItem.(bool) {
+				parseChan <- Trees[0]
+			}
+			return
 		}
 	}
-	parseChan <- Trees[0]
-	close(parseChan)
-}
-
-func PrintTree(tree parseTree) {
-	ptree := makePTree(tree)
-	renderTree := pterm.DefaultTree.WithRoot(ptree)
-	renderTree.Render()
 }
 
-func makePTree(tree parseTree) pterm.TreeNode {
-	root := pterm.TreeNode{Text: tree.leaf.name, Children: []pterm.TreeNode{}}
-	for _, t := range tree.branches {
-		root.Children = append(root.Children, makePTree(t))
+// Line and column of the first branch which has a line
+func firstPosition(branches []ParseTree) (int, int) {
+	for _, branch := range branches {
+		if branch.Leaf.Line != 0 {
+			return branch.Leaf.Line, branch.Leaf.Col
+		}
 	}
-	return root
+	return 0, 0
 }
@@ -1,55 +1,204 @@
 package parser
 
 import (
+	"compiler/diag"
 	"compiler/lexer"
+	"context"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
+	"sync"
 )
 
-type ParseTree struct {
-	identifier string
-	leaves     *ParseTree
+func createParser(test bool) (*SLR_parsing_Table, *Grammar) {
+	return createParserFor(defGrammar(test), "START")
 }
 
-func createParser(test bool) (*SLR_parsing_Table, *Grammar) {
-	rules := defGrammar(test)
-	grammar := MakeGrammar(rules, "START")
+func createParserFor(rules []Rule, start string) (*SLR_parsing_Table, *Grammar) {
+	grammar := MakeGrammar(rules, start)
+	table := grammar.CreateSLRParser()
+	for _, conflict := range table.Conflicts() {
+		fmt.Println(diag.ActionConflict.Format("Grammar is not SLR(1), " + conflict.String()))
+	}
+	return table, table.grammar
+}
+
+// Augments the grammar and builds the LR(0) automaton and the SLR(1) table for it.
+// Conflicts are resolved and can be looked at with Conflicts
+func (grammar *Grammar) CreateSLRParser() *SLR_parsing_Table {
 	grammar.Augment()
 	first := grammar.FIRST()
-	first["S"] = append(first["S"], "namespace") // TODO
-	first["START"] = append(first["START"], "namespace")
 	follow := grammar.FOLLOW(first)
 	grammar.follow = follow
 	grammar.CalcClosure()
 
 	automata := grammar.CreateSLRAutomata()
-	table := automata.CreateSLRTable(grammar)
-	
-	return table, grammar
+	return automata.CreateSLRTable(grammar)
 }
 
-func Parse(path string, test bool) (parseTree, bool) {
-	
-	parseTreeChannel := make(chan any)
-	go createParseTree(parseTreeChannel)
+// Parses the tokens with the table. LINE tokens only count the lines, the token list has to end with "$"
+func (table *SLR_parsing_Table) Parse(tokens []lexer.Token) (*ParseTree, error) {
+	return table.ParseContext(context.Background(), tokens)
+}
 
-	tokenChannel := make(chan lexer.Token)
+// Parse, which stops when the context is done. The error is the one of the context then
+func (table *SLR_parsing_Table) ParseContext(ctx context.Context, tokens []lexer.Token) (*ParseTree, error) {
+	tokenChannel := make(chan lexer.Token, len(tokens))
+	for _, token := range tokens {
+		tokenChannel <- token
+	}
+	close(tokenChannel)
+
+	var out strings.Builder
+	tree, ok := parseTokenStream(ctx, tokenChannel, table, table.grammar, 0, lexer.Options{Output: &out})
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if !ok {
+		return nil, errors.New(strings.TrimSpace(out.String()))
+	}
+	return &tree, nil
+}
+
+func Parse(path string, test bool, options lexer.Options) (ParseTree, bool) {
+	tree, ok, _ := ParseContext(context.Background(), path, test, options)
+	return tree, ok
+}
+
+// Parse, which stops when the context is done. The error is the one of the context then
+func ParseContext(ctx context.Context, path string, test bool, options lexer.Options) (ParseTree, bool, error) {
+	slrTable, grammar := createParser(test)
+	return parseFile(ctx, path, slrTable, grammar, options)
+}
+
+// Result of parsing one of the files given to ParseFiles
+type FileResult struct {
+	Path string
+	Tree ParseTree
+	Ok   bool
+	// Everything the lexer and parser wrote for this file
+	Output string
+	// Error of the context, if the file was not parsed to the end
+	Err error
+	// Printed the diagnostics of the file, with the limit of Options.Printer. nil if the options have no printer
+	Printer *diag.Printer
+}
+
+// Parses the files with at most workers files at the same time. The parsing table is built once and shared.
+// The results are in the order of the paths, and the output of each file is collected separately,
+// so the output does not depend on which file finishes first
+func ParseFiles(paths []string, test bool, options lexer.Options, workers int) []FileResult {
+	return ParseFilesContext(context.Background(), paths, test, options, workers)
+}
+
+// ParseFiles, which stops when the context is done. Files which were not parsed have the error of the context
+func ParseFilesContext(ctx context.Context, paths []string, test bool, options lexer.Options, workers int) []FileResult {
+	if workers < 1 {
+		workers = 1
+	}
+	slrTable, grammar := createParser(test)
+	results := make([]FileResult, len(paths))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				var output strings.Builder
+				fileOptions := options
+				fileOptions.Output = &output
+				fileOptions.File = paths[i]
+				// Every file gets its own printer, so the limit does not depend on which file finishes first
+				if options.Printer != nil {
+					fileOptions.Printer = diag.MakePrinter(options.Printer.Limit)
+				}
+				tree, ok, err := parseFile(ctx, paths[i], slrTable, grammar, fileOptions)
+				results[i] = FileResult{Path: paths[i], Tree: tree, Ok: ok, Output: output.String(), Err: err, Printer: fileOptions.Printer}
+			}
+		}()
+	}
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// Parse for source code which is not in a file. The tree and the messages are the same as for a file
+func ParseSource(ctx context.Context, source string, test bool, options lexer.Options) (ParseTree, bool, error) {
 	slrTable, grammar := createParser(test)
+	return parseLexed(ctx, func(tokenChannel chan lexer.Token, lexerOptions lexer.Options) {
+		lexer.LexReader(ctx, strings.NewReader(source), tokenChannel, lexerOptions)
+	}, slrTable, grammar, options)
+}
+
+func parseFile(ctx context.Context, path string, slrTable *SLR_parsing_Table, grammar *Grammar, options lexer.Options) (ParseTree, bool, error) {
+	if options.File == "" {
+		options.File = path
+	}
+	return parseLexed(ctx, func(tokenChannel chan lexer.Token, lexerOptions lexer.Options) {
+		lexer.LexContext(ctx, path, tokenChannel, lexerOptions)
+	}, slrTable, grammar, options)
+}
 
-	go lexer.Lex(path, tokenChannel)
+// lex runs as go routine and sends the tokens to the channel
+func parseLexed(ctx context.Context, lex func(chan lexer.Token, lexer.Options), slrTable *SLR_parsing_Table, grammar *Grammar, options lexer.Options) (ParseTree, bool, error) {
+	if ctx.Err() != nil {
+		return ParseTree{}, false, ctx.Err()
+	}
+	// Lexer and parser run at the same time, so they get their own buffers to keep the output in a fixed order
+	var lexerOutput strings.Builder
+	lexerOptions := options
+	lexerOptions.Output = &lexerOutput
+	tokenChannel := make(chan lexer.Token)
+	go lex(tokenChannel, lexerOptions)
+
+	var parserOutput strings.Builder
+	parserOptions := options
+	parserOptions.Output = &parserOutput
+	tree, accepts := parseTokenStream(ctx, tokenChannel, slrTable, grammar, 0, parserOptions)
+	// Let the lexer finish after a syntax error
+	for range tokenChannel {
+	}
+
+	if ctx.Err() != nil {
+		return ParseTree{}, false, ctx.Err()
+	}
+	out := options.Writer()
+	fmt.Fprint(out, lexerOutput.String())
+	fmt.Fprint(out, parserOutput.String())
+	if accepts {
+		fmt.Fprintln(out, "Code passed parser")
+		fmt.Fprint(out, RenderTree(tree))
+	}
+	return tree, accepts, nil
+}
+
+// Runs the SLR parser over the tokens in the channel and builds the parse tree. Stops without output when the context is done
+func parseTokenStream(ctx context.Context, tokenChannel chan lexer.Token, slrTable *SLR_parsing_Table, grammar *Grammar, linecount int, options lexer.Options) (ParseTree, bool) {
+	parseTreeChannel := make(chan any)
+	go createParseTree(parseTreeChannel)
 
-	linecount := 0
 	stack := makeStack(0)
 	accepts := false
 
 	for true {
+		if ctx.Err() != nil {
+			parseTreeChannel <- false
+			return ParseTree{}, false
+		}
 		token := lexer.GetNext(tokenChannel)
 
 		if token.Identifier == "" {
 			if accepts {
 				break
 			} else {
-				parseError(*token, linecount, *stack, slrTable, parseTreeChannel)
+				parseError(*token, linecount, *stack, slrTable, parseTreeChannel, options)
+				return ParseTree{}, false
 			}
 		}
 		if token.Identifier == "LINE" {
@@ -62,11 +211,19 @@ func Parse(path string, test bool) (parseTree, bool) {
 			stackVal := stack.peek().(*any)
 			res, err := slrTable.GetAction((*stackVal).(int), token.Identifier)
 			if err != nil {
-				parseError(*token, linecount, *stack, slrTable, parseTreeChannel)
-				return parseTree{}, false
+				parseError(*token, linecount, *stack, slrTable, parseTreeChannel, options)
+				return ParseTree{}, false
 			}
 			switch res.actionType {
 			case "Shift":
+				if token.Identifier == "interpolatedstring" {
+					segments, ok := parseInterpolation(ctx, token.Value.([]lexer.StringSegment), linecount, options)
+					if !ok {
+						parseTreeChannel <- false
+						return ParseTree{}, false
+					}
+					token.Value = segments
+				}
 				parseTreeChannel <- *token
 				stack.add(token)
 				stack.add(res.value)
@@ -83,8 +240,8 @@ func Parse(path string, test bool) (parseTree, bool) {
 				stateBefore := stack.peek().(*any)
 				gotoVal, err := slrTable.GetGoto((*stateBefore).(int), reductionRule.nonTerminal)
 				if err != nil {
-					parseError(*token, linecount, *stack, slrTable, parseTreeChannel)
-					return parseTree{}, false
+					parseError(*token, linecount, *stack, slrTable, parseTreeChannel, options)
+					return ParseTree{}, false
 				}
 				stack.add(reductionRule.nonTerminal)
 				stack.add(gotoVal.val)
@@ -93,28 +250,25 @@ func Parse(path string, test bool) (parseTree, bool) {
 			}
 		}
 	}
-	if accepts {
-		parseTreeChannel <- true
-		fmt.Println("Code passed parser")
-		select {
-		case tree := <- parseTreeChannel:
-			PrintTree(tree.(parseTree))
-			return tree.(parseTree), true
-		}
-	} else {
-		parseError(lexer.Token{}, linecount, *stack, slrTable, parseTreeChannel)
-		return parseTree{}, false
+	parseTreeChannel <- true
+	tree, ok := (<-parseTreeChannel).(ParseTree)
+	if !ok {
+		options.Emit(diag.MakeDiagnostic(diag.ParseTreeBroken, "Parse Tree Error, the tree of the input could not be built", linecount))
+		return ParseTree{}, false
 	}
+	return tree, true
 }
 
-
-
-func parseError(token lexer.Token, linecount int, stack Stack, table *SLR_parsing_Table, donechan chan any) {
+func parseError(token lexer.Token, linecount int, stack Stack, table *SLR_parsing_Table, donechan chan any, options lexer.Options) {
 	donechan <- false
 	
-	lineString := strconv.Itoa(linecount)
 	state := stack.peek().(*any)
-	next := table.getNextExpectedTokens((*state).(int))
+	options.Emit(syntaxDiagnostic(token, linecount, table.getNextExpectedTokens((*state).(int)), options.File))
+}
+
+// The diagnostic for the unexpected token, next are the terminals the parser expected instead
+func syntaxDiagnostic(token lexer.Token, linecount int, next []string, file string) *diag.Diagnostic {
+	lineString := strconv.Itoa(linecount)
 
 	conv, err := token.Value.(int)
 	if err && conv != 0 {
@@ -124,15 +278,37 @@ func parseError(token lexer.Token, linecount int, stack Stack, table *SLR_parsin
 	nextString := formatNext(next)
 
 	if token.Identifier == "$" {
-		fmt.Println("Unexpected end of file reached. At line: " + lineString + ".\nExpecting: " + nextString)
-		return
+		diagnostic := diag.MakeDiagnosticAt(diag.UnexpectedEOF, "Unexpected end of file reached. At line: "+lineString+".\nExpecting: "+nextString, diag.Position{File: file, Line: linecount})
+		suggestInsertion(diagnostic, next, "")
+		return diagnostic
 	}
 	unexpected := formatToken(token)
 
-	fmt.Println("Syntax Error. Unexpected: \"" + unexpected + "\" at line " + lineString + ".\nExpecting: " + nextString)
+	diagnostic := diag.MakeDiagnosticAt(diag.UnexpectedToken, "Syntax Error. Unexpected: \""+unexpected+"\" at line "+lineString+".\nExpecting: "+nextString, diag.Position{File: file, Line: linecount, Col: token.Col})
+	suggestInsertion(diagnostic, next, unexpected)
+	return diagnostic
+}
+
+// Suggests inserting the missing punctuation, if it is clear which one is missing:
+// Either it is the only expected token, or a ";" is expected
+func suggestInsertion(diagnostic *diag.Diagnostic, next []string, before string) {
+	insertable := []string{";", ")", "}", "]"}
+	missing := ""
+	if len(next) == 1 && contains(insertable, next[0]) != -1 {
+		missing = next[0]
+	} else if contains(next, ";") != -1 {
+		missing = ";"
+	}
+	if missing == "" {
+		return
+	}
+	diagnostic.Suggest("missing \""+missing+"\"", diag.Edit{Line: diagnostic.Line, Col: diagnostic.Col, Before: before, Insert: missing})
 }
 
 func formatNext(next []string) string {
+	if len(next) == 0 {
+		return ""
+	}
 	returnstring := ""
 	for _, n := range next {
 		nextString := ""
@@ -145,7 +321,7 @@ func formatNext(next []string) string {
 			nextString = "+, -"
 		case "name":
 			// Nothing :)
-		case "intliteral":
+		case "intliteral", "doubleliteral":
 			nextString = "num"
 		case "boolliteral":
 			nextString = "bool"
@@ -160,10 +336,14 @@ func formatNext(next []string) string {
 
 func formatToken(token lexer.Token) string {
 	switch token.Identifier {
-	case "name", "logicaloperator", "multoperator", "unaryoperator", "boolliteral":
+	case "name", "logicaloperator", "multoperator", "unaryoperator":
 		return token.Value.(string)
+	case "boolliteral":
+		return strconv.FormatBool(token.Value.(bool))
 	case "intliteral":
 		return strconv.Itoa(token.Value.(int))
+	case "doubleliteral":
+		return strconv.FormatFloat(token.Value.(float64), 'f', -1, 64)
 	case "stringliteral":
 		return "string"
 	default:
@@ -87,15 +87,20 @@ Step 6:
 
 func (grammar *Grammar) Augment() {
 	oldStart := grammar.start
+	// S, unless the grammar uses S already as a non terminal or a terminal
 	newStart := "S"
+	for contains(grammar.nonTerminals, newStart) != -1 || contains(grammar.terminals, newStart) != -1 {
+		newStart += "'"
+	}
 	grammar.start = newStart
-	grammar.nonTerminals = append(grammar.nonTerminals, "S")
+	grammar.nonTerminals = append(grammar.nonTerminals, newStart)
 	grammar.AddRule(newStart, []string{oldStart})
 }
 
 func PrintFirst(first map[string][]string){
 	fmt.Println("FIRST:")	
-	for nt, t := range first{
+	for _, nt := range sortedKeys(first){
+		t := first[nt]
 		fmt.Print(nt)
 		fmt.Print(": ")
 		for _, n := range t{
@@ -108,7 +113,8 @@ func PrintFirst(first map[string][]string){
 
 func PrintFollow(follow map[string][]string){
 	fmt.Println("FOLLOW:")	
-	for nt, t := range follow{
+	for _, nt := range sortedKeys(follow){
+		t := follow[nt]
 		fmt.Print(nt)
 		fmt.Print(": ")
 		for _, n := range t{
@@ -0,0 +1,91 @@
+package parser
+
+import (
+	"compiler/lexer"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The tokens of the words, with the LINE token in front and "$" at the end
+func words(input string) []lexer.Token {
+	tokens := []lexer.Token{{Identifier: "LINE", Value: "1", Line: 1}}
+	for _, word := range strings.Fields(input) {
+		tokens = append(tokens, lexer.Token{Identifier: word, Value: word, Line: 1})
+	}
+	return append(tokens, lexer.Token{Identifier: "$", Value: "$", Line: 1})
+}
+
+func table(t *testing.T, bnf string) *SLR_parsing_Table {
+	t.Helper()
+	grammar, err := ParseBNF(bnf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return grammar.CreateSLRParser()
+}
+
+// Fails the test if f does not return in time, instead of hanging the test run
+func within(t *testing.T, f func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("did not finish")
+	}
+}
+
+func TestAugmentAvoidsTerminalS(t *testing.T) {
+	slr := table(t, "A -> S\nA ->\nB -> S C")
+	if slr.grammar.start == "S" {
+		t.Fatal("the new start symbol is the terminal S")
+	}
+	within(t, func() {
+		if _, err := slr.Parse(words("C")); err == nil {
+			t.Error("C is accepted")
+		}
+		if _, err := slr.Parse(words("S")); err != nil {
+			t.Error(err)
+		}
+		if _, err := slr.Parse(words("")); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestBrokenTreeIsAnError(t *testing.T) {
+	slr := table(t, "A -> x")
+	// A broken table which accepts before anything is shifted, there is no tree to hand back
+	slr.actionTable[0]["x"] = &Action{actionType: "Accept"}
+	within(t, func() {
+		if _, err := slr.Parse([]lexer.Token{{Identifier: "x", Value: "x"}}); err == nil {
+			t.Error("no error")
+		}
+	})
+}
+
+// The items T -> a . b and T -> ab . are the same if the symbols are just concatenated
+func TestStatesOfCollidingSymbolNames(t *testing.T) {
+	slr := table(t, "S -> T\nT -> a b\nT -> ab")
+	// The start, after S, after T, after a, after ab and after a b
+	if len(slr.actionTable) != 6 {
+		t.Errorf("%d states, want 6", len(slr.actionTable))
+	}
+	within(t, func() {
+		for _, input := range []string{"a b", "ab"} {
+			if _, err := slr.Parse(words(input)); err != nil {
+				t.Errorf("%s: %v", input, err)
+			}
+		}
+		for _, input := range []string{"a", "ab b", "b"} {
+			if _, err := slr.Parse(words(input)); err == nil {
+				t.Errorf("%s is accepted", input)
+			}
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"compiler/lexer"
+	"strconv"
+)
+
+/*
+Pratt parser (precedence climbing) for the expressions of a precedence table, so expression grammars need not be
+turned into LL(1) form. The binding powers come from PrecedenceTable.BindingPower, the operands from a callback:
+
+	expression(min):
+		left = operand
+		while the next token is an operator with left binding power >= min:
+			take the operator, right = expression(its right binding power), left = left op right
+
+It can be used on its own with any operand parser, e.g. recursive descent, or in the LL(1) parser with
+LL1_parsing_Table.WithPrecedence, which parses the operands with the table.
+The trees are the ones of the collapsed grammar (see CollapsePrecedence): "Top -> Top op Top" and "Top -> Operand".
+*/
+
+type PrattParser struct {
+	Table PrecedenceTable
+	// Parses an operand at tokens[position], returns it with the position after it
+	Operand func(tokens []lexer.Token, position int) (*ParseTree, int, error)
+}
+
+// Parses the longest expression at tokens[position], returns it with the position after it.
+// LINE tokens between the operands and operators are skipped
+func (pratt PrattParser) Parse(tokens []lexer.Token, position int) (*ParseTree, int, error) {
+	return pratt.parse(tokens, position, 0)
+}
+
+func (pratt PrattParser) parse(tokens []lexer.Token, position int, minPower int) (*ParseTree, int, error) {
+	operand, position, err := pratt.Operand(tokens, position)
+	if err != nil {
+		return nil, position, err
+	}
+	left := &ParseTree{Leaf: ParseLeaf{Name: pratt.Table.Top, Value: 0, Line: operand.Leaf.Line}, Branches: []ParseTree{*operand}}
+	for {
+		next := skipLines(tokens, position)
+		if next == len(tokens) {
+			return left, position, nil
+		}
+		operator := tokens[next]
+		leftPower, rightPower, ok := pratt.Table.BindingPower(operator.Identifier)
+		if !ok || leftPower < minPower {
+			return left, position, nil
+		}
+		right, after, err := pratt.parse(tokens, next+1, rightPower)
+		if err != nil {
+			return nil, after, err
+		}
+		position = after
+		operatorLeaf := ParseTree{Leaf: ParseLeaf{Name: operator.Identifier, Value: operator.Value, Line: operator.Line}, Branches: []ParseTree{}}
+		left = &ParseTree{Leaf: ParseLeaf{Name: pratt.Table.Top, Value: 0, Line: left.Leaf.Line}, Branches: []ParseTree{*left, operatorLeaf, *right}}
+	}
+}
+
+// Index of the first token at or after the position which is not a LINE token
+func skipLines(tokens []lexer.Token, position int) int {
+	for position < len(tokens) && tokens[position].Identifier == "LINE" {
+		position++
+	}
+	return position
+}
+
+// The line of the last LINE token in front of the position, for error messages
+func lineBefore(tokens []lexer.Token, position int) int {
+	for i := min(position, len(tokens)) - 1; i >= 0; i-- {
+		if tokens[i].Identifier == "LINE" {
+			line, _ := strconv.Atoi(tokens[i].Value.(string))
+			return line
+		}
+	}
+	return 0
+}
@@ -0,0 +1,225 @@
+package parser
+
+/*
+Grammars from yacc often encode operator precedence in the structure, one non terminal per level:
+
+	E -> E + T | E - T | T
+	T -> T * F | F
+	F -> ( E ) | num
+
+PrecedenceTables finds these chains. Every level has the rules "A -> A op B" (left associative) or "A -> B op A"
+(right associative) for its operators and the single rule "A -> B" to the next level.
+CollapsePrecedence replaces a chain by one non terminal, "E -> E + E | E - E | E * E | F", and the table decides the
+shift/reduce conflicts of the ambiguous rules like %left and %right in yacc do, so the parse trees keep the precedence.
+*/
+
+type PrecedenceLevel struct {
+	NonTerminal string
+	Operators   []string
+	// left or right
+	Associativity string
+}
+
+type PrecedenceTable struct {
+	// The non terminal of the lowest level, the one the rest of the grammar uses
+	Top string
+	// Lowest precedence first
+	Levels []PrecedenceLevel
+	// The non terminal below the highest level, e.g. F
+	Operand string
+}
+
+// Precedence of an operator in a collapsed grammar
+type precedence struct {
+	// Starts at 1 for the lowest level
+	level         int
+	associativity string
+}
+
+// Binding powers of the operator for a Pratt parser, higher binds stronger.
+// Left associative operators bind stronger to the right, so the left side is reduced first
+func (table PrecedenceTable) BindingPower(operator string) (left int, right int, ok bool) {
+	for i, level := range table.Levels {
+		if contains(level.Operators, operator) == -1 {
+			continue
+		}
+		if level.Associativity == "left" {
+			return 2*i + 1, 2*i + 2, true
+		}
+		return 2*i + 2, 2*i + 1, true
+	}
+	return 0, 0, false
+}
+
+// The precedence chains of the grammar, in the order of their top non terminals
+func (grammar *Grammar) PrecedenceTables() []PrecedenceTable {
+	levels := make(map[string]*precedenceCandidate)
+	for _, nonTerminal := range grammar.nonTerminals {
+		if candidate := grammar.precedenceLevel(nonTerminal); candidate != nil {
+			levels[nonTerminal] = candidate
+		}
+	}
+
+	// A level belongs to the chain above it, if only the level above uses it
+	owned := make(map[string]bool)
+	for _, candidate := range levels {
+		if _, isLevel := levels[candidate.next]; isLevel && grammar.onlyUsedBy(candidate.next, candidate.level.NonTerminal) {
+			owned[candidate.next] = true
+		}
+	}
+
+	tables := []PrecedenceTable{}
+	for _, nonTerminal := range grammar.nonTerminals {
+		candidate, isLevel := levels[nonTerminal]
+		if !isLevel || owned[nonTerminal] {
+			continue
+		}
+		table := PrecedenceTable{Top: nonTerminal}
+		for {
+			table.Levels = append(table.Levels, candidate.level)
+			next, isLevel := levels[candidate.next]
+			if !isLevel || !owned[candidate.next] {
+				table.Operand = candidate.next
+				break
+			}
+			candidate = next
+		}
+		tables = append(tables, table)
+	}
+	return tables
+}
+
+type precedenceCandidate struct {
+	level PrecedenceLevel
+	// The non terminal of the next level
+	next string
+}
+
+// nil if the rules of the non terminal are not exactly one precedence level
+func (grammar *Grammar) precedenceLevel(nonTerminal string) *precedenceCandidate {
+	candidate := &precedenceCandidate{level: PrecedenceLevel{NonTerminal: nonTerminal}}
+	chained := false
+	for _, rule := range grammar.rules {
+		if rule.nonTerminal != nonTerminal {
+			continue
+		}
+		production := rule.production
+		switch {
+		case len(production) == 1 && !chained && production[0] != nonTerminal && grammar.isNonTerminal(production[0]):
+			chained = true
+			if candidate.next != "" && candidate.next != production[0] {
+				return nil
+			}
+			candidate.next = production[0]
+		case len(production) == 3 && !grammar.isNonTerminal(production[1]):
+			associativity, next := "left", production[2]
+			if production[2] == nonTerminal {
+				associativity, next = "right", production[0]
+			} else if production[0] != nonTerminal {
+				return nil
+			}
+			if next == nonTerminal || !grammar.isNonTerminal(next) || candidate.next != "" && candidate.next != next {
+				return nil
+			}
+			if candidate.level.Associativity != "" && candidate.level.Associativity != associativity {
+				return nil
+			}
+			candidate.next = next
+			candidate.level.Associativity = associativity
+			if contains(candidate.level.Operators, production[1]) == -1 {
+				candidate.level.Operators = append(candidate.level.Operators, production[1])
+			}
+		default:
+			return nil
+		}
+	}
+	if !chained || len(candidate.level.Operators) == 0 {
+		return nil
+	}
+	return candidate
+}
+
+// Whether the symbol is not the start symbol and only appears in the rules of the user and its own
+func (grammar *Grammar) onlyUsedBy(symbol string, user string) bool {
+	if symbol == grammar.start {
+		return false
+	}
+	for _, rule := range grammar.rules {
+		if rule.nonTerminal != user && rule.nonTerminal != symbol && contains(rule.production, symbol) != -1 {
+			return false
+		}
+	}
+	return true
+}
+
+func (grammar *Grammar) isNonTerminal(symbol string) bool {
+	return contains(grammar.nonTerminals, symbol) != -1
+}
+
+// A new grammar with the chain of the table replaced by its top non terminal. The rules of the chain become
+// "Top -> Top op Top" for every operator, lowest precedence first, and "Top -> Operand" at the place of the first one.
+// Tables built from the new grammar resolve the conflicts between these rules by the precedence
+func (grammar *Grammar) CollapsePrecedence(table PrecedenceTable) *Grammar {
+	chain := []string{}
+	for _, level := range table.Levels {
+		chain = append(chain, level.NonTerminal)
+	}
+
+	rules := []Rule{}
+	collapsed := false
+	for _, rule := range grammar.rules {
+		if contains(chain, rule.nonTerminal) == -1 {
+			rules = append(rules, rule)
+			continue
+		}
+		if collapsed {
+			continue
+		}
+		collapsed = true
+		for _, level := range table.Levels {
+			for _, operator := range level.Operators {
+				rules = append(rules, MakeRule(table.Top, []string{table.Top, operator, table.Top}))
+			}
+		}
+		rules = append(rules, MakeRule(table.Top, []string{table.Operand}))
+	}
+
+	newGrammar := MakeGrammar(rules, grammar.start)
+	newGrammar.docs = grammar.docs
+	newGrammar.precedence = make(map[string]precedence)
+	for terminal, p := range grammar.precedence {
+		newGrammar.precedence[terminal] = p
+	}
+	// Above the levels of tables which were collapsed before
+	base := 0
+	for _, p := range newGrammar.precedence {
+		base = max(base, p.level)
+	}
+	for i, level := range table.Levels {
+		for _, operator := range level.Operators {
+			newGrammar.precedence[operator] = precedence{level: base + i + 1, associativity: level.Associativity}
+		}
+	}
+	return newGrammar
+}
+
+// Decides a shift/reduce conflict by precedence: the stronger one wins, on the same level left associative reduces
+// and right associative shifts. ok is false if the terminal or the rule has no precedence
+func (grammar *Grammar) resolveByPrecedence(terminal string, rule Rule) (shift bool, ok bool) {
+	terminalPrecedence, ok := grammar.precedence[terminal]
+	if !ok {
+		return false, false
+	}
+	// The precedence of a rule is the one of its last terminal with precedence
+	for i := len(rule.production) - 1; i >= 0; i-- {
+		rulePrecedence, found := grammar.precedence[rule.production[i]]
+		if !found {
+			continue
+		}
+		if terminalPrecedence.level != rulePrecedence.level {
+			return terminalPrecedence.level > rulePrecedence.level, true
+		}
+		return terminalPrecedence.associativity == "right", true
+	}
+	return false, false
+}
@@ -0,0 +1,35 @@
+//go:build !js
+
+package parser
+
+import "github.com/pterm/pterm"
+
+// pterm needs a terminal, which js/wasm does not have. render_js.go renders the trees there
+
+func PrintTree(tree ParseTree) {
+	ptree := makePTree(tree)
+	renderTree := pterm.DefaultTree.WithRoot(ptree)
+	renderTree.Render()
+}
+
+func RenderTree(tree ParseTree) string {
+	ptree := makePTree(tree)
+	rendered, _ := pterm.DefaultTree.WithRoot(ptree).Srender()
+	return rendered
+}
+
+func makePTree(tree ParseTree) pterm.TreeNode {
+	root := pterm.TreeNode{Text: tree.Leaf.Name, Children: []pterm.TreeNode{}}
+	for _, t := range tree.Branches {
+		root.Children = append(root.Children, makePTree(t))
+	}
+	// Show the expressions embedded in interpolated strings below the string
+	if segments, ok := tree.Leaf.Value.([]InterpolationSegment); ok {
+		for _, segment := range segments {
+			if segment.Expression != nil {
+				root.Children = append(root.Children, makePTree(*segment.Expression))
+			}
+		}
+	}
+	return root
+}
@@ -0,0 +1,45 @@
+//go:build js
+
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Same layout as the pterm tree of render.go, without colors
+
+func PrintTree(tree ParseTree) {
+	fmt.Print(RenderTree(tree))
+}
+
+func RenderTree(tree ParseTree) string {
+	var rendered strings.Builder
+	rendered.WriteString(tree.Leaf.Name + "\n")
+	renderBranches(&rendered, treeChildren(tree), "")
+	return rendered.String()
+}
+
+func renderBranches(rendered *strings.Builder, children []ParseTree, indent string) {
+	for i, child := range children {
+		branch, next := "├─", "│ "
+		if i == len(children)-1 {
+			branch, next = "└─", "  "
+		}
+		rendered.WriteString(indent + branch + child.Leaf.Name + "\n")
+		renderBranches(rendered, treeChildren(child), indent+next)
+	}
+}
+
+// The branches, and the expressions embedded in interpolated strings below the string
+func treeChildren(tree ParseTree) []ParseTree {
+	children := append([]ParseTree{}, tree.Branches...)
+	if segments, ok := tree.Leaf.Value.([]InterpolationSegment); ok {
+		for _, segment := range segments {
+			if segment.Expression != nil {
+				children = append(children, *segment.Expression)
+			}
+		}
+	}
+	return children
+}
@@ -0,0 +1,218 @@
+package parser
+
+import (
+	"html"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+/*
+Report of a parsing table as a standalone HTML page, which needs no Graphviz: the LR(0) automaton as inline SVG,
+a table of the states with their items, actions and gotos, and the conflicts with an example input.
+The automaton is laid out in columns by the distance of the states from state 0. Transitions are curves
+from the right side of a state to the left side of the next one, so the ones going back loop around.
+Tables read with UnmarshalTable have no automaton, their report only has the tables.
+*/
+
+// Layout of the automaton, in pixels
+const (
+	reportCharWidth  = 7
+	reportLineHeight = 16
+	reportPadding    = 6
+	reportColumnGap  = 90
+	reportRowGap     = 24
+	reportMargin     = 20
+)
+
+type reportBox struct {
+	x, y, width, height int
+}
+
+func (table *SLR_parsing_Table) HTMLReport(title string) string {
+	var page strings.Builder
+	page.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>" + html.EscapeString(title) + "</title>\n")
+	page.WriteString("<style>\nbody { font-family: sans-serif; margin: 1em; }\ncode, pre { font-family: monospace; margin: 0; }\n" +
+		"table { border-collapse: collapse; }\ntd, th { border: 1px solid #ccc; padding: 2px 8px; text-align: left; vertical-align: top; }\n" +
+		".conflict { background: #ffe0e0; }\n</style>\n</head>\n<body>\n")
+	page.WriteString("<h1>" + html.EscapeString(title) + "</h1>\n")
+
+	stats := table.Stats()
+	page.WriteString("<p>" + strconv.Itoa(stats.States) + " states, " + strconv.Itoa(stats.Rules) + " rules, " +
+		strconv.Itoa(stats.ShiftReduce) + " shift/reduce and " + strconv.Itoa(stats.ReduceReduce) + " reduce/reduce conflicts.</p>\n")
+
+	conflicts := table.Conflicts()
+	conflictStates := make(map[int]bool)
+	if len(conflicts) > 0 {
+		page.WriteString("<h2>Conflicts</h2>\n<table>\n<tr><th>State</th><th>Terminal</th><th>Kind</th><th>Rules</th><th>Example</th></tr>\n")
+		for _, conflict := range conflicts {
+			conflictStates[conflict.State] = true
+			rules := []string{}
+			for _, rule := range conflict.Rules {
+				rules = append(rules, html.EscapeString(rule.String()))
+			}
+			page.WriteString("<tr><td><a href=\"#state-" + strconv.Itoa(conflict.State) + "\">" + strconv.Itoa(conflict.State) + "</a></td><td><code>" +
+				html.EscapeString(conflict.Symbol) + "</code></td><td>" + conflict.Kind + "</td><td><code>" + strings.Join(rules, "<br>") +
+				"</code></td><td><code>" + html.EscapeString(strings.Join(conflict.Example, " ")) + "</code></td></tr>\n")
+		}
+		page.WriteString("</table>\n")
+	}
+
+	if table.automata != nil {
+		page.WriteString("<h2>Automaton</h2>\n" + table.reportSVG(conflictStates) + "\n")
+	}
+
+	page.WriteString("<h2>States</h2>\n<table>\n<tr><th>State</th>")
+	if table.automata != nil {
+		page.WriteString("<th>Items</th>")
+	}
+	page.WriteString("<th>Actions</th><th>Gotos</th></tr>\n")
+	for state := range stats.States {
+		class := ""
+		if conflictStates[state] {
+			class = " class=\"conflict\""
+		}
+		page.WriteString("<tr id=\"state-" + strconv.Itoa(state) + "\"" + class + "><td>" + strconv.Itoa(state) + "</td>")
+		if table.automata != nil {
+			items := []string{}
+			for _, item := range table.automata.states[state].rules {
+				items = append(items, html.EscapeString(item.String()))
+			}
+			page.WriteString("<td><pre>" + strings.Join(items, "\n") + "</pre></td>")
+		}
+		actions := []string{}
+		for _, terminal := range sortedKeys(table.actionTable[state]) {
+			actions = append(actions, html.EscapeString(terminal)+": "+table.describeAction(*table.actionTable[state][terminal]))
+		}
+		gotos := []string{}
+		for _, nonTerminal := range sortedKeys(table.gotoToTable[state]) {
+			gotos = append(gotos, html.EscapeString(nonTerminal)+": "+stateLink(table.gotoToTable[state][nonTerminal].val))
+		}
+		page.WriteString("<td><pre>" + strings.Join(actions, "\n") + "</pre></td><td><pre>" + strings.Join(gotos, "\n") + "</pre></td></tr>\n")
+	}
+	page.WriteString("</table>\n</body>\n</html>\n")
+	return page.String()
+}
+
+func (table *SLR_parsing_Table) describeAction(action Action) string {
+	switch action.actionType {
+	case "Shift":
+		return "shift " + stateLink(action.value)
+	case "Reduce":
+		return "reduce " + html.EscapeString(table.grammar.rules[action.value].String())
+	}
+	return "accept"
+}
+
+func stateLink(state int) string {
+	return "<a href=\"#state-" + strconv.Itoa(state) + "\">" + strconv.Itoa(state) + "</a>"
+}
+
+// The automaton as SVG, states with a conflict are red
+func (table *SLR_parsing_Table) reportSVG(conflictStates map[int]bool) string {
+	states := table.automata.states
+	columns := table.automata.columns()
+
+	boxes := make([]reportBox, len(states))
+	x := reportMargin
+	height := 0
+	for _, column := range columns {
+		width := 0
+		for _, state := range column {
+			lines := []string{"State " + strconv.Itoa(state)}
+			for _, item := range states[state].rules {
+				lines = append(lines, item.String())
+			}
+			for _, line := range lines {
+				width = max(width, len([]rune(line))*reportCharWidth+2*reportPadding)
+			}
+			boxes[state].height = len(lines)*reportLineHeight + 2*reportPadding
+		}
+		y := reportMargin
+		for _, state := range column {
+			boxes[state].x, boxes[state].y, boxes[state].width = x, y, width
+			y += boxes[state].height + reportRowGap
+		}
+		height = max(height, y)
+		x += width + reportColumnGap
+	}
+
+	var svg strings.Builder
+	svg.WriteString("<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"" + strconv.Itoa(x) + "\" height=\"" + strconv.Itoa(height+reportMargin) + "\" font-family=\"monospace\" font-size=\"12\">")
+	svg.WriteString("<defs><marker id=\"arrow\" viewBox=\"0 0 10 10\" refX=\"10\" refY=\"5\" markerWidth=\"8\" markerHeight=\"8\" orient=\"auto\">" +
+		"<path d=\"M0 0 L10 5 L0 10 z\" fill=\"#555\"/></marker></defs>")
+
+	for _, state := range states {
+		from := boxes[state.id]
+		for _, symbol := range slices.Sorted(maps.Keys(state.transitions)) {
+			next := state.transitions[symbol]
+			path, labelX, labelY := reportCurve(from, boxes[next], next == state.id)
+			svg.WriteString("<path d=\"" + path + "\" fill=\"none\" stroke=\"#555\" marker-end=\"url(#arrow)\"/>")
+			svg.WriteString("<text x=\"" + strconv.Itoa(labelX) + "\" y=\"" + strconv.Itoa(labelY) + "\" text-anchor=\"middle\" fill=\"#a00\">" + html.EscapeString(symbol) + "</text>")
+		}
+	}
+
+	for _, state := range states {
+		box := boxes[state.id]
+		fill := "#f4f8ff"
+		if conflictStates[state.id] {
+			fill = "#ffe0e0"
+		}
+		svg.WriteString("<a href=\"#state-" + strconv.Itoa(state.id) + "\"><rect x=\"" + strconv.Itoa(box.x) + "\" y=\"" + strconv.Itoa(box.y) + "\" width=\"" + strconv.Itoa(box.width) +
+			"\" height=\"" + strconv.Itoa(box.height) + "\" fill=\"" + fill + "\" stroke=\"#333\"/>")
+		lineY := box.y + reportPadding + reportLineHeight - 4
+		svg.WriteString("<text x=\"" + strconv.Itoa(box.x+reportPadding) + "\" y=\"" + strconv.Itoa(lineY) + "\" font-weight=\"bold\">State " + strconv.Itoa(state.id) + "</text>")
+		for _, item := range state.rules {
+			lineY += reportLineHeight
+			svg.WriteString("<text x=\"" + strconv.Itoa(box.x+reportPadding) + "\" y=\"" + strconv.Itoa(lineY) + "\">" + html.EscapeString(item.String()) + "</text>")
+		}
+		svg.WriteString("</a>")
+	}
+	svg.WriteString("</svg>")
+	return svg.String()
+}
+
+// Bezier curve from the right side of a state to the left side of the next one, or over the top right corner
+// back to the state itself. The label goes to the middle of the curve
+func reportCurve(from reportBox, to reportBox, loop bool) (string, int, int) {
+	x1, y1 := from.x+from.width, from.y+from.height/2
+	x2, y2 := to.x, to.y+to.height/2
+	bend := max(40, (max(x1, x2)-min(x1, x2))/3)
+	c1x, c1y, c2x, c2y := x1+bend, y1, x2-bend, y2
+	if loop {
+		x2, y2 = from.x+from.width-20, from.y
+		c1x, c1y, c2x, c2y = x1+40, y1, x2, y2-40
+	}
+	path := "M" + railPoint(x1, y1) + " C" + railPoint(c1x, c1y) + " " + railPoint(c2x, c2y) + " " + railPoint(x2, y2)
+	return path, (x1 + 3*c1x + 3*c2x + x2) / 8, (y1+3*c1y+3*c2y+y2)/8 - 3
+}
+
+// The states by their distance from state 0, each column sorted
+func (automata *SLR_automata) columns() [][]int {
+	depth := map[int]int{0: 0}
+	work := []int{0}
+	for len(work) > 0 {
+		state := work[0]
+		work = work[1:]
+		transitions := automata.states[state].transitions
+		for _, symbol := range slices.Sorted(maps.Keys(transitions)) {
+			if _, seen := depth[transitions[symbol]]; !seen {
+				depth[transitions[symbol]] = depth[state] + 1
+				work = append(work, transitions[symbol])
+			}
+		}
+	}
+	columns := [][]int{}
+	for _, state := range automata.states {
+		d, ok := depth[state.id]
+		if !ok {
+			continue
+		}
+		for len(columns) <= d {
+			columns = append(columns, []int{})
+		}
+		columns[d] = append(columns[d], state.id)
+	}
+	return columns
+}
@@ -0,0 +1,344 @@
+package parser
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+/*
+Sanity checks of a grammar, and the transforms which make a grammar fit for top down parsing (see ll1.go):
+
+	Left recursion    A =>+ A α, the LL(1) table has conflicts and recursive descent does not end. Fine for LR
+	Cycles            A =>+ A, the grammar is ambiguous
+	Unreachable       non terminals which no derivation from the start symbol uses
+	Non productive    non terminals which derive no string of terminals, and the rules which use them
+
+Derivations through nullable non terminals count, A -> B A with B -> ε is left recursive.
+EliminateLeftRecursion and LeftFactor return a new grammar, the grammar they are called on does not change.
+*/
+
+// The left recursive non terminals as cycles A, B, ...: A -> B ..., B -> ... A with only nullable symbols in front.
+// A cycle of one non terminal is direct left recursion. Every left recursive non terminal is in one of the cycles
+func (grammar *Grammar) LeftRecursion() [][]string {
+	nullable := grammar.Nullable()
+	return grammar.symbolCycles(func(rule Rule, i int) bool {
+		return allNullable(rule.production[:i], nullable)
+	})
+}
+
+// The non terminals which derive themselves, as cycles like LeftRecursion: the rest of the rules can be empty
+func (grammar *Grammar) Cycles() [][]string {
+	nullable := grammar.Nullable()
+	return grammar.symbolCycles(func(rule Rule, i int) bool {
+		return allNullable(rule.production[:i], nullable) && allNullable(rule.production[i+1:], nullable)
+	})
+}
+
+// Non terminals the start symbol does not derive, in the order of the grammar
+func (grammar *Grammar) Unreachable() []string {
+	reached := map[string]bool{grammar.start: true}
+	work := []string{grammar.start}
+	for len(work) > 0 {
+		symbol := work[len(work)-1]
+		work = work[:len(work)-1]
+		for _, rule := range grammar.rules {
+			if rule.nonTerminal != symbol {
+				continue
+			}
+			for _, s := range rule.production {
+				if grammar.isNonTerminal(s) && !reached[s] {
+					reached[s] = true
+					work = append(work, s)
+				}
+			}
+		}
+	}
+	unreachable := []string{}
+	for _, nonTerminal := range grammar.nonTerminals {
+		if !reached[nonTerminal] {
+			unreachable = append(unreachable, nonTerminal)
+		}
+	}
+	return unreachable
+}
+
+// Non terminals which derive no string of terminals, and the rules which use one of them. No parse uses these rules
+func (grammar *Grammar) NonProductive() ([]string, []Rule) {
+	yields := grammar.shortestYields()
+	nonTerminals := []string{}
+	for _, nonTerminal := range grammar.nonTerminals {
+		if _, ok := yields[nonTerminal]; !ok {
+			nonTerminals = append(nonTerminals, nonTerminal)
+		}
+	}
+	rules := []Rule{}
+	for _, rule := range grammar.rules {
+		for _, s := range rule.production {
+			if contains(nonTerminals, s) != -1 || contains(nonTerminals, rule.nonTerminal) != -1 {
+				rules = append(rules, rule)
+				break
+			}
+		}
+	}
+	return nonTerminals, rules
+}
+
+// Cycles, unreachable and non productive non terminals as messages, like yacc warns about useless rules.
+// Left recursion is not in it, LR parsers like it
+func (grammar *Grammar) Warnings() []string {
+	warnings := []string{}
+	for _, cycle := range grammar.Cycles() {
+		warnings = append(warnings, "cycle "+strings.Join(append(cycle, cycle[0]), " => ")+", the grammar is ambiguous")
+	}
+	for _, nonTerminal := range grammar.Unreachable() {
+		warnings = append(warnings, "non terminal "+nonTerminal+" is unreachable from "+grammar.start)
+	}
+	nonTerminals, rules := grammar.NonProductive()
+	for _, nonTerminal := range nonTerminals {
+		warnings = append(warnings, "non terminal "+nonTerminal+" derives no terminals")
+	}
+	for _, rule := range rules {
+		warnings = append(warnings, "rule "+rule.String()+" is never used")
+	}
+	return warnings
+}
+
+// Shortest cycle back to every non terminal over the edges A -> production[i] of the rules the filter takes.
+// Each cycle once, rotated to start with the non terminal which comes first in the grammar
+func (grammar *Grammar) symbolCycles(edge func(rule Rule, i int) bool) [][]string {
+	next := make(map[string][]string)
+	for _, rule := range grammar.rules {
+		for i, s := range rule.production {
+			if grammar.isNonTerminal(s) && edge(rule, i) && contains(next[rule.nonTerminal], s) == -1 {
+				next[rule.nonTerminal] = append(next[rule.nonTerminal], s)
+			}
+		}
+	}
+
+	cycles := [][]string{}
+	for _, start := range grammar.nonTerminals {
+		// Breadth first, so the first way back is the shortest
+		previous := make(map[string]string)
+		work := []string{start}
+		found := false
+		for len(work) > 0 && !found {
+			symbol := work[0]
+			work = work[1:]
+			for _, s := range next[symbol] {
+				if s == start {
+					previous[start] = symbol
+					found = true
+					break
+				}
+				if _, seen := previous[s]; !seen {
+					previous[s] = symbol
+					work = append(work, s)
+				}
+			}
+		}
+		if !found {
+			continue
+		}
+		cycle := []string{start}
+		for symbol := previous[start]; symbol != start; symbol = previous[symbol] {
+			cycle = append(cycle, symbol)
+		}
+		slices.Reverse(cycle[1:])
+
+		first := 0
+		for i, symbol := range cycle {
+			if contains(grammar.nonTerminals, symbol) < contains(grammar.nonTerminals, cycle[first]) {
+				first = i
+			}
+		}
+		cycle = slices.Concat(cycle[first:], cycle[:first])
+		if !slices.ContainsFunc(cycles, func(c []string) bool { return slices.Equal(c, cycle) }) {
+			cycles = append(cycles, cycle)
+		}
+	}
+	return cycles
+}
+
+func allNullable(symbols []string, nullable map[string]bool) bool {
+	for _, s := range symbols {
+		if !nullable[s] {
+			return false
+		}
+	}
+	return true
+}
+
+// A grammar for the same language without left recursion (dragon book, algorithm 4.19). The non terminals are put in
+// the order of the grammar, A -> B α with B before A gets the rules of B in front of α, then direct left recursion
+//
+//	A -> A α | β   becomes   A -> β A',  A' -> α A' | ε
+//
+// Needs a grammar without cycles. Left recursion through nullable non terminals stays, like A -> B A with B -> ε
+func (grammar *Grammar) EliminateLeftRecursion() (*Grammar, error) {
+	if cycles := grammar.Cycles(); len(cycles) > 0 {
+		return nil, errors.New("Grammar Error: the cycle " + strings.Join(append(cycles[0], cycles[0][0]), " => ") + " can not be removed")
+	}
+	productions := grammar.productions()
+	used := slices.Concat(grammar.nonTerminals, grammar.terminals)
+	// The new non terminal of each left recursive one
+	tails := make(map[string]string)
+
+	for i, a := range grammar.nonTerminals {
+		for _, b := range grammar.nonTerminals[:i] {
+			replaced := [][]string{}
+			for _, production := range productions[a] {
+				if len(production) == 0 || production[0] != b {
+					replaced = appendProduction(replaced, production)
+					continue
+				}
+				for _, bProduction := range productions[b] {
+					replaced = appendProduction(replaced, slices.Concat(bProduction, production[1:]))
+				}
+			}
+			productions[a] = replaced
+		}
+
+		recursive, rest := [][]string{}, [][]string{}
+		for _, production := range productions[a] {
+			if len(production) > 0 && production[0] == a {
+				recursive = append(recursive, production[1:])
+			} else {
+				rest = append(rest, production)
+			}
+		}
+		if len(recursive) == 0 {
+			continue
+		}
+		tail := freshNonTerminal(a, used)
+		used = append(used, tail)
+		tails[a] = tail
+		productions[a] = nil
+		for _, beta := range rest {
+			productions[a] = append(productions[a], slices.Concat(beta, []string{tail}))
+		}
+		for _, alpha := range recursive {
+			productions[tail] = append(productions[tail], slices.Concat(alpha, []string{tail}))
+		}
+		productions[tail] = append(productions[tail], []string{})
+	}
+
+	order := []string{}
+	for _, nonTerminal := range grammar.nonTerminals {
+		order = append(order, nonTerminal)
+		if tail, ok := tails[nonTerminal]; ok {
+			order = append(order, tail)
+		}
+	}
+	return grammar.withProductions(order, productions), nil
+}
+
+// A grammar for the same language in which no two rules of a non terminal start with the same symbol
+//
+//	A -> α β1 | α β2 | γ   becomes   A -> α A' | γ,  A' -> β1 | β2
+//
+// α is the longest prefix of all rules of A with the same first symbol. The new non terminals are factored as well
+func (grammar *Grammar) LeftFactor() *Grammar {
+	productions := grammar.productions()
+	used := slices.Concat(grammar.nonTerminals, grammar.terminals)
+	order := slices.Clone(grammar.nonTerminals)
+
+	for i := 0; i < len(order); i++ {
+		a := order[i]
+		// The new non terminals come right after a
+		insert := i + 1
+		for {
+			group := []int{}
+			for j, production := range productions[a] {
+				if len(production) == 0 {
+					continue
+				}
+				group = []int{j}
+				for k := j + 1; k < len(productions[a]); k++ {
+					if other := productions[a][k]; len(other) > 0 && other[0] == production[0] {
+						group = append(group, k)
+					}
+				}
+				if len(group) > 1 {
+					break
+				}
+			}
+			if len(group) < 2 {
+				break
+			}
+
+			prefix := productions[a][group[0]]
+			for _, j := range group[1:] {
+				production := productions[a][j]
+				length := 0
+				for length < min(len(prefix), len(production)) && prefix[length] == production[length] {
+					length++
+				}
+				prefix = prefix[:length]
+			}
+			tail := freshNonTerminal(a, used)
+			used = append(used, tail)
+			order = slices.Insert(order, insert, tail)
+			insert++
+			for _, j := range group {
+				productions[tail] = appendProduction(productions[tail], productions[a][j][len(prefix):])
+			}
+
+			factored := [][]string{}
+			for j, production := range productions[a] {
+				switch {
+				case j == group[0]:
+					factored = append(factored, slices.Concat(prefix, []string{tail}))
+				case !slices.Contains(group, j):
+					factored = append(factored, production)
+				}
+			}
+			productions[a] = factored
+		}
+	}
+	return grammar.withProductions(order, productions)
+}
+
+// The productions of every non terminal, without duplicates, in the order of the rules
+func (grammar *Grammar) productions() map[string][][]string {
+	productions := make(map[string][][]string)
+	for _, rule := range grammar.rules {
+		productions[rule.nonTerminal] = appendProduction(productions[rule.nonTerminal], slices.Clone(rule.production))
+	}
+	return productions
+}
+
+// A grammar with the rules of the non terminals in that order, which keeps the start symbol and the docs
+func (grammar *Grammar) withProductions(order []string, productions map[string][][]string) *Grammar {
+	rules := []Rule{}
+	for _, nonTerminal := range order {
+		for _, production := range productions[nonTerminal] {
+			rules = append(rules, MakeRule(nonTerminal, production))
+		}
+	}
+	newGrammar := MakeGrammar(rules, grammar.start)
+	newGrammar.docs = grammar.docs
+	return newGrammar
+}
+
+func appendProduction(productions [][]string, production []string) [][]string {
+	if slices.ContainsFunc(productions, func(p []string) bool { return slices.Equal(p, production) }) {
+		return productions
+	}
+	return append(productions, production)
+}
+
+// A' for A and <A'> for <A>, with more ' until the name is not used yet
+func freshNonTerminal(base string, used []string) string {
+	name := base
+	for {
+		if strings.HasPrefix(name, "<") && strings.HasSuffix(name, ">") {
+			name = name[:len(name)-1] + "'>"
+		} else {
+			name += "'"
+		}
+		if !slices.Contains(used, name) {
+			return name
+		}
+	}
+}
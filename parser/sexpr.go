@@ -0,0 +1,175 @@
+package parser
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+/*
+Grammar from S-expressions, easier to write from other programs than BNF:
+
+	(grammar
+	  (start EXPRESSION)
+	  (rule EXPRESSION (EXPRESSION + TERM) (TERM))
+	  (rule TERM ("(" EXPRESSION ")") (name))
+	  (rule LIST (item LIST) ()))
+
+Every alternative is a list of symbols, () is the empty word. Symbols containing
+whitespace, parentheses, quotes or ; are written as Go string literals. ; starts a comment.
+(start ...) is optional, without it the non terminal of the first rule is the start symbol.
+Every symbol with a rule is a non terminal, everything else is a terminal.
+*/
+func ParseSExpr(source string) (*Grammar, error) {
+	expressions, err := readSExprs(source)
+	if err != nil {
+		return nil, err
+	}
+	if len(expressions) != 1 || !expressions[0].isList("grammar") {
+		return nil, errors.New("S-expression Error: Expected one (grammar ...)")
+	}
+
+	rules := []Rule{}
+	start := ""
+	for _, definition := range expressions[0].list[1:] {
+		switch {
+		case definition.isList("start"):
+			if len(definition.list) != 2 || definition.list[1].list != nil {
+				return nil, definition.error("Expected (start SYMBOL)")
+			}
+			start = definition.list[1].atom
+		case definition.isList("rule"):
+			if len(definition.list) < 3 || definition.list[1].list != nil {
+				return nil, definition.error("Expected (rule NONTERMINAL (symbols...)...)")
+			}
+			nonTerminal := definition.list[1].atom
+			for _, alternative := range definition.list[2:] {
+				if alternative.list == nil {
+					return nil, alternative.error("Alternatives of " + nonTerminal + " have to be lists")
+				}
+				production := []string{}
+				for _, symbol := range alternative.list {
+					if symbol.list != nil {
+						return nil, symbol.error("Alternatives can not contain lists")
+					}
+					production = append(production, symbol.atom)
+				}
+				rules = append(rules, MakeRule(nonTerminal, production))
+			}
+		default:
+			return nil, definition.error("Expected (start ...) or (rule ...)")
+		}
+	}
+
+	if len(rules) == 0 {
+		return nil, errors.New("S-expression Error: Grammar has no rules")
+	}
+	if start == "" {
+		start = rules[0].nonTerminal
+	}
+	return MakeGrammar(rules, start), nil
+}
+
+// The grammar as S-expression, ParseSExpr reads it back
+func (grammar *Grammar) SExpr() string {
+	var text strings.Builder
+	text.WriteString("(grammar\n  (start " + sexprAtom(grammar.start) + ")")
+	for i, rule := range grammar.rules {
+		// Rules of the same non terminal are written as one
+		if i == 0 || grammar.rules[i-1].nonTerminal != rule.nonTerminal {
+			if i > 0 {
+				text.WriteString(")")
+			}
+			text.WriteString("\n  (rule " + sexprAtom(rule.nonTerminal))
+		}
+		symbols := []string{}
+		for _, symbol := range rule.production {
+			symbols = append(symbols, sexprAtom(symbol))
+		}
+		text.WriteString(" (" + strings.Join(symbols, " ") + ")")
+	}
+	if len(grammar.rules) > 0 {
+		text.WriteString(")")
+	}
+	text.WriteString(")\n")
+	return text.String()
+}
+
+// Either an atom or a list. Empty lists are not nil
+type sexpr struct {
+	atom string
+	list []sexpr
+	line int
+}
+
+func (expression sexpr) isList(head string) bool {
+	return len(expression.list) > 0 && expression.list[0].list == nil && expression.list[0].atom == head
+}
+
+func (expression sexpr) error(message string) error {
+	return errors.New("S-expression Error at line " + strconv.Itoa(expression.line) + ": " + message)
+}
+
+func readSExprs(source string) ([]sexpr, error) {
+	// The outermost list collects the top level expressions
+	stack := []sexpr{{list: []sexpr{}}}
+	line := 1
+	runes := []rune(source)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case c == '\n':
+			line++
+		case c == ' ' || c == '\t' || c == '\r':
+		case c == ';':
+			for i+1 < len(runes) && runes[i+1] != '\n' {
+				i++
+			}
+		case c == '(':
+			stack = append(stack, sexpr{list: []sexpr{}, line: line})
+		case c == ')':
+			if len(stack) == 1 {
+				return nil, sexpr{line: line}.error("Unexpected )")
+			}
+			closed := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			stack[len(stack)-1].list = append(stack[len(stack)-1].list, closed)
+		case c == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				if runes[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(runes) {
+				return nil, sexpr{line: line}.error("Unclosed string")
+			}
+			atom, err := strconv.Unquote(string(runes[i : end+1]))
+			if err != nil {
+				return nil, sexpr{line: line}.error("Invalid string " + string(runes[i:end+1]))
+			}
+			stack[len(stack)-1].list = append(stack[len(stack)-1].list, sexpr{atom: atom, line: line})
+			i = end
+		default:
+			end := i
+			for end < len(runes) && !strings.ContainsRune(" \t\r\n();\"", runes[end]) {
+				end++
+			}
+			stack[len(stack)-1].list = append(stack[len(stack)-1].list, sexpr{atom: string(runes[i:end]), line: line})
+			i = end - 1
+		}
+	}
+	if len(stack) > 1 {
+		return nil, stack[len(stack)-1].error("Unclosed (")
+	}
+	return stack[0].list, nil
+}
+
+// Quotes symbols which would not be read back as one atom
+func sexprAtom(symbol string) string {
+	if symbol == "" || strings.ContainsAny(symbol, " \t\r\n();\"") {
+		return strconv.Quote(symbol)
+	}
+	return symbol
+}
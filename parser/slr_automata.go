@@ -1,11 +1,19 @@
 package parser
 
 import (
+	"compiler/diag"
 	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
 )
 
 type SLR_automata struct {
-	states []State
+	// Pointers, so the transitions added to a state after it was stored are in the automaton
+	states []*State
+	// Canonical key of the item set -> index of the state in states
+	stateKeys map[string]int
 }
 type State struct {
 	id          int
@@ -22,14 +30,13 @@ type GrammarClosure struct {
 	closure map[string][]ItemRule
 }
 
-var stateIndex int
-
+// The automaton numbers its states itself, so several grammars can be built in different go routines at the same time
 func (grammar *Grammar) CreateSLRAutomata() *SLR_automata {
-	stateIndex = 0
 	automata := new(SLR_automata)
+	automata.stateKeys = make(map[string]int)
 	var startRule Rule
 	for _, r := range grammar.rules {
-		if r.nonTerminal == "S" {
+		if r.nonTerminal == grammar.start {
 			startRule = r
 			break
 		}
@@ -39,9 +46,9 @@ func (grammar *Grammar) CreateSLRAutomata() *SLR_automata {
 	startItemRule.dot = 0
 	startItemRule.rule = startRule
 	startState := makeState([]ItemRule{*startItemRule}, *grammarClosure)
-	automata.states = append(automata.states, *startState)
+	automata.states = append(automata.states, startState)
+	automata.stateKeys[startState.key()] = 0
 	startState.id = 0
-	stateIndex++
 	startState.GoTo(automata, *grammarClosure)
 	return automata
 }
@@ -111,17 +118,21 @@ func (state *State) addClosureRecursive(closure GrammarClosure, done map[string]
 
 func (oldState *State) GoTo(automata *SLR_automata, closure GrammarClosure) {
 	rulesPerSymbol := make(map[string][]ItemRule)
+	// The symbols in the order of the items, so the states get the same numbers in every run
+	symbols := []string{}
 
 	for _, r := range oldState.rules {
 		if r.dot < len(r.rule.production) {
 			if rulesPerSymbol[r.rule.production[r.dot]] == nil {
 				rulesPerSymbol[r.rule.production[r.dot]] = []ItemRule{}
+				symbols = append(symbols, r.rule.production[r.dot])
 			}
 			rulesPerSymbol[r.rule.production[r.dot]] = append(rulesPerSymbol[r.rule.production[r.dot]], r)
 		}
 	}
 
-	for symbol, rules := range rulesPerSymbol {
+	for _, symbol := range symbols {
+		rules := rulesPerSymbol[symbol]
 		newRules := []ItemRule{}
 		for _, rule := range rules {
 			newItemRule := new(ItemRule)
@@ -134,13 +145,13 @@ func (oldState *State) GoTo(automata *SLR_automata, closure GrammarClosure) {
 
 		existingState, doesNotExist := automata.stateDoesNotExist(newState)
 		if doesNotExist {
-			newState.id = stateIndex
-			stateIndex++
-			automata.states = append(automata.states, *newState)
+			newState.id = len(automata.states)
+			automata.states = append(automata.states, newState)
+			automata.stateKeys[newState.key()] = len(automata.states) - 1
 			if oldState.transitions[symbol] != 0 {
 				fmt.Print("Conflict in state")
 				fmt.Println(oldState.id)
-				panic("SLR automata failed")
+				panic(diag.TransitionConflict.Format("SLR automata failed"))
 			}
 			oldState.transitions[symbol] = newState.id
 			newState.GoTo(automata, closure)
@@ -148,7 +159,7 @@ func (oldState *State) GoTo(automata *SLR_automata, closure GrammarClosure) {
 			if oldState.transitions[symbol] != 0 {
 				fmt.Print("Conflict in state")
 				fmt.Println(oldState.id)
-				panic("SLR automata failed")
+				panic(diag.TransitionConflict.Format("SLR automata failed"))
 			}
 			oldState.transitions[symbol] = existingState.id
 			//fmt.Println("Deleted:")
@@ -158,33 +169,26 @@ func (oldState *State) GoTo(automata *SLR_automata, closure GrammarClosure) {
 }
 
 func (automata *SLR_automata) stateDoesNotExist(newState *State) (*State, bool) {
-	for _, existingState := range automata.states {
-		if len(existingState.rules) != len(newState.rules) {
-			continue
-		}
-		thisIsTheItem := true
-		for _, existingRule := range existingState.rules {
-			ruleFound := false
-			for _, newRule := range newState.rules {
-				if areTheRulesTheSame(newRule, existingRule) {
-					// Found the rule, breaks loop over the rules of the new automata
-					ruleFound = true
-					break
-				}
-			}
-			if !ruleFound {
-				thisIsTheItem = false
-				break
-			}
-		}
-		if thisIsTheItem {
-			return &existingState, false
-		}
+	index, ok := automata.stateKeys[newState.key()]
+	if ok {
+		return automata.states[index], false
 	}
 	// Have not found a valid State
 	return &State{}, true
 }
 
+// Canonical key of the item set of the state: The sorted items, so two states with the same items get the same key.
+// Symbols are joined with a 0 byte, which can not be part of a symbol, so [a b] and [ab] stay different
+func (state *State) key() string {
+	items := []string{}
+	for _, r := range state.rules {
+		item := r.rule.nonTerminal + "\x00" + strings.Join(r.rule.production, "\x00") + "\x00" + strconv.Itoa(r.dot)
+		items = append(items, item)
+	}
+	slices.Sort(items)
+	return strings.Join(items, "\x01")
+}
+
 func areTheRulesTheSame(existingRule ItemRule, newRule ItemRule) bool {
 	// Fucking hours OMG FUCK
 	if existingRule.dot != newRule.dot || existingRule.rule.nonTerminal != newRule.rule.nonTerminal || len(newRule.rule.production) != len(existingRule.rule.production) {
@@ -213,9 +217,9 @@ func (automata *SLR_automata) Print() {
 			fmt.Println(r.dot)
 		}
 		fmt.Println("Transitions:")
-		for input, endStateId := range state.transitions {
+		for _, input := range slices.Sorted(maps.Keys(state.transitions)) {
 			fmt.Print("-> ")
-			b := endStateId
+			b := state.transitions[input]
 			fmt.Print(b)
 			fmt.Println(" with " + input)
 		}
@@ -0,0 +1,63 @@
+package parser_test
+
+import (
+	"compiler/ast"
+	"compiler/diag"
+	"compiler/lexer"
+	"compiler/parser"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The parser of the language never panics or hangs. What the parser accepts, the tolerant parser accepts without repairs
+func FuzzParseSource(f *testing.F) {
+	paths, _ := filepath.Glob("../testcode/*.cs")
+	for _, path := range paths {
+		if source, err := os.ReadFile(path); err == nil {
+			f.Add(string(source))
+		}
+	}
+	for _, seed := range []string{
+		"",
+		"using System; namespace N { class P { static void Main(string[] args) { } } }",
+		"using System; namespace N { class P { static int F(int a) { return a * (2 + a; } } }",
+		"using System; namespace N { class P { static void Main(string[] args) { Console.WriteLine($\"{1 +}\"); } } }",
+		"} } { ( ; ;",
+	} {
+		f.Add(seed)
+	}
+	// Built once, ParseSource would build it for every input
+	table := parser.Language(true).CreateSLRParser()
+	f.Fuzz(func(t *testing.T, source string) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		tokenChannel := make(chan lexer.Token)
+		go lexer.LexReader(ctx, strings.NewReader(source), tokenChannel, lexer.Options{Output: io.Discard})
+		tokens := []lexer.Token{}
+		for token := range tokenChannel {
+			tokens = append(tokens, token)
+		}
+
+		tree, err := table.ParseContext(ctx, tokens)
+		if ctx.Err() != nil {
+			t.Fatal("parsing did not finish in time")
+		}
+		if err == nil {
+			if _, err := ast.Build(*tree); err != nil {
+				t.Fatalf("no AST for a tree the parser accepted: %v", err)
+			}
+		}
+		repaired := 0
+		tolerantTree, tolerantOk := table.ParseTolerant(tokens, lexer.Options{Output: io.Discard, Report: func(*diag.Diagnostic) { repaired++ }})
+		if err == nil && (!tolerantOk || repaired > 0) {
+			t.Fatal("the tolerant parser repaired code the parser accepted")
+		}
+		// Broken trees only need to be built without panicking
+		ast.Build(tolerantTree)
+	})
+}
@@ -0,0 +1,98 @@
+package parser
+
+// Size and shape of a parsing table, e.g. to compare the SLR and the LALR table of a grammar
+type TableStats struct {
+	States       int
+	Terminals    int
+	NonTerminals int
+	Rules        int
+	// Entries of the action and the goto table
+	Shifts  int
+	Reduces int
+	Gotos   int
+	// Conflicts found while building the table, the table has only one action left for each
+	ShiftReduce  int
+	ReduceReduce int
+	// States which reduce by the same rule for every terminal they have an action for
+	DefaultReductions int
+	// States the parser can not get to from state 0. Always empty for built tables, loaded ones may have some
+	Unreachable []int
+}
+
+// Whether the grammar needed no conflict resolution, so the table parses it like written
+func (stats TableStats) Deterministic() bool {
+	return stats.ShiftReduce == 0 && stats.ReduceReduce == 0
+}
+
+func (table *SLR_parsing_Table) Stats() TableStats {
+	stats := TableStats{States: table.stateCount(), Terminals: len(table.grammar.terminals), NonTerminals: len(table.grammar.nonTerminals), Rules: len(table.grammar.rules)}
+	for _, actions := range table.actionTable {
+		rule := -1
+		for _, action := range actions {
+			if action == nil {
+				continue
+			}
+			switch action.actionType {
+			case "Shift":
+				stats.Shifts++
+				rule = -2
+			case "Reduce":
+				stats.Reduces++
+				if rule == -1 {
+					rule = action.value
+				} else if rule != action.value {
+					rule = -2
+				}
+			default:
+				rule = -2
+			}
+		}
+		if rule >= 0 {
+			stats.DefaultReductions++
+		}
+	}
+	for _, gotos := range table.gotoToTable {
+		stats.Gotos += len(gotos)
+	}
+	for _, conflict := range table.conflicts {
+		if conflict.Kind == "shift/reduce" {
+			stats.ShiftReduce++
+		} else {
+			stats.ReduceReduce++
+		}
+	}
+
+	reached := table.reachable()
+	for state := range stats.States {
+		if !reached[state] {
+			stats.Unreachable = append(stats.Unreachable, state)
+		}
+	}
+	return stats
+}
+
+// The states which shifts and gotos lead to, starting at state 0
+func (table *SLR_parsing_Table) reachable() map[int]bool {
+	reached := map[int]bool{0: true}
+	work := []int{0}
+	for len(work) > 0 {
+		state := work[len(work)-1]
+		work = work[:len(work)-1]
+		next := []int{}
+		for _, action := range table.actionTable[state] {
+			if action != nil && action.actionType == "Shift" {
+				next = append(next, action.value)
+			}
+		}
+		for _, goTo := range table.gotoToTable[state] {
+			next = append(next, goTo.val)
+		}
+		for _, state := range next {
+			if !reached[state] {
+				reached[state] = true
+				work = append(work, state)
+			}
+		}
+	}
+	return reached
+}
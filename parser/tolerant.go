@@ -0,0 +1,403 @@
+package parser
+
+import (
+	"compiler/lexer"
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+/*
+Error tolerant parsing for editors, which have to work on broken code all the time. The tolerant parser
+never gives up, at a syntax error it reports the diagnostic, repairs the input and goes on:
+
+  - If one of ; ) ] } in front of the token lets the parser go on, it is inserted.
+    At the end of the file as many as are needed to close everything
+  - Else the smallest region around the error is replaced by an ERROR node. The region is made of the
+    subtrees on top of the stack and the tokens after them, the ERROR node stands for a non terminal like
+    EXPRESSION or STATEMENTBLOCK which the parser can go on with
+  - Tokens which fit nowhere are skipped
+
+Regions are counted in tokens, so an error in an expression does not throw away the whole statement.
+Errors in the next three tokens after a repair are not reported, they are mostly caused by the repair.
+*/
+
+// Leaf name of the regions the tolerant parser could not parse. The branches are the subtrees and tokens of the region
+const ErrorSymbol = "ERROR"
+
+// Value of ERROR leaves
+type ErrorRegion struct {
+	// The non terminal the region stands for
+	NonTerminal string
+	// Line and column after the last token of the region. The start of the region if it is empty
+	EndLine int
+	EndCol  int
+}
+
+// The non terminals an ERROR node may stand for, the ones the AST has a place for.
+// Tried in this order, so the most specific ones come first
+var errorNonTerminals = []string{
+	"PRIMARY", "FACTOR", "TERM", "EXPRESSION",
+	"FUNCCALL", "RETURN", "VARIABLEDECLARATION", "VARASSIGN", "IF", "WHILE", "STATEMENTBLOCK",
+	"FUNC", "FUNCBLOCK", "CLASS", "USINGBLOCK",
+}
+
+// Tokens which are inserted to repair the input, innermost first
+var closers = []string{";", ")", "]", "}"}
+
+// Parse, but syntax errors are repaired instead of failing, so there is always a tree.
+// ok is false if there were errors, the tree has ERROR nodes for the regions which were replaced then
+func ParseTolerant(ctx context.Context, path string, test bool, options lexer.Options) (ParseTree, bool, error) {
+	if options.File == "" {
+		options.File = path
+	}
+	return parseTolerantLexed(ctx, func(tokenChannel chan lexer.Token) {
+		lexer.LexContext(ctx, path, tokenChannel, options)
+	}, test, options)
+}
+
+// ParseTolerant for source code which is not in a file
+func ParseSourceTolerant(ctx context.Context, source string, test bool, options lexer.Options) (ParseTree, bool, error) {
+	return parseTolerantLexed(ctx, func(tokenChannel chan lexer.Token) {
+		lexer.LexReader(ctx, strings.NewReader(source), tokenChannel, options)
+	}, test, options)
+}
+
+// The whole file is lexed first, repairs look at the tokens after the error
+func parseTolerantLexed(ctx context.Context, lex func(chan lexer.Token), test bool, options lexer.Options) (ParseTree, bool, error) {
+	if ctx.Err() != nil {
+		return ParseTree{}, false, ctx.Err()
+	}
+	tokenChannel := make(chan lexer.Token)
+	go lex(tokenChannel)
+	tokens := []lexer.Token{}
+	for token := range tokenChannel {
+		tokens = append(tokens, token)
+	}
+
+	slrTable, _ := createParser(test)
+	tree, ok := slrTable.parseTolerant(ctx, tokens, 0, options)
+	if ctx.Err() != nil {
+		return ParseTree{}, false, ctx.Err()
+	}
+	return tree, ok, nil
+}
+
+// Parses the tokens like Parse, but repairs syntax errors instead of failing. The diagnostics go to the options
+func (table *SLR_parsing_Table) ParseTolerant(tokens []lexer.Token, options lexer.Options) (ParseTree, bool) {
+	return table.parseTolerant(context.Background(), tokens, 0, options)
+}
+
+type tolerantEntry struct {
+	state int
+	tree  ParseTree
+	// Number of tokens in the tree and the last one of them
+	tokens int
+	last   lexer.Token
+}
+
+func (entry *tolerantEntry) add(child tolerantEntry) {
+	entry.tokens += child.tokens
+	if child.tokens > 0 {
+		entry.last = child.last
+	}
+}
+
+type tolerantParser struct {
+	ctx       context.Context
+	table     *SLR_parsing_Table
+	options   lexer.Options
+	tokens    []lexer.Token
+	linecount int
+	stack     []tolerantEntry
+	// Tokens to shift until errors are reported again
+	quiet  int
+	failed bool
+}
+
+// A repair: pop entries from the stack, skip the tokens up to next and push an ERROR node for the non terminal.
+// Without non terminal nothing is pushed, the tokens are dropped
+type repair struct {
+	pops        int
+	next        int
+	nonTerminal string
+	state       int
+	// Number of tokens in the region
+	cost int
+}
+
+func (table *SLR_parsing_Table) parseTolerant(ctx context.Context, tokens []lexer.Token, linecount int, options lexer.Options) (ParseTree, bool) {
+	p := &tolerantParser{ctx: ctx, table: table, options: options, tokens: slices.Clone(tokens), linecount: linecount}
+	if len(p.tokens) == 0 || p.tokens[len(p.tokens)-1].Identifier != "$" {
+		p.tokens = append(p.tokens, lexer.Token{Identifier: "$", Value: "$", Line: linecount})
+	}
+	p.stack = []tolerantEntry{{state: 0}}
+
+	for i := 0; ; {
+		if ctx.Err() != nil {
+			return ParseTree{}, false
+		}
+		token := p.tokens[i]
+		if token.Identifier == "LINE" {
+			p.linecount, _ = strconv.Atoi(token.Value.(string))
+			i++
+			continue
+		}
+
+		action, err := table.GetAction(p.stack[len(p.stack)-1].state, token.Identifier)
+		if err == nil && action.actionType == "Reduce" && p.reduce(table.grammar.rules[action.value]) {
+			continue
+		}
+		if err != nil || action.actionType == "Reduce" {
+			next, ok := p.recover(i)
+			if !ok {
+				return p.giveUp(i), false
+			}
+			i = next
+			continue
+		}
+		if action.actionType == "Accept" {
+			return p.stack[len(p.stack)-1].tree, !p.failed
+		}
+		p.stack = append(p.stack, tolerantEntry{state: action.value, tree: p.leaf(token), tokens: 1, last: token})
+		p.quiet = max(p.quiet-1, 0)
+		i++
+	}
+}
+
+func (p *tolerantParser) leaf(token lexer.Token) ParseTree {
+	if token.Identifier == "interpolatedstring" {
+		token.Value = p.interpolation(token.Value.([]lexer.StringSegment))
+	}
+	return ParseTree{Leaf: ParseLeaf{Name: token.Identifier, Value: token.Value, Line: token.Line, Col: token.Col, Trivia: token.Trivia}, Branches: []ParseTree{}}
+}
+
+// Like parseInterpolation, broken expressions become ERROR trees
+func (p *tolerantParser) interpolation(segments []lexer.StringSegment) []InterpolationSegment {
+	table, _ := getExpressionParser()
+	parsed := []InterpolationSegment{}
+	for _, segment := range segments {
+		if segment.Expression == nil {
+			parsed = append(parsed, InterpolationSegment{Text: segment.Text})
+			continue
+		}
+		tree, ok := table.parseTolerant(p.ctx, segment.Expression, p.linecount, p.options)
+		p.failed = p.failed || !ok
+		parsed = append(parsed, InterpolationSegment{Text: segment.Text, Expression: &tree})
+	}
+	return parsed
+}
+
+// false if the stack has no goto for the rule
+func (p *tolerantParser) reduce(rule Rule) bool {
+	below := len(p.stack) - len(rule.production)
+	gotoVal, err := p.table.GetGoto(p.stack[below-1].state, rule.nonTerminal)
+	if err != nil {
+		return false
+	}
+	entry := tolerantEntry{state: gotoVal.val, tree: ParseTree{Leaf: ParseLeaf{Name: rule.nonTerminal, Value: 0}, Branches: []ParseTree{}}}
+	for _, child := range p.stack[below:] {
+		entry.tree.Branches = append(entry.tree.Branches, child.tree)
+		entry.add(child)
+	}
+	entry.tree.Leaf.Line, entry.tree.Leaf.Col = firstPosition(entry.tree.Branches)
+	p.stack = append(p.stack[:below], entry)
+	return true
+}
+
+func (p *tolerantParser) states() []int {
+	states := []int{}
+	for _, entry := range p.stack {
+		states = append(states, entry.state)
+	}
+	return states
+}
+
+// Reports the error at the token and repairs the input. Returns the index of the token to go on with,
+// false if there is no repair
+func (p *tolerantParser) recover(i int) (int, bool) {
+	token := p.tokens[i]
+	states := p.states()
+	if p.quiet == 0 {
+		p.options.Emit(syntaxDiagnostic(token, p.linecount, p.table.getNextExpectedTokens(states[len(states)-1]), p.options.File))
+	}
+	p.failed = true
+	p.quiet = 3
+	p.defaultReductions()
+	states = p.states()
+
+	if inserted, ok := p.insertion(states, token.Identifier); ok {
+		p.insert(i, inserted)
+		return i, true
+	}
+	best := p.search(i, states)
+	if best == nil {
+		return 0, false
+	}
+	p.replace(i, *best)
+	return best.next, true
+}
+
+// SLR tables find errors only after the reductions by the FOLLOW sets. Undoes some of it: states
+// which can only reduce by one rule are reduced, so the finished subtrees do not end up in the region
+func (p *tolerantParser) defaultReductions() {
+	for range len(p.table.actionTable) {
+		rule := -1
+		for _, action := range p.table.actionTable[p.stack[len(p.stack)-1].state] {
+			if action == nil {
+				continue
+			}
+			if action.actionType != "Reduce" || rule != -1 && rule != action.value {
+				return
+			}
+			rule = action.value
+		}
+		if rule == -1 || !p.reduce(p.table.grammar.rules[rule]) {
+			return
+		}
+	}
+}
+
+// The closers which let the parser go on with the terminal
+func (p *tolerantParser) insertion(states []int, terminal string) ([]string, bool) {
+	if terminal == "$" {
+		return p.table.closing(states)
+	}
+	for _, closer := range closers {
+		if after, ok := p.table.shift(states, closer); ok && p.table.accepts(after, terminal) {
+			return []string{closer}, true
+		}
+	}
+	return nil, false
+}
+
+// The closers which end the input after the states, the first one which fits is taken every time
+func (table *SLR_parsing_Table) closing(states []int) ([]string, bool) {
+	inserted := []string{}
+	for range 2*len(states) + 2 {
+		if table.accepts(states, "$") {
+			return inserted, true
+		}
+		found := false
+		for _, closer := range closers {
+			if after, ok := table.shift(states, closer); ok {
+				states = after
+				inserted = append(inserted, closer)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// Whether the parser can go on with the terminal after the states. The end of the input may be closed by closers
+func (p *tolerantParser) fits(states []int, terminal string) bool {
+	if terminal == "$" {
+		_, ok := p.table.closing(states)
+		return ok
+	}
+	return p.table.accepts(states, terminal)
+}
+
+// The repair with the smallest region. For every token from the error on, the stack is popped until there is a
+// non terminal after which the token fits. Ties go to the fewest pops, unless more pops join an earlier region
+func (p *tolerantParser) search(i int, states []int) *repair {
+	var best *repair
+	skipped := 0
+	for j := i; j < len(p.tokens); j++ {
+		terminal := p.tokens[j].Identifier
+		if terminal == "LINE" {
+			continue
+		}
+		if best != nil && skipped >= best.cost {
+			break
+		}
+		covered := skipped
+		for pops := 0; pops < len(states); pops++ {
+			if best != nil && covered > best.cost {
+				break
+			}
+			// Regions next to each other become one
+			merges := pops > 0 && p.stack[len(p.stack)-pops].tree.Leaf.Name == ErrorSymbol
+			if best == nil || covered < best.cost || merges {
+				below := states[:len(states)-pops]
+				for _, nonTerminal := range errorNonTerminals {
+					gotoVal, err := p.table.GetGoto(below[len(below)-1], nonTerminal)
+					if err == nil && p.fits(append(slices.Clone(below), gotoVal.val), terminal) {
+						best = &repair{pops: pops, next: j, nonTerminal: nonTerminal, state: gotoVal.val, cost: covered}
+						break
+					}
+				}
+			}
+			// The tokens of earlier regions are lost already
+			if entry := p.stack[len(p.stack)-1-pops]; entry.tree.Leaf.Name != ErrorSymbol {
+				covered += entry.tokens
+			}
+		}
+		if j > i && (best == nil || skipped < best.cost) && p.fits(states, terminal) {
+			best = &repair{next: j, cost: skipped}
+		}
+		skipped++
+	}
+	return best
+}
+
+func (p *tolerantParser) insert(i int, closers []string) {
+	inserted := []lexer.Token{}
+	for _, closer := range closers {
+		inserted = append(inserted, lexer.Token{Identifier: closer, Value: closer, Line: p.tokens[i].Line})
+	}
+	p.tokens = slices.Insert(p.tokens, i, inserted...)
+}
+
+func (p *tolerantParser) replace(i int, r repair) {
+	start := p.tokens[i]
+	region := tolerantEntry{state: r.state, tree: ParseTree{Leaf: ParseLeaf{Name: ErrorSymbol}, Branches: []ParseTree{}}}
+	for _, entry := range p.stack[len(p.stack)-r.pops:] {
+		region.tree.Branches = append(region.tree.Branches, entry.tree)
+		region.add(entry)
+	}
+	p.stack = p.stack[:len(p.stack)-r.pops]
+	for _, token := range p.tokens[i:r.next] {
+		if token.Identifier == "LINE" {
+			p.linecount, _ = strconv.Atoi(token.Value.(string))
+			continue
+		}
+		region.tree.Branches = append(region.tree.Branches, ParseTree{Leaf: ParseLeaf{Name: token.Identifier, Value: token.Value, Line: token.Line, Col: token.Col, Trivia: token.Trivia}, Branches: []ParseTree{}})
+		region.add(tolerantEntry{tokens: 1, last: token})
+	}
+	if r.nonTerminal == "" {
+		return
+	}
+
+	region.tree.Leaf.Line, region.tree.Leaf.Col = firstPosition(region.tree.Branches)
+	value := ErrorRegion{NonTerminal: r.nonTerminal, EndLine: start.Line, EndCol: start.Col}
+	if region.tree.Leaf.Line == 0 {
+		region.tree.Leaf.Line, region.tree.Leaf.Col = start.Line, start.Col
+	}
+	if region.tokens > 0 {
+		value.EndLine, value.EndCol = region.last.Line, 0
+		if region.last.Col != 0 {
+			value.EndCol = region.last.Col + region.last.Length
+		}
+	}
+	region.tree.Leaf.Value = value
+	p.stack = append(p.stack, region)
+}
+
+// Without repair, the whole input becomes one ERROR node for the start symbol
+func (p *tolerantParser) giveUp(i int) ParseTree {
+	start := p.table.grammar.start
+	for _, rule := range p.table.grammar.rules {
+		if rule.nonTerminal == p.table.grammar.start {
+			start = rule.production[0]
+		}
+	}
+	p.replace(i, repair{pops: len(p.stack) - 1, next: len(p.tokens) - 1, nonTerminal: start})
+	return p.stack[len(p.stack)-1].tree
+}
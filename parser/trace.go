@@ -0,0 +1,164 @@
+package parser
+
+import (
+	"compiler/lexer"
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+/*
+Step by step record of a parse, to see why an input is accepted or not: every shift, reduce and accept with the
+stack before it, and the token the parser failed at with the terminals it expected there. String prints it as the
+table of the textbooks:
+
+	stack        input      action
+	0            id + id $  shift 5
+	0 id 5       + id $     reduce T -> id, goto 3
+*/
+
+// One action of the parser
+type TraceStep struct {
+	// Index of the token in the input, LINE tokens are not counted
+	Position int
+	// The terminal the parser looks at
+	Lookahead string
+	// The stack before the step, the top is the last one. Symbols[i] was shifted or reduced to get to States[i+1]
+	States  []int
+	Symbols []string
+	// Shift, Reduce, Accept or Error
+	Action string
+	// The state of a shift, the state of the goto after a reduce
+	Next int
+	// The rule of a reduce
+	Rule string
+}
+
+type Trace struct {
+	Steps    []TraceStep
+	Accepted bool
+	// The terminals of the input, with "$" at the end
+	Input []string
+	// Where the parser failed, if it did not accept, and the terminals it could have taken there
+	Failed   lexer.Token
+	Expected []string
+}
+
+// Reductions the trace may do in a row, without a shift. Broken tables can reduce forever without taking input
+const traceReductions = 1000
+
+// Parses the tokens like Parse and records every step. A missing "$" at the end is added.
+// The error is set if the parser runs past the end of the input or reduces too often in a row, the trace has the steps up to there
+func (table *SLR_parsing_Table) Trace(tokens []lexer.Token) (Trace, error) {
+	return table.TraceContext(context.Background(), tokens)
+}
+
+// Trace, which stops when the context is done. The error is the one of the context then
+func (table *SLR_parsing_Table) TraceContext(ctx context.Context, tokens []lexer.Token) (Trace, error) {
+	terminals := []lexer.Token{}
+	for _, token := range tokens {
+		if token.Identifier != "LINE" {
+			terminals = append(terminals, token)
+		}
+	}
+	if len(terminals) == 0 || terminals[len(terminals)-1].Identifier != "$" {
+		terminals = append(terminals, lexer.Token{Identifier: "$", Value: "$"})
+	}
+
+	trace := Trace{}
+	for _, token := range terminals {
+		trace.Input = append(trace.Input, token.Identifier)
+	}
+	states := []int{0}
+	symbols := []string{}
+	position := 0
+	reductions := 0
+	for {
+		if ctx.Err() != nil {
+			return trace, ctx.Err()
+		}
+		if position >= len(terminals) {
+			return trace, errors.New("Trace Error: The parser shifted \"$\" and went on past the end of the input")
+		}
+		if reductions > traceReductions {
+			return trace, errors.New("Trace Error: The parser reduced more than " + strconv.Itoa(traceReductions) + " times in a row at token " + strconv.Itoa(position) + ", the table may reduce forever")
+		}
+		token := terminals[position]
+		step := TraceStep{Position: position, Lookahead: token.Identifier, States: slices.Clone(states), Symbols: slices.Clone(symbols)}
+		action, err := table.GetAction(states[len(states)-1], token.Identifier)
+		if err != nil {
+			step.Action = "Error"
+			trace.Steps = append(trace.Steps, step)
+			trace.Failed = token
+			trace.Expected = table.expectedAfter(states)
+			break
+		}
+		step.Action = action.actionType
+		switch action.actionType {
+		case "Shift":
+			step.Next = action.value
+			states = append(states, action.value)
+			symbols = append(symbols, token.Identifier)
+			position++
+			reductions = 0
+		case "Reduce":
+			reductions++
+			rule := table.grammar.rules[action.value]
+			step.Rule = rule.String()
+			reduced, err := table.reduce(states, action.value)
+			if err != nil {
+				// Only broken tables have no goto after a reduce
+				step.Action = "Error"
+				trace.Failed = token
+				break
+			}
+			states = reduced
+			step.Next = states[len(states)-1]
+			symbols = append(symbols[:len(symbols)-len(rule.production)], rule.nonTerminal)
+		case "Accept":
+			trace.Accepted = true
+		}
+		trace.Steps = append(trace.Steps, step)
+		if step.Action != "Shift" && step.Action != "Reduce" {
+			break
+		}
+	}
+	return trace, nil
+}
+
+func (trace Trace) String() string {
+	rows := [][3]string{{"stack", "input", "action"}}
+	for _, step := range trace.Steps {
+		stack := []string{strconv.Itoa(step.States[0])}
+		for j, symbol := range step.Symbols {
+			stack = append(stack, symbol, strconv.Itoa(step.States[j+1]))
+		}
+		action := ""
+		switch step.Action {
+		case "Shift":
+			action = "shift " + strconv.Itoa(step.Next)
+		case "Reduce":
+			action = "reduce " + step.Rule + ", goto " + strconv.Itoa(step.Next)
+		case "Accept":
+			action = "accept"
+		default:
+			action = "error, expected " + strings.Join(trace.Expected, " ")
+		}
+		rows = append(rows, [3]string{strings.Join(stack, " "), strings.Join(trace.Input[step.Position:], " "), action})
+	}
+
+	widths := [2]int{}
+	for _, row := range rows {
+		widths[0] = max(widths[0], len(row[0]))
+		widths[1] = max(widths[1], len(row[1]))
+	}
+	var text strings.Builder
+	for _, row := range rows {
+		text.WriteString(row[0] + strings.Repeat(" ", widths[0]-len(row[0])+2))
+		text.WriteString(row[1] + strings.Repeat(" ", widths[1]-len(row[1])+2))
+		text.WriteString(row[2] + "\n")
+	}
+	return text.String()
+}
@@ -0,0 +1,56 @@
+package parser
+
+import (
+	"compiler/lexer"
+	"testing"
+)
+
+func TestTraceEmptyInput(t *testing.T) {
+	slr := table(t, "A -> '$'")
+	within(t, func() {
+		trace, err := slr.Trace(nil)
+		if err == nil {
+			t.Fatal("no error after shifting the end of the input")
+		}
+		if trace.Accepted {
+			t.Error("accepted")
+		}
+	})
+}
+
+func TestTraceAddsMissingEnd(t *testing.T) {
+	slr := table(t, "LIST -> item LIST\nLIST ->")
+	tokens := []lexer.Token{{Identifier: "item", Value: "item"}, {Identifier: "item", Value: "item"}}
+	trace, err := slr.Trace(tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !trace.Accepted {
+		t.Fatal("not accepted:\n" + trace.String())
+	}
+	if got := trace.Input; len(got) != 3 || got[2] != "$" {
+		t.Errorf("input %v", got)
+	}
+}
+
+func TestTraceStepLimit(t *testing.T) {
+	slr := table(t, "A -> x B\nB ->")
+	// A broken table which reduces B -> ε in state 0 on x and goes back to state 0
+	empty := -1
+	for i, rule := range slr.grammar.rules {
+		if rule.nonTerminal == "B" {
+			empty = i
+		}
+	}
+	slr.actionTable[0]["x"] = &Action{actionType: "Reduce", value: empty}
+	slr.gotoToTable[0] = map[string]*GoTo{"B": {val: 0}}
+	within(t, func() {
+		trace, err := slr.Trace(words("x"))
+		if err == nil {
+			t.Fatal("no error for a parser which reduces forever")
+		}
+		if len(trace.Steps) > traceReductions+1 {
+			t.Errorf("%d steps", len(trace.Steps))
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package parser
+
+/*
+Example inputs of a grammar, found by breadth first search:
+the shortest sentence of the grammar, and for a conflict of a table an input which runs the parser into
+the state of the conflict followed by the terminal of the conflict. The way to the state has the fewest symbols,
+its non terminals are replaced by the shortest terminal string they derive, so the examples only contain terminals.
+*/
+
+// The shortest input the grammar accepts, false if it accepts none
+func (grammar *Grammar) ShortestSentence() ([]string, bool) {
+	sentence, ok := grammar.shortestYields()[grammar.start]
+	return sentence, ok
+}
+
+// Input which makes the table run into the conflict: the terminals up to the state of the conflict and
+// the terminal of the conflict ("$" is the end of the input). false if the state can not be reached
+func (table *SLR_parsing_Table) ConflictExample(conflict SLRConflict) ([]string, bool) {
+	return table.conflictExample(conflict, table.grammar.shortestYields())
+}
+
+func (table *SLR_parsing_Table) conflictExample(conflict SLRConflict, yields map[string][]string) ([]string, bool) {
+	// The edge each state was reached by, the shortest way to a state has the fewest symbols
+	type edge struct {
+		from   int
+		symbol string
+	}
+	reached := map[int]edge{0: {from: -1}}
+	work := []int{0}
+	for len(work) > 0 {
+		if _, found := reached[conflict.State]; found {
+			break
+		}
+		state := work[0]
+		work = work[1:]
+		next := func(symbol string, to int) {
+			if _, done := reached[to]; !done {
+				reached[to] = edge{from: state, symbol: symbol}
+				work = append(work, to)
+			}
+		}
+		actions := table.actionTable[state]
+		for _, terminal := range sortedKeys(actions) {
+			if action := actions[terminal]; action != nil && action.actionType == "Shift" {
+				next(terminal, action.value)
+			}
+		}
+		gotos := table.gotoToTable[state]
+		for _, nonTerminal := range sortedKeys(gotos) {
+			// Only non terminals which derive some input lead to states the parser can get to
+			if _, ok := yields[nonTerminal]; ok {
+				next(nonTerminal, gotos[nonTerminal].val)
+			}
+		}
+	}
+	if _, found := reached[conflict.State]; !found {
+		return nil, false
+	}
+
+	symbols := []string{}
+	for state := conflict.State; reached[state].from != -1; state = reached[state].from {
+		symbols = append([]string{reached[state].symbol}, symbols...)
+	}
+	example := []string{}
+	for _, symbol := range symbols {
+		if yield, ok := yields[symbol]; ok {
+			example = append(example, yield...)
+		} else {
+			example = append(example, symbol)
+		}
+	}
+	return append(example, conflict.Symbol), true
+}
+
+// The shortest terminal string every non terminal derives, calculated as fixed point like Nullable.
+// Non terminals which derive no terminal string are missing
+func (grammar *Grammar) shortestYields() map[string][]string {
+	yields := make(map[string][]string)
+	changed := true
+	for changed {
+		changed = false
+		for _, r := range grammar.rules {
+			yield := []string{}
+			complete := true
+			for _, s := range r.production {
+				if !grammar.isNonTerminal(s) {
+					yield = append(yield, s)
+					continue
+				}
+				sub, ok := yields[s]
+				if !ok {
+					complete = false
+					break
+				}
+				yield = append(yield, sub...)
+			}
+			if old, ok := yields[r.nonTerminal]; complete && (!ok || len(yield) < len(old)) {
+				yields[r.nonTerminal] = yield
+				changed = true
+			}
+		}
+	}
+	return yields
+}
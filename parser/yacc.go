@@ -0,0 +1,386 @@
+package parser
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+/*
+Grammar from a yacc like (.y) file:
+
+	%{
+	// Copied to the top of the generated file, e.g. imports
+	%}
+	%token NUM
+	%start expr
+	%%
+	expr : expr '+' term   { $$ = $1.(int) + $3.(int) }
+	     | term
+	     ;
+	term : NUM
+	     | '(' expr ')'    { $$ = $2 }
+	     | %empty
+	     ;
+	%%
+	// Copied to the end of the generated file
+
+Every symbol with a rule is a non terminal, everything else is a terminal. Quoted symbols are always terminals.
+The action of a rule is Go code, $$ is the value of the rule, $1, $2, ... the values of the symbols.
+Without an action $$ is $1. Actions are only allowed at the end of an alternative.
+%token is optional, %start defaults to the non terminal of the first rule.
+The // comment lines right in front of a rule are the doc comment of its non terminal.
+
+Syntax errors are recovered from like in yacc: the terminal error in a rule, "stmt : error ';'", stands for the
+broken input. The parser pops states until one can take error, then skips the tokens which can not come next.
+Without such a rule "%sync ';' '}'" names tokens the parser skips to, it goes on after the next one of them.
+Errors in the three tokens after a recovery are not reported. The value of error is nil.
+
+For a generated lexer (see lexgen.go) "%token NUM /[0-9]+/" gives a terminal a regular expression (Go syntax)
+and "%skip /[ \t\n]+/" describes input between the tokens. Quoted terminals match their text.
+*/
+
+type YaccGrammar struct {
+	Grammar *Grammar
+	// Semantic action of every rule in Grammar, "" if the rule has none
+	Actions []string
+	// Code between %{ and %}
+	Prologue string
+	// Code after the second %%
+	Epilogue string
+	// The terminals with a regular expression, in the order of their declarations
+	Patterns []TokenPattern
+	// Regular expressions of the input which is skipped between tokens
+	Skip []string
+	// Quoted terminals, in the order they appear in the rules
+	Literals []string
+	// Tokens to skip to after a syntax error which no error rule catches, from %sync
+	Sync []string
+}
+
+// The terminal of the rules for syntax errors, the lexer never returns it
+const yaccError = "error"
+
+// From "%token NAME /regex/"
+type TokenPattern struct {
+	Terminal string
+	Pattern  string
+}
+
+type yaccSymbol struct {
+	text string
+	// name / literal / action / directive / punctuation
+	kind string
+	line int
+	// The // comment lines right in front of a name
+	doc string
+}
+
+func ParseYacc(source string) (*YaccGrammar, error) {
+	yacc := new(YaccGrammar)
+
+	// Declarations are everything before the first %%. The newline in front makes the lines start at 1 and finds a %% in the first line
+	declarations, rest, found := strings.Cut("\n"+source, "\n%%")
+	if !found {
+		return nil, errors.New("Yacc Error: Missing %% before the rules")
+	}
+	start := ""
+	prologue := []string{}
+	inPrologue := false
+	for i, declaration := range strings.Split(declarations, "\n") {
+		lineString := strconv.Itoa(i)
+		trimmed := strings.TrimSpace(declaration)
+		switch {
+		case inPrologue && trimmed == "%}":
+			inPrologue = false
+		case inPrologue:
+			prologue = append(prologue, declaration)
+		case trimmed == "%{":
+			inPrologue = true
+		case trimmed == "" || strings.HasPrefix(trimmed, "//"):
+		default:
+			fields := strings.Fields(trimmed)
+			switch fields[0] {
+			case "%token":
+				// Documents the terminals, every symbol without rules is one. With a regular expression for the lexer
+				if pattern, ok, err := yaccPattern(trimmed, fields, 2, lineString); err != nil {
+					return nil, err
+				} else if ok {
+					yacc.Patterns = append(yacc.Patterns, TokenPattern{Terminal: fields[1], Pattern: pattern})
+				}
+			case "%skip":
+				pattern, ok, err := yaccPattern(trimmed, fields, 1, lineString)
+				if err != nil {
+					return nil, err
+				}
+				if !ok {
+					return nil, errors.New("Yacc Error at line " + lineString + ": Expected \"%skip /regex/\"")
+				}
+				yacc.Skip = append(yacc.Skip, pattern)
+			case "%sync":
+				if len(fields) < 2 {
+					return nil, errors.New("Yacc Error at line " + lineString + ": Expected \"%sync TOKEN ...\"")
+				}
+				for _, field := range fields[1:] {
+					if len(field) > 2 && (field[0] == '\'' || field[0] == '"') && field[len(field)-1] == field[0] {
+						field = field[1 : len(field)-1]
+					}
+					yacc.Sync = append(yacc.Sync, field)
+				}
+			case "%start":
+				if len(fields) != 2 {
+					return nil, errors.New("Yacc Error at line " + lineString + ": Expected \"%start NONTERMINAL\"")
+				}
+				start = fields[1]
+			default:
+				return nil, errors.New("Yacc Error at line " + lineString + ": Unknown declaration " + fields[0])
+			}
+		}
+	}
+	if inPrologue {
+		return nil, errors.New("Yacc Error: Missing %}")
+	}
+	yacc.Prologue = strings.Join(prologue, "\n")
+
+	rulesSource, epilogue, _ := strings.Cut(rest, "\n%%")
+	yacc.Epilogue = strings.Trim(epilogue, "\n")
+
+	symbols, err := splitYacc(rulesSource, strings.Count(declarations, "\n")+1)
+	if err != nil {
+		return nil, err
+	}
+	rules, actions, docs, err := parseYaccRules(symbols)
+	if err != nil {
+		return nil, err
+	}
+	for _, symbol := range symbols {
+		if symbol.kind == "literal" && contains(yacc.Literals, symbol.text) == -1 {
+			yacc.Literals = append(yacc.Literals, symbol.text)
+		}
+	}
+	if start == "" {
+		start = rules[0].nonTerminal
+	}
+	yacc.Grammar = MakeGrammar(rules, start)
+	for nonTerminal, doc := range docs {
+		yacc.Grammar.SetDoc(nonTerminal, doc)
+	}
+	if contains(yacc.Grammar.nonTerminals, start) == -1 {
+		return nil, errors.New("Yacc Error: Start symbol " + start + " has no rules")
+	}
+	yacc.Actions = actions
+	return yacc, nil
+}
+
+// The /regex/ after the directive and the given number of names, false if the declaration has none
+func yaccPattern(declaration string, fields []string, names int, lineString string) (string, bool, error) {
+	slash := strings.Index(declaration, "/")
+	if slash == -1 {
+		return "", false, nil
+	}
+	// Everything from the first / to the last one, so the expression can contain spaces and /
+	pattern := declaration[slash:]
+	if len(fields) <= names || !strings.HasPrefix(fields[names], "/") || len(pattern) < 3 || !strings.HasSuffix(pattern, "/") {
+		return "", false, errors.New("Yacc Error at line " + lineString + ": Expected \"" + fields[0] + strings.Repeat(" NAME", names-1) + " /regex/\"")
+	}
+	pattern = pattern[1 : len(pattern)-1]
+	if _, err := regexp.Compile(pattern); err != nil {
+		return "", false, errors.New("Yacc Error at line " + lineString + ": " + err.Error())
+	}
+	return pattern, true, nil
+}
+
+// Rules are "NONTERMINAL : symbols { action } | symbols ;". The ; of the last rule can be left out
+func parseYaccRules(symbols []yaccSymbol) ([]Rule, []string, map[string]string, error) {
+	rules := []Rule{}
+	actions := []string{}
+	docs := make(map[string]string)
+	i := 0
+	for i < len(symbols) {
+		if symbols[i].kind != "name" || i+1 >= len(symbols) || !symbols[i+1].is(":") {
+			return nil, nil, nil, symbols[i].error("Expected \"NONTERMINAL :\"")
+		}
+		nonTerminal := symbols[i].text
+		if symbols[i].doc != "" {
+			docs[nonTerminal] = strings.TrimSpace(docs[nonTerminal] + "\n" + symbols[i].doc)
+		}
+		i += 2
+
+		production := []string{}
+		action := ""
+		hasAction := false
+		for {
+			// A name followed by : starts the next rule
+			endOfRule := i >= len(symbols) || symbols[i].is(";") ||
+				(symbols[i].kind == "name" && i+1 < len(symbols) && symbols[i+1].is(":"))
+			if endOfRule || symbols[i].is("|") {
+				rules = append(rules, MakeRule(nonTerminal, production))
+				actions = append(actions, action)
+				production = []string{}
+				action = ""
+				hasAction = false
+				if endOfRule {
+					if i < len(symbols) && symbols[i].is(";") {
+						i++
+					}
+					break
+				}
+				i++
+				continue
+			}
+
+			symbol := symbols[i]
+			i++
+			switch {
+			case hasAction:
+				return nil, nil, nil, symbol.error("Actions are only allowed at the end of an alternative")
+			case symbol.kind == "action":
+				action = symbol.text
+				hasAction = true
+			case symbol.text == "%empty":
+			case symbol.kind == "directive":
+				return nil, nil, nil, symbol.error("Unknown directive " + symbol.text)
+			case symbol.is(":") || symbol.text == "$":
+				return nil, nil, nil, symbol.error("Unexpected \"" + symbol.text + "\"")
+			default:
+				production = append(production, symbol.text)
+			}
+		}
+	}
+	if len(rules) == 0 {
+		return nil, nil, nil, errors.New("Yacc Error: Grammar has no rules")
+	}
+	return rules, actions, docs, nil
+}
+
+// Quoted literals like ';' are no punctuation
+func (symbol yaccSymbol) is(punctuation string) bool {
+	return symbol.kind == "punctuation" && symbol.text == punctuation
+}
+
+func (symbol yaccSymbol) error(message string) error {
+	return errors.New("Yacc Error at line " + strconv.Itoa(symbol.line) + ": " + message)
+}
+
+// Splits the rules into names, quoted literals, {actions}, %directives and the punctuation : | ;
+func splitYacc(source string, line int) ([]yaccSymbol, error) {
+	symbols := []yaccSymbol{}
+	// The // comment lines in front of the next symbol, a line without anything ends them
+	comment := []string{}
+	blank := true
+	add := func(symbol yaccSymbol) {
+		if symbol.kind == "name" {
+			symbol.doc = strings.Join(comment, "\n")
+		}
+		symbols = append(symbols, symbol)
+		comment = []string{}
+		blank = false
+	}
+	runes := []rune(source)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+		switch {
+		case c == '\n':
+			if blank {
+				comment = []string{}
+			}
+			blank = true
+			line++
+		case unicode.IsSpace(c):
+		case c == '/' && next == '/':
+			start := i + 2
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			// Comments behind symbols do not document the next rule
+			if blank {
+				comment = append(comment, strings.TrimSpace(string(runes[start:i])))
+			}
+			blank = false
+			i--
+		case c == '/' && next == '*':
+			end := strings.Index(string(runes[i+2:]), "*/")
+			if end == -1 {
+				return nil, errors.New("Yacc Error at line " + strconv.Itoa(line) + ": Unclosed comment")
+			}
+			comment := []rune(string(runes[i+2:])[:end])
+			line += strings.Count(string(comment), "\n")
+			i += len(comment) + 3
+		case c == '\'' || c == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != c && runes[end] != '\n' {
+				end++
+			}
+			if end >= len(runes) || runes[end] != c || end == i+1 {
+				return nil, errors.New("Yacc Error at line " + strconv.Itoa(line) + ": Broken literal")
+			}
+			add(yaccSymbol{text: string(runes[i+1 : end]), kind: "literal", line: line})
+			i = end
+		case c == '{':
+			end, err := skipGoBlock(runes, i)
+			if err != nil {
+				return nil, errors.New("Yacc Error at line " + strconv.Itoa(line) + ": " + err.Error())
+			}
+			code := string(runes[i+1 : end])
+			add(yaccSymbol{text: strings.TrimSpace(code), kind: "action", line: line})
+			line += strings.Count(code, "\n")
+			i = end
+		case c == ':' || c == '|' || c == ';':
+			add(yaccSymbol{text: string(c), kind: "punctuation", line: line})
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune(":|;{'\"", runes[end]) {
+				end++
+			}
+			kind := "name"
+			if c == '%' {
+				kind = "directive"
+			}
+			add(yaccSymbol{text: string(runes[i:end]), kind: kind, line: line})
+			i = end - 1
+		}
+	}
+	return symbols, nil
+}
+
+// Index of the } closing the { at start. Braces in strings, runes and comments of the Go code do not count
+func skipGoBlock(runes []rune, start int) (int, error) {
+	depth := 0
+	for i := start; i < len(runes); i++ {
+		switch runes[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		case '"', '\'', '`':
+			quote := runes[i]
+			for i++; i < len(runes) && runes[i] != quote; i++ {
+				if runes[i] == '\\' && quote != '`' {
+					i++
+				}
+			}
+		case '/':
+			if i+1 < len(runes) && runes[i+1] == '/' {
+				for i < len(runes) && runes[i] != '\n' {
+					i++
+				}
+			} else if i+1 < len(runes) && runes[i+1] == '*' {
+				end := strings.Index(string(runes[i+2:]), "*/")
+				if end == -1 {
+					return 0, errors.New("Unclosed comment in action")
+				}
+				i += len([]rune(string(runes[i+2:])[:end])) + 3
+			}
+		}
+	}
+	return 0, errors.New("Unclosed action")
+}
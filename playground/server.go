@@ -0,0 +1,276 @@
+package playground
+
+import (
+	"compiler/lexer"
+	"compiler/parser"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+HTTP server to try out grammars, the backend of a web playground. Every endpoint takes a JSON object:
+
+	{"grammar": "E -> E + T | T\nT -> id", "format": "bnf", "parser": "slr", "input": "id + id", "collapse": false}
+
+format is bnf (default, see parser.ParseBNF), yacc or sexpr (see parser.ParseSExpr), parser is slr (default) or lalr.
+The input is a list of terminals separated by whitespace, their value is the terminal itself.
+The analysis lists the operator precedence chains of the grammar (see parser.PrecedenceTables),
+with collapse they are replaced by one non terminal each before the parser is built.
+
+	POST /analyze  start symbol, nullable non terminals, FIRST and FOLLOW sets, conflicts, the size of the table, the automaton as DOT,
+	               left recursion and warnings about cycles, unreachable and non productive non terminals
+	POST /parse    everything from /analyze, the parse tree of the input as JSON and as DOT and the steps of the parser
+
+Errors in the grammar or the input are reported in the "error" field with status 200,
+only requests which are not JSON get a 400. The parser of the input is stopped after 5 seconds, with an error.
+*/
+
+// Grammars bigger than this are refused, the automaton grows fast
+const maxRequestSize = 1 << 20
+
+// Time the parser of the input gets per request
+const requestTimeout = 5 * time.Second
+
+type request struct {
+	Grammar string `json:"grammar"`
+	Format  string `json:"format"`
+	Parser  string `json:"parser"`
+	Input   string `json:"input"`
+	// Collapse the operator precedence chains
+	Collapse bool `json:"collapse"`
+}
+
+type conflict struct {
+	State  int      `json:"state"`
+	Symbol string   `json:"symbol"`
+	Kind   string   `json:"kind"`
+	Rules  []string `json:"rules"`
+	// Input which runs into the conflict
+	Example []string `json:"example"`
+}
+
+type analysis struct {
+	Start     string              `json:"start"`
+	Nullable  []string            `json:"nullable"`
+	First     map[string][]string `json:"first"`
+	Follow    map[string][]string `json:"follow"`
+	Conflicts []conflict          `json:"conflicts"`
+	Automaton string              `json:"automaton"`
+	// Size of the table, see parser.TableStats
+	Stats stats `json:"stats"`
+	// Precedence chains of the grammar as it was given
+	Precedence []precedenceTable `json:"precedence"`
+	// Left recursive cycles and the cycles, unreachable and non productive non terminals, see parser/sanity.go
+	LeftRecursion [][]string `json:"leftRecursion"`
+	Warnings      []string   `json:"warnings"`
+}
+
+type stats struct {
+	States            int   `json:"states"`
+	Terminals         int   `json:"terminals"`
+	NonTerminals      int   `json:"nonTerminals"`
+	Rules             int   `json:"rules"`
+	Shifts            int   `json:"shifts"`
+	Reduces           int   `json:"reduces"`
+	Gotos             int   `json:"gotos"`
+	DefaultReductions int   `json:"defaultReductions"`
+	Unreachable       []int `json:"unreachable"`
+	Deterministic     bool  `json:"deterministic"`
+}
+
+type precedenceLevel struct {
+	NonTerminal   string   `json:"nonTerminal"`
+	Operators     []string `json:"operators"`
+	Associativity string   `json:"associativity"`
+}
+
+type precedenceTable struct {
+	Top     string            `json:"top"`
+	Levels  []precedenceLevel `json:"levels"`
+	Operand string            `json:"operand"`
+}
+
+type treeNode struct {
+	Symbol   string     `json:"symbol"`
+	Value    any        `json:"value,omitempty"`
+	Line     int        `json:"line,omitempty"`
+	Children []treeNode `json:"children,omitempty"`
+}
+
+type response struct {
+	*analysis
+	Tree    *treeNode `json:"tree,omitempty"`
+	TreeDot string    `json:"treeDot,omitempty"`
+	// Every step of the parser as table, see parser.Trace
+	Trace string `json:"trace,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /analyze", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, false)
+	})
+	mux.HandleFunc("POST /parse", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, true)
+	})
+	return mux
+}
+
+func serve(w http.ResponseWriter, r *http.Request, parse bool) {
+	var req request
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&req); err != nil {
+		http.Error(w, "Request is not a JSON object: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(handle(ctx, req, parse))
+}
+
+// Broken grammars can make the parser construction panic, that is reported as error of the request.
+// The parser of the input stops when the context is done
+func handle(ctx context.Context, req request, parse bool) (resp response) {
+	defer func() {
+		if r := recover(); r != nil {
+			resp = response{Error: fmt.Sprint(r)}
+		}
+	}()
+
+	grammar, err := readGrammar(req)
+	if err != nil {
+		return response{Error: err.Error()}
+	}
+	precedence := grammar.PrecedenceTables()
+	// Before the table is built, that adds the start rule
+	leftRecursion, warnings := grammar.LeftRecursion(), grammar.Warnings()
+	if req.Collapse {
+		for _, chain := range precedence {
+			grammar = grammar.CollapsePrecedence(chain)
+		}
+	}
+	table, err := build(grammar, req.Parser)
+	if err != nil {
+		return response{Error: err.Error()}
+	}
+
+	resp.analysis = analyze(grammar, table)
+	resp.LeftRecursion, resp.Warnings = leftRecursion, warnings
+	for _, chain := range precedence {
+		levels := []precedenceLevel{}
+		for _, level := range chain.Levels {
+			levels = append(levels, precedenceLevel{NonTerminal: level.NonTerminal, Operators: level.Operators, Associativity: level.Associativity})
+		}
+		resp.Precedence = append(resp.Precedence, precedenceTable{Top: chain.Top, Levels: levels, Operand: chain.Operand})
+	}
+	if !parse {
+		return resp
+	}
+	trace, err := table.TraceContext(ctx, tokens(req.Input))
+	resp.Trace = trace.String()
+	if err != nil {
+		resp.Error = timeoutError(err)
+		return resp
+	}
+	tree, err := table.ParseContext(ctx, tokens(req.Input))
+	if err != nil {
+		resp.Error = timeoutError(err)
+		return resp
+	}
+	node := makeTreeNode(*tree)
+	resp.Tree = &node
+	resp.TreeDot = parser.TreeDot(*tree)
+	return resp
+}
+
+// The error of the request, a context which ran out says so
+func timeoutError(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "The parser took longer than " + requestTimeout.String() + ", the request was stopped"
+	}
+	return err.Error()
+}
+
+func build(grammar *parser.Grammar, kind string) (*parser.SLR_parsing_Table, error) {
+	switch kind {
+	case "", "slr":
+		return grammar.CreateSLRParser(), nil
+	case "lalr":
+		return grammar.CreateLALRParser(), nil
+	}
+	return nil, errors.New("Unknown parser " + kind + ", use slr or lalr")
+}
+
+func readGrammar(req request) (*parser.Grammar, error) {
+	if strings.TrimSpace(req.Grammar) == "" {
+		return nil, errors.New("No grammar given")
+	}
+	switch req.Format {
+	case "", "bnf":
+		return parser.ParseBNF(req.Grammar)
+	case "yacc":
+		yacc, err := parser.ParseYacc(req.Grammar)
+		if err != nil {
+			return nil, err
+		}
+		return yacc.Grammar, nil
+	case "sexpr":
+		return parser.ParseSExpr(req.Grammar)
+	}
+	return nil, errors.New("Unknown grammar format " + req.Format + ", use bnf, yacc or sexpr")
+}
+
+func analyze(grammar *parser.Grammar, table *parser.SLR_parsing_Table) *analysis {
+	first := grammar.FIRST()
+	result := &analysis{Start: grammar.Start(), Nullable: []string{}, First: first, Follow: grammar.FOLLOW(first), Conflicts: []conflict{}, Automaton: table.Dot(), Precedence: []precedenceTable{}}
+	for symbol, nullable := range grammar.Nullable() {
+		if nullable {
+			result.Nullable = append(result.Nullable, symbol)
+		}
+	}
+	slices.Sort(result.Nullable)
+	tableStats := table.Stats()
+	result.Stats = stats{States: tableStats.States, Terminals: tableStats.Terminals, NonTerminals: tableStats.NonTerminals, Rules: tableStats.Rules,
+		Shifts: tableStats.Shifts, Reduces: tableStats.Reduces, Gotos: tableStats.Gotos, DefaultReductions: tableStats.DefaultReductions,
+		Unreachable: append([]int{}, tableStats.Unreachable...), Deterministic: tableStats.Deterministic()}
+	for _, c := range table.Conflicts() {
+		rules := []string{}
+		for _, rule := range c.Rules {
+			rules = append(rules, rule.String())
+		}
+		result.Conflicts = append(result.Conflicts, conflict{State: c.State, Symbol: c.Symbol, Kind: c.Kind, Rules: rules, Example: append([]string{}, c.Example...)})
+	}
+	return result
+}
+
+// One token per word, with a LINE token for every line like the lexer sends them
+func tokens(input string) []lexer.Token {
+	tokens := []lexer.Token{}
+	for i, line := range strings.Split(input, "\n") {
+		tokens = append(tokens, lexer.Token{Identifier: "LINE", Value: strconv.Itoa(i + 1), Line: i + 1})
+		for _, word := range strings.Fields(line) {
+			tokens = append(tokens, lexer.Token{Identifier: word, Value: word, Line: i + 1})
+		}
+	}
+	return append(tokens, lexer.Token{Identifier: "$", Value: "$", Line: strings.Count(input, "\n") + 1})
+}
+
+func makeTreeNode(tree parser.ParseTree) treeNode {
+	node := treeNode{Symbol: tree.Leaf.Name, Line: tree.Leaf.Line}
+	// Non terminals have the value 0
+	if len(tree.Branches) == 0 && tree.Leaf.Value != 0 {
+		node.Value = tree.Leaf.Value
+	}
+	for _, branch := range tree.Branches {
+		node.Children = append(node.Children, makeTreeNode(branch))
+	}
+	return node
+}
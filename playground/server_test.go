@@ -0,0 +1,63 @@
+package playground
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The fields of the response the tests look at
+type result struct {
+	Tree  *treeNode `json:"tree"`
+	Error string    `json:"error"`
+}
+
+func post(t *testing.T, path string, body string) result {
+	t.Helper()
+	server := httptest.NewServer(Handler())
+	defer server.Close()
+	client := &http.Client{Timeout: 10 * time.Second}
+	r, err := client.Post(server.URL+path, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Body.Close()
+	var resp result
+	if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestParse(t *testing.T) {
+	resp := post(t, "/parse", `{"grammar": "E -> E + T | T\nT -> id", "input": "id + id"}`)
+	if resp.Error != "" || resp.Tree == nil {
+		t.Fatalf("error %q, tree %v", resp.Error, resp.Tree)
+	}
+}
+
+func TestPathologicalRequests(t *testing.T) {
+	for _, body := range []string{
+		// The terminal S was once the start symbol of the augmented grammar too, the parser looped
+		`{"grammar": "A -> S\nA ->\nB -> S C", "input": "C"}`,
+		// The parser shifts the "$" of the grammar and runs out of input
+		`{"grammar": "A -> '$'", "input": ""}`,
+	} {
+		if resp := post(t, "/parse", body); resp.Error == "" {
+			t.Errorf("no error for %s", body)
+		}
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	resp := handle(ctx, request{Grammar: "E -> E + T | T\nT -> id", Input: "id + id"}, true)
+	if resp.Error == "" {
+		t.Error("no error after the context is done")
+	}
+}
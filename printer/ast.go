@@ -0,0 +1,226 @@
+package printer
+
+import (
+	"compiler/ast"
+	"strconv"
+	"strings"
+)
+
+/*
+Documents for the nodes of the AST, the base of a formatter. The layout:
+	usings, a blank line, then namespace and class with the braces at the end of the line
+	a blank line between functions, one statement per line, } else { on one line
+	calls and parameter lists which do not fit get one argument per line
+	binary operators which do not fit break after the operator, the operands of one precedence level together
+Parentheses are only printed where the precedence needs them, so (a + b) + c becomes a + b + c.
+The AST has no comments, they are lost. The tokens with their comments are in the CST, see parser/cst.go.
+*/
+
+// The source of the node, a program ends with a line break
+func (printer *Printer) Print(node ast.Node) string {
+	out := printer.Render(Node(node))
+	if _, ok := node.(*ast.Program); ok {
+		out += "\n"
+	}
+	return out
+}
+
+// The document of a program, function, parameter, block, statement or expression
+func Node(node ast.Node) Doc {
+	switch n := node.(type) {
+	case *ast.Program:
+		return program(n)
+	case *ast.Function:
+		return function(n)
+	case *ast.Param:
+		return Text(n.Type + " " + n.Name)
+	case *ast.Block:
+		return block(n)
+	case ast.Stmt:
+		return statement(n)
+	case ast.Expr:
+		return expression(n, 0)
+	}
+	return Text("")
+}
+
+func program(n *ast.Program) Doc {
+	docs := []Doc{}
+	for _, using := range n.Usings {
+		docs = append(docs, Text("using "+using+";"), HardLine())
+	}
+	if len(n.Usings) > 0 {
+		docs = append(docs, HardLine())
+	}
+	functions := []Doc{}
+	for _, f := range n.Functions {
+		functions = append(functions, function(f))
+	}
+	for _, err := range n.Errors {
+		functions = append(functions, errorDoc(err))
+	}
+	class := Concat(Text("class "+n.Class+" {"), Indent(Concat(HardLine(), Join(Concat(HardLine(), HardLine()), functions))), HardLine(), Text("}"))
+	docs = append(docs, Text("namespace "+n.Namespace+" {"), Indent(Concat(HardLine(), class)), HardLine(), Text("}"))
+	return Concat(docs...)
+}
+
+func function(n *ast.Function) Doc {
+	params := []Doc{}
+	for _, param := range n.Params {
+		params = append(params, Text(param.Type+" "+param.Name))
+	}
+	head := Text("static " + n.ReturnType + " " + n.Name)
+	if n.Extern {
+		head = Text("static extern " + n.ReturnType + " " + n.Name)
+	}
+	signature := Concat(head, list(params))
+	if n.Body == nil {
+		return Concat(signature, Text(";"))
+	}
+	return Concat(signature, Text(" "), block(n.Body))
+}
+
+// ( elements ), one per line if they do not fit
+func list(elements []Doc) Doc {
+	if len(elements) == 0 {
+		return Text("()")
+	}
+	return Group(Concat(Text("("), Indent(Concat(SoftLine(), Join(Concat(Text(","), Line()), elements))), SoftLine(), Text(")")))
+}
+
+func block(n *ast.Block) Doc {
+	if len(n.Statements) == 0 {
+		return Text("{}")
+	}
+	statements := []Doc{}
+	for _, s := range n.Statements {
+		statements = append(statements, statement(s))
+	}
+	return Concat(Text("{"), Indent(Concat(HardLine(), Join(HardLine(), statements))), HardLine(), Text("}"))
+}
+
+func statement(s ast.Stmt) Doc {
+	switch n := s.(type) {
+	case *ast.VarDecl:
+		if n.Value == nil {
+			return Text(n.Type + " " + n.Name + ";")
+		}
+		return Group(Concat(Text(n.Type+" "+n.Name+" = "), expression(n.Value, 0), Text(";")))
+	case *ast.Assign:
+		return Group(Concat(Text(n.Name+" = "), expression(n.Value, 0), Text(";")))
+	case *ast.CallStmt:
+		return Concat(expression(n.Call, 0), Text(";"))
+	case *ast.Return:
+		if n.Value == nil {
+			return Text("return;")
+		}
+		return Group(Concat(Text("return "), expression(n.Value, 0), Text(";")))
+	case *ast.If:
+		doc := Concat(condition("if", n.Cond), block(n.Then))
+		if n.Else != nil {
+			doc = Concat(doc, Text(" else "), block(n.Else))
+		}
+		return doc
+	case *ast.While:
+		return Concat(condition("while", n.Cond), block(n.Body))
+	case *ast.Error:
+		return errorDoc(n)
+	}
+	return Text("")
+}
+
+// if ( condition ) {, the condition on its own lines if it does not fit
+func condition(keyword string, cond ast.Expr) Doc {
+	return Concat(Text(keyword+" "), Group(Concat(Text("("), Indent(Concat(SoftLine(), expression(cond, 0))), SoftLine(), Text(")"))), Text(" "))
+}
+
+// Regions the tolerant parser could not parse have no source any more
+func errorDoc(n *ast.Error) Doc {
+	return Text("/* ERROR " + n.NonTerminal + " */")
+}
+
+// Precedence levels of the grammar, higher binds stronger. All binary operators are left associative
+func precedence(op string) int {
+	switch op {
+	case "*", "/", "%":
+		return 3
+	case "+", "-":
+		return 2
+	}
+	return 1
+}
+
+// Operands of unary operators, literals and calls
+const primary = 4
+
+// The expression in a place which needs at least the precedence, in parentheses if it binds weaker
+func expression(expr ast.Expr, min int) Doc {
+	switch e := expr.(type) {
+	case *ast.IntLit:
+		return Text(strconv.Itoa(e.Value))
+	case *ast.DoubleLit:
+		value := strconv.FormatFloat(e.Value, 'f', -1, 64)
+		if !strings.Contains(value, ".") {
+			value += ".0"
+		}
+		return Text(value)
+	case *ast.BoolLit:
+		return Text(strconv.FormatBool(e.Value))
+	case *ast.StringLit:
+		return Text("\"" + e.Value + "\"")
+	case *ast.InterpolatedString:
+		docs := []Doc{Text("$\"")}
+		for _, part := range e.Parts {
+			if part.Expr != nil {
+				docs = append(docs, Text("{"), expression(part.Expr, 0), Text("}"))
+			} else {
+				docs = append(docs, Text(strings.NewReplacer("{", "{{", "}", "}}").Replace(part.Text)))
+			}
+		}
+		return Concat(append(docs, Text("\""))...)
+	case *ast.Ident:
+		return Text(e.Name)
+	case *ast.Call:
+		name := e.Name
+		if e.Receiver != "" {
+			name = e.Receiver + "." + name
+		}
+		args := []Doc{}
+		for _, arg := range e.Args {
+			args = append(args, expression(arg, 0))
+		}
+		return Concat(Text(name), list(args))
+	case *ast.Unary:
+		// -(-1) needs no parentheses either, - -1 are two operators for the lexer
+		return Concat(Text(e.Op), expression(e.Operand, primary))
+	case *ast.Binary:
+		level := precedence(e.Op)
+		// The chain of operators of the same level, left associative: a + b - c is (a + b) - c
+		operands := []ast.Expr{e.Right}
+		operators := []string{e.Op}
+		left := e.Left
+		for {
+			inner, ok := left.(*ast.Binary)
+			if !ok || precedence(inner.Op) != level {
+				break
+			}
+			operands = append([]ast.Expr{inner.Right}, operands...)
+			operators = append([]string{inner.Op}, operators...)
+			left = inner.Left
+		}
+		rest := []Doc{}
+		for i, operand := range operands {
+			rest = append(rest, Text(" "+operators[i]), Line(), expression(operand, level+1))
+		}
+		doc := Group(Concat(expression(left, level), Indent(Concat(rest...))))
+		return parenthesize(doc, level < min)
+	}
+	return Text("")
+}
+
+func parenthesize(doc Doc, needed bool) Doc {
+	if !needed {
+		return doc
+	}
+	return Concat(Text("("), doc, Text(")"))
+}
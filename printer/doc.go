@@ -0,0 +1,200 @@
+package printer
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+Pretty printing with a document algebra, after Wadler's "A prettier printer" like Prettier does it. A layout is
+built from a few documents and rendered for a line width:
+
+	Text("x")       the text, without line breaks
+	Line()          a space, or a line break if its group does not fit in the line
+	SoftLine()      nothing, or a line break if its group does not fit in the line
+	HardLine()      always a line break, the groups around it do not fit
+	Concat(docs...) the documents after each other
+	Group(doc)      the lines of doc which are not in inner groups are either all spaces or all breaks
+	Indent(doc)     the line breaks in doc are indented one level more
+
+The outermost group which does not fit is broken first, its inner groups are tried on their own again:
+
+	Group(Concat(Text("f("), Indent(Concat(SoftLine(), Text("a,"), Line(), Text("b"))), SoftLine(), Text(")")))
+
+is f(a, b) if it fits and else one argument per line.
+*/
+
+type Doc interface {
+	isDoc()
+}
+
+type text string
+
+type line struct {
+	// What the line is when its group is flat
+	flat string
+	hard bool
+}
+
+type concat []Doc
+
+type group struct {
+	doc Doc
+}
+
+type indent struct {
+	doc Doc
+}
+
+func (text) isDoc()   {}
+func (line) isDoc()   {}
+func (concat) isDoc() {}
+func (group) isDoc()  {}
+func (indent) isDoc() {}
+
+func Text(s string) Doc {
+	return text(s)
+}
+
+func Line() Doc {
+	return line{flat: " "}
+}
+
+func SoftLine() Doc {
+	return line{}
+}
+
+func HardLine() Doc {
+	return line{hard: true}
+}
+
+func Concat(docs ...Doc) Doc {
+	return concat(docs)
+}
+
+func Group(doc Doc) Doc {
+	return group{doc}
+}
+
+func Indent(doc Doc) Doc {
+	return indent{doc}
+}
+
+// The documents with the separator between them
+func Join(separator Doc, docs []Doc) Doc {
+	joined := concat{}
+	for i, doc := range docs {
+		if i > 0 {
+			joined = append(joined, separator)
+		}
+		joined = append(joined, doc)
+	}
+	return joined
+}
+
+type Printer struct {
+	// The width of the lines in characters, groups are broken to stay within it where they can
+	Width int
+	// One level of indentation. Counts as many characters as it has, also a tab
+	Indent string
+}
+
+func MakePrinter(width int) *Printer {
+	printer := new(Printer)
+	printer.Width = width
+	printer.Indent = "    "
+	return printer
+}
+
+// A document to lay out, with the indentation and the mode of the group it is in
+type command struct {
+	level int
+	flat  bool
+	doc   Doc
+}
+
+// Lays out the document. Lines have no white space at their end
+func (printer *Printer) Render(doc Doc) string {
+	var out strings.Builder
+	// Characters in the current line, and the indentation which is written in front of the next text
+	col := 0
+	pending := -1
+	stack := []command{{doc: doc}}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		switch d := current.doc.(type) {
+		case text:
+			if d == "" {
+				continue
+			}
+			if pending >= 0 {
+				out.WriteString(strings.Repeat(printer.Indent, pending))
+				pending = -1
+			}
+			out.WriteString(string(d))
+			col += utf8.RuneCountInString(string(d))
+		case line:
+			if current.flat && !d.hard {
+				stack = append(stack, command{current.level, true, text(d.flat)})
+				continue
+			}
+			out.WriteString("\n")
+			pending = current.level
+			col = current.level * utf8.RuneCountInString(printer.Indent)
+		case concat:
+			for i := len(d) - 1; i >= 0; i-- {
+				stack = append(stack, command{current.level, current.flat, d[i]})
+			}
+		case indent:
+			stack = append(stack, command{current.level + 1, current.flat, d.doc})
+		case group:
+			flat := command{current.level, true, d.doc}
+			if current.flat || printer.fits(printer.Width-col, flat, stack) {
+				stack = append(stack, flat)
+			} else {
+				stack = append(stack, command{current.level, false, d.doc})
+			}
+		}
+	}
+	return out.String()
+}
+
+// Whether the command fits into the width up to the next line break, which may be in the commands after it
+func (printer *Printer) fits(width int, next command, rest []command) bool {
+	stack := []command{next}
+	for width >= 0 {
+		if len(stack) == 0 {
+			if len(rest) == 0 {
+				return true
+			}
+			stack = append(stack, rest[len(rest)-1])
+			rest = rest[:len(rest)-1]
+		}
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		switch d := current.doc.(type) {
+		case text:
+			width -= utf8.RuneCountInString(string(d))
+		case line:
+			if d.hard {
+				// A flat group can not have a line break, a broken one ends the line here
+				return !current.flat
+			}
+			if !current.flat {
+				return true
+			}
+			width -= utf8.RuneCountInString(d.flat)
+		case concat:
+			for i := len(d) - 1; i >= 0; i-- {
+				stack = append(stack, command{current.level, current.flat, d[i]})
+			}
+		case indent:
+			stack = append(stack, command{current.level + 1, current.flat, d.doc})
+		case group:
+			// Inner groups of the measured one are flat, the ones after it break where the command after it breaks
+			stack = append(stack, command{current.level, current.flat, d.doc})
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package regalloc
+
+import (
+	"compiler/ast"
+	"maps"
+	"slices"
+)
+
+/*
+Liveness of the variables of a function, computed backwards over the statements:
+	x = e: x is dead before the statement, the variables of e are live
+	if: live before are the variables of the condition and everything live before one of the branches
+	while: the loop head is a fixed point, the variables live there are live after the body as well
+	return: nothing after it is live
+Variables are identified by name. Variables in different scopes with the same name count as one,
+that only adds interference.
+
+Two variables interfere if one of them is written while the other one is live, they can not share a register.
+*/
+
+type Liveness struct {
+	// Variables live after the statement, sorted
+	LiveOut map[ast.Stmt][]string
+	// Variables live when the function starts. Only parameters for programs which passed the name check
+	LiveIn []string
+	// Variable -> variables which interfere with it
+	Interference map[string]map[string]bool
+	// Variable -> uses, a use in a loop counts 10 times per loop
+	Uses map[string]int
+}
+
+type set map[string]bool
+
+func Analyze(function *ast.Function) *Liveness {
+	live := &Liveness{LiveOut: make(map[ast.Stmt][]string), Interference: make(map[string]map[string]bool), Uses: make(map[string]int)}
+	if function.Body == nil {
+		return live
+	}
+	countUses(function.Body, 1, live.Uses)
+
+	in := live.block(function.Body, set{})
+	// Parameters are all written when the function starts
+	entry := maps.Clone(in)
+	for _, param := range function.Params {
+		entry[param.Name] = true
+	}
+	for _, param := range function.Params {
+		live.define(param.Name, entry)
+	}
+	live.LiveIn = sorted(in)
+	return live
+}
+
+func (live *Liveness) block(block *ast.Block, out set) set {
+	for i := len(block.Statements) - 1; i >= 0; i-- {
+		out = live.statement(block.Statements[i], out)
+	}
+	return out
+}
+
+// Returns the variables live before the statement
+func (live *Liveness) statement(statement ast.Stmt, out set) set {
+	live.LiveOut[statement] = sorted(out)
+	in := maps.Clone(out)
+	switch s := statement.(type) {
+	case *ast.VarDecl:
+		live.define(s.Name, out)
+		delete(in, s.Name)
+		if s.Value != nil {
+			uses(s.Value, in)
+		}
+	case *ast.Assign:
+		live.define(s.Name, out)
+		delete(in, s.Name)
+		uses(s.Value, in)
+	case *ast.CallStmt:
+		uses(s.Call, in)
+	case *ast.Return:
+		live.LiveOut[statement] = []string{}
+		in = set{}
+		if s.Value != nil {
+			uses(s.Value, in)
+		}
+	case *ast.If:
+		in = maps.Clone(live.block(s.Then, out))
+		if s.Else != nil {
+			maps.Copy(in, live.block(s.Else, out))
+		} else {
+			maps.Copy(in, out)
+		}
+		uses(s.Cond, in)
+	case *ast.While:
+		head := maps.Clone(out)
+		uses(s.Cond, head)
+		for {
+			next := maps.Clone(head)
+			maps.Copy(next, live.block(s.Body, head))
+			if len(next) == len(head) {
+				break
+			}
+			head = next
+		}
+		in = head
+	}
+	return in
+}
+
+// The variable is written while the variables in out are live
+func (live *Liveness) define(name string, out set) {
+	live.node(name)
+	for other := range out {
+		if other == name {
+			continue
+		}
+		live.node(other)
+		live.Interference[name][other] = true
+		live.Interference[other][name] = true
+	}
+}
+
+func (live *Liveness) node(name string) {
+	if live.Interference[name] == nil {
+		live.Interference[name] = make(map[string]bool)
+	}
+}
+
+// Adds the variables read by the node to the set
+func uses(node ast.Node, in set) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok {
+			in[ident.Name] = true
+		}
+		return true
+	})
+}
+
+func countUses(node ast.Node, weight int, counts map[string]int) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch e := n.(type) {
+		case *ast.Ident:
+			counts[e.Name] += weight
+		case *ast.Assign:
+			counts[e.Name] += weight
+		case *ast.While:
+			countUses(e.Cond, weight*10, counts)
+			countUses(e.Body, weight*10, counts)
+			return false
+		}
+		return true
+	})
+}
+
+func sorted(s set) []string {
+	names := []string{}
+	for name := range s {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,101 @@
+package regalloc
+
+import (
+	"compiler/ast"
+	"slices"
+)
+
+/*
+Register allocation by graph coloring (Chaitin, with the optimistic coloring of Briggs):
+	Simplify: a variable with less neighbours than registers always gets a register, it is removed from the graph.
+	If there is none, the variable with the lowest spill cost per neighbour is removed, it might still get one.
+	Select: the variables get a register in reverse order, the lowest one no neighbour has.
+	Variables without a register are spilled: they stay in the stack frame, every use is a load or store.
+
+The spill cost of a variable is its number of uses, uses in loops count 10 times per loop.
+*/
+
+type Allocation struct {
+	Registers int
+	// Variable -> register, 0 to Registers-1
+	Assigned map[string]int
+	// Variables without a register, sorted
+	Spilled []string
+	// Loads and stores the spilled variables need, weighted like the spill cost
+	SpillCost int
+}
+
+// Allocates the variables and parameters of the function to the registers
+func Allocate(function *ast.Function, registers int) *Allocation {
+	live := Analyze(function)
+	allocation := &Allocation{Registers: registers, Assigned: make(map[string]int), Spilled: []string{}}
+
+	// Graph which gets smaller during simplify
+	degree := make(map[string]int)
+	for name, neighbours := range live.Interference {
+		degree[name] = len(neighbours)
+	}
+	stack := []string{}
+	for len(degree) > 0 {
+		next := ""
+		for _, name := range sortedKeys(degree) {
+			if degree[name] < registers {
+				next = name
+				break
+			}
+		}
+		if next == "" {
+			next = spillCandidate(degree, live.Uses)
+		}
+		stack = append(stack, next)
+		delete(degree, next)
+		for neighbour := range live.Interference[next] {
+			if _, ok := degree[neighbour]; ok {
+				degree[neighbour]--
+			}
+		}
+	}
+
+	for i := len(stack) - 1; i >= 0; i-- {
+		name := stack[i]
+		taken := make(map[int]bool)
+		for neighbour := range live.Interference[name] {
+			if register, ok := allocation.Assigned[neighbour]; ok {
+				taken[register] = true
+			}
+		}
+		register := 0
+		for taken[register] {
+			register++
+		}
+		if register < registers {
+			allocation.Assigned[name] = register
+			continue
+		}
+		allocation.Spilled = append(allocation.Spilled, name)
+		allocation.SpillCost += live.Uses[name]
+	}
+	slices.Sort(allocation.Spilled)
+	return allocation
+}
+
+// The variable which is the cheapest to spill, compared to how much it frees the graph
+func spillCandidate(degree map[string]int, uses map[string]int) string {
+	best := ""
+	for _, name := range sortedKeys(degree) {
+		// cost / degree, compared without dividing
+		if best == "" || uses[name]*degree[best] < uses[best]*degree[name] {
+			best = name
+		}
+	}
+	return best
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
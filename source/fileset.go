@@ -0,0 +1,248 @@
+package source
+
+import (
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+/*
+The sources of a compilation, like go/token. Every file gets a range of Pos values, so a single int
+names a place in any of the files. Files map byte offsets to lines and columns and the other way round.
+
+A comment "//line name:line" at the start of a line makes the next line line of the file name,
+e.g. for code which was generated from another file. Adjusted positions follow these directives,
+the excerpts of diagnostics still come from the real lines.
+*/
+
+// Offset in a file set. NoPos is no position, the first file starts at 1
+type Pos int
+
+const NoPos Pos = 0
+
+type FileSet struct {
+	mutex sync.Mutex
+	// Base of the next file
+	base  int
+	files []*File
+}
+
+type File struct {
+	name    string
+	base    int
+	content []byte
+	// Offsets of the line starts, the first line starts at 0
+	lines []int
+	// //line directives, by the real line they start at
+	infos []lineInfo
+}
+
+type lineInfo struct {
+	// First real line the directive applies to
+	line int
+	file string
+	// Adjusted number of that line
+	adjusted int
+}
+
+func MakeFileSet() *FileSet {
+	newSet := new(FileSet)
+	newSet.base = 1
+	return newSet
+}
+
+// Adds the content of a file. A file added again with the same name replaces the old one in Lookup
+func (set *FileSet) AddFile(name string, content []byte) *File {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+	file := makeFile(name, set.base, content)
+	// One more, so the end of the file has a position too
+	set.base += len(content) + 1
+	set.files = append(set.files, file)
+	return file
+}
+
+// The file which was added last with the name, nil if there is none
+func (set *FileSet) Lookup(name string) *File {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+	for i := len(set.files) - 1; i >= 0; i-- {
+		if set.files[i].name == name {
+			return set.files[i]
+		}
+	}
+	return nil
+}
+
+// Lookup, which reads the file from disk if it was not added
+func (set *FileSet) Load(name string) (*File, error) {
+	if file := set.Lookup(name); file != nil {
+		return file, nil
+	}
+	content, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return set.AddFile(name, content), nil
+}
+
+// The file the position is in, nil for NoPos and positions outside of every file
+func (set *FileSet) File(pos Pos) *File {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+	i, found := slices.BinarySearchFunc(set.files, pos, func(file *File, pos Pos) int {
+		return file.base - int(pos)
+	})
+	if !found {
+		i--
+	}
+	if i < 0 || int(pos) > set.files[i].base+len(set.files[i].content) {
+		return nil
+	}
+	return set.files[i]
+}
+
+// The adjusted position of pos, the zero Position if it is in no file
+func (set *FileSet) Position(pos Pos) Position {
+	file := set.File(pos)
+	if file == nil {
+		return Position{}
+	}
+	return file.Adjust(file.Position(file.Offset(pos)))
+}
+
+// The position after the //line directives of its file. Positions in unknown files stay the same
+func (set *FileSet) Adjust(position Position) Position {
+	file := set.Lookup(position.File)
+	if file == nil {
+		return position
+	}
+	return file.Adjust(position)
+}
+
+func makeFile(name string, base int, content []byte) *File {
+	file := &File{name: name, base: base, content: content, lines: []int{0}}
+	for offset, b := range content {
+		if b == '\n' {
+			file.lines = append(file.lines, offset+1)
+		}
+	}
+	for i := range file.lines {
+		text, _ := file.Line(i + 1)
+		if info, ok := parseDirective(text); ok {
+			info.line = i + 2
+			file.infos = append(file.infos, info)
+		}
+	}
+	return file
+}
+
+// "//line name:line", the name may be empty and contain colons
+func parseDirective(text string) (lineInfo, bool) {
+	rest, ok := strings.CutPrefix(text, "//line ")
+	if !ok {
+		return lineInfo{}, false
+	}
+	colon := strings.LastIndex(rest, ":")
+	if colon == -1 {
+		return lineInfo{}, false
+	}
+	line, err := strconv.Atoi(strings.TrimSpace(rest[colon+1:]))
+	if err != nil || line < 1 {
+		return lineInfo{}, false
+	}
+	return lineInfo{file: rest[:colon], adjusted: line}, true
+}
+
+func (file *File) Name() string {
+	return file.name
+}
+
+func (file *File) Base() int {
+	return file.base
+}
+
+func (file *File) Size() int {
+	return len(file.content)
+}
+
+func (file *File) LineCount() int {
+	return len(file.lines)
+}
+
+// Position of the byte offset in the file, offsets past the end are the end
+func (file *File) Pos(offset int) Pos {
+	return Pos(file.base + min(max(offset, 0), len(file.content)))
+}
+
+func (file *File) Offset(pos Pos) int {
+	return min(max(int(pos)-file.base, 0), len(file.content))
+}
+
+// Offset of the first byte of the line, -1 if the file has no such line
+func (file *File) LineStart(line int) int {
+	if line < 1 || line > len(file.lines) {
+		return -1
+	}
+	return file.lines[line-1]
+}
+
+// Offset of the character at the line and column, -1 if there is no such line
+func (file *File) OffsetOf(line int, col int) int {
+	text, ok := file.Line(line)
+	if !ok {
+		return -1
+	}
+	offset := 0
+	for i := 1; i < col && offset < len(text); i++ {
+		_, size := utf8.DecodeRuneInString(text[offset:])
+		offset += size
+	}
+	return file.lines[line-1] + offset
+}
+
+// Text of the line without the line end
+func (file *File) Line(line int) (string, bool) {
+	start := file.LineStart(line)
+	if start == -1 {
+		return "", false
+	}
+	end := len(file.content)
+	if line < len(file.lines) {
+		end = file.lines[line] - 1
+	}
+	return strings.TrimSuffix(string(file.content[start:end]), "\r"), true
+}
+
+// The real line and column of the byte offset, the column counts characters
+func (file *File) Position(offset int) Position {
+	offset = min(max(offset, 0), len(file.content))
+	i, found := slices.BinarySearch(file.lines, offset)
+	if !found {
+		i--
+	}
+	col := utf8.RuneCount(file.content[file.lines[i]:offset]) + 1
+	return Position{File: file.name, Line: i + 1, Col: col}
+}
+
+// The position after the //line directives above it
+func (file *File) Adjust(position Position) Position {
+	i, found := slices.BinarySearchFunc(file.infos, position.Line, func(info lineInfo, line int) int {
+		return info.line - line
+	})
+	if !found {
+		i--
+	}
+	if i < 0 {
+		return position
+	}
+	info := file.infos[i]
+	if info.file != "" {
+		position.File = info.file
+	}
+	position.Line = info.adjusted + position.Line - info.line
+	return position
+}
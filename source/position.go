@@ -0,0 +1,30 @@
+package source
+
+import "strconv"
+
+// Where something is in a source, for people. Line and Col start at 1, 0 if unknown. File is empty for sources which are not from a file
+type Position struct {
+	File string
+	Line int
+	// Column in characters, not bytes
+	Col int
+}
+
+// file:line:col, missing parts are left out
+func (position Position) String() string {
+	parts := ""
+	if position.File != "" {
+		parts = position.File
+	}
+	if position.Line == 0 {
+		return parts
+	}
+	if parts != "" {
+		parts += ":"
+	}
+	parts += strconv.Itoa(position.Line)
+	if position.Col != 0 {
+		parts += ":" + strconv.Itoa(position.Col)
+	}
+	return parts
+}
@@ -0,0 +1,49 @@
+package symtab
+
+import (
+	"compiler/ast"
+	"compiler/diag"
+	"context"
+)
+
+/*
+Name resolution which binds the AST. The names are checked like Check does it, and every Assign, Ident and Call gets
+the declaration of its name in its Decl field, so later passes follow the names without a symbol table:
+
+	diagnostics := symtab.Bind(program)
+	ident.Decl.(*ast.Param)    the parameter the name refers to
+
+Names which are not declared stay unbound, they are in the diagnostics with their positions like names declared
+twice. Binding again (e.g. after an optimization pass changed the tree) replaces the old bindings.
+*/
+
+// Checks the names of the program and binds them. Returns the diagnostics of Check
+func Bind(program *ast.Program) []*diag.Diagnostic {
+	diagnostics, _ := BindContext(context.Background(), program)
+	return diagnostics
+}
+
+// Bind, which stops before the next function when the context is done and returns the error of the context.
+// Nothing is bound then
+func BindContext(ctx context.Context, program *ast.Program) ([]*diag.Diagnostic, error) {
+	_, diagnostics, uses, err := check(ctx, program)
+	if err != nil {
+		return nil, err
+	}
+	ast.Inspect(program, func(node ast.Node) bool {
+		var decl ast.Decl
+		if symbol, ok := uses[node]; ok {
+			decl = symbol.Decl
+		}
+		switch n := node.(type) {
+		case *ast.Assign:
+			n.Decl = decl
+		case *ast.Ident:
+			n.Decl = decl
+		case *ast.Call:
+			n.Decl = decl
+		}
+		return true
+	})
+	return diagnostics, nil
+}
@@ -0,0 +1,184 @@
+package symtab
+
+import (
+	"compiler/ast"
+	"compiler/diag"
+	"context"
+	"slices"
+	"strconv"
+)
+
+/*
+Checks the names of a program with the scoping rules of C#:
+	Functions can be called before they are declared.
+	A variable can only be used after its declaration, even though its scope is the whole block.
+	A parameter or variable can not have the name of another parameter or variable of an enclosing scope.
+Calls with a receiver other than the class (Console.WriteLine) are built in and not checked.
+*/
+
+type checker struct {
+	program *ast.Program
+	scope   *Scope
+	// Variables of the scope which are declared further down in the block
+	pending     map[*Scope]map[string]bool
+	diagnostics *[]*diag.Diagnostic
+	// The symbol of every name which was found, by the Assign, Ident or Call using it
+	uses map[ast.Node]*Symbol
+}
+
+// Returns the class scope with the functions, and the diagnostics ordered by line
+func Check(program *ast.Program) (*Scope, []*diag.Diagnostic) {
+	scope, diagnostics, _ := CheckContext(context.Background(), program)
+	return scope, diagnostics
+}
+
+// Check, which stops before the next function when the context is done and returns the error of the context
+func CheckContext(ctx context.Context, program *ast.Program) (*Scope, []*diag.Diagnostic, error) {
+	scope, diagnostics, _, err := check(ctx, program)
+	return scope, diagnostics, err
+}
+
+// The symbols the names of the program refer to, by the Assign, Ident or Call which uses them.
+// Names which are not declared and built in functions are missing
+func Resolve(program *ast.Program) map[ast.Node]*Symbol {
+	_, _, uses, _ := check(context.Background(), program)
+	return uses
+}
+
+func check(ctx context.Context, program *ast.Program) (*Scope, []*diag.Diagnostic, map[ast.Node]*Symbol, error) {
+	diagnostics := []*diag.Diagnostic{}
+	classScope := MakeScope(nil)
+	c := &checker{program: program, scope: classScope, pending: make(map[*Scope]map[string]bool), diagnostics: &diagnostics, uses: make(map[ast.Node]*Symbol)}
+
+	// Functions first, so they can be called before their declaration
+	for _, function := range program.Functions {
+		c.declare(&Symbol{Name: function.Name, Kind: Function, Type: function.ReturnType, Decl: function})
+	}
+	for _, function := range program.Functions {
+		if ctx.Err() != nil {
+			return nil, nil, nil, ctx.Err()
+		}
+		ast.Walk(c, function)
+	}
+
+	slices.SortStableFunc(diagnostics, func(a *diag.Diagnostic, b *diag.Diagnostic) int {
+		return a.Line - b.Line
+	})
+	return classScope, diagnostics, c.uses, nil
+}
+
+func (c *checker) Visit(node ast.Node) ast.Visitor {
+	switch n := node.(type) {
+	case *ast.Function:
+		// The parameters get their own scope around the body
+		return c.inScope(MakeScope(c.scope))
+	case *ast.Param:
+		c.declare(&Symbol{Name: n.Name, Kind: Param, Type: n.Type, Decl: n})
+	case *ast.Block:
+		inner := c.inScope(MakeScope(c.scope))
+		c.pending[inner.scope] = make(map[string]bool)
+		for _, statement := range n.Statements {
+			if varDecl, ok := statement.(*ast.VarDecl); ok {
+				c.pending[inner.scope][varDecl.Name] = true
+			}
+		}
+		return inner
+	case *ast.VarDecl:
+		// int a = a; uses a before it is declared, so the value comes first
+		if n.Value != nil {
+			ast.Walk(c, n.Value)
+		}
+		delete(c.pending[c.scope], n.Name)
+		c.declare(&Symbol{Name: n.Name, Kind: Variable, Type: n.Type, Decl: n})
+		return nil
+	case *ast.Assign:
+		c.use(n, n.Name, Variable)
+	case *ast.Ident:
+		c.use(n, n.Name, Variable)
+	case *ast.Call:
+		if n.Receiver == "" || n.Receiver == c.program.Class {
+			c.use(n, n.Name, Function)
+		}
+	}
+	return c
+}
+
+func (c *checker) inScope(scope *Scope) *checker {
+	inner := *c
+	inner.scope = scope
+	return &inner
+}
+
+func (c *checker) declare(symbol *Symbol) {
+	line := symbol.Line()
+	if existing := c.scope.Declare(symbol); existing != nil {
+		diagnostic := c.report(diag.Redeclared, "The "+string(symbol.Kind)+" "+symbol.Name+" at line "+strconv.Itoa(line)+" is already declared at line "+strconv.Itoa(existing.Line()), symbol.Pos())
+		diagnostic.AddNote(symbol.Name+" is declared here", position(existing.Pos()))
+		return
+	}
+	if symbol.Kind == Function {
+		return
+	}
+	if hidden := c.scope.Shadowed(symbol.Name); hidden != nil && hidden.Kind != Function {
+		diagnostic := c.report(diag.HidesName, "The "+string(symbol.Kind)+" "+symbol.Name+" at line "+strconv.Itoa(line)+" hides the "+string(hidden.Kind)+" declared at line "+strconv.Itoa(hidden.Line()), symbol.Pos())
+		diagnostic.AddNote("the hidden "+string(hidden.Kind)+" is declared here", position(hidden.Pos()))
+	}
+}
+
+func (c *checker) use(node ast.Node, name string, kind Kind) {
+	pos := node.Pos()
+	line := pos.Line
+	symbol := c.scope.Lookup(name)
+	if kind == Function {
+		if symbol == nil || symbol.Kind != Function {
+			// Variables can have the name of a function, so only the class scope counts
+			symbol = c.classScope().LookupLocal(name)
+		}
+		if symbol == nil {
+			diagnostic := c.report(diag.NotDeclared, "Function "+name+" at line "+strconv.Itoa(line)+" does not exist", pos)
+			suggestName(diagnostic, c.classScope().Closest(name, Function), name, pos)
+			return
+		}
+		c.uses[node] = symbol
+		return
+	}
+	if symbol != nil && symbol.Kind != Function {
+		c.uses[node] = symbol
+		return
+	}
+	for scope := c.scope; scope != nil; scope = scope.parent {
+		if c.pending[scope][name] {
+			c.report(diag.UsedBeforeDeclared, "Variable "+name+" is used at line "+strconv.Itoa(line)+" before it is declared", pos)
+			return
+		}
+	}
+	diagnostic := c.report(diag.NotDeclared, "Variable "+name+" at line "+strconv.Itoa(line)+" is not declared", pos)
+	suggestName(diagnostic, c.scope.Closest(name, Variable), name, pos)
+}
+
+// Suggests renaming to the declared name which is closest, if there is one. The position of a call can be the one of
+// its receiver, the edit replaces the first use of the name after it
+func suggestName(diagnostic *diag.Diagnostic, closest *Symbol, name string, pos ast.Position) {
+	if closest == nil {
+		return
+	}
+	diagnostic.Suggest("did you mean "+closest.Name+"?", diag.Edit{Line: pos.Line, Col: pos.Col, Before: name, Insert: closest.Name, Replace: true})
+}
+
+func (c *checker) classScope() *Scope {
+	scope := c.scope
+	for scope.parent != nil {
+		scope = scope.parent
+	}
+	return scope
+}
+
+func (c *checker) report(code diag.Code, message string, pos ast.Position) *diag.Diagnostic {
+	diagnostic := diag.MakeDiagnosticAt(code, "Name Error: "+message, position(pos))
+	*c.diagnostics = append(*c.diagnostics, diagnostic)
+	return diagnostic
+}
+
+func position(pos ast.Position) diag.Position {
+	return diag.Position{Line: pos.Line, Col: pos.Col}
+}
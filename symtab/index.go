@@ -0,0 +1,100 @@
+package symtab
+
+import "compiler/ast"
+
+/*
+Index of the names of a program by line, for completion in an editor.
+Only lines are known, so a function reaches until the next function starts
+and a nested block until the next statement of the enclosing block.
+*/
+
+type Index struct {
+	program *ast.Program
+	// The functions of the program
+	functions *Scope
+}
+
+func MakeIndex(program *ast.Program) *Index {
+	index := new(Index)
+	index.program = program
+	index.functions = MakeScope(nil)
+	for _, function := range program.Functions {
+		index.functions.Declare(&Symbol{Name: function.Name, Kind: Function, Type: function.ReturnType, Decl: function})
+	}
+	return index
+}
+
+// The class name of the program
+func (index *Index) Class() string {
+	return index.program.Class
+}
+
+// The functions of the program in the order they were declared
+func (index *Index) Functions() []*Symbol {
+	return index.functions.Symbols()
+}
+
+// The parameters and variables which can be used at the line, the innermost first, then the functions.
+// Variables are visible after the line of their declaration, hidden names are left out
+func (index *Index) Visible(line int) []*Symbol {
+	scope := index.functions
+	if function := index.functionAt(line); function != nil {
+		scope = MakeScope(scope)
+		for _, param := range function.Params {
+			scope.Declare(&Symbol{Name: param.Name, Kind: Param, Type: param.Type, Decl: param})
+		}
+		if function.Body != nil {
+			scope = blockScope(function.Body, line, scope)
+		}
+	}
+
+	symbols := []*Symbol{}
+	seen := make(map[string]bool)
+	for current := scope; current != nil; current = current.Parent() {
+		for _, symbol := range current.Symbols() {
+			if !seen[symbol.Name] {
+				seen[symbol.Name] = true
+				symbols = append(symbols, symbol)
+			}
+		}
+	}
+	return symbols
+}
+
+func (index *Index) functionAt(line int) *ast.Function {
+	var found *ast.Function
+	for _, function := range index.program.Functions {
+		if function.Pos().Line <= line {
+			found = function
+		}
+	}
+	return found
+}
+
+// The scope of the innermost block around the line, with the variables declared above it
+func blockScope(block *ast.Block, line int, parent *Scope) *Scope {
+	scope := MakeScope(parent)
+	for i, statement := range block.Statements {
+		if statement.Pos().Line >= line {
+			break
+		}
+		if varDecl, ok := statement.(*ast.VarDecl); ok {
+			scope.Declare(&Symbol{Name: varDecl.Name, Kind: Variable, Type: varDecl.Type, Decl: varDecl})
+			continue
+		}
+		if i+1 < len(block.Statements) && block.Statements[i+1].Pos().Line <= line {
+			continue
+		}
+		// The last statement before the line, the line may be in one of its blocks
+		switch n := statement.(type) {
+		case *ast.If:
+			if n.Else != nil && n.Else.Pos().Line <= line {
+				return blockScope(n.Else, line, scope)
+			}
+			return blockScope(n.Then, line, scope)
+		case *ast.While:
+			return blockScope(n.Body, line, scope)
+		}
+	}
+	return scope
+}
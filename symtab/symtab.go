@@ -0,0 +1,148 @@
+package symtab
+
+import "compiler/ast"
+
+/*
+Symbol table with nested scopes:
+	Class scope: the functions of the program
+		Function scope: the parameters
+			Block scope: the local variables, one scope for every { }
+A name can only be declared once per scope. Lookup searches the scope and then the enclosing scopes,
+so a declaration in an inner scope shadows the ones outside.
+*/
+
+type Kind string
+
+const (
+	Function Kind = "function"
+	Param    Kind = "parameter"
+	Variable Kind = "variable"
+)
+
+type Symbol struct {
+	Name string
+	Kind Kind
+	// Type of the variable or parameter, return type of the function
+	Type string
+	// Function, Param or VarDecl which declared the symbol
+	Decl ast.Decl
+}
+
+type Scope struct {
+	parent  *Scope
+	symbols map[string]*Symbol
+	// Names in order of declaration
+	order []string
+}
+
+// nil parent for the outermost scope
+func MakeScope(parent *Scope) *Scope {
+	newScope := new(Scope)
+	newScope.parent = parent
+	newScope.symbols = make(map[string]*Symbol)
+	return newScope
+}
+
+func (scope *Scope) Parent() *Scope {
+	return scope.parent
+}
+
+// Adds the symbol to the scope. If the name is declared in this scope already,
+// nothing is added and the existing symbol is returned, else nil
+func (scope *Scope) Declare(symbol *Symbol) *Symbol {
+	if existing, ok := scope.symbols[symbol.Name]; ok {
+		return existing
+	}
+	scope.symbols[symbol.Name] = symbol
+	scope.order = append(scope.order, symbol.Name)
+	return nil
+}
+
+// Finds the name in this scope or the enclosing ones, nil if it is not declared
+func (scope *Scope) Lookup(name string) *Symbol {
+	for current := scope; current != nil; current = current.parent {
+		if symbol, ok := current.symbols[name]; ok {
+			return symbol
+		}
+	}
+	return nil
+}
+
+// Finds the name only in this scope
+func (scope *Scope) LookupLocal(name string) *Symbol {
+	return scope.symbols[name]
+}
+
+// The symbol of an enclosing scope a declaration of the name in this scope would hide, nil if there is none
+func (scope *Scope) Shadowed(name string) *Symbol {
+	if scope.parent == nil {
+		return nil
+	}
+	return scope.parent.Lookup(name)
+}
+
+// The symbols of this scope in the order they were declared
+func (scope *Scope) Symbols() []*Symbol {
+	symbols := []*Symbol{}
+	for _, name := range scope.order {
+		symbols = append(symbols, scope.symbols[name])
+	}
+	return symbols
+}
+
+// Line of the declaration
+func (symbol *Symbol) Line() int {
+	return symbol.Pos().Line
+}
+
+// Position of the declaration
+func (symbol *Symbol) Pos() ast.Position {
+	if symbol.Decl == nil {
+		return ast.Position{}
+	}
+	return symbol.Decl.Pos()
+}
+
+// The symbol visible from this scope whose name is closest to the name, for names which are not declared. Variables
+// and parameters are found for Variable, functions for Function. nil if no name is close enough: at most a third of
+// the letters may be different
+func (scope *Scope) Closest(name string, kind Kind) *Symbol {
+	var closest *Symbol
+	best := max(len([]rune(name))/3, 1) + 1
+	seen := make(map[string]bool)
+	for current := scope; current != nil; current = current.parent {
+		// Inner scopes first, so a hidden name is not suggested
+		for _, symbol := range current.Symbols() {
+			if seen[symbol.Name] || (symbol.Kind == Function) != (kind == Function) {
+				continue
+			}
+			seen[symbol.Name] = true
+			if d := distance(name, symbol.Name); d < best {
+				closest, best = symbol, d
+			}
+		}
+	}
+	return closest
+}
+
+// Levenshtein distance: the number of letters which have to be inserted, removed or replaced
+func distance(a string, b string) int {
+	from, to := []rune(a), []rune(b)
+	previous := make([]int, len(to)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(from); i++ {
+		current := make([]int, len(to)+1)
+		current[0] = i
+		for j := 1; j <= len(to); j++ {
+			cost := 1
+			if from[i-1] == to[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(to)]
+}
@@ -0,0 +1,57 @@
+package symtab
+
+import (
+	"compiler/ast"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"count", "count", 0},
+		{"cout", "count", 1},
+		{"Sqare", "Square", 1},
+		{"numbr", "nubmer", 2},
+		{"", "abc", 3},
+		{"größe", "grösse", 2},
+	}
+	for _, test := range tests {
+		if got := distance(test.a, test.b); got != test.want {
+			t.Errorf("distance(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestClosest(t *testing.T) {
+	class := MakeScope(nil)
+	class.Declare(&Symbol{Name: "Count", Kind: Function})
+	function := MakeScope(class)
+	function.Declare(&Symbol{Name: "total", Kind: Param, Decl: &ast.Param{Name: "total"}})
+	block := MakeScope(function)
+	block.Declare(&Symbol{Name: "count", Kind: Variable})
+
+	tests := []struct {
+		name string
+		kind Kind
+		want string
+	}{
+		{"cout", Variable, "count"},
+		{"totl", Variable, "total"},
+		{"Cont", Function, "Count"},
+		// Functions are not suggested for variables and the other way around
+		{"Count", Variable, "count"},
+		{"xyz", Variable, ""},
+		{"c", Variable, ""},
+	}
+	for _, test := range tests {
+		got := ""
+		if symbol := block.Closest(test.name, test.kind); symbol != nil {
+			got = symbol.Name
+		}
+		if got != test.want {
+			t.Errorf("Closest(%q, %s) = %q, want %q", test.name, test.kind, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package types
+
+import (
+	"compiler/ast"
+	"compiler/diag"
+	"compiler/eval"
+	"context"
+	"slices"
+	"strconv"
+)
+
+/*
+Type checking of a program. The type of every expression is inferred bottom up:
+	Literals have their type, names the type of their declaration.
+	+ - * / % of numbers: int if both sides are int, else double. + with a string on one side is a string.
+	< > <= >= compare numbers, == != compare values which can be assigned to each other, && || take bools.
+	Calls have the return type of the function. The built in functions are generic and are unified with the arguments.
+Names which are not declared are reported by symtab, here they get the type invalid, which fits everywhere.
+Constant int expressions which overflow or divide by zero are errors, like in C# (see the eval package).
+*/
+
+// Result of Check: the type of every expression and of every declaration
+type Info struct {
+	Types map[ast.Expr]Type
+	Decls map[ast.Decl]Type
+}
+
+// Signatures of the built in functions. Called with a new type variable for every call
+var builtins = map[string]func() *Func{
+	"Console.WriteLine": func() *Func { return &Func{Params: []Type{MakeVar()}, Result: Void} },
+	"Console.Write":     func() *Func { return &Func{Params: []Type{MakeVar()}, Result: Void} },
+}
+
+type checker struct {
+	program     *ast.Program
+	info        *Info
+	functions   map[string]*ast.Function
+	function    *ast.Function
+	diagnostics []*diag.Diagnostic
+}
+
+type scope struct {
+	parent    *scope
+	variables map[string]Type
+}
+
+func Check(program *ast.Program) (*Info, []*diag.Diagnostic) {
+	info, diagnostics, _ := CheckContext(context.Background(), program)
+	return info, diagnostics
+}
+
+// Check, which stops before the next function when the context is done and returns the error of the context
+func CheckContext(ctx context.Context, program *ast.Program) (*Info, []*diag.Diagnostic, error) {
+	c := new(checker)
+	c.program = program
+	c.info = &Info{Types: make(map[ast.Expr]Type), Decls: make(map[ast.Decl]Type)}
+	c.functions = make(map[string]*ast.Function)
+	c.diagnostics = []*diag.Diagnostic{}
+	for _, function := range program.Functions {
+		c.functions[function.Name] = function
+		c.info.Decls[function] = c.signature(function)
+	}
+
+	for _, function := range program.Functions {
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		c.function = function
+		functionScope := &scope{variables: make(map[string]Type)}
+		for _, param := range function.Params {
+			paramType := c.typeOf(param.Type, param.Pos())
+			c.info.Decls[param] = paramType
+			functionScope.variables[param.Name] = paramType
+		}
+		if function.Body != nil {
+			c.block(function.Body, functionScope)
+			c.constants(function.Body)
+		}
+	}
+
+	slices.SortStableFunc(c.diagnostics, func(a *diag.Diagnostic, b *diag.Diagnostic) int {
+		return a.Line - b.Line
+	})
+	return c.info, c.diagnostics, nil
+}
+
+// Type of the expression after Check, invalid if it was not checked
+func (info *Info) TypeOf(expr ast.Expr) Type {
+	if t, ok := info.Types[expr]; ok {
+		return Resolve(t)
+	}
+	return Invalid
+}
+
+func (c *checker) signature(function *ast.Function) *Func {
+	signature := &Func{Result: c.typeOf(function.ReturnType, function.Pos())}
+	for _, param := range function.Params {
+		signature.Params = append(signature.Params, c.typeOf(param.Type, param.Pos()))
+	}
+	return signature
+}
+
+func (c *checker) typeOf(name string, pos ast.Position) Type {
+	t := FromName(name)
+	if t == nil {
+		c.report(diag.TypeMismatch, "Unknown type "+name+" at line "+strconv.Itoa(pos.Line), pos)
+		return Invalid
+	}
+	return t
+}
+
+func (s *scope) lookup(name string) Type {
+	for current := s; current != nil; current = current.parent {
+		if t, ok := current.variables[name]; ok {
+			return t
+		}
+	}
+	return Invalid
+}
+
+func (c *checker) block(block *ast.Block, parent *scope) {
+	blockScope := &scope{parent: parent, variables: make(map[string]Type)}
+	for _, statement := range block.Statements {
+		c.statement(statement, blockScope)
+	}
+}
+
+func (c *checker) statement(statement ast.Stmt, s *scope) {
+	pos := statement.Pos()
+	line := pos.Line
+	switch n := statement.(type) {
+	case *ast.VarDecl:
+		declared := c.typeOf(n.Type, pos)
+		if n.Value != nil {
+			c.assignable(c.expr(n.Value, s), declared, "Can not assign", pos)
+		}
+		c.info.Decls[n] = declared
+		s.variables[n.Name] = declared
+	case *ast.Assign:
+		c.assignable(c.expr(n.Value, s), s.lookup(n.Name), "Can not assign", pos)
+	case *ast.CallStmt:
+		c.expr(n.Call, s)
+	case *ast.Return:
+		result := c.info.Decls[c.function].(*Func).Result
+		switch {
+		case n.Value == nil && result != Void && result != Invalid:
+			c.report(diag.TypeMismatch, "Function "+c.function.Name+" has to return "+result.String()+" at line "+strconv.Itoa(line), pos)
+		case n.Value != nil && result == Void:
+			c.expr(n.Value, s)
+			c.report(diag.TypeMismatch, "Function "+c.function.Name+" is void and can not return a value at line "+strconv.Itoa(line), pos)
+		case n.Value != nil:
+			c.assignable(c.expr(n.Value, s), result, "Can not return", pos)
+		}
+	case *ast.If:
+		c.condition(n.Cond, s)
+		c.block(n.Then, s)
+		if n.Else != nil {
+			c.block(n.Else, s)
+		}
+	case *ast.While:
+		c.condition(n.Cond, s)
+		c.block(n.Body, s)
+	}
+}
+
+func (c *checker) condition(cond ast.Expr, s *scope) {
+	if t := c.expr(cond, s); !AssignableTo(t, Bool) {
+		c.report(diag.TypeMismatch, "Condition at line "+strconv.Itoa(cond.Pos().Line)+" is "+t.String()+", not bool", cond.Pos())
+	}
+}
+
+func (c *checker) assignable(value Type, target Type, action string, pos ast.Position) {
+	if !AssignableTo(value, target) {
+		c.report(diag.TypeMismatch, action+" "+Resolve(value).String()+" to "+Resolve(target).String()+" at line "+strconv.Itoa(pos.Line), pos)
+	}
+}
+
+func (c *checker) expr(expr ast.Expr, s *scope) Type {
+	t := c.inferExpr(expr, s)
+	c.info.Types[expr] = t
+	return t
+}
+
+func (c *checker) inferExpr(expr ast.Expr, s *scope) Type {
+	pos := expr.Pos()
+	switch e := expr.(type) {
+	case *ast.IntLit:
+		return Int
+	case *ast.DoubleLit:
+		return Double
+	case *ast.BoolLit:
+		return Bool
+	case *ast.StringLit:
+		return String
+	case *ast.InterpolatedString:
+		// Every value can be put into the string
+		for _, part := range e.Parts {
+			if part.Expr != nil {
+				c.hasValue(c.expr(part.Expr, s), "put into a string", part.Expr.Pos())
+			}
+		}
+		return String
+	case *ast.Ident:
+		return s.lookup(e.Name)
+	case *ast.Unary:
+		operand := c.expr(e.Operand, s)
+		if !IsNumeric(operand) {
+			c.report(diag.TypeMismatch, "Operator "+e.Op+" needs a number, got "+operand.String()+" at line "+strconv.Itoa(pos.Line), pos)
+			return Invalid
+		}
+		return operand
+	case *ast.Binary:
+		return c.binary(e, c.expr(e.Left, s), c.expr(e.Right, s), pos)
+	case *ast.Call:
+		return c.call(e, s, pos)
+	}
+	return Invalid
+}
+
+func (c *checker) binary(e *ast.Binary, left Type, right Type, pos ast.Position) Type {
+	left, right = Resolve(left), Resolve(right)
+	if left == Invalid || right == Invalid {
+		return Invalid
+	}
+	switch e.Op {
+	case "+", "-", "*", "/", "%":
+		if e.Op == "+" && (left == String || right == String) {
+			return String
+		}
+		if IsNumeric(left) && IsNumeric(right) {
+			if left == Int && right == Int {
+				return Int
+			}
+			return Double
+		}
+	case "<", ">", "<=", ">=":
+		if IsNumeric(left) && IsNumeric(right) {
+			return Bool
+		}
+	case "==", "!=":
+		if AssignableTo(left, right) || AssignableTo(right, left) {
+			return Bool
+		}
+	case "&&", "||":
+		if left == Bool && right == Bool {
+			return Bool
+		}
+	}
+	c.report(diag.TypeMismatch, "Operator "+e.Op+" can not be used with "+left.String()+" and "+right.String()+" at line "+strconv.Itoa(pos.Line), pos)
+	return Invalid
+}
+
+func (c *checker) call(call *ast.Call, s *scope, pos ast.Position) Type {
+	args := []Type{}
+	for _, arg := range call.Args {
+		args = append(args, c.expr(arg, s))
+	}
+
+	var signature *Func
+	if function, ok := c.functions[call.Name]; ok && (call.Receiver == "" || call.Receiver == c.program.Class) {
+		signature = c.info.Decls[function].(*Func)
+	} else if builtin, ok := builtins[call.FullName()]; ok {
+		signature = builtin()
+		// WriteLine() prints an empty line
+		if len(args) == 0 {
+			return signature.Result
+		}
+	} else {
+		// Reported by symtab, or a library function the checker does not know
+		return Invalid
+	}
+
+	if len(args) != len(signature.Params) {
+		c.report(diag.ArgumentCount, "Function "+call.FullName()+" takes "+strconv.Itoa(len(signature.Params))+" arguments, but got "+strconv.Itoa(len(args))+" at line "+strconv.Itoa(pos.Line), pos)
+		return signature.Result
+	}
+	for i, arg := range args {
+		// A type variable would be bound to void
+		if !c.hasValue(arg, "passed to "+call.FullName(), call.Args[i].Pos()) {
+			continue
+		}
+		param := Resolve(signature.Params[i])
+		if _, generic := param.(*Var); generic {
+			if err := Unify(param, arg); err != nil {
+				c.report(diag.TypeMismatch, err.Error()+" at line "+strconv.Itoa(pos.Line), pos)
+			}
+			continue
+		}
+		c.assignable(arg, param, "Can not pass", call.Args[i].Pos())
+	}
+	return signature.Result
+}
+
+// Reports the use of a void value, the result of a call to a void function
+func (c *checker) hasValue(t Type, use string, pos ast.Position) bool {
+	if Resolve(t) != Void {
+		return true
+	}
+	c.report(diag.TypeMismatch, "A void value can not be "+use+" at line "+strconv.Itoa(pos.Line), pos)
+	return false
+}
+
+// Reports the constant expressions which overflow or divide by zero. Only the largest constant expressions are
+// evaluated, so every error is reported once
+func (c *checker) constants(body *ast.Block) {
+	ast.Inspect(body, func(n ast.Node) bool {
+		expr, ok := n.(ast.Expr)
+		if !ok {
+			return true
+		}
+		_, constant, d := eval.Eval(expr, nil)
+		if d != nil {
+			c.diagnostics = append(c.diagnostics, d)
+		}
+		return !constant
+	})
+}
+
+func (c *checker) report(code diag.Code, message string, pos ast.Position) {
+	c.diagnostics = append(c.diagnostics, diag.MakeDiagnosticAt(code, "Type Error: "+message, diag.Position{Line: pos.Line, Col: pos.Col}))
+}
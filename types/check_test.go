@@ -0,0 +1,79 @@
+package types_test
+
+import (
+	"compiler/ast"
+	"compiler/diag"
+	"compiler/lexer"
+	"compiler/parser"
+	"compiler/symtab"
+	"compiler/types"
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+// The diagnostics of the type checker for a program with the statements in Main and the function V
+func check(t *testing.T, statements string) []*diag.Diagnostic {
+	t.Helper()
+	source := `using System;
+
+namespace Types {
+    class Program {
+        static void V() {
+            Console.WriteLine("V");
+        }
+
+        static int Id(int a) {
+            return a;
+        }
+
+        static void Main(string[] args) {
+` + statements + `
+        }
+    }
+}
+`
+	tree, ok, err := parser.ParseSource(context.Background(), source, true, lexer.Options{Output: io.Discard})
+	if !ok {
+		t.Fatal("parse failed", err)
+	}
+	program, err := ast.Build(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diagnostics := symtab.Bind(program); len(diagnostics) > 0 {
+		t.Fatal(diagnostics[0])
+	}
+	_, diagnostics := types.Check(program)
+	return diagnostics
+}
+
+func TestVoidHasNoValue(t *testing.T) {
+	tests := []string{
+		`Console.WriteLine(V());`,
+		`Console.Write(V());`,
+		`Console.WriteLine($"value {V()}");`,
+		`Console.WriteLine(Id(V()));`,
+	}
+	for _, test := range tests {
+		diagnostics := check(t, test)
+		if len(diagnostics) != 1 || diagnostics[0].Code != diag.TypeMismatch || !strings.Contains(diagnostics[0].Message, "void value") {
+			t.Errorf("%s: got %v", test, diagnostics)
+		}
+	}
+}
+
+func TestValuesPass(t *testing.T) {
+	tests := []string{
+		`V();`,
+		`Console.WriteLine();`,
+		`Console.WriteLine(Id(1));`,
+		`Console.WriteLine($"value {Id(1)} {true} {1.5}");`,
+	}
+	for _, test := range tests {
+		if diagnostics := check(t, test); len(diagnostics) > 0 {
+			t.Errorf("%s: got %v", test, diagnostics[0])
+		}
+	}
+}
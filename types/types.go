@@ -0,0 +1,237 @@
+package types
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+/*
+Types of the language and the rules to combine them:
+	Basic: int, double, bool, string, void, and invalid for expressions which already have an error
+	Array: string[] (the arguments of Main)
+	Func: the signature of a function
+	Struct: a named record of fields
+	Var: a type variable, which is bound by Unify. Used for the built in functions, which take any type
+*/
+
+type Type interface {
+	String() string
+}
+
+type Basic struct {
+	Name string
+}
+
+type Array struct {
+	Elem Type
+}
+
+type Func struct {
+	Params []Type
+	Result Type
+}
+
+type Struct struct {
+	Name   string
+	Fields []Field
+}
+
+type Field struct {
+	Name string
+	Type Type
+}
+
+type Var struct {
+	id int
+	// nil until the variable is unified with a type
+	Bound Type
+}
+
+var (
+	Int     = &Basic{"int"}
+	Double  = &Basic{"double"}
+	Bool    = &Basic{"bool"}
+	String  = &Basic{"string"}
+	Void    = &Basic{"void"}
+	Invalid = &Basic{"invalid"}
+)
+
+var varCount atomic.Int64
+
+func (basic *Basic) String() string {
+	return basic.Name
+}
+
+func (array *Array) String() string {
+	return array.Elem.String() + "[]"
+}
+
+func (function *Func) String() string {
+	params := []string{}
+	for _, param := range function.Params {
+		params = append(params, param.String())
+	}
+	return function.Result.String() + "(" + strings.Join(params, ", ") + ")"
+}
+
+func (structure *Struct) String() string {
+	return structure.Name
+}
+
+func (variable *Var) String() string {
+	if variable.Bound != nil {
+		return variable.Bound.String()
+	}
+	return "T" + strconv.Itoa(variable.id)
+}
+
+// Type variable which is not bound yet
+func MakeVar() *Var {
+	newVar := new(Var)
+	newVar.id = int(varCount.Add(1))
+	return newVar
+}
+
+// Type of a type name of the source, e.g. "int" or "string[]". nil for unknown names
+func FromName(name string) Type {
+	if elem, ok := strings.CutSuffix(name, "[]"); ok {
+		elemType := FromName(elem)
+		if elemType == nil {
+			return nil
+		}
+		return &Array{Elem: elemType}
+	}
+	for _, basic := range []*Basic{Int, Double, Bool, String, Void} {
+		if basic.Name == name {
+			return basic
+		}
+	}
+	return nil
+}
+
+// Follows bound type variables to the type they stand for
+func Resolve(t Type) Type {
+	for {
+		variable, ok := t.(*Var)
+		if !ok || variable.Bound == nil {
+			return t
+		}
+		t = variable.Bound
+	}
+}
+
+func Identical(a Type, b Type) bool {
+	a, b = Resolve(a), Resolve(b)
+	switch x := a.(type) {
+	case *Basic:
+		return x == b
+	case *Array:
+		y, ok := b.(*Array)
+		return ok && Identical(x.Elem, y.Elem)
+	case *Func:
+		y, ok := b.(*Func)
+		if !ok || len(x.Params) != len(y.Params) || !Identical(x.Result, y.Result) {
+			return false
+		}
+		for i := range x.Params {
+			if !Identical(x.Params[i], y.Params[i]) {
+				return false
+			}
+		}
+		return true
+	case *Struct:
+		// Structs are nominal
+		y, ok := b.(*Struct)
+		return ok && x.Name == y.Name
+	case *Var:
+		return x == b
+	}
+	return false
+}
+
+// A value of type value can be stored in a target variable. Like in C#, an int is converted to double implicitly.
+// Invalid can be assigned to and from everything, so one error is not reported again
+func AssignableTo(value Type, target Type) bool {
+	value, target = Resolve(value), Resolve(target)
+	if value == Invalid || target == Invalid {
+		return true
+	}
+	if value == Int && target == Double {
+		return true
+	}
+	return Identical(value, target)
+}
+
+func IsNumeric(t Type) bool {
+	t = Resolve(t)
+	return t == Int || t == Double || t == Invalid
+}
+
+// Makes the two types the same by binding type variables
+func Unify(a Type, b Type) error {
+	a, b = Resolve(a), Resolve(b)
+	if variable, ok := a.(*Var); ok {
+		return bind(variable, b)
+	}
+	if variable, ok := b.(*Var); ok {
+		return bind(variable, a)
+	}
+	switch x := a.(type) {
+	case *Array:
+		if y, ok := b.(*Array); ok {
+			return Unify(x.Elem, y.Elem)
+		}
+	case *Func:
+		y, ok := b.(*Func)
+		if !ok || len(x.Params) != len(y.Params) {
+			break
+		}
+		for i := range x.Params {
+			if err := Unify(x.Params[i], y.Params[i]); err != nil {
+				return err
+			}
+		}
+		return Unify(x.Result, y.Result)
+	default:
+		if Identical(a, b) || a == Invalid || b == Invalid {
+			return nil
+		}
+	}
+	return errors.New("Can not unify " + a.String() + " and " + b.String())
+}
+
+func bind(variable *Var, t Type) error {
+	if variable == t {
+		return nil
+	}
+	if occurs(variable, t) {
+		return errors.New("Can not unify " + variable.String() + " and " + t.String() + ", the type would contain itself")
+	}
+	variable.Bound = t
+	return nil
+}
+
+func occurs(variable *Var, t Type) bool {
+	switch x := Resolve(t).(type) {
+	case *Var:
+		return x == variable
+	case *Array:
+		return occurs(variable, x.Elem)
+	case *Func:
+		for _, param := range x.Params {
+			if occurs(variable, param) {
+				return true
+			}
+		}
+		return occurs(variable, x.Result)
+	case *Struct:
+		for _, field := range x.Fields {
+			if occurs(variable, field.Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package vm
+
+import (
+	"compiler/artifact"
+	"errors"
+	"strconv"
+)
+
+// Payload of MarshalBinary. The version is raised when the layout changes, with a migration for the old one
+var bytecodeFormat = artifact.Format{Kind: artifact.Bytecode, Version: 1, Compatible: 1}
+
+// Tags of the constants
+const (
+	intConstant = iota
+	doubleConstant
+	boolConstant
+	stringConstant
+)
+
+// The program in the artifact format
+func (program *Program) MarshalBinary() ([]byte, error) {
+	var encoder artifact.Encoder
+	encoder.Uint(len(program.Constants))
+	for i, constant := range program.Constants {
+		switch v := constant.(type) {
+		case int:
+			encoder.Uint(intConstant)
+			encoder.Int(v)
+		case float64:
+			encoder.Uint(doubleConstant)
+			encoder.Float(v)
+		case bool:
+			encoder.Uint(boolConstant)
+			encoder.Bool(v)
+		case string:
+			encoder.Uint(stringConstant)
+			encoder.String(v)
+		default:
+			return nil, errors.New("Artifact Error: Constant " + strconv.Itoa(i) + " has a type the bytecode can not store")
+		}
+	}
+	encoder.Strings(program.Operators)
+	encoder.Strings(program.Hosts)
+	encoder.Strings(program.Externs)
+	encoder.Uint(program.Main)
+
+	encoder.Uint(len(program.Functions))
+	for _, function := range program.Functions {
+		encoder.String(function.Name)
+		encoder.Uint(function.Params)
+		encoder.Uint(function.Locals)
+		encoder.Bool(function.Void)
+		encoder.Uint(len(function.Code))
+		for i, instruction := range function.Code {
+			encoder.Uint(int(instruction.Op))
+			encoder.Int(instruction.A)
+			encoder.Int(instruction.B)
+			encoder.Uint(function.Lines[i])
+		}
+	}
+	return bytecodeFormat.Seal(encoder.Bytes()), nil
+}
+
+// Reads a program written by MarshalBinary. The operands are checked to be in range,
+// but not that the stack fits, so only run bytecode from Compile
+func Unmarshal(data []byte) (*Program, error) {
+	decoder, err := bytecodeFormat.Open(data)
+	if err != nil {
+		return nil, err
+	}
+
+	program := new(Program)
+	for range decoder.Len() {
+		switch decoder.Uint() {
+		case intConstant:
+			program.Constants = append(program.Constants, decoder.Int())
+		case doubleConstant:
+			program.Constants = append(program.Constants, decoder.Float())
+		case boolConstant:
+			program.Constants = append(program.Constants, decoder.Bool())
+		case stringConstant:
+			program.Constants = append(program.Constants, decoder.String())
+		default:
+			decoder.Fail("Unknown constant")
+		}
+	}
+	program.Operators = decoder.Strings()
+	program.Hosts = decoder.Strings()
+	program.Externs = decoder.Strings()
+	program.Main = decoder.Uint()
+
+	for range decoder.Len() {
+		function := &Function{Name: decoder.String(), Params: decoder.Uint(), Locals: decoder.Uint(), Void: decoder.Bool()}
+		for range decoder.Len() {
+			function.Code = append(function.Code, Instruction{Op: Opcode(decoder.Uint()), A: decoder.Int(), B: decoder.Int()})
+			function.Lines = append(function.Lines, decoder.Uint())
+		}
+		program.Functions = append(program.Functions, function)
+	}
+	if err := decoder.Finish(); err != nil {
+		return nil, err
+	}
+	if message := program.validate(); message != "" {
+		decoder.Fail(message)
+		return nil, decoder.Err()
+	}
+	return program, nil
+}
+
+// Returns what is wrong, "" if the program can be run
+func (program *Program) validate() string {
+	if program.Main >= len(program.Functions) {
+		return "Main function out of range"
+	}
+	if program.Functions[program.Main].Params > 1 {
+		return "Main can only take the arguments"
+	}
+	for _, function := range program.Functions {
+		if function.Params > function.Locals {
+			return "Function " + function.Name + " has more parameters than local variables"
+		}
+		// Every function ends with a return or a jump back
+		if len(function.Code) == 0 {
+			return "Function " + function.Name + " has no code"
+		}
+		switch function.Code[len(function.Code)-1].Op {
+		case Return, ReturnVoid, MissingReturn, Jump:
+		default:
+			return "Function " + function.Name + " does not end with a return"
+		}
+		for pc, instruction := range function.Code {
+			if !program.validOperand(function, instruction) {
+				return "Invalid instruction " + strconv.Itoa(pc) + " in function " + function.Name
+			}
+		}
+	}
+	return ""
+}
+
+func (program *Program) validOperand(function *Function, instruction Instruction) bool {
+	in := func(i int, length int) bool {
+		return i >= 0 && i < length
+	}
+	switch instruction.Op {
+	case Const:
+		return in(instruction.A, len(program.Constants))
+	case Load, Store:
+		return in(instruction.A, function.Locals)
+	case Unary, Binary:
+		return in(instruction.A, len(program.Operators))
+	case Jump, JumpIfFalse, JumpIfFalseOrPop, JumpIfTrueOrPop:
+		return in(instruction.A, len(function.Code))
+	case Concat:
+		return instruction.A >= 0
+	case Call:
+		return in(instruction.A, len(program.Functions))
+	case CallHost:
+		return in(instruction.A, len(program.Hosts)) && instruction.B >= 0
+	}
+	return int(instruction.Op) < len(opcodeNames)
+}
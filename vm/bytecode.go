@@ -0,0 +1,85 @@
+package vm
+
+import "compiler/interp"
+
+/*
+Stack based bytecode. Every function has its own code, the operands of the instructions are
+indices into the constants, the local variables, the functions or the host functions.
+
+Calls push the arguments, which become the first local variables of the called function.
+The other local variables live above them on the value stack, the operands of the
+instructions above those.
+*/
+
+type Opcode byte
+
+const (
+	// Push Constants[A]
+	Const Opcode = iota
+	// Push local variable A
+	Load
+	// Pop into local variable A
+	Store
+	Pop
+	// Apply Operators[A] to the value on top
+	Unary
+	// Convert an int on top to double, everything else stays
+	ToDouble
+	// Pop right and left, push left Operators[A] right
+	Binary
+	// Continue at A
+	Jump
+	// Pop, continue at A if it is false
+	JumpIfFalse
+	// For && and ||: continue at A if the bool on top is false (true), else pop it
+	JumpIfFalseOrPop
+	JumpIfTrueOrPop
+	// Pop A values and push them formatted and joined, for interpolated strings
+	Concat
+	// Call Functions[A] with its number of parameters on the stack
+	Call
+	// Call the host function Hosts[A] with B arguments
+	CallHost
+	// Return the value on top, or nothing
+	Return
+	ReturnVoid
+	// The end of a function which should have returned a value
+	MissingReturn
+)
+
+var opcodeNames = []string{"CONST", "LOAD", "STORE", "POP", "UNARY", "TODOUBLE", "BINARY", "JUMP", "JUMPIFFALSE", "JUMPIFFALSEORPOP", "JUMPIFTRUEORPOP", "CONCAT", "CALL", "CALLHOST", "RETURN", "RETURNVOID", "MISSINGRETURN"}
+
+func (op Opcode) String() string {
+	return opcodeNames[op]
+}
+
+type Instruction struct {
+	Op Opcode
+	A  int
+	B  int
+}
+
+type Function struct {
+	Name   string
+	Params int
+	// Parameters included. Variables in different scopes get different slots
+	Locals int
+	// The value of a void function is not used
+	Void bool
+	Code []Instruction
+	// Source line of every instruction, for runtime errors
+	Lines []int
+}
+
+type Program struct {
+	Functions []*Function
+	Constants []interp.Value
+	// Operators of Unary and Binary
+	Operators []string
+	// Builtin (Console.WriteLine) and extern functions, called by name
+	Hosts []string
+	// Extern functions declared by the program, they have to be bound before it runs
+	Externs []string
+	// Index of Main in Functions
+	Main int
+}
@@ -0,0 +1,307 @@
+package vm
+
+import (
+	"compiler/ast"
+	"compiler/interp"
+	"errors"
+)
+
+// Compiles a program, which passed the name and type checks, to bytecode
+func Compile(program *ast.Program) (*Program, error) {
+	result := &Program{Main: -1}
+	functions := make(map[string]int)
+	for _, function := range program.Functions {
+		if function.Extern {
+			continue
+		}
+		functions[function.Name] = len(result.Functions)
+		result.Functions = append(result.Functions, &Function{Name: function.Name, Params: len(function.Params), Void: function.ReturnType == "void"})
+	}
+	for _, name := range []string{"Main", "main"} {
+		if index, ok := functions[name]; ok {
+			result.Main = index
+			break
+		}
+	}
+	if result.Main == -1 {
+		return nil, errors.New("Bytecode Error: No Main function found")
+	}
+
+	c := &compiler{program: result, class: program.Class, functions: functions, externs: make(map[string]*ast.Function), constants: make(map[interp.Value]int)}
+	for _, function := range program.Functions {
+		if function.Extern {
+			c.externs[function.Name] = function
+			result.Externs = append(result.Externs, function.Name)
+		}
+	}
+	for _, function := range program.Functions {
+		if function.Extern {
+			continue
+		}
+		if err := c.function(function, result.Functions[functions[function.Name]]); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+type compiler struct {
+	program   *Program
+	class     string
+	functions map[string]int
+	externs   map[string]*ast.Function
+	constants map[interp.Value]int
+
+	// Of the function being compiled
+	target     *Function
+	returnType string
+	scopes     []map[string]local
+	line       int
+}
+
+type local struct {
+	slot int
+	typ  string
+}
+
+// Compile errors are only possible for programs which did not pass the checks, they panic with a compileError
+type compileError struct {
+	message string
+}
+
+func (c *compiler) function(function *ast.Function, target *Function) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			compileErr, ok := r.(compileError)
+			if !ok {
+				panic(r)
+			}
+			err = errors.New("Bytecode Error: " + compileErr.message)
+		}
+	}()
+
+	c.target = target
+	c.scopes = []map[string]local{{}}
+	c.line = function.Pos().Line
+	c.returnType = function.ReturnType
+	for _, param := range function.Params {
+		slot := c.declare(param.Name, param.Type)
+		// Arguments are converted by the called function, like the interpreter does
+		if param.Type == "double" {
+			c.emit(Load, slot)
+			c.emit(ToDouble, 0)
+			c.emit(Store, slot)
+		}
+	}
+	c.block(function.Body)
+	if target.Void {
+		c.emit(ReturnVoid, 0)
+	} else {
+		c.emit(MissingReturn, 0)
+	}
+	return nil
+}
+
+func (c *compiler) block(block *ast.Block) {
+	c.scopes = append(c.scopes, map[string]local{})
+	for _, statement := range block.Statements {
+		c.statement(statement)
+	}
+	c.scopes = c.scopes[:len(c.scopes)-1]
+}
+
+func (c *compiler) statement(statement ast.Stmt) {
+	c.line = statement.Pos().Line
+	switch s := statement.(type) {
+	case *ast.VarDecl:
+		if s.Value == nil {
+			c.emit(Const, c.constant(interp.ZeroValue(s.Type)))
+		} else {
+			c.expr(s.Value)
+		}
+		c.convert(s.Type)
+		c.emit(Store, c.declare(s.Name, s.Type))
+	case *ast.Assign:
+		variable := c.lookup(s.Name)
+		c.expr(s.Value)
+		c.convert(variable.typ)
+		c.emit(Store, variable.slot)
+	case *ast.CallStmt:
+		if c.call(s.Call) {
+			c.emit(Pop, 0)
+		}
+	case *ast.Return:
+		if s.Value == nil {
+			c.emit(ReturnVoid, 0)
+			return
+		}
+		c.expr(s.Value)
+		c.convert(c.returnType)
+		c.emit(Return, 0)
+	case *ast.If:
+		c.expr(s.Cond)
+		toElse := c.emit(JumpIfFalse, 0)
+		c.block(s.Then)
+		if s.Else == nil {
+			c.patch(toElse)
+			return
+		}
+		toEnd := c.emit(Jump, 0)
+		c.patch(toElse)
+		c.block(s.Else)
+		c.patch(toEnd)
+	case *ast.While:
+		start := len(c.target.Code)
+		c.expr(s.Cond)
+		toEnd := c.emit(JumpIfFalse, 0)
+		c.block(s.Body)
+		c.emit(Jump, start)
+		c.patch(toEnd)
+	}
+}
+
+func (c *compiler) expr(expr ast.Expr) {
+	switch e := expr.(type) {
+	case *ast.IntLit:
+		c.emit(Const, c.constant(e.Value))
+	case *ast.DoubleLit:
+		c.emit(Const, c.constant(e.Value))
+	case *ast.BoolLit:
+		c.emit(Const, c.constant(e.Value))
+	case *ast.StringLit:
+		c.emit(Const, c.constant(e.Value))
+	case *ast.InterpolatedString:
+		for _, part := range e.Parts {
+			if part.Expr == nil {
+				c.emit(Const, c.constant(part.Text))
+			} else {
+				c.expr(part.Expr)
+			}
+		}
+		c.emit(Concat, len(e.Parts))
+	case *ast.Ident:
+		c.emit(Load, c.lookup(e.Name).slot)
+	case *ast.Unary:
+		c.expr(e.Operand)
+		c.emit(Unary, c.operator(e.Op))
+	case *ast.Binary:
+		c.expr(e.Left)
+		switch e.Op {
+		case "&&", "||":
+			op := JumpIfFalseOrPop
+			if e.Op == "||" {
+				op = JumpIfTrueOrPop
+			}
+			toEnd := c.emit(op, 0)
+			c.expr(e.Right)
+			c.patch(toEnd)
+		default:
+			c.expr(e.Right)
+			c.emit(Binary, c.operator(e.Op))
+		}
+	case *ast.Call:
+		if !c.call(e) {
+			panic(compileError{"The void function " + e.FullName() + " has no value, at line " + itoa(c.line)})
+		}
+	}
+}
+
+// Returns whether the call leaves a value on the stack
+func (c *compiler) call(call *ast.Call) bool {
+	// Program.Fib() is the same as Fib()
+	if call.Receiver == "" || call.Receiver == c.class {
+		if index, ok := c.functions[call.Name]; ok {
+			c.args(call.Args, nil)
+			c.emit(Call, index)
+			return !c.program.Functions[index].Void
+		}
+		if extern, ok := c.externs[call.Name]; ok {
+			c.args(call.Args, extern.Params)
+			c.emitHost(call.Name, len(call.Args))
+			c.convert(extern.ReturnType)
+			return extern.ReturnType != "void"
+		}
+	}
+	c.args(call.Args, nil)
+	c.emitHost(call.FullName(), len(call.Args))
+	// Builtins return nothing, but the value is still pushed
+	return true
+}
+
+// Host functions get their arguments converted to the parameter types, program functions convert them themselves
+func (c *compiler) args(args []ast.Expr, params []*ast.Param) {
+	for i, arg := range args {
+		c.expr(arg)
+		if i < len(params) {
+			c.convert(params[i].Type)
+		}
+	}
+}
+
+func (c *compiler) emitHost(name string, args int) {
+	for i, host := range c.program.Hosts {
+		if host == name {
+			c.emit2(CallHost, i, args)
+			return
+		}
+	}
+	c.program.Hosts = append(c.program.Hosts, name)
+	c.emit2(CallHost, len(c.program.Hosts)-1, args)
+}
+
+func (c *compiler) convert(typ string) {
+	if typ == "double" {
+		c.emit(ToDouble, 0)
+	}
+}
+
+func (c *compiler) declare(name string, typ string) int {
+	slot := c.target.Locals
+	c.target.Locals++
+	c.scopes[len(c.scopes)-1][name] = local{slot: slot, typ: typ}
+	return slot
+}
+
+func (c *compiler) lookup(name string) local {
+	for i := len(c.scopes) - 1; i >= 0; i-- {
+		if variable, ok := c.scopes[i][name]; ok {
+			return variable
+		}
+	}
+	panic(compileError{"Variable " + name + " is not declared, at line " + itoa(c.line)})
+}
+
+func (c *compiler) constant(value interp.Value) int {
+	if index, ok := c.constants[value]; ok {
+		return index
+	}
+	c.program.Constants = append(c.program.Constants, value)
+	c.constants[value] = len(c.program.Constants) - 1
+	return len(c.program.Constants) - 1
+}
+
+func (c *compiler) operator(op string) int {
+	for i, operator := range c.program.Operators {
+		if operator == op {
+			return i
+		}
+	}
+	c.program.Operators = append(c.program.Operators, op)
+	return len(c.program.Operators) - 1
+}
+
+// Returns the index of the instruction, for patch
+func (c *compiler) emit(op Opcode, a int) int {
+	return c.emit2(op, a, 0)
+}
+
+func (c *compiler) emit2(op Opcode, a int, b int) int {
+	c.target.Code = append(c.target.Code, Instruction{Op: op, A: a, B: b})
+	c.target.Lines = append(c.target.Lines, c.line)
+	return len(c.target.Code) - 1
+}
+
+// Makes the jump at index go to the next instruction
+func (c *compiler) patch(index int) {
+	c.target.Code[index].A = len(c.target.Code)
+}
@@ -0,0 +1,58 @@
+package vm
+
+import (
+	"compiler/interp"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Readable listing of the bytecode, one instruction per line with its source line and operand
+func (program *Program) Disassemble() string {
+	var text strings.Builder
+	for i, function := range program.Functions {
+		if i > 0 {
+			text.WriteString("\n")
+		}
+		fmt.Fprintf(&text, "%s: %d params, %d locals\n", function.Name, function.Params, function.Locals)
+		for pc, instruction := range function.Code {
+			line := fmt.Sprintf("  %04d  line %-4d %-16s %s", pc, function.Lines[pc], instruction.Op, program.operand(instruction))
+			text.WriteString(strings.TrimRight(line, " ") + "\n")
+		}
+	}
+	return text.String()
+}
+
+func (program *Program) operand(instruction Instruction) string {
+	switch instruction.Op {
+	case Const:
+		value := program.Constants[instruction.A]
+		switch v := value.(type) {
+		case string:
+			return strconv.Quote(v)
+		case float64:
+			// 2.0 and 2 are different constants
+			if !strings.ContainsAny(interp.Format(v), ".∞") {
+				return interp.Format(v) + ".0"
+			}
+		}
+		return interp.Format(value)
+	case Load, Store:
+		return "$" + itoa(instruction.A)
+	case Unary, Binary:
+		return program.Operators[instruction.A]
+	case Jump, JumpIfFalse, JumpIfFalseOrPop, JumpIfTrueOrPop:
+		return "-> " + fmt.Sprintf("%04d", instruction.A)
+	case Concat:
+		return itoa(instruction.A)
+	case Call:
+		return program.Functions[instruction.A].Name
+	case CallHost:
+		return program.Hosts[instruction.A] + " " + itoa(instruction.B)
+	}
+	return ""
+}
+
+func itoa(i int) string {
+	return strconv.Itoa(i)
+}
@@ -0,0 +1,206 @@
+package vm
+
+import (
+	"compiler/diag"
+	"compiler/interp"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Runs bytecode with the same values, builtins and runtime errors as the interpreter in package interp
+type VM struct {
+	program  *Program
+	out      io.Writer
+	builtins map[string]interp.Builtin
+	externs  map[string]interp.Builtin
+
+	stack  []interp.Value
+	frames []frame
+}
+
+type frame struct {
+	function *Function
+	pc       int
+	// Index of the first local variable on the stack
+	base int
+}
+
+// Same limit as the interpreter
+const maxCallDepth = 10000
+
+func New(program *Program, out io.Writer) *VM {
+	newVM := new(VM)
+	newVM.program = program
+	newVM.out = out
+	newVM.builtins = interp.Builtins()
+	newVM.externs = interp.HostFunctions()
+	return newVM
+}
+
+// Binds a host function to the extern function with the name, replacing the default one
+func (vm *VM) Bind(name string, host interp.Builtin) {
+	vm.externs[name] = host
+}
+
+// Runs Main (or main) with the arguments
+func (vm *VM) Run(args []string) error {
+	for _, name := range vm.program.Externs {
+		if _, ok := vm.externs[name]; !ok {
+			return runtimeError("Extern function " + name + " is not bound to a host function")
+		}
+	}
+	main := vm.program.Functions[vm.program.Main]
+	// Main can be declared without the parameter for the arguments
+	if main.Params > 1 {
+		return runtimeError("Function " + main.Name + " takes " + itoa(main.Params) + " arguments, but got 1")
+	}
+	hosts := make([]interp.Builtin, len(vm.program.Hosts))
+	for i, name := range vm.program.Hosts {
+		if slices.Contains(vm.program.Externs, name) {
+			hosts[i] = vm.externs[name]
+		} else {
+			hosts[i] = vm.builtins[name]
+		}
+	}
+
+	vm.stack = []interp.Value{}
+	if main.Params == 1 {
+		vm.stack = append(vm.stack, args)
+	}
+	vm.frames = nil
+	vm.enter(main)
+	return vm.execute(hosts)
+}
+
+// The arguments are already on the stack
+func (vm *VM) enter(function *Function) {
+	base := len(vm.stack) - function.Params
+	for i := function.Params; i < function.Locals; i++ {
+		vm.stack = append(vm.stack, nil)
+	}
+	vm.frames = append(vm.frames, frame{function: function, base: base})
+}
+
+func (vm *VM) execute(hosts []interp.Builtin) error {
+	program := vm.program
+	for {
+		f := &vm.frames[len(vm.frames)-1]
+		instruction := f.function.Code[f.pc]
+		f.pc++
+
+		switch instruction.Op {
+		case Const:
+			vm.push(program.Constants[instruction.A])
+		case Load:
+			vm.push(vm.stack[f.base+instruction.A])
+		case Store:
+			vm.stack[f.base+instruction.A] = vm.pop()
+		case Pop:
+			vm.pop()
+		case Unary:
+			value, err := interp.UnaryOperation(program.Operators[instruction.A], vm.pop())
+			if err != nil {
+				return err
+			}
+			vm.push(value)
+		case ToDouble:
+			if i, ok := vm.top().(int); ok {
+				vm.stack[len(vm.stack)-1] = float64(i)
+			}
+		case Binary:
+			// The operators of the interpreter, int results are wrapped to 32 bits there
+			right := vm.pop()
+			value, err := interp.BinaryOperation(program.Operators[instruction.A], vm.pop(), right)
+			if err != nil {
+				return err
+			}
+			vm.push(value)
+		case Jump:
+			f.pc = instruction.A
+		case JumpIfFalse:
+			value := vm.pop()
+			b, ok := value.(bool)
+			if !ok {
+				return runtimeError("Condition is not a bool: " + interp.Format(value))
+			}
+			if !b {
+				f.pc = instruction.A
+			}
+		case JumpIfFalseOrPop, JumpIfTrueOrPop:
+			op := "&&"
+			if instruction.Op == JumpIfTrueOrPop {
+				op = "||"
+			}
+			b, ok := vm.top().(bool)
+			if !ok {
+				return runtimeError("Operator " + op + " needs bools, got " + interp.Format(vm.top()))
+			}
+			if b == (instruction.Op == JumpIfTrueOrPop) {
+				f.pc = instruction.A
+			} else {
+				vm.pop()
+			}
+		case Concat:
+			var text strings.Builder
+			for _, value := range vm.stack[len(vm.stack)-instruction.A:] {
+				text.WriteString(interp.Format(value))
+			}
+			vm.stack = vm.stack[:len(vm.stack)-instruction.A]
+			vm.push(text.String())
+		case Call:
+			function := program.Functions[instruction.A]
+			if len(vm.frames) >= maxCallDepth {
+				return runtimeError("Stack overflow in function " + function.Name + ", the recursion does not seem to end")
+			}
+			vm.enter(function)
+		case CallHost:
+			host := hosts[instruction.A]
+			if host == nil {
+				return runtimeError("Function " + program.Hosts[instruction.A] + " does not exist")
+			}
+			args := slices.Clone(vm.stack[len(vm.stack)-instruction.B:])
+			vm.stack = vm.stack[:len(vm.stack)-instruction.B]
+			value, err := host(vm.out, args)
+			if err != nil {
+				return err
+			}
+			vm.push(value)
+		case Return, ReturnVoid:
+			var value interp.Value
+			if instruction.Op == Return {
+				value = vm.pop()
+			}
+			vm.stack = vm.stack[:f.base]
+			vm.frames = vm.frames[:len(vm.frames)-1]
+			if len(vm.frames) == 0 {
+				return nil
+			}
+			// Calls of void functions leave nothing on the stack
+			if instruction.Op == Return {
+				vm.push(value)
+			}
+		case MissingReturn:
+			return runtimeError("Function " + f.function.Name + " ended without returning a value")
+		}
+	}
+}
+
+func (vm *VM) push(value interp.Value) {
+	vm.stack = append(vm.stack, value)
+}
+
+func (vm *VM) pop() interp.Value {
+	value := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+	return value
+}
+
+func (vm *VM) top() interp.Value {
+	return vm.stack[len(vm.stack)-1]
+}
+
+func runtimeError(message string) error {
+	return errors.New(diag.RuntimeError.Format("Runtime Error: " + message))
+}
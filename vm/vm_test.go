@@ -0,0 +1,160 @@
+package vm_test
+
+import (
+	"compiler/amd64"
+	"compiler/ast"
+	"compiler/interp"
+	"compiler/lexer"
+	"compiler/parser"
+	"compiler/symtab"
+	"compiler/vm"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The values are in variables, constant expressions which overflow are errors of the type checker
+const overflow = `using System;
+
+namespace Overflow {
+    class Program {
+        static int Twice(int a) {
+            return a * 2;
+        }
+
+        static void Main(string[] args) {
+            int max = 2147483647;
+            int min = -2147483648;
+            Console.WriteLine(max + 1);
+            Console.WriteLine(Twice(max));
+            Console.WriteLine(min - 1);
+            Console.WriteLine(-min);
+            Console.WriteLine(max * max);
+            Console.WriteLine(65536 * max);
+            Console.WriteLine(min / 2);
+            Console.WriteLine(max);
+        }
+    }
+}
+`
+
+func program(t *testing.T, source string) *ast.Program {
+	t.Helper()
+	tree, ok, err := parser.ParseSource(context.Background(), source, true, lexer.Options{Output: io.Discard})
+	if !ok {
+		t.Fatal("parse failed", err)
+	}
+	program, err := ast.Build(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diagnostics := symtab.Bind(program); len(diagnostics) > 0 {
+		t.Fatal(diagnostics[0])
+	}
+	return program
+}
+
+func runInterpreter(t *testing.T, source string) string {
+	var out strings.Builder
+	if err := interp.New(program(t, source), &out).Run(nil); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func runVM(t *testing.T, source string) string {
+	bytecode, err := vm.Compile(program(t, source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if err := vm.New(bytecode, &out).Run(nil); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+// Builds the assembly with gcc and runs it, skips the test without gcc
+func runAmd64(t *testing.T, source string) string {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc is not installed")
+	}
+	text, err := amd64.Generate(program(t, source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	assembly := filepath.Join(dir, "program.s")
+	binary := filepath.Join(dir, "program")
+	if err := os.WriteFile(assembly, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("gcc", assembly, "-o", binary, "-lm").CombinedOutput(); err != nil {
+		t.Fatal(string(out))
+	}
+	out, err := exec.Command(binary).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestIntOverflowIsTheSameOnAllBackends(t *testing.T) {
+	want := "-2147483648\n-2\n2147483647\n-2147483648\n1\n-65536\n-1073741824\n2147483647\n"
+	if got := runInterpreter(t, overflow); got != want {
+		t.Errorf("interpreter:\n%s\nwant:\n%s", got, want)
+	}
+	if got := runVM(t, overflow); got != want {
+		t.Errorf("VM:\n%s\nwant:\n%s", got, want)
+	}
+	if got := runAmd64(t, overflow); got != want {
+		t.Errorf("amd64:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestMainWithoutParameter(t *testing.T) {
+	source := `using System;
+
+namespace NoArgs {
+    class Program {
+        static int Twice(int a) {
+            return a * 2;
+        }
+
+        static void Main() {
+            Console.WriteLine(Twice(21));
+        }
+    }
+}
+`
+	if got := runVM(t, source); got != "42\n" {
+		t.Errorf("got %q", got)
+	}
+	// Saved and read again, like with -save
+	bytecode, err := vm.Compile(program(t, source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := bytecode.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytecode, err = vm.Unmarshal(data); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if err := vm.New(bytecode, &out).Run(nil); err != nil || out.String() != "42\n" {
+		t.Errorf("got %q, %v", out.String(), err)
+	}
+}
+
+func TestMarshalUnknownConstant(t *testing.T) {
+	bytecode := &vm.Program{Constants: []interp.Value{1, []string{"args"}}, Functions: []*vm.Function{{Name: "Main"}}}
+	if _, err := bytecode.MarshalBinary(); err == nil {
+		t.Error("no error for a constant which can not be stored")
+	}
+}
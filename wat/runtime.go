@@ -0,0 +1,290 @@
+package wat
+
+// Runtime functions every module contains. Strings are pointers to a 4 byte length followed by the UTF-8 bytes.
+// $alloc never frees, programs are short lived
+const runtime = `
+  (func $alloc (param $size i32) (result i32)
+    (local $ptr i32)
+    global.get $heap
+    local.set $ptr
+    ;; Keep the heap aligned to 4 bytes
+    global.get $heap
+    local.get $size
+    i32.add
+    i32.const 3
+    i32.add
+    i32.const -4
+    i32.and
+    global.set $heap
+    block $enough
+      global.get $heap
+      memory.size
+      i32.const 16
+      i32.shl
+      i32.le_u
+      br_if $enough
+      global.get $heap
+      memory.size
+      i32.const 16
+      i32.shl
+      i32.sub
+      i32.const 16
+      i32.shr_u
+      i32.const 1
+      i32.add
+      memory.grow
+      i32.const -1
+      i32.ne
+      br_if $enough
+      unreachable
+    end
+    local.get $ptr)
+
+  (func $concat (param $a i32) (param $b i32) (result i32)
+    (local $result i32)
+    local.get $a
+    i32.load
+    local.get $b
+    i32.load
+    i32.add
+    call $newString
+    local.set $result
+    local.get $result
+    i32.const 4
+    i32.add
+    local.get $a
+    i32.const 4
+    i32.add
+    local.get $a
+    i32.load
+    memory.copy
+    local.get $result
+    i32.const 4
+    i32.add
+    local.get $a
+    i32.load
+    i32.add
+    local.get $b
+    i32.const 4
+    i32.add
+    local.get $b
+    i32.load
+    memory.copy
+    local.get $result)
+
+  ;; Division by zero is a runtime error, int.MinValue / -1 wraps around
+  (func $divide (param $a i32) (param $b i32) (result i32)
+    local.get $b
+    call $checkDivisor
+    local.get $b
+    i32.const -1
+    i32.eq
+    if
+      i32.const 0
+      local.get $a
+      i32.sub
+      return
+    end
+    local.get $a
+    local.get $b
+    i32.div_s)
+
+  ;; x % -1 is 0
+  (func $remainder (param $a i32) (param $b i32) (result i32)
+    local.get $b
+    call $checkDivisor
+    local.get $b
+    i32.const -1
+    i32.eq
+    if
+      i32.const 0
+      return
+    end
+    local.get $a
+    local.get $b
+    i32.rem_s)
+
+  (func $checkDivisor (param $b i32)
+    local.get $b
+    i32.eqz
+    if
+      global.get $divisionByZero
+      i32.const 4
+      i32.add
+      global.get $divisionByZero
+      i32.load
+      call $error
+      unreachable
+    end)
+
+  ;; Allocates a string of the length, the bytes are not set
+  (func $newString (param $length i32) (result i32)
+    (local $ptr i32)
+    local.get $length
+    i32.const 4
+    i32.add
+    call $alloc
+    local.set $ptr
+    local.get $ptr
+    local.get $length
+    i32.store
+    local.get $ptr)
+
+  (func $stringEquals (param $a i32) (param $b i32) (result i32)
+    (local $i i32)
+    local.get $a
+    i32.load
+    local.get $b
+    i32.load
+    i32.ne
+    if
+      i32.const 0
+      return
+    end
+    block $done
+      loop $next
+        local.get $i
+        local.get $a
+        i32.load
+        i32.ge_u
+        br_if $done
+        local.get $a
+        local.get $i
+        i32.add
+        i32.load8_u offset=4
+        local.get $b
+        local.get $i
+        i32.add
+        i32.load8_u offset=4
+        i32.ne
+        if
+          i32.const 0
+          return
+        end
+        local.get $i
+        i32.const 1
+        i32.add
+        local.set $i
+        br $next
+      end
+    end
+    i32.const 1)
+
+  (func $intToString (param $value i32) (result i32)
+    (local $abs i64)
+    (local $end i32)
+    (local $pos i32)
+    (local $result i32)
+    ;; Digits are written backwards into the scratch buffer, i64 so -2147483648 has an absolute value
+    local.get $value
+    i64.extend_i32_s
+    local.set $abs
+    local.get $value
+    i32.const 0
+    i32.lt_s
+    if
+      i64.const 0
+      local.get $abs
+      i64.sub
+      local.set $abs
+    end
+    i32.const 48
+    local.tee $end
+    local.set $pos
+    loop $digit
+      local.get $pos
+      i32.const 1
+      i32.sub
+      local.tee $pos
+      local.get $abs
+      i64.const 10
+      i64.rem_u
+      i32.wrap_i64
+      i32.const 48
+      i32.add
+      i32.store8
+      local.get $abs
+      i64.const 10
+      i64.div_u
+      local.tee $abs
+      i64.const 0
+      i64.ne
+      br_if $digit
+    end
+    local.get $value
+    i32.const 0
+    i32.lt_s
+    if
+      local.get $pos
+      i32.const 1
+      i32.sub
+      local.tee $pos
+      i32.const 45
+      i32.store8
+    end
+    local.get $end
+    local.get $pos
+    i32.sub
+    call $newString
+    local.set $result
+    local.get $result
+    i32.const 4
+    i32.add
+    local.get $pos
+    local.get $end
+    local.get $pos
+    i32.sub
+    memory.copy
+    local.get $result)
+
+  (func $doubleToString (param $value f64) (result i32)
+    (local $length i32)
+    (local $result i32)
+    local.get $value
+    i32.const 16
+    call $formatDouble
+    local.set $length
+    local.get $length
+    call $newString
+    local.set $result
+    local.get $result
+    i32.const 4
+    i32.add
+    i32.const 16
+    local.get $length
+    memory.copy
+    local.get $result)
+
+  (func $boolToString (param $value i32) (result i32)
+    global.get $true
+    global.get $false
+    local.get $value
+    select)
+
+  ;; Remainder of doubles like C# %, wasm has no instruction for it
+  (func $fmod (param $a f64) (param $b f64) (result f64)
+    local.get $a
+    local.get $a
+    local.get $b
+    f64.div
+    f64.trunc
+    local.get $b
+    f64.mul
+    f64.sub)
+
+  (func $print (param $string i32) (param $newline i32)
+    local.get $string
+    i32.const 4
+    i32.add
+    local.get $string
+    i32.load
+    call $write
+    local.get $newline
+    if
+      global.get $newlineString
+      i32.const 4
+      i32.add
+      i32.const 1
+      call $write
+    end)
+`
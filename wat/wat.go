@@ -0,0 +1,566 @@
+package wat
+
+import (
+	"compiler/ast"
+	"compiler/diag"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/*
+WebAssembly text format (WAT) of a checked program. The output can be turned into a module with
+wat2wasm (it needs bulk memory, on by default) and checked with wasm-validate.
+
+Types: int -> i32, double -> f64, bool -> i32, string -> i32 pointer. string[] is only the argument of
+Main, it is passed as 0. Strings live in the exported linear memory:
+
+	0 - 15    unused, so no string has the address 0
+	16 - 47   scratch buffer for number formatting
+	48 -      string literals as data segments: 4 byte little endian length, then the UTF-8 bytes
+	after     the heap, strings built at runtime (concatenation, formatting)
+
+The module imports from "env":
+
+	write(ptr i32, len i32)                  writes the bytes to the output
+	formatDouble(value f64, ptr i32) -> i32  writes the double like C# ToString() to ptr (at most 32 bytes), returns the length
+	error(ptr i32, len i32)                  reports the runtime error, like division by zero, and stops the program
+	and every extern function of the program, with its own name
+
+and exports "memory" and "main", which runs Main.
+*/
+
+// First address after the scratch buffer
+const dataStart = 48
+
+// The message of the interpreter
+var divisionByZero = diag.RuntimeError.Format("Runtime Error: Division by zero")
+
+func Generate(program *ast.Program) (string, error) {
+	g := &generator{program: program, functions: make(map[string]*ast.Function), strings: make(map[string]int), next: dataStart}
+	for _, function := range program.Functions {
+		g.functions[function.Name] = function
+	}
+	main := g.functions["Main"]
+	if main == nil {
+		main = g.functions["main"]
+	}
+	if main == nil || main.Extern {
+		return "", errors.New("WAT Error: No Main function found")
+	}
+
+	// Constants the runtime needs
+	g.stringLiteral("True")
+	g.stringLiteral("False")
+	g.stringLiteral("\n")
+	g.stringLiteral("")
+	g.stringLiteral(divisionByZero)
+
+	var functions strings.Builder
+	for _, function := range program.Functions {
+		if function.Extern {
+			continue
+		}
+		code, err := g.generateFunction(function)
+		if err != nil {
+			return "", err
+		}
+		functions.WriteString(code)
+	}
+
+	var module strings.Builder
+	module.WriteString("(module\n")
+	module.WriteString("  (import \"env\" \"write\" (func $write (param i32 i32)))\n")
+	module.WriteString("  (import \"env\" \"formatDouble\" (func $formatDouble (param f64 i32) (result i32)))\n")
+	module.WriteString("  (import \"env\" \"error\" (func $error (param i32 i32)))\n")
+	for _, function := range program.Functions {
+		if function.Extern {
+			module.WriteString("  (import \"env\" \"" + escape(function.Name) + "\" (func $" + identifier(function.Name) + signature(function) + "))\n")
+		}
+	}
+	module.WriteString("  (memory (export \"memory\") 1)\n")
+	for _, literal := range g.literals {
+		module.WriteString("  (data (i32.const " + strconv.Itoa(g.strings[literal]) + ") \"" + dataString(literal) + "\")\n")
+	}
+	module.WriteString("  (global $heap (mut i32) (i32.const " + strconv.Itoa(align(g.next)) + "))\n")
+	module.WriteString("  (global $true i32 (i32.const " + strconv.Itoa(g.strings["True"]) + "))\n")
+	module.WriteString("  (global $false i32 (i32.const " + strconv.Itoa(g.strings["False"]) + "))\n")
+	module.WriteString("  (global $newlineString i32 (i32.const " + strconv.Itoa(g.strings["\n"]) + "))\n")
+	module.WriteString("  (global $divisionByZero i32 (i32.const " + strconv.Itoa(g.strings[divisionByZero]) + "))\n")
+	module.WriteString(runtime)
+	module.WriteString(functions.String())
+	// Main gets no arguments from the host
+	module.WriteString("\n  (func (export \"main\")\n")
+	if len(main.Params) == 1 {
+		module.WriteString("    i32.const 0\n")
+	}
+	module.WriteString("    call $" + identifier(main.Name))
+	if main.ReturnType != "void" {
+		module.WriteString("\n    drop")
+	}
+	module.WriteString(")\n)\n")
+	return module.String(), nil
+}
+
+type generator struct {
+	program   *ast.Program
+	functions map[string]*ast.Function
+	// Address of every string literal, in the order they were found
+	strings  map[string]int
+	literals []string
+	next     int
+
+	// Of the function being generated
+	function *ast.Function
+	scopes   []map[string]local
+	locals   []local
+	labels   int
+	code     strings.Builder
+	depth    int
+}
+
+type local struct {
+	name string
+	typ  string
+}
+
+// Unsupported programs panic with a generateError, only possible if they did not pass the checks
+type generateError struct {
+	message string
+}
+
+func (g *generator) generateFunction(function *ast.Function) (code string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			generateErr, ok := r.(generateError)
+			if !ok {
+				panic(r)
+			}
+			err = errors.New("WAT Error: " + generateErr.message)
+		}
+	}()
+
+	g.function = function
+	g.scopes = []map[string]local{{}}
+	g.locals = nil
+	g.labels = 0
+	g.code.Reset()
+	g.depth = 2
+
+	params := []string{}
+	for _, param := range function.Params {
+		variable := g.declare(param.Name, param.Type)
+		params = append(params, "(param $"+variable.name+" "+valueType(param.Type)+")")
+	}
+	paramCount := len(g.locals)
+	g.block(function.Body)
+	if function.ReturnType != "void" {
+		// Like the interpreter: a function which should return a value must not end
+		g.emit("unreachable")
+	}
+
+	header := "\n  (func $" + identifier(function.Name)
+	if len(params) > 0 {
+		header += " " + strings.Join(params, " ")
+	}
+	if function.ReturnType != "void" {
+		header += " (result " + valueType(function.ReturnType) + ")"
+	}
+	header += "\n"
+	for _, variable := range g.locals[paramCount:] {
+		header += "    (local $" + variable.name + " " + valueType(variable.typ) + ")\n"
+	}
+	return header + strings.TrimSuffix(g.code.String(), "\n") + ")\n", nil
+}
+
+func (g *generator) block(block *ast.Block) {
+	g.scopes = append(g.scopes, map[string]local{})
+	for _, statement := range block.Statements {
+		g.statement(statement)
+	}
+	g.scopes = g.scopes[:len(g.scopes)-1]
+}
+
+func (g *generator) statement(statement ast.Stmt) {
+	switch s := statement.(type) {
+	case *ast.VarDecl:
+		if s.Value == nil {
+			g.zero(s.Type)
+		} else {
+			g.convert(g.expr(s.Value), s.Type)
+		}
+		g.emit("local.set $" + g.declare(s.Name, s.Type).name)
+	case *ast.Assign:
+		variable := g.lookup(s.Name)
+		g.convert(g.expr(s.Value), variable.typ)
+		g.emit("local.set $" + variable.name)
+	case *ast.CallStmt:
+		if g.call(s.Call) != "void" {
+			g.emit("drop")
+		}
+	case *ast.Return:
+		if s.Value != nil {
+			g.convert(g.expr(s.Value), g.function.ReturnType)
+		}
+		g.emit("return")
+	case *ast.If:
+		g.condition(s.Cond)
+		g.emit("if")
+		g.nested(func() { g.block(s.Then) })
+		if s.Else != nil {
+			g.emit("else")
+			g.nested(func() { g.block(s.Else) })
+		}
+		g.emit("end")
+	case *ast.While:
+		g.labels++
+		label := strconv.Itoa(g.labels)
+		g.emit("block $break" + label)
+		g.nested(func() {
+			g.emit("loop $continue" + label)
+			g.nested(func() {
+				g.condition(s.Cond)
+				g.emit("i32.eqz")
+				g.emit("br_if $break" + label)
+				g.block(s.Body)
+				g.emit("br $continue" + label)
+			})
+			g.emit("end")
+		})
+		g.emit("end")
+	}
+}
+
+func (g *generator) condition(cond ast.Expr) {
+	if typ := g.expr(cond); typ != "bool" {
+		panic(generateError{"Condition is not a bool, at line " + strconv.Itoa(cond.Pos().Line)})
+	}
+}
+
+// Emits the expression and returns its type
+func (g *generator) expr(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.IntLit:
+		g.emit("i32.const " + strconv.Itoa(e.Value))
+		return "int"
+	case *ast.DoubleLit:
+		g.emit("f64.const " + formatDouble(e.Value))
+		return "double"
+	case *ast.BoolLit:
+		if e.Value {
+			g.emit("i32.const 1")
+		} else {
+			g.emit("i32.const 0")
+		}
+		return "bool"
+	case *ast.StringLit:
+		g.emit("i32.const " + strconv.Itoa(g.stringLiteral(e.Value)))
+		return "string"
+	case *ast.InterpolatedString:
+		g.emit("i32.const " + strconv.Itoa(g.stringLiteral("")))
+		for _, part := range e.Parts {
+			if part.Expr == nil {
+				g.emit("i32.const " + strconv.Itoa(g.stringLiteral(part.Text)))
+			} else {
+				g.toString(g.expr(part.Expr))
+			}
+			g.emit("call $concat")
+		}
+		return "string"
+	case *ast.Ident:
+		variable := g.lookup(e.Name)
+		g.emit("local.get $" + variable.name)
+		return variable.typ
+	case *ast.Unary:
+		if e.Op != "-" {
+			return g.expr(e.Operand)
+		}
+		if typ := g.typeOf(e.Operand); typ == "double" {
+			g.expr(e.Operand)
+			g.emit("f64.neg")
+			return typ
+		}
+		g.emit("i32.const 0")
+		g.expr(e.Operand)
+		g.emit("i32.sub")
+		return "int"
+	case *ast.Binary:
+		return g.binary(e)
+	case *ast.Call:
+		typ := g.call(e)
+		if typ == "void" {
+			panic(generateError{"The void function " + e.FullName() + " has no value, at line " + strconv.Itoa(e.Pos().Line)})
+		}
+		return typ
+	}
+	panic(generateError{"Unknown expression at line " + strconv.Itoa(expr.Pos().Line)})
+}
+
+// div_s and rem_s trap on 0 and on int.MinValue / -1, $divide and $remainder behave like the interpreter
+var intInstructions = map[string]string{"+": "i32.add", "-": "i32.sub", "*": "i32.mul", "/": "call $divide", "%": "call $remainder",
+	"<": "i32.lt_s", ">": "i32.gt_s", "<=": "i32.le_s", ">=": "i32.ge_s", "==": "i32.eq", "!=": "i32.ne"}
+
+var doubleInstructions = map[string]string{"+": "f64.add", "-": "f64.sub", "*": "f64.mul", "/": "f64.div", "%": "call $fmod",
+	"<": "f64.lt", ">": "f64.gt", "<=": "f64.le", ">=": "f64.ge", "==": "f64.eq", "!=": "f64.ne"}
+
+func (g *generator) binary(e *ast.Binary) string {
+	left, right := g.typeOf(e.Left), g.typeOf(e.Right)
+	switch {
+	case e.Op == "&&" || e.Op == "||":
+		// Short circuit: the right side is only evaluated if needed
+		g.condition(e.Left)
+		g.emit("if (result i32)")
+		g.nested(func() {
+			if e.Op == "&&" {
+				g.condition(e.Right)
+			} else {
+				g.emit("i32.const 1")
+			}
+		})
+		g.emit("else")
+		g.nested(func() {
+			if e.Op == "&&" {
+				g.emit("i32.const 0")
+			} else {
+				g.condition(e.Right)
+			}
+		})
+		g.emit("end")
+	case e.Op == "+" && (left == "string" || right == "string"):
+		g.toString(g.expr(e.Left))
+		g.toString(g.expr(e.Right))
+		g.emit("call $concat")
+	case (e.Op == "==" || e.Op == "!=") && left == "string":
+		g.expr(e.Left)
+		g.expr(e.Right)
+		g.emit("call $stringEquals")
+		if e.Op == "!=" {
+			g.emit("i32.eqz")
+		}
+	case left == "double" || right == "double":
+		g.convert(g.expr(e.Left), "double")
+		g.convert(g.expr(e.Right), "double")
+		g.emit(doubleInstructions[e.Op])
+	default:
+		g.expr(e.Left)
+		g.expr(e.Right)
+		g.emit(intInstructions[e.Op])
+	}
+	return g.typeOf(e)
+}
+
+// Returns the return type of the call
+func (g *generator) call(call *ast.Call) string {
+	if function, ok := g.functions[call.Name]; ok && (call.Receiver == "" || call.Receiver == g.program.Class) {
+		for i, arg := range call.Args {
+			g.convert(g.expr(arg), function.Params[i].Type)
+		}
+		g.emit("call $" + identifier(function.Name))
+		return function.ReturnType
+	}
+	switch call.FullName() {
+	case "Console.WriteLine", "Console.Write":
+		if len(call.Args) == 0 {
+			g.emit("i32.const " + strconv.Itoa(g.stringLiteral("")))
+		} else {
+			g.toString(g.expr(call.Args[0]))
+		}
+		if call.Name == "WriteLine" {
+			g.emit("i32.const 1")
+		} else {
+			g.emit("i32.const 0")
+		}
+		g.emit("call $print")
+		return "void"
+	}
+	panic(generateError{"Function " + call.FullName() + " does not exist, at line " + strconv.Itoa(call.Pos().Line)})
+}
+
+// Type of the expression without emitting anything, the same rules as in package types
+func (g *generator) typeOf(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.IntLit:
+		return "int"
+	case *ast.DoubleLit:
+		return "double"
+	case *ast.BoolLit:
+		return "bool"
+	case *ast.StringLit, *ast.InterpolatedString:
+		return "string"
+	case *ast.Ident:
+		return g.lookup(e.Name).typ
+	case *ast.Unary:
+		return g.typeOf(e.Operand)
+	case *ast.Binary:
+		switch e.Op {
+		case "&&", "||", "<", ">", "<=", ">=", "==", "!=":
+			return "bool"
+		}
+		left, right := g.typeOf(e.Left), g.typeOf(e.Right)
+		switch {
+		case e.Op == "+" && (left == "string" || right == "string"):
+			return "string"
+		case left == "double" || right == "double":
+			return "double"
+		}
+		return "int"
+	case *ast.Call:
+		if function, ok := g.functions[e.Name]; ok && (e.Receiver == "" || e.Receiver == g.program.Class) {
+			return function.ReturnType
+		}
+		return "void"
+	}
+	return "void"
+}
+
+func (g *generator) toString(typ string) {
+	switch typ {
+	case "int":
+		g.emit("call $intToString")
+	case "double":
+		g.emit("call $doubleToString")
+	case "bool":
+		g.emit("call $boolToString")
+	}
+}
+
+// The only implicit conversion: int to double
+func (g *generator) convert(from string, to string) {
+	if from == "int" && to == "double" {
+		g.emit("f64.convert_i32_s")
+	}
+}
+
+func (g *generator) zero(typ string) {
+	switch typ {
+	case "double":
+		g.emit("f64.const 0")
+	case "string":
+		g.emit("i32.const " + strconv.Itoa(g.stringLiteral("")))
+	default:
+		g.emit("i32.const 0")
+	}
+}
+
+// Every declaration gets its own local, x, x_1, x_2 ... for variables with the same name
+func (g *generator) declare(name string, typ string) local {
+	variable := local{name: identifier(name), typ: typ}
+	for i := 1; g.used(variable.name); i++ {
+		variable.name = identifier(name) + "_" + strconv.Itoa(i)
+	}
+	g.locals = append(g.locals, variable)
+	g.scopes[len(g.scopes)-1][name] = variable
+	return variable
+}
+
+func (g *generator) used(name string) bool {
+	for _, variable := range g.locals {
+		if variable.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *generator) lookup(name string) local {
+	for i := len(g.scopes) - 1; i >= 0; i-- {
+		if variable, ok := g.scopes[i][name]; ok {
+			return variable
+		}
+	}
+	panic(generateError{"Variable " + name + " is not declared"})
+}
+
+// Address of the literal in the data segments
+func (g *generator) stringLiteral(text string) int {
+	if address, ok := g.strings[text]; ok {
+		return address
+	}
+	address := align(g.next)
+	g.strings[text] = address
+	g.literals = append(g.literals, text)
+	g.next = address + 4 + len(text)
+	return address
+}
+
+func (g *generator) nested(body func()) {
+	g.depth++
+	body()
+	g.depth--
+}
+
+func (g *generator) emit(instruction string) {
+	g.code.WriteString(strings.Repeat("  ", g.depth) + instruction + "\n")
+}
+
+// Names of the source can contain letters WAT does not allow in identifiers, those are written as _uXXXX
+func identifier(name string) string {
+	var id strings.Builder
+	for _, c := range name {
+		if c < 0x80 && (c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			id.WriteRune(c)
+		} else {
+			fmt.Fprintf(&id, "_u%04X", c)
+		}
+	}
+	return id.String()
+}
+
+func signature(function *ast.Function) string {
+	text := ""
+	for _, param := range function.Params {
+		text += " (param " + valueType(param.Type) + ")"
+	}
+	if function.ReturnType != "void" {
+		text += " (result " + valueType(function.ReturnType) + ")"
+	}
+	return text
+}
+
+func valueType(typ string) string {
+	if typ == "double" {
+		return "f64"
+	}
+	return "i32"
+}
+
+func align(address int) int {
+	return (address + 3) &^ 3
+}
+
+func formatDouble(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "inf"
+	case math.IsInf(value, -1):
+		return "-inf"
+	case math.IsNaN(value):
+		return "nan"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// The length as 4 bytes little endian, then the bytes
+func dataString(text string) string {
+	var data strings.Builder
+	length := len(text)
+	for i := 0; i < 4; i++ {
+		fmt.Fprintf(&data, "\\%02x", byte(length>>(8*i)))
+	}
+	return data.String() + escape(text)
+}
+
+// Content of a WAT string, bytes which are not printable ASCII are escaped as \hh
+func escape(text string) string {
+	var data strings.Builder
+	for i := 0; i < len(text); i++ {
+		if c := text[i]; c >= 0x20 && c < 0x7f && c != '"' && c != '\\' {
+			data.WriteByte(c)
+		} else {
+			fmt.Fprintf(&data, "\\%02x", c)
+		}
+	}
+	return data.String()
+}